@@ -242,6 +242,31 @@ func (src *MachineHealthCheck) ConvertTo(dstRaw conversion.Hub) error {
 	if restored.Spec.UnhealthyRange != nil {
 		dst.Spec.UnhealthyRange = restored.Spec.UnhealthyRange
 	}
+	dst.Spec.UnreachableTaintTimeout = restored.Spec.UnreachableTaintTimeout
+	dst.Spec.MaxInFlightRemediations = restored.Spec.MaxInFlightRemediations
+	dst.Spec.RemediationDisabled = restored.Spec.RemediationDisabled
+	dst.Spec.ExcludedNodeRoles = restored.Spec.ExcludedNodeRoles
+	dst.Spec.PreserveQuorum = restored.Spec.PreserveQuorum
+	dst.Spec.RemediationGracePeriodAfterClusterCreation = restored.Spec.RemediationGracePeriodAfterClusterCreation
+	dst.Spec.MaxUnhealthyBase = restored.Spec.MaxUnhealthyBase
+	dst.Spec.RespectPodDisruptionBudgets = restored.Spec.RespectPodDisruptionBudgets
+	dst.Spec.KubeletVersionMismatchTimeout = restored.Spec.KubeletVersionMismatchTimeout
+	dst.Spec.UnhealthyConditionsGracePeriodAfterNodeCreation = restored.Spec.UnhealthyConditionsGracePeriodAfterNodeCreation
+	dst.Spec.KubeletRestartGracePeriod = restored.Spec.KubeletRestartGracePeriod
+	dst.Spec.InfrastructureMissingTimeout = restored.Spec.InfrastructureMissingTimeout
+	dst.Spec.ScaleDownUnhealthyMachines = restored.Spec.ScaleDownUnhealthyMachines
+	dst.Spec.NodeMissingTimeout = restored.Spec.NodeMissingTimeout
+	dst.Spec.MaxUnhealthyFloor = restored.Spec.MaxUnhealthyFloor
+	dst.Spec.UnhealthyObservationThreshold = restored.Spec.UnhealthyObservationThreshold
+	dst.Spec.DrainBeforeRemediation = restored.Spec.DrainBeforeRemediation
+	dst.Spec.DrainBeforeRemediationTimeout = restored.Spec.DrainBeforeRemediationTimeout
+	dst.Spec.NodeLeaseStaleTimeout = restored.Spec.NodeLeaseStaleTimeout
+	dst.Spec.MaxConsecutiveFailedReplacements = restored.Spec.MaxConsecutiveFailedReplacements
+	dst.Status.RemediationHistory = restored.Status.RemediationHistory
+	dst.Status.UnhealthyObservations = restored.Status.UnhealthyObservations
+	dst.Status.ConsecutiveFailedReplacements = restored.Status.ConsecutiveFailedReplacements
+	dst.Status.TargetHealth = restored.Status.TargetHealth
+	dst.Status.NextRemediationTime = restored.Status.NextRemediationTime
 
 	return nil
 }
@@ -295,6 +320,10 @@ func Convert_v1beta1_MachineHealthCheckSpec_To_v1alpha3_MachineHealthCheckSpec(i
 	return autoConvert_v1beta1_MachineHealthCheckSpec_To_v1alpha3_MachineHealthCheckSpec(in, out, s)
 }
 
+func Convert_v1beta1_MachineHealthCheckStatus_To_v1alpha3_MachineHealthCheckStatus(in *clusterv1.MachineHealthCheckStatus, out *MachineHealthCheckStatus, s apiconversion.Scope) error {
+	return autoConvert_v1beta1_MachineHealthCheckStatus_To_v1alpha3_MachineHealthCheckStatus(in, out, s)
+}
+
 func Convert_v1alpha3_ClusterStatus_To_v1beta1_ClusterStatus(in *ClusterStatus, out *clusterv1.ClusterStatus, s apiconversion.Scope) error {
 	return autoConvert_v1alpha3_ClusterStatus_To_v1beta1_ClusterStatus(in, out, s)
 }
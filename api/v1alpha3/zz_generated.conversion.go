@@ -210,11 +210,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.MachineHealthCheckStatus)(nil), (*MachineHealthCheckStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_MachineHealthCheckStatus_To_v1alpha3_MachineHealthCheckStatus(a.(*v1beta1.MachineHealthCheckStatus), b.(*MachineHealthCheckStatus), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*MachineList)(nil), (*v1beta1.MachineList)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha3_MachineList_To_v1beta1_MachineList(a.(*MachineList), b.(*v1beta1.MachineList), scope)
 	}); err != nil {
@@ -340,6 +335,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta1.MachineHealthCheckStatus)(nil), (*MachineHealthCheckStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_MachineHealthCheckStatus_To_v1alpha3_MachineHealthCheckStatus(a.(*v1beta1.MachineHealthCheckStatus), b.(*MachineHealthCheckStatus), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1beta1.MachineRollingUpdateDeployment)(nil), (*MachineRollingUpdateDeployment)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta1_MachineRollingUpdateDeployment_To_v1alpha3_MachineRollingUpdateDeployment(a.(*v1beta1.MachineRollingUpdateDeployment), b.(*MachineRollingUpdateDeployment), scope)
 	}); err != nil {
@@ -958,9 +958,29 @@ func autoConvert_v1beta1_MachineHealthCheckSpec_To_v1alpha3_MachineHealthCheckSp
 	out.ClusterName = in.ClusterName
 	out.Selector = in.Selector
 	out.UnhealthyConditions = *(*[]UnhealthyCondition)(unsafe.Pointer(&in.UnhealthyConditions))
+	// WARNING: in.UnhealthyConditionsGracePeriodAfterNodeCreation requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubeletRestartGracePeriod requires manual conversion: does not exist in peer-type
 	out.MaxUnhealthy = (*intstr.IntOrString)(unsafe.Pointer(in.MaxUnhealthy))
+	// WARNING: in.MaxUnhealthyFloor requires manual conversion: does not exist in peer-type
+	// WARNING: in.UnhealthyObservationThreshold requires manual conversion: does not exist in peer-type
+	// WARNING: in.MaxUnhealthyBase requires manual conversion: does not exist in peer-type
 	// WARNING: in.UnhealthyRange requires manual conversion: does not exist in peer-type
 	out.NodeStartupTimeout = (*metav1.Duration)(unsafe.Pointer(in.NodeStartupTimeout))
+	// WARNING: in.NodeMissingTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.InfrastructureMissingTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.UnreachableTaintTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.NodeLeaseStaleTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubeletVersionMismatchTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.MaxInFlightRemediations requires manual conversion: does not exist in peer-type
+	// WARNING: in.MaxConsecutiveFailedReplacements requires manual conversion: does not exist in peer-type
+	// WARNING: in.RemediationDisabled requires manual conversion: does not exist in peer-type
+	// WARNING: in.RemediationGracePeriodAfterClusterCreation requires manual conversion: does not exist in peer-type
+	// WARNING: in.PreserveQuorum requires manual conversion: does not exist in peer-type
+	// WARNING: in.ExcludedNodeRoles requires manual conversion: does not exist in peer-type
+	// WARNING: in.RespectPodDisruptionBudgets requires manual conversion: does not exist in peer-type
+	// WARNING: in.ScaleDownUnhealthyMachines requires manual conversion: does not exist in peer-type
+	// WARNING: in.DrainBeforeRemediation requires manual conversion: does not exist in peer-type
+	// WARNING: in.DrainBeforeRemediationTimeout requires manual conversion: does not exist in peer-type
 	out.RemediationTemplate = (*v1.ObjectReference)(unsafe.Pointer(in.RemediationTemplate))
 	return nil
 }
@@ -987,14 +1007,14 @@ func autoConvert_v1beta1_MachineHealthCheckStatus_To_v1alpha3_MachineHealthCheck
 	out.ObservedGeneration = in.ObservedGeneration
 	out.Targets = *(*[]string)(unsafe.Pointer(&in.Targets))
 	out.Conditions = *(*Conditions)(unsafe.Pointer(&in.Conditions))
+	// WARNING: in.RemediationHistory requires manual conversion: does not exist in peer-type
+	// WARNING: in.UnhealthyObservations requires manual conversion: does not exist in peer-type
+	// WARNING: in.ConsecutiveFailedReplacements requires manual conversion: does not exist in peer-type
+	// WARNING: in.TargetHealth requires manual conversion: does not exist in peer-type
+	// WARNING: in.NextRemediationTime requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_MachineHealthCheckStatus_To_v1alpha3_MachineHealthCheckStatus is an autogenerated conversion function.
-func Convert_v1beta1_MachineHealthCheckStatus_To_v1alpha3_MachineHealthCheckStatus(in *v1beta1.MachineHealthCheckStatus, out *MachineHealthCheckStatus, s conversion.Scope) error {
-	return autoConvert_v1beta1_MachineHealthCheckStatus_To_v1alpha3_MachineHealthCheckStatus(in, out, s)
-}
-
 func autoConvert_v1alpha3_MachineList_To_v1beta1_MachineList(in *MachineList, out *v1beta1.MachineList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
 	if in.Items != nil {
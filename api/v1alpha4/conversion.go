@@ -261,13 +261,57 @@ func (dst *MachineDeploymentList) ConvertFrom(srcRaw conversion.Hub) error {
 func (src *MachineHealthCheck) ConvertTo(dstRaw conversion.Hub) error {
 	dst := dstRaw.(*clusterv1.MachineHealthCheck)
 
-	return Convert_v1alpha4_MachineHealthCheck_To_v1beta1_MachineHealthCheck(src, dst, nil)
+	if err := Convert_v1alpha4_MachineHealthCheck_To_v1beta1_MachineHealthCheck(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Manually restore data.
+	restored := &clusterv1.MachineHealthCheck{}
+	if ok, err := utilconversion.UnmarshalData(src, restored); err != nil || !ok {
+		return err
+	}
+
+	dst.Spec.UnreachableTaintTimeout = restored.Spec.UnreachableTaintTimeout
+	dst.Spec.MaxInFlightRemediations = restored.Spec.MaxInFlightRemediations
+	dst.Spec.RemediationDisabled = restored.Spec.RemediationDisabled
+	dst.Spec.ExcludedNodeRoles = restored.Spec.ExcludedNodeRoles
+	dst.Spec.PreserveQuorum = restored.Spec.PreserveQuorum
+	dst.Spec.RemediationGracePeriodAfterClusterCreation = restored.Spec.RemediationGracePeriodAfterClusterCreation
+	dst.Spec.MaxUnhealthyBase = restored.Spec.MaxUnhealthyBase
+	dst.Spec.RespectPodDisruptionBudgets = restored.Spec.RespectPodDisruptionBudgets
+	dst.Spec.KubeletVersionMismatchTimeout = restored.Spec.KubeletVersionMismatchTimeout
+	dst.Spec.UnhealthyConditionsGracePeriodAfterNodeCreation = restored.Spec.UnhealthyConditionsGracePeriodAfterNodeCreation
+	dst.Spec.KubeletRestartGracePeriod = restored.Spec.KubeletRestartGracePeriod
+	dst.Spec.InfrastructureMissingTimeout = restored.Spec.InfrastructureMissingTimeout
+	dst.Spec.ScaleDownUnhealthyMachines = restored.Spec.ScaleDownUnhealthyMachines
+	dst.Spec.NodeMissingTimeout = restored.Spec.NodeMissingTimeout
+	dst.Spec.MaxUnhealthyFloor = restored.Spec.MaxUnhealthyFloor
+	dst.Spec.UnhealthyObservationThreshold = restored.Spec.UnhealthyObservationThreshold
+	dst.Spec.DrainBeforeRemediation = restored.Spec.DrainBeforeRemediation
+	dst.Spec.DrainBeforeRemediationTimeout = restored.Spec.DrainBeforeRemediationTimeout
+	dst.Spec.NodeLeaseStaleTimeout = restored.Spec.NodeLeaseStaleTimeout
+	dst.Spec.MaxConsecutiveFailedReplacements = restored.Spec.MaxConsecutiveFailedReplacements
+	dst.Status.RemediationHistory = restored.Status.RemediationHistory
+	dst.Status.UnhealthyObservations = restored.Status.UnhealthyObservations
+	dst.Status.ConsecutiveFailedReplacements = restored.Status.ConsecutiveFailedReplacements
+	dst.Status.TargetHealth = restored.Status.TargetHealth
+	dst.Status.NextRemediationTime = restored.Status.NextRemediationTime
+	return nil
 }
 
 func (dst *MachineHealthCheck) ConvertFrom(srcRaw conversion.Hub) error {
 	src := srcRaw.(*clusterv1.MachineHealthCheck)
 
-	return Convert_v1beta1_MachineHealthCheck_To_v1alpha4_MachineHealthCheck(src, dst, nil)
+	if err := Convert_v1beta1_MachineHealthCheck_To_v1alpha4_MachineHealthCheck(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Preserve Hub data on down-conversion except for metadata
+	if err := utilconversion.MarshalData(src, dst); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (src *MachineHealthCheckList) ConvertTo(dstRaw conversion.Hub) error {
@@ -317,3 +361,13 @@ func Convert_v1beta1_ControlPlaneClass_To_v1alpha4_ControlPlaneClass(in *cluster
 	// controlPlaneClass.machineHealthCheck has been added with v1beta1.
 	return autoConvert_v1beta1_ControlPlaneClass_To_v1alpha4_ControlPlaneClass(in, out, s)
 }
+
+func Convert_v1beta1_MachineHealthCheckSpec_To_v1alpha4_MachineHealthCheckSpec(in *clusterv1.MachineHealthCheckSpec, out *MachineHealthCheckSpec, s apiconversion.Scope) error {
+	// Fields added in v1beta1 are restored from the annotation.
+	return autoConvert_v1beta1_MachineHealthCheckSpec_To_v1alpha4_MachineHealthCheckSpec(in, out, s)
+}
+
+func Convert_v1beta1_MachineHealthCheckStatus_To_v1alpha4_MachineHealthCheckStatus(in *clusterv1.MachineHealthCheckStatus, out *MachineHealthCheckStatus, s apiconversion.Scope) error {
+	// Fields added in v1beta1 are restored from the annotation.
+	return autoConvert_v1beta1_MachineHealthCheckStatus_To_v1alpha4_MachineHealthCheckStatus(in, out, s)
+}
@@ -96,9 +96,24 @@ const (
 	// that was cloned for the machine. This annotation is set only during cloning a template. Older/adopted machines will not have this annotation.
 	TemplateClonedFromGroupKindAnnotation = "cluster.x-k8s.io/cloned-from-groupkind"
 
+	// NodeRoleLabelPrefix is the prefix of the labels used to define the roles of a node, e.g. node-role.kubernetes.io/control-plane.
+	NodeRoleLabelPrefix = "node-role.kubernetes.io/"
+
 	// MachineSkipRemediationAnnotation is the annotation used to mark the machines that should not be considered for remediation by MachineHealthCheck reconciler.
 	MachineSkipRemediationAnnotation = "cluster.x-k8s.io/skip-remediation"
 
+	// RemediationInProgressAnnotation is the annotation used by the owner of a machine, e.g. KCP, to mark a machine it is already remediating;
+	// MachineHealthCheck reconciler does not initiate a remediation for machines with this annotation, thus avoiding double remediation.
+	RemediationInProgressAnnotation = "cluster.x-k8s.io/remediation-in-progress"
+
+	// RemediationIDAnnotation is the annotation set by the MachineHealthCheck reconciler on the machines it remediates, with the ID
+	// of the remediation recorded in the MachineHealthCheck status, thus allowing to correlate a remediation with its replacement machine.
+	RemediationIDAnnotation = "cluster.x-k8s.io/remediation-id"
+
+	// RemediationGloballyDisabledAnnotation is the annotation used on the ConfigMap configured as kill switch for the
+	// MachineHealthCheck reconciler to halt the remediations of all the MachineHealthChecks, e.g. during an incident.
+	RemediationGloballyDisabledAnnotation = "cluster.x-k8s.io/remediation-globally-disabled"
+
 	// ClusterSecretType defines the type of secret created by core components.
 	ClusterSecretType corev1.SecretType = "cluster.x-k8s.io/secret" //nolint:gosec
 
@@ -149,6 +149,26 @@ const (
 
 	// UnhealthyNodeConditionReason is the reason used when a machine's node has one of the MachineHealthCheck's unhealthy conditions.
 	UnhealthyNodeConditionReason = "UnhealthyNode"
+
+	// UnreachableNodeTaintReason is the reason used when a machine's node has the unreachable taint for longer than
+	// the MachineHealthCheck's UnreachableTaintTimeout.
+	UnreachableNodeTaintReason = "UnreachableNode"
+
+	// NodeLeaseStaleReason is the reason used when a machine's node did not renew its Lease for longer than
+	// the MachineHealthCheck's NodeLeaseStaleTimeout.
+	NodeLeaseStaleReason = "NodeLeaseStale"
+
+	// KubeletVersionMismatchReason is the reason used when a machine's node reports a kubelet version different
+	// from the machine's version for longer than the MachineHealthCheck's KubeletVersionMismatchTimeout.
+	KubeletVersionMismatchReason = "KubeletVersionMismatch"
+
+	// NodeReplacedReason is the reason used when a machine's node has been replaced by a new node with the same name,
+	// i.e. the UID of the node does not match the UID in the machine's NodeRef.
+	NodeReplacedReason = "NodeReplaced"
+
+	// InfrastructureMissingReason is the reason used when the infrastructure object referenced by a machine has been
+	// deleted for longer than the MachineHealthCheck's InfrastructureMissingTimeout.
+	InfrastructureMissingReason = "InfrastructureMissing"
 )
 
 const (
@@ -211,6 +231,40 @@ const (
 	// TooManyUnhealthyReason is the reason used when too many Machines are unhealthy and the MachineHealthCheck is blocked
 	// from making any further remediations.
 	TooManyUnhealthyReason = "TooManyUnhealthy"
+
+	// TooBroadSelectorReason is the reason used when the MachineHealthCheck selector is empty or matches more Machines
+	// than allowed, and the MachineHealthCheck is blocked from making any further remediations.
+	TooBroadSelectorReason = "TooBroadSelector"
+
+	// RemediationDisabledReason is the reason used when remediation is disabled for the MachineHealthCheck,
+	// and the MachineHealthCheck is only reporting the health of the Machines.
+	RemediationDisabledReason = "RemediationDisabled"
+
+	// ClusterTooNewReason is the reason used when remediation is deferred because the Cluster has been created
+	// more recently than the remediation grace period after the Cluster creation of the MachineHealthCheck.
+	ClusterTooNewReason = "ClusterTooNew"
+
+	// RemediationGloballyDisabledReason is the reason used when remediation is disabled for all the MachineHealthChecks
+	// by the kill switch of the MachineHealthCheck reconciler, and the MachineHealthCheck is only reporting the health
+	// of the Machines.
+	RemediationGloballyDisabledReason = "RemediationGloballyDisabled"
+
+	// ReplacementsFailingReason is the reason used when remediation is paused because the machines replacing the
+	// remediated ones keep failing to become ready, and manual intervention is required.
+	ReplacementsFailingReason = "ReplacementsFailing"
+
+	// ClusterNotFoundReason is the reason used when the Cluster referenced by the MachineHealthCheck does not exist,
+	// e.g. because of a typo in spec.clusterName, and the MachineHealthCheck can't check or remediate any Machine.
+	ClusterNotFoundReason = "ClusterNotFound"
+
+	// SelectorScopedToClusterCondition is set on MachineHealthChecks whose selector matches Machines not belonging
+	// to the Cluster of the MachineHealthCheck; it is removed as soon as the selector matches only Machines of the Cluster.
+	SelectorScopedToClusterCondition ConditionType = "SelectorScopedToCluster"
+
+	// SelectorMatchesForeignClusterReason (Severity=Warning) is the reason used when the MachineHealthCheck selector
+	// matches Machines belonging to a Cluster other than the Cluster of the MachineHealthCheck, e.g. because of
+	// overlapping labels; those Machines are excluded from the health check.
+	SelectorMatchesForeignClusterReason = "SelectorMatchesForeignCluster"
 )
 
 // Conditions and condition Reasons for  MachineDeployments.
@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package index
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// MachineHealthCheckClusterNameField is used by the MachineHealthCheck controller to index MachineHealthChecks
+	// by Cluster name, so Cluster events can be mapped to MachineHealthChecks without scanning the whole namespace.
+	MachineHealthCheckClusterNameField = "spec.clusterName"
+)
+
+// ByMachineHealthCheckClusterName adds the machine health check cluster name index to the
+// managers cache.
+func ByMachineHealthCheckClusterName(ctx context.Context, mgr ctrl.Manager) error {
+	if err := mgr.GetCache().IndexField(ctx, &clusterv1.MachineHealthCheck{},
+		MachineHealthCheckClusterNameField,
+		machineHealthCheckByClusterName,
+	); err != nil {
+		return errors.Wrap(err, "error setting index field")
+	}
+	return nil
+}
+
+func machineHealthCheckByClusterName(o client.Object) []string {
+	mhc, ok := o.(*clusterv1.MachineHealthCheck)
+	if !ok {
+		panic(fmt.Sprintf("Expected a MachineHealthCheck but got a %T", o))
+	}
+	if mhc.Spec.ClusterName != "" {
+		return []string{mhc.Spec.ClusterName}
+	}
+	return nil
+}
@@ -22,6 +22,24 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// MaxRemediationHistory is the maximum number of remediation records kept in the status of a MachineHealthCheck.
+const MaxRemediationHistory = 10
+
+// MaxUnhealthyBase defines which machines MaxUnhealthy is evaluated against.
+type MaxUnhealthyBase string
+
+const (
+	// MaxUnhealthyBaseExpectedMachines evaluates MaxUnhealthy against all the machines matched by the selector,
+	// i.e. the expected machines; machines which are still waiting for a node or failing a health check within
+	// the timeout are counted as not healthy. This is the default.
+	MaxUnhealthyBaseExpectedMachines MaxUnhealthyBase = "ExpectedMachines"
+
+	// MaxUnhealthyBaseObservedTargets evaluates MaxUnhealthy against the machines whose health has been observed,
+	// i.e. the machines which are either healthy or unhealthy; machines which are still waiting for a node
+	// or failing a health check within the timeout, e.g. during a scale up, are not counted at all.
+	MaxUnhealthyBaseObservedTargets MaxUnhealthyBase = "ObservedTargets"
+)
+
 // ANCHOR: MachineHealthCheckSpec
 
 // MachineHealthCheckSpec defines the desired state of MachineHealthCheck.
@@ -40,11 +58,52 @@ type MachineHealthCheckSpec struct {
 	// +kubebuilder:validation:MinItems=1
 	UnhealthyConditions []UnhealthyCondition `json:"unhealthyConditions"`
 
+	// UnhealthyConditionsGracePeriodAfterNodeCreation is the duration after the creation of a node during which
+	// its unhealthy conditions don't count, given that a node briefly reports e.g. Ready=False while the kubelet and
+	// the CNI stabilize; the timeout of the unhealthy conditions is counted from the end of the grace period.
+	// This is measured from the creation of the node, unlike NodeStartupTimeout, which is measured from the creation
+	// of the machine and applies until the node exists.
+	// If not set, unhealthy conditions count as soon as the node is created.
+	// +optional
+	UnhealthyConditionsGracePeriodAfterNodeCreation *metav1.Duration `json:"unhealthyConditionsGracePeriodAfterNodeCreation,omitempty"`
+
+	// KubeletRestartGracePeriod is the duration after the Ready condition of a node transitions while the kubelet
+	// keeps posting heartbeats, e.g. when the Ready condition briefly flips while a restarted kubelet stabilizes,
+	// during which an unhealthy Ready condition doesn't count; the timeout of the unhealthy Ready condition is counted
+	// from the end of the grace period. Other unhealthy conditions are not affected, and neither is a node which is
+	// down, whose Ready condition is set to Unknown once the heartbeats stop.
+	// If not set, an unhealthy Ready condition counts as soon as it transitions.
+	// +optional
+	KubeletRestartGracePeriod *metav1.Duration `json:"kubeletRestartGracePeriod,omitempty"`
+
 	// Any further remediation is only allowed if at most "MaxUnhealthy" machines selected by
 	// "selector" are not healthy.
 	// +optional
 	MaxUnhealthy *intstr.IntOrString `json:"maxUnhealthy,omitempty"`
 
+	// MaxUnhealthyFloor is the minimum number of machines a percentage MaxUnhealthy resolves to; percentages are
+	// rounded down, so e.g. "25%" of 2 machines resolves to 0, which never allows remediation.
+	// It does not apply when MaxUnhealthy is an absolute number, nor to UnhealthyRange.
+	// If not set, percentages are not floored.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxUnhealthyFloor int32 `json:"maxUnhealthyFloor,omitempty"`
+
+	// UnhealthyObservationThreshold is the number of consecutive health checks a Machine must be found unhealthy at,
+	// on top of failing the health checks for longer than their timeouts, before being remediated; any health check
+	// finding the Machine healthy resets the count. This allows to tolerate bursty node status updates.
+	// If not set, or set to 1, Machines are remediated as soon as they are found unhealthy.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	UnhealthyObservationThreshold int32 `json:"unhealthyObservationThreshold,omitempty"`
+
+	// MaxUnhealthyBase defines which machines MaxUnhealthy is evaluated against, both for computing
+	// percentages and for counting the machines which are not healthy; it does not apply to UnhealthyRange.
+	// If not set, ExpectedMachines is used.
+	// +optional
+	// +kubebuilder:validation:Enum=ExpectedMachines;ObservedTargets
+	MaxUnhealthyBase MaxUnhealthyBase `json:"maxUnhealthyBase,omitempty"`
+
 	// Any further remediation is only allowed if the number of machines selected by "selector" as not healthy
 	// is within the range of "UnhealthyRange". Takes precedence over MaxUnhealthy.
 	// Eg. "[3-5]" - This means that remediation will be allowed only when:
@@ -61,6 +120,121 @@ type MachineHealthCheckSpec struct {
 	// +optional
 	NodeStartupTimeout *metav1.Duration `json:"nodeStartupTimeout,omitempty"`
 
+	// NodeMissingTimeout is the duration after which a machine whose node has been deleted from the workload cluster,
+	// while the machine still references it, will be considered to have failed and will be remediated.
+	// The timeout is counted from when the machine reported its node as not found; this allows e.g. to tolerate nodes
+	// which are deleted and re-registered by the cloud provider.
+	// If not set, a machine is considered to have failed as soon as its node is missing.
+	// +optional
+	NodeMissingTimeout *metav1.Duration `json:"nodeMissingTimeout,omitempty"`
+
+	// InfrastructureMissingTimeout is the duration after which a machine whose infrastructure object has been deleted
+	// out of band, e.g. together with the underlying cloud instance, will be considered to have failed and will be
+	// remediated, given that such a machine never recovers.
+	// The timeout is counted from the last transition of the InfrastructureReady condition of the machine, or from
+	// its creation, which could be earlier than the actual deletion of the infrastructure object.
+	// If not set, the infrastructure object is not checked.
+	// +optional
+	InfrastructureMissingTimeout *metav1.Duration `json:"infrastructureMissingTimeout,omitempty"`
+
+	// UnreachableTaintTimeout is the duration after which a machine whose node has the
+	// node.kubernetes.io/unreachable taint will be considered to have failed and will be remediated.
+	// The unreachable taint is added as soon as a node stops heartbeating, so this allows
+	// to remediate unreachable nodes faster than waiting for the Ready condition to become Unknown.
+	// If not set, the unreachable taint is not considered.
+	// +optional
+	UnreachableTaintTimeout *metav1.Duration `json:"unreachableTaintTimeout,omitempty"`
+
+	// NodeLeaseStaleTimeout is the duration after which a machine whose node did not renew its Lease in the
+	// kube-node-lease namespace will be considered to have failed and will be remediated.
+	// The node Lease is renewed by the kubelet every few seconds, so this allows to remediate nodes which stopped
+	// heartbeating faster than waiting for the Ready condition to become Unknown.
+	// If not set, the node Lease is not considered.
+	// +optional
+	NodeLeaseStaleTimeout *metav1.Duration `json:"nodeLeaseStaleTimeout,omitempty"`
+
+	// KubeletVersionMismatchTimeout is the duration after which a machine whose node reports a kubelet version
+	// different from the machine's version will be considered to have failed and will be remediated, e.g. when
+	// a node comes back with an unexpected kubelet version after a failed or rolled back upgrade.
+	// Given that nodes don't record when the kubelet version changed, the mismatch is timed from the creation
+	// of the node or from the last transition of its Ready condition, whichever is more recent.
+	// If not set, the kubelet version is not considered.
+	// +optional
+	KubeletVersionMismatchTimeout *metav1.Duration `json:"kubeletVersionMismatchTimeout,omitempty"`
+
+	// MaxInFlightRemediations is the maximum number of remediations which can be in progress at the same time.
+	// A remediation is considered in progress from when the machine is marked for remediation until it is deleted,
+	// and while the machines replacing it don't have a node yet.
+	// New remediations are not started until the number of remediations in progress drops below this value.
+	// If not set, the number of remediations in progress is not limited.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxInFlightRemediations *int32 `json:"maxInFlightRemediations,omitempty"`
+
+	// MaxConsecutiveFailedReplacements is the number of consecutive failed replacements after which remediation is
+	// paused; a replacement is considered failed when a machine created after the previous remediation is remediated
+	// before it ever got a node, e.g. because of a bad image or a broken bootstrap configuration, in which case
+	// remediating further would just burn machines. Remediation resumes as soon as a machine created after the last
+	// remediation is found healthy, e.g. once the configuration of the machines has been fixed.
+	// If not set, remediation is never paused because of failed replacements.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConsecutiveFailedReplacements *int32 `json:"maxConsecutiveFailedReplacements,omitempty"`
+
+	// RemediationDisabled disables remediation for the machines targeted by this MachineHealthCheck.
+	// When set, the health of the machines is still checked and reported in the status and in the machine conditions,
+	// but no remediation is ever performed; this allows to use the MachineHealthCheck for monitoring only.
+	// +optional
+	RemediationDisabled bool `json:"remediationDisabled,omitempty"`
+
+	// RemediationGracePeriodAfterClusterCreation is the duration after the creation of the Cluster during which
+	// remediation is deferred, given that machines are expected to churn while the Cluster is initially provisioned.
+	// The health of the machines is still checked and reported while remediation is deferred.
+	// If not set, remediation is not deferred.
+	// +optional
+	RemediationGracePeriodAfterClusterCreation *metav1.Duration `json:"remediationGracePeriodAfterClusterCreation,omitempty"`
+
+	// PreserveQuorum, if set, allows remediation only if a quorum of the machines selected by "selector", i.e. (N/2)+1
+	// of the expected machines, are healthy, in addition to the limits defined by MaxUnhealthy or UnhealthyRange.
+	// This is useful for groups of machines which must preserve quorum, e.g. the machines hosting etcd members.
+	// +optional
+	PreserveQuorum bool `json:"preserveQuorum,omitempty"`
+
+	// ExcludedNodeRoles is a list of node roles, e.g. control-plane; machines whose node has the
+	// node-role.kubernetes.io/<role> label for any of these roles are not targeted by this MachineHealthCheck,
+	// even if they are matched by the selector. This allows e.g. to prevent a MachineHealthCheck
+	// meant for worker machines from remediating control plane machines.
+	// +optional
+	ExcludedNodeRoles []string `json:"excludedNodeRoles,omitempty"`
+
+	// RespectPodDisruptionBudgets, if set, defers the remediation of a machine while evicting the pods running on its node
+	// would violate any PodDisruptionBudget in the workload cluster; this prevents remediation from causing
+	// availability incidents for stateful workloads, at the cost of keeping unhealthy machines around longer.
+	// NOTE: This requires the workload cluster to serve the policy/v1 API, i.e. Kubernetes v1.21 or newer.
+	// +optional
+	RespectPodDisruptionBudgets bool `json:"respectPodDisruptionBudgets,omitempty"`
+
+	// ScaleDownUnhealthyMachines, if set, remediates unhealthy machines owned by a MachineSet by setting the
+	// cluster.x-k8s.io/delete-machine annotation on them and scaling down by one the MachineDeployment owning the
+	// MachineSet, or the MachineSet if it has no MachineDeployment, instead of having them replaced by the MachineSet;
+	// the deletion policy of the MachineSet then picks the annotated machines. This leaves managing the number of machines
+	// to a single controller, e.g. the cluster autoscaler, which can then scale up again if required.
+	// Machines not owned by a MachineSet, or owned by a MachineDeployment which is rolling out, are remediated as usual.
+	// +optional
+	ScaleDownUnhealthyMachines bool `json:"scaleDownUnhealthyMachines,omitempty"`
+
+	// DrainBeforeRemediation, if set, cordons and drains the node of an unhealthy machine before the machine is
+	// remediated, so the pods running on it are evicted gracefully before the machine gets deleted.
+	// Draining is best effort: if the node can't be drained within DrainBeforeRemediationTimeout, the machine is
+	// remediated regardless. It does not apply when the remediation is handed off via RemediationTemplate.
+	// +optional
+	DrainBeforeRemediation bool `json:"drainBeforeRemediation,omitempty"`
+
+	// DrainBeforeRemediationTimeout is the maximum time spent draining the node of an unhealthy machine when
+	// DrainBeforeRemediation is set; it must be greater than 0. If not set, 1 minute is used.
+	// +optional
+	DrainBeforeRemediationTimeout *metav1.Duration `json:"drainBeforeRemediationTimeout,omitempty"`
+
 	// RemediationTemplate is a reference to a remediation template
 	// provided by an infrastructure provider.
 	//
@@ -107,7 +281,9 @@ type MachineHealthCheckStatus struct {
 	CurrentHealthy int32 `json:"currentHealthy"`
 
 	// RemediationsAllowed is the number of further remediations allowed by this machine health check before
-	// maxUnhealthy short circuiting will be applied
+	// maxUnhealthy short circuiting will be applied.
+	// It is computed from maxUnhealthy, resolved against the current number of machines when it is a percentage,
+	// or unhealthyRange, and the current number of unhealthy machines; it is 0 while remediation is short-circuited.
 	// +kubebuilder:validation:Minimum=0
 	// +optional
 	RemediationsAllowed int32 `json:"remediationsAllowed"`
@@ -123,10 +299,88 @@ type MachineHealthCheckStatus struct {
 	// Conditions defines current service state of the MachineHealthCheck.
 	// +optional
 	Conditions Conditions `json:"conditions,omitempty"`
+
+	// RemediationHistory contains the most recent remediations initiated by the MachineHealthCheck, oldest first;
+	// only the last MaxRemediationHistory remediations are kept.
+	// +optional
+	// +kubebuilder:validation:MaxItems=10
+	RemediationHistory []RemediationRecord `json:"remediationHistory,omitempty"`
+
+	// UnhealthyObservations contains, for each Machine found unhealthy by the last health check, the number of
+	// consecutive health checks it has been found unhealthy at; it is used only if UnhealthyObservationThreshold is set.
+	// +optional
+	UnhealthyObservations []UnhealthyObservation `json:"unhealthyObservations,omitempty"`
+
+	// ConsecutiveFailedReplacements is the number of consecutive remediations of machines which failed to replace a
+	// previously remediated machine, i.e. which never got a node; it is reset as soon as a machine created after
+	// the last remediation is found healthy. See MaxConsecutiveFailedReplacements.
+	// +optional
+	ConsecutiveFailedReplacements int32 `json:"consecutiveFailedReplacements,omitempty"`
+
+	// TargetHealth contains, for each Machine failing a health check, either unhealthy or still within the timeout
+	// of the failing health check, why the Machine is failing the health check and when it is going to be considered
+	// unhealthy; healthy Machines are not included.
+	// +optional
+	TargetHealth []TargetHealth `json:"targetHealth,omitempty"`
+
+	// NextRemediationTime is the earliest time at which a Machine failing a health check but still within the
+	// timeout is going to be considered unhealthy, unless it recovers; it is not set when no Machine is pending.
+	// +optional
+	NextRemediationTime *metav1.Time `json:"nextRemediationTime,omitempty"`
 }
 
 // ANCHOR_END: MachineHealthCheckStatus
 
+// RemediationRecord is a record of a remediation initiated by a MachineHealthCheck.
+type RemediationRecord struct {
+	// ID uniquely identifies the remediation; it is also set on the remediated Machine in the
+	// cluster.x-k8s.io/remediation-id annotation and referenced by the remediation event, which allows
+	// to correlate the remediation with the Machine replacing the remediated one.
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// Time is when the remediation was initiated.
+	Time metav1.Time `json:"time"`
+
+	// Machine is the name of the remediated Machine.
+	Machine string `json:"machine"`
+
+	// Reason is the reason why the Machine has been found unhealthy.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// TargetHealth describes why a Machine targeted by a MachineHealthCheck is failing a health check.
+type TargetHealth struct {
+	// Machine is the name of the Machine.
+	Machine string `json:"machine"`
+
+	// Reason identifies the failing health check, e.g. UnhealthyNode when an unhealthy condition matches the node.
+	Reason string `json:"reason"`
+
+	// Message is a human readable description of the failing health check, e.g. the matching unhealthy condition.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Since is when the Machine started failing the health check, if known.
+	// +optional
+	Since *metav1.Time `json:"since,omitempty"`
+
+	// UnhealthyAt is when the timeout of the failing health check elapses, i.e. when the Machine is going to be
+	// considered unhealthy unless it recovers, or when it has been considered unhealthy.
+	// +optional
+	UnhealthyAt *metav1.Time `json:"unhealthyAt,omitempty"`
+}
+
+// UnhealthyObservation is the number of consecutive health checks a Machine has been found unhealthy at.
+type UnhealthyObservation struct {
+	// Machine is the name of the Machine.
+	Machine string `json:"machine"`
+
+	// Count is the number of consecutive health checks the Machine has been found unhealthy at.
+	Count int32 `json:"count"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:path=machinehealthchecks,shortName=mhc;mhcs,scope=Namespaced,categories=cluster-api
 // +kubebuilder:storageversion
@@ -135,6 +389,8 @@ type MachineHealthCheckStatus struct {
 // +kubebuilder:printcolumn:name="ExpectedMachines",type="integer",JSONPath=".status.expectedMachines",description="Number of machines currently monitored"
 // +kubebuilder:printcolumn:name="MaxUnhealthy",type="string",JSONPath=".spec.maxUnhealthy",description="Maximum number of unhealthy machines allowed"
 // +kubebuilder:printcolumn:name="CurrentHealthy",type="integer",JSONPath=".status.currentHealthy",description="Current observed healthy machines"
+// +kubebuilder:printcolumn:name="RemediationsAllowed",type="integer",JSONPath=".status.remediationsAllowed",description="Number of further remediations allowed before short-circuiting"
+// +kubebuilder:printcolumn:name="NextRemediation",type="string",JSONPath=".status.nextRemediationTime",description="Time at which the next pending machine is going to be considered unhealthy"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of MachineHealthCheck"
 
 // MachineHealthCheck is the Schema for the machinehealthchecks API.
@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -144,6 +145,85 @@ func (m *MachineHealthCheck) validate(old *MachineHealthCheck) error {
 		)
 	}
 
+	if m.Spec.NodeMissingTimeout != nil && m.Spec.NodeMissingTimeout.Seconds() < 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "nodeMissingTimeout"), m.Spec.NodeMissingTimeout.Seconds(), "must be greater than or equal to 0"),
+		)
+	}
+
+	if m.Spec.InfrastructureMissingTimeout != nil && m.Spec.InfrastructureMissingTimeout.Seconds() < 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "infrastructureMissingTimeout"), m.Spec.InfrastructureMissingTimeout.Seconds(), "must be greater than or equal to 0"),
+		)
+	}
+
+	if m.Spec.UnreachableTaintTimeout != nil && m.Spec.UnreachableTaintTimeout.Seconds() < 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "unreachableTaintTimeout"), m.Spec.UnreachableTaintTimeout.Seconds(), "must be greater than or equal to 0"),
+		)
+	}
+
+	if m.Spec.UnhealthyConditionsGracePeriodAfterNodeCreation != nil && m.Spec.UnhealthyConditionsGracePeriodAfterNodeCreation.Seconds() < 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "unhealthyConditionsGracePeriodAfterNodeCreation"), m.Spec.UnhealthyConditionsGracePeriodAfterNodeCreation.Seconds(), "must be greater than or equal to 0"),
+		)
+	}
+
+	if m.Spec.KubeletRestartGracePeriod != nil && m.Spec.KubeletRestartGracePeriod.Seconds() < 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "kubeletRestartGracePeriod"), m.Spec.KubeletRestartGracePeriod.Seconds(), "must be greater than or equal to 0"),
+		)
+	}
+
+	if m.Spec.KubeletVersionMismatchTimeout != nil && m.Spec.KubeletVersionMismatchTimeout.Seconds() < 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "kubeletVersionMismatchTimeout"), m.Spec.KubeletVersionMismatchTimeout.Seconds(), "must be greater than or equal to 0"),
+		)
+	}
+
+	if m.Spec.RemediationGracePeriodAfterClusterCreation != nil && m.Spec.RemediationGracePeriodAfterClusterCreation.Seconds() < 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "remediationGracePeriodAfterClusterCreation"), m.Spec.RemediationGracePeriodAfterClusterCreation.Seconds(), "must be greater than or equal to 0"),
+		)
+	}
+
+	if m.Spec.DrainBeforeRemediationTimeout != nil && m.Spec.DrainBeforeRemediationTimeout.Seconds() <= 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "drainBeforeRemediationTimeout"), m.Spec.DrainBeforeRemediationTimeout.Seconds(), "must be greater than 0"),
+		)
+	}
+
+	for i, role := range m.Spec.ExcludedNodeRoles {
+		if errs := validation.IsQualifiedName(NodeRoleLabelPrefix + role); role == "" || len(errs) > 0 {
+			allErrs = append(
+				allErrs,
+				field.Invalid(field.NewPath("spec", "excludedNodeRoles").Index(i), role, "must be a valid node role"),
+			)
+		}
+	}
+
+	if m.Spec.MaxInFlightRemediations != nil && *m.Spec.MaxInFlightRemediations < 1 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "maxInFlightRemediations"), *m.Spec.MaxInFlightRemediations, "must be greater than or equal to 1"),
+		)
+	}
+
+	if m.Spec.MaxConsecutiveFailedReplacements != nil && *m.Spec.MaxConsecutiveFailedReplacements < 1 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "maxConsecutiveFailedReplacements"), *m.Spec.MaxConsecutiveFailedReplacements, "must be greater than or equal to 1"),
+		)
+	}
+
 	if m.Spec.MaxUnhealthy != nil {
 		if _, err := intstr.GetScaledValueFromIntOrPercent(m.Spec.MaxUnhealthy, 0, false); err != nil {
 			allErrs = append(
@@ -24,6 +24,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
 
 	utildefaulting "sigs.k8s.io/cluster-api/util/defaulting"
 )
@@ -213,6 +214,606 @@ func TestMachineHealthCheckNodeStartupTimeout(t *testing.T) {
 	}
 }
 
+func TestMachineHealthCheckNodeMissingTimeout(t *testing.T) {
+	zero := metav1.Duration{Duration: 0}
+	oneMinute := metav1.Duration{Duration: 1 * time.Minute}
+	minusOneMinute := metav1.Duration{Duration: -1 * time.Minute}
+
+	tests := []struct {
+		name      string
+		timeout   *metav1.Duration
+		expectErr bool
+	}{
+		{
+			name:      "when the nodeMissingTimeout is not given",
+			timeout:   nil,
+			expectErr: false,
+		},
+		{
+			name:      "when the nodeMissingTimeout is greater than 0",
+			timeout:   &oneMinute,
+			expectErr: false,
+		},
+		{
+			name:      "when the nodeMissingTimeout is 0",
+			timeout:   &zero,
+			expectErr: false,
+		},
+		{
+			name:      "when the nodeMissingTimeout is less than 0",
+			timeout:   &minusOneMinute,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				NodeMissingTimeout: tt.timeout,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckUnreachableTaintTimeout(t *testing.T) {
+	zero := metav1.Duration{Duration: 0}
+	oneMinute := metav1.Duration{Duration: 1 * time.Minute}
+	minusOneMinute := metav1.Duration{Duration: -1 * time.Minute}
+
+	tests := []struct {
+		name      string
+		timeout   *metav1.Duration
+		expectErr bool
+	}{
+		{
+			name:      "when the unreachableTaintTimeout is not given",
+			timeout:   nil,
+			expectErr: false,
+		},
+		{
+			name:      "when the unreachableTaintTimeout is greater than 0",
+			timeout:   &oneMinute,
+			expectErr: false,
+		},
+		{
+			name:      "when the unreachableTaintTimeout is 0",
+			timeout:   &zero,
+			expectErr: false,
+		},
+		{
+			name:      "when the unreachableTaintTimeout is less than 0",
+			timeout:   &minusOneMinute,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				UnreachableTaintTimeout: tt.timeout,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckKubeletVersionMismatchTimeout(t *testing.T) {
+	zero := metav1.Duration{Duration: 0}
+	oneMinute := metav1.Duration{Duration: 1 * time.Minute}
+	minusOneMinute := metav1.Duration{Duration: -1 * time.Minute}
+
+	tests := []struct {
+		name      string
+		timeout   *metav1.Duration
+		expectErr bool
+	}{
+		{
+			name:      "when the kubeletVersionMismatchTimeout is not given",
+			timeout:   nil,
+			expectErr: false,
+		},
+		{
+			name:      "when the kubeletVersionMismatchTimeout is greater than 0",
+			timeout:   &oneMinute,
+			expectErr: false,
+		},
+		{
+			name:      "when the kubeletVersionMismatchTimeout is 0",
+			timeout:   &zero,
+			expectErr: false,
+		},
+		{
+			name:      "when the kubeletVersionMismatchTimeout is less than 0",
+			timeout:   &minusOneMinute,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				KubeletVersionMismatchTimeout: tt.timeout,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckUnhealthyConditionsGracePeriodAfterNodeCreation(t *testing.T) {
+	zero := metav1.Duration{Duration: 0}
+	oneMinute := metav1.Duration{Duration: 1 * time.Minute}
+	minusOneMinute := metav1.Duration{Duration: -1 * time.Minute}
+
+	tests := []struct {
+		name      string
+		timeout   *metav1.Duration
+		expectErr bool
+	}{
+		{
+			name:      "when the unhealthyConditionsGracePeriodAfterNodeCreation is not given",
+			timeout:   nil,
+			expectErr: false,
+		},
+		{
+			name:      "when the unhealthyConditionsGracePeriodAfterNodeCreation is greater than 0",
+			timeout:   &oneMinute,
+			expectErr: false,
+		},
+		{
+			name:      "when the unhealthyConditionsGracePeriodAfterNodeCreation is 0",
+			timeout:   &zero,
+			expectErr: false,
+		},
+		{
+			name:      "when the unhealthyConditionsGracePeriodAfterNodeCreation is less than 0",
+			timeout:   &minusOneMinute,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				UnhealthyConditionsGracePeriodAfterNodeCreation: tt.timeout,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckKubeletRestartGracePeriod(t *testing.T) {
+	zero := metav1.Duration{Duration: 0}
+	oneMinute := metav1.Duration{Duration: 1 * time.Minute}
+	minusOneMinute := metav1.Duration{Duration: -1 * time.Minute}
+
+	tests := []struct {
+		name      string
+		timeout   *metav1.Duration
+		expectErr bool
+	}{
+		{
+			name:      "when the kubeletRestartGracePeriod is not given",
+			timeout:   nil,
+			expectErr: false,
+		},
+		{
+			name:      "when the kubeletRestartGracePeriod is greater than 0",
+			timeout:   &oneMinute,
+			expectErr: false,
+		},
+		{
+			name:      "when the kubeletRestartGracePeriod is 0",
+			timeout:   &zero,
+			expectErr: false,
+		},
+		{
+			name:      "when the kubeletRestartGracePeriod is less than 0",
+			timeout:   &minusOneMinute,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				KubeletRestartGracePeriod: tt.timeout,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckInfrastructureMissingTimeout(t *testing.T) {
+	zero := metav1.Duration{Duration: 0}
+	oneMinute := metav1.Duration{Duration: 1 * time.Minute}
+	minusOneMinute := metav1.Duration{Duration: -1 * time.Minute}
+
+	tests := []struct {
+		name      string
+		timeout   *metav1.Duration
+		expectErr bool
+	}{
+		{
+			name:      "when the infrastructureMissingTimeout is not given",
+			timeout:   nil,
+			expectErr: false,
+		},
+		{
+			name:      "when the infrastructureMissingTimeout is greater than 0",
+			timeout:   &oneMinute,
+			expectErr: false,
+		},
+		{
+			name:      "when the infrastructureMissingTimeout is 0",
+			timeout:   &zero,
+			expectErr: false,
+		},
+		{
+			name:      "when the infrastructureMissingTimeout is less than 0",
+			timeout:   &minusOneMinute,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				InfrastructureMissingTimeout: tt.timeout,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckRemediationGracePeriodAfterClusterCreation(t *testing.T) {
+	zero := metav1.Duration{Duration: 0}
+	oneMinute := metav1.Duration{Duration: 1 * time.Minute}
+	minusOneMinute := metav1.Duration{Duration: -1 * time.Minute}
+
+	tests := []struct {
+		name      string
+		timeout   *metav1.Duration
+		expectErr bool
+	}{
+		{
+			name:      "when the remediationGracePeriodAfterClusterCreation is not given",
+			timeout:   nil,
+			expectErr: false,
+		},
+		{
+			name:      "when the remediationGracePeriodAfterClusterCreation is greater than 0",
+			timeout:   &oneMinute,
+			expectErr: false,
+		},
+		{
+			name:      "when the remediationGracePeriodAfterClusterCreation is 0",
+			timeout:   &zero,
+			expectErr: false,
+		},
+		{
+			name:      "when the remediationGracePeriodAfterClusterCreation is less than 0",
+			timeout:   &minusOneMinute,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				RemediationGracePeriodAfterClusterCreation: tt.timeout,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckDrainBeforeRemediationTimeout(t *testing.T) {
+	zero := metav1.Duration{Duration: 0}
+	oneMinute := metav1.Duration{Duration: 1 * time.Minute}
+	minusOneMinute := metav1.Duration{Duration: -1 * time.Minute}
+
+	tests := []struct {
+		name      string
+		timeout   *metav1.Duration
+		expectErr bool
+	}{
+		{
+			name:      "when the drainBeforeRemediationTimeout is not given",
+			timeout:   nil,
+			expectErr: false,
+		},
+		{
+			name:      "when the drainBeforeRemediationTimeout is greater than 0",
+			timeout:   &oneMinute,
+			expectErr: false,
+		},
+		{
+			name:      "when the drainBeforeRemediationTimeout is 0",
+			timeout:   &zero,
+			expectErr: true,
+		},
+		{
+			name:      "when the drainBeforeRemediationTimeout is less than 0",
+			timeout:   &minusOneMinute,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				DrainBeforeRemediation:        true,
+				DrainBeforeRemediationTimeout: tt.timeout,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckMaxInFlightRemediations(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     *int32
+		expectErr bool
+	}{
+		{
+			name:      "when maxInFlightRemediations is not given",
+			value:     nil,
+			expectErr: false,
+		},
+		{
+			name:      "when maxInFlightRemediations is 1",
+			value:     pointer.Int32(1),
+			expectErr: false,
+		},
+		{
+			name:      "when maxInFlightRemediations is 0",
+			value:     pointer.Int32(0),
+			expectErr: true,
+		},
+		{
+			name:      "when maxInFlightRemediations is less than 0",
+			value:     pointer.Int32(-1),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				MaxInFlightRemediations: tt.value,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckMaxConsecutiveFailedReplacements(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     *int32
+		expectErr bool
+	}{
+		{
+			name:      "when maxConsecutiveFailedReplacements is not given",
+			value:     nil,
+			expectErr: false,
+		},
+		{
+			name:      "when maxConsecutiveFailedReplacements is 3",
+			value:     pointer.Int32(3),
+			expectErr: false,
+		},
+		{
+			name:      "when maxConsecutiveFailedReplacements is 0",
+			value:     pointer.Int32(0),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				MaxConsecutiveFailedReplacements: tt.value,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckExcludedNodeRoles(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     []string
+		expectErr bool
+	}{
+		{
+			name:      "when excludedNodeRoles is not given",
+			value:     nil,
+			expectErr: false,
+		},
+		{
+			name:      "when excludedNodeRoles contains valid roles",
+			value:     []string{"control-plane", "master"},
+			expectErr: false,
+		},
+		{
+			name:      "when excludedNodeRoles contains an empty role",
+			value:     []string{""},
+			expectErr: true,
+		},
+		{
+			name:      "when excludedNodeRoles contains an invalid role",
+			value:     []string{"control plane"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				ExcludedNodeRoles: tt.value,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
 func TestMachineHealthCheckMaxUnhealthy(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -1064,6 +1064,16 @@ func (in *MachineHealthCheckSpec) DeepCopyInto(out *MachineHealthCheckSpec) {
 		*out = make([]UnhealthyCondition, len(*in))
 		copy(*out, *in)
 	}
+	if in.UnhealthyConditionsGracePeriodAfterNodeCreation != nil {
+		in, out := &in.UnhealthyConditionsGracePeriodAfterNodeCreation, &out.UnhealthyConditionsGracePeriodAfterNodeCreation
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.KubeletRestartGracePeriod != nil {
+		in, out := &in.KubeletRestartGracePeriod, &out.KubeletRestartGracePeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.MaxUnhealthy != nil {
 		in, out := &in.MaxUnhealthy, &out.MaxUnhealthy
 		*out = new(intstr.IntOrString)
@@ -1079,6 +1089,56 @@ func (in *MachineHealthCheckSpec) DeepCopyInto(out *MachineHealthCheckSpec) {
 		*out = new(metav1.Duration)
 		**out = **in
 	}
+	if in.NodeMissingTimeout != nil {
+		in, out := &in.NodeMissingTimeout, &out.NodeMissingTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.InfrastructureMissingTimeout != nil {
+		in, out := &in.InfrastructureMissingTimeout, &out.InfrastructureMissingTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.UnreachableTaintTimeout != nil {
+		in, out := &in.UnreachableTaintTimeout, &out.UnreachableTaintTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.NodeLeaseStaleTimeout != nil {
+		in, out := &in.NodeLeaseStaleTimeout, &out.NodeLeaseStaleTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.KubeletVersionMismatchTimeout != nil {
+		in, out := &in.KubeletVersionMismatchTimeout, &out.KubeletVersionMismatchTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxInFlightRemediations != nil {
+		in, out := &in.MaxInFlightRemediations, &out.MaxInFlightRemediations
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxConsecutiveFailedReplacements != nil {
+		in, out := &in.MaxConsecutiveFailedReplacements, &out.MaxConsecutiveFailedReplacements
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RemediationGracePeriodAfterClusterCreation != nil {
+		in, out := &in.RemediationGracePeriodAfterClusterCreation, &out.RemediationGracePeriodAfterClusterCreation
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ExcludedNodeRoles != nil {
+		in, out := &in.ExcludedNodeRoles, &out.ExcludedNodeRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DrainBeforeRemediationTimeout != nil {
+		in, out := &in.DrainBeforeRemediationTimeout, &out.DrainBeforeRemediationTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.RemediationTemplate != nil {
 		in, out := &in.RemediationTemplate, &out.RemediationTemplate
 		*out = new(v1.ObjectReference)
@@ -1111,6 +1171,29 @@ func (in *MachineHealthCheckStatus) DeepCopyInto(out *MachineHealthCheckStatus)
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RemediationHistory != nil {
+		in, out := &in.RemediationHistory, &out.RemediationHistory
+		*out = make([]RemediationRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UnhealthyObservations != nil {
+		in, out := &in.UnhealthyObservations, &out.UnhealthyObservations
+		*out = make([]UnhealthyObservation, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetHealth != nil {
+		in, out := &in.TargetHealth, &out.TargetHealth
+		*out = make([]TargetHealth, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NextRemediationTime != nil {
+		in, out := &in.NextRemediationTime, &out.NextRemediationTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineHealthCheckStatus.
@@ -1536,6 +1619,45 @@ func (in *PatchSelectorMatchMachineDeploymentClass) DeepCopy() *PatchSelectorMat
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationRecord) DeepCopyInto(out *RemediationRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationRecord.
+func (in *RemediationRecord) DeepCopy() *RemediationRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetHealth) DeepCopyInto(out *TargetHealth) {
+	*out = *in
+	if in.Since != nil {
+		in, out := &in.Since, &out.Since
+		*out = (*in).DeepCopy()
+	}
+	if in.UnhealthyAt != nil {
+		in, out := &in.UnhealthyAt, &out.UnhealthyAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetHealth.
+func (in *TargetHealth) DeepCopy() *TargetHealth {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetHealth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Topology) DeepCopyInto(out *Topology) {
 	*out = *in
@@ -1584,6 +1706,21 @@ func (in *UnhealthyCondition) DeepCopy() *UnhealthyCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnhealthyObservation) DeepCopyInto(out *UnhealthyObservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnhealthyObservation.
+func (in *UnhealthyObservation) DeepCopy() *UnhealthyObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(UnhealthyObservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VariableSchema) DeepCopyInto(out *VariableSchema) {
 	*out = *in
@@ -17,6 +17,9 @@ limitations under the License.
 package controllers
 
 import (
+	"strings"
+
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -116,13 +119,48 @@ type MachineHealthCheckReconciler struct {
 
 	// WatchFilterValue is the label value used to filter events prior to reconciliation.
 	WatchFilterValue string
+
+	// RemediationNotificationEndpoint, if set, is the HTTP endpoint notified every time a remediation is initiated.
+	RemediationNotificationEndpoint string
+
+	// MaxTargets, if greater than zero, is the maximum number of Machines a MachineHealthCheck can target.
+	MaxTargets int
+
+	// RemediationKillSwitchConfigMap, if set, is the namespace/name of the ConfigMap which halts the remediations of all
+	// the MachineHealthChecks while it has the cluster.x-k8s.io/remediation-globally-disabled annotation set to "true".
+	RemediationKillSwitchConfigMap string
+
+	// FailureDomainRoundRobinRemediation, if true, selects the unhealthy Machines to remediate round-robin across failure domains.
+	FailureDomainRoundRobinRemediation bool
+
+	// ReadNodesFromWorkloadCluster, if true, reads the nodes of the workload clusters with an uncached client at every reconcile.
+	ReadNodesFromWorkloadCluster bool
 }
 
 func (r *MachineHealthCheckReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
+	var remediationNotifier machinehealthcheckcontroller.RemediationNotifier
+	if r.RemediationNotificationEndpoint != "" {
+		remediationNotifier = &machinehealthcheckcontroller.WebhookRemediationNotifier{Endpoint: r.RemediationNotificationEndpoint}
+	}
+
+	var killSwitch client.ObjectKey
+	if r.RemediationKillSwitchConfigMap != "" {
+		parts := strings.Split(r.RemediationKillSwitchConfigMap, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return errors.Errorf("invalid remediation kill switch ConfigMap %q, must be in the namespace/name format", r.RemediationKillSwitchConfigMap)
+		}
+		killSwitch = client.ObjectKey{Namespace: parts[0], Name: parts[1]}
+	}
+
 	return (&machinehealthcheckcontroller.Reconciler{
-		Client:           r.Client,
-		Tracker:          r.Tracker,
-		WatchFilterValue: r.WatchFilterValue,
+		Client:                             r.Client,
+		Tracker:                            r.Tracker,
+		WatchFilterValue:                   r.WatchFilterValue,
+		RemediationNotifier:                remediationNotifier,
+		MaxTargets:                         r.MaxTargets,
+		RemediationKillSwitchConfigMap:     killSwitch,
+		FailureDomainRoundRobinRemediation: r.FailureDomainRoundRobinRemediation,
+		ReadNodesFromWorkloadCluster:       r.ReadNodesFromWorkloadCluster,
 	}).SetupWithManager(ctx, mgr, options)
 }
 
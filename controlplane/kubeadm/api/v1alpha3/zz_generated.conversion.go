@@ -256,6 +256,7 @@ func autoConvert_v1beta1_KubeadmControlPlaneStatus_To_v1alpha3_KubeadmControlPla
 	} else {
 		out.Conditions = nil
 	}
+	// WARNING: in.EtcdLearners requires manual conversion: does not exist in peer-type
 	return nil
 }
 
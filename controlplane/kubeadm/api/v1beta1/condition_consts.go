@@ -56,6 +56,17 @@ const (
 	RollingUpdateInProgressReason = "RollingUpdateInProgress"
 )
 
+const (
+	// MachinesConfigurationTrackedCondition documents that KubeadmControlPlane can detect configuration changes
+	// for all the machines it controls.
+	MachinesConfigurationTrackedCondition clusterv1.ConditionType = "MachinesConfigurationTracked"
+
+	// MissingConfigurationHashReason (Severity=Info) documents a KubeadmControlPlane controlling machines which have
+	// neither the configuration hash label nor the ClusterConfiguration annotation, e.g. adopted or manually created
+	// machines, which are not rolled out on changes to the KubeadmControlPlane ClusterConfiguration.
+	MissingConfigurationHashReason = "MissingConfigurationHash"
+)
+
 const (
 	// ResizedCondition documents a KubeadmControlPlane that is resizing the set of controlled machines.
 	ResizedCondition clusterv1.ConditionType = "Resized"
@@ -129,6 +140,10 @@ const (
 	// EtcdMemberInspectionFailedReason documents a failure in inspecting the etcd member status.
 	EtcdMemberInspectionFailedReason = "MemberInspectionFailed"
 
+	// EtcdMemberNodeNotInitializedReason documents the etcd member can't be inspected yet because the node hosting it
+	// has not been initialized by the cloud provider (the node has no ProviderID); this is a transient state.
+	EtcdMemberNodeNotInitializedReason = "NodeNotInitialized"
+
 	// EtcdMemberUnhealthyReason (Severity=Error) documents a Machine's etcd member is unhealthy.
 	EtcdMemberUnhealthyReason = "EtcdMemberUnhealthy"
 
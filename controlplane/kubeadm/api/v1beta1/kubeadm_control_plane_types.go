@@ -49,6 +49,13 @@ const (
 	// KubeadmClusterConfigurationAnnotation is a machine annotation that stores the json-marshalled string of KCP ClusterConfiguration.
 	// This annotation is used to detect any changes in ClusterConfiguration and trigger machine rollout in KCP.
 	KubeadmClusterConfigurationAnnotation = "controlplane.cluster.x-k8s.io/kubeadm-cluster-configuration"
+
+	// EtcdEndpointsAnnotation is a comma separated list of the DNS names, optionally with a port, the etcd members
+	// of the workload cluster can be reached at, e.g. "cp-0.etcd.example.com,cp-1.etcd.example.com:2379".
+	// The first label of each DNS name must be the name of the node hosting the etcd member, and the DNS name must be
+	// included in the SANs of the etcd server certificate. This annotation is used only if the KubeadmControlPlane
+	// controller is configured to connect to etcd with the DirectToEndpoints strategy.
+	EtcdEndpointsAnnotation = "controlplane.cluster.x-k8s.io/etcd-endpoints"
 )
 
 // KubeadmControlPlaneSpec defines the desired state of KubeadmControlPlane.
@@ -201,6 +208,20 @@ type KubeadmControlPlaneStatus struct {
 	// Conditions defines current service state of the KubeadmControlPlane.
 	// +optional
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+
+	// EtcdLearners lists the etcd members which are currently learners, together with the time they have
+	// been first observed as learners; this is used to detect learners which are never promoted to voting members.
+	// +optional
+	EtcdLearners []EtcdLearner `json:"etcdLearners,omitempty"`
+}
+
+// EtcdLearner defines the observed state of an etcd member which is a learner.
+type EtcdLearner struct {
+	// Name is the name of the etcd member, i.e. the name of the node hosting it.
+	Name string `json:"name"`
+
+	// Since is the time the etcd member has been first observed as a learner.
+	Since metav1.Time `json:"since"`
 }
 
 // +kubebuilder:object:root=true
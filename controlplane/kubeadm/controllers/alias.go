@@ -25,6 +25,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
 	kubeadmcontrolplanecontrollers "sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/controllers"
 )
 
@@ -36,6 +37,52 @@ type KubeadmControlPlaneReconciler struct {
 
 	EtcdDialTimeout time.Duration
 
+	// EtcdNamespace is the namespace where the etcd pods are running in the workload clusters;
+	// if not set, kube-system is used.
+	EtcdNamespace string
+
+	// EtcdConnectionStrategy defines how to connect to the etcd members of the workload clusters, either ProxyViaPod,
+	// DirectToNode or DirectToEndpoints; if not set, the etcd pods are reached by port-forwarding via the workload cluster API server.
+	EtcdConnectionStrategy string
+
+	// EtcdDBSizeWarningThreshold is the percentage of the etcd backend database quota above which
+	// a warning is reported for an etcd member; 0 disables the check.
+	EtcdDBSizeWarningThreshold int
+
+	// EtcdDialRetries is the number of times connecting to an etcd member is retried when checking its health,
+	// before reporting the member as unhealthy; 0 disables retries.
+	EtcdDialRetries int
+
+	// EtcdLearnerPromotionTimeout is how long an etcd member can be a learner before it is reported as unhealthy,
+	// given that a learner which is never promoted to voting member usually means a stuck scale up; 0 disables the check.
+	EtcdLearnerPromotionTimeout time.Duration
+
+	// EtcdMemberConsistencyMode defines how the etcd members are required to agree on the list of members for being
+	// considered consistent, either Unanimous or Quorum; if not set, all the etcd members are required to agree.
+	EtcdMemberConsistencyMode string
+
+	// EtcdHealthCacheTTL is how long the result of an etcd health check for a cluster is re-used; 0 disables caching.
+	EtcdHealthCacheTTL time.Duration
+
+	// EtcdClientCertCommonName is the CommonName of the client certificate generated for connecting to etcd;
+	// if not set, cluster-api.x-k8s.io is used.
+	EtcdClientCertCommonName string
+
+	// EtcdClientCertOrganization is the Organization of the client certificate generated for connecting to etcd.
+	EtcdClientCertOrganization []string
+
+	// IncludeUnlabeledMachines, if set, also considers the machines lacking the cluster name label whose owner chain
+	// resolves to the cluster, e.g. adopted or legacy machines.
+	IncludeUnlabeledMachines bool
+
+	// BackfillClusterNameLabel, if set together with IncludeUnlabeledMachines, sets the cluster name label on
+	// the unlabeled machines.
+	BackfillClusterNameLabel bool
+
+	// RecreateMissingEtcdMembers, if set, re-creates the etcd member of control plane nodes whose member has been
+	// removed while the node is still there.
+	RecreateMissingEtcdMembers bool
+
 	// WatchFilterValue is the label value used to filter events prior to reconciliation.
 	WatchFilterValue string
 }
@@ -43,10 +90,22 @@ type KubeadmControlPlaneReconciler struct {
 // SetupWithManager sets up the reconciler with the Manager.
 func (r *KubeadmControlPlaneReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
 	return (&kubeadmcontrolplanecontrollers.KubeadmControlPlaneReconciler{
-		Client:           r.Client,
-		APIReader:        r.APIReader,
-		Tracker:          r.Tracker,
-		EtcdDialTimeout:  r.EtcdDialTimeout,
-		WatchFilterValue: r.WatchFilterValue,
+		Client:                      r.Client,
+		APIReader:                   r.APIReader,
+		Tracker:                     r.Tracker,
+		EtcdDialTimeout:             r.EtcdDialTimeout,
+		EtcdNamespace:               r.EtcdNamespace,
+		EtcdConnectionStrategy:      internal.EtcdConnectionStrategy(r.EtcdConnectionStrategy),
+		EtcdDBSizeWarningThreshold:  r.EtcdDBSizeWarningThreshold,
+		EtcdDialRetries:             r.EtcdDialRetries,
+		EtcdLearnerPromotionTimeout: r.EtcdLearnerPromotionTimeout,
+		EtcdMemberConsistencyMode:   internal.EtcdMemberConsistencyMode(r.EtcdMemberConsistencyMode),
+		EtcdHealthCacheTTL:          r.EtcdHealthCacheTTL,
+		EtcdClientCertCommonName:    r.EtcdClientCertCommonName,
+		EtcdClientCertOrganization:  r.EtcdClientCertOrganization,
+		IncludeUnlabeledMachines:    r.IncludeUnlabeledMachines,
+		BackfillClusterNameLabel:    r.BackfillClusterNameLabel,
+		RecreateMissingEtcdMembers:  r.RecreateMissingEtcdMembers,
+		WatchFilterValue:            r.WatchFilterValue,
 	}).SetupWithManager(ctx, mgr, options)
 }
@@ -20,17 +20,29 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controllers/remote"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd"
 	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/secret"
 )
 
@@ -39,6 +51,75 @@ const (
 	KubeadmControlPlaneControllerName = "kubeadm-controlplane-controller"
 )
 
+var (
+	// ErrEtcdCASecretNotFound signals that the etcd CA secret of a cluster could not be found, e.g. because
+	// it is not created yet.
+	ErrEtcdCASecretNotFound = errors.New("etcd CA secret not found")
+
+	// ErrEtcdCACertMissing signals that the etcd CA secret of a cluster exists, but it does not contain
+	// the CA certificate.
+	ErrEtcdCACertMissing = errors.New("etcd CA certificate is missing")
+
+	// ErrEtcdCACertInvalid signals that the etcd CA secret of a cluster contains a CA certificate which
+	// can't be parsed, e.g. because the PEM data is malformed.
+	ErrEtcdCACertInvalid = errors.New("etcd CA certificate is invalid")
+
+	// ErrEtcdHealthUnknown signals that the etcd health of a cluster could not be determined, e.g. because the
+	// workload cluster can't be reached; this is different from etcd being known to be unhealthy.
+	ErrEtcdHealthUnknown = errors.New("etcd health is unknown")
+)
+
+// etcdHealthUnknownError wraps the error which prevented determining the etcd health of a cluster;
+// it matches ErrEtcdHealthUnknown, while preserving the original error.
+type etcdHealthUnknownError struct {
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e *etcdHealthUnknownError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrEtcdHealthUnknown, e.Err)
+}
+
+// Unwrap satisfies the unwrap error inteface.
+func (e *etcdHealthUnknownError) Unwrap() error { return e.Err }
+
+// Is reports if the target is ErrEtcdHealthUnknown.
+func (e *etcdHealthUnknownError) Is(target error) bool { return target == ErrEtcdHealthUnknown }
+
+// EtcdHealthUnknownPolicy defines how an etcd health check which could not determine the etcd health is handled.
+type EtcdHealthUnknownPolicy string
+
+const (
+	// EtcdHealthUnknownFailClosed treats an unknown etcd health as unhealthy, i.e. ErrEtcdHealthUnknown is returned.
+	EtcdHealthUnknownFailClosed EtcdHealthUnknownPolicy = "FailClosed"
+
+	// EtcdHealthUnknownFailOpen treats an unknown etcd health as healthy, i.e. ErrEtcdHealthUnknown is ignored.
+	EtcdHealthUnknownFailOpen EtcdHealthUnknownPolicy = "FailOpen"
+)
+
+// apply returns the result of an etcd health check according to the policy; errors other than ErrEtcdHealthUnknown,
+// i.e. etcd being known to be unhealthy, are always returned.
+func (p EtcdHealthUnknownPolicy) apply(err error) error {
+	if p == EtcdHealthUnknownFailOpen && errors.Is(err, ErrEtcdHealthUnknown) {
+		return nil
+	}
+	return err
+}
+
+// EtcdHealthCheckMode defines how thoroughly EtcdIsHealthy checks the etcd health.
+type EtcdHealthCheckMode string
+
+const (
+	// EtcdHealthCheckFull connects to the etcd member hosted on each control plane node, checking alarms, learners,
+	// the consistency of the members and the correspondence between members and machines. This is the default.
+	EtcdHealthCheckFull EtcdHealthCheckMode = "Full"
+
+	// EtcdHealthCheckQuorumOnly connects to a single etcd member, checking only that the etcd cluster has a leader
+	// and a quorum of voting members; this trades thoroughness for speed, e.g. for a quick gate during time sensitive
+	// operations which only need to know that etcd can make progress.
+	EtcdHealthCheckQuorumOnly EtcdHealthCheckMode = "QuorumOnly"
+)
+
 // ManagementCluster defines all behaviors necessary for something to function as a management cluster.
 type ManagementCluster interface {
 	client.Reader
@@ -46,6 +127,9 @@ type ManagementCluster interface {
 	GetMachinesForCluster(ctx context.Context, cluster *clusterv1.Cluster, filters ...collections.Func) (collections.Machines, error)
 	GetMachinePoolsForCluster(ctx context.Context, cluster *clusterv1.Cluster) (*expv1.MachinePoolList, error)
 	GetWorkloadCluster(ctx context.Context, clusterKey client.ObjectKey) (WorkloadCluster, error)
+	CloseEtcdClients(clusterKey client.ObjectKey) error
+	RemoveEtcdMemberForMachine(ctx context.Context, clusterKey client.ObjectKey, machine *clusterv1.Machine) error
+	EtcdHealthDetails(ctx context.Context, kcp *controlplanev1.KubeadmControlPlane, clusterKey client.ObjectKey) (*EtcdHealthDetails, error)
 }
 
 // Management holds operations on the management cluster.
@@ -53,6 +137,78 @@ type Management struct {
 	Client          client.Reader
 	Tracker         *remote.ClusterCacheTracker
 	EtcdDialTimeout time.Duration
+
+	// SystemNamespace is the namespace where the control plane components and the kubeadm, kubelet, kube-proxy
+	// and CoreDNS objects live in the workload clusters; if not set, kube-system is used.
+	SystemNamespace string
+
+	// EtcdNamespace is the namespace where the etcd pods are running in the workload clusters;
+	// if not set, SystemNamespace is used.
+	EtcdNamespace string
+
+	// EtcdConnectionStrategy defines how to connect to the etcd members of the workload clusters;
+	// if not set, EtcdConnectionProxyViaPod is used.
+	EtcdConnectionStrategy EtcdConnectionStrategy
+
+	// EtcdNodeAddressResolver, if set, returns the host and port to dial for the etcd member hosted on a node,
+	// e.g. for providers exposing etcd on a custom address or port; the etcd members are then dialed directly,
+	// regardless of EtcdConnectionStrategy.
+	EtcdNodeAddressResolver func(node corev1.Node) (string, int, error)
+
+	// EtcdDBSizeWarningThreshold is the percentage of the etcd backend database quota above which
+	// a warning is reported for an etcd member; 0 disables the check.
+	EtcdDBSizeWarningThreshold int
+
+	// EtcdDialRetries is the number of times connecting to an etcd member and listing members is retried
+	// when checking the etcd member health, before reporting the member as unhealthy; 0 disables retries.
+	EtcdDialRetries int
+
+	// EtcdLearnerPromotionTimeout is how long an etcd member can be a learner before it is reported as unhealthy,
+	// given that a learner which is never promoted to voting member usually means a stuck scale up; 0 disables the check.
+	EtcdLearnerPromotionTimeout time.Duration
+
+	// EtcdMemberConsistencyMode defines how the etcd members are required to agree on the list of members for being
+	// considered consistent; if not set, EtcdMemberConsistencyUnanimous is used.
+	EtcdMemberConsistencyMode EtcdMemberConsistencyMode
+
+	// ControlPlaneNodeLabels are the labels used to identify the control plane nodes of the workload clusters,
+	// which are hosting the etcd members; a node is considered a control plane node if it has any of the labels.
+	// If not set, the node-role.kubernetes.io/control-plane and node-role.kubernetes.io/master labels are used.
+	ControlPlaneNodeLabels []string
+
+	// IncludeUnlabeledMachines, if set, makes GetMachinesForCluster also return the machines lacking the cluster name
+	// label whose owner chain resolves to the cluster, e.g. adopted or legacy machines whose labels weren't set at creation.
+	// The cluster name label is set on the returned copies of those machines, so label based filters match them.
+	// NOTE: The machines are never modified; see KubeadmControlPlaneReconciler.BackfillClusterNameLabel for persisting the label.
+	IncludeUnlabeledMachines bool
+
+	// EtcdHealthCacheTTL is how long the result of an etcd health check for a cluster is re-used
+	// by EtcdHealthDetails and EtcdIsHealthy; 0 disables caching.
+	// NOTE: The cached result is dropped whenever the etcd members of the cluster are changed.
+	EtcdHealthCacheTTL time.Duration
+
+	// EtcdHealthUnknownPolicy defines how EtcdIsHealthy handles checks which could not determine the etcd health,
+	// e.g. because the workload cluster can't be reached; if not set, EtcdHealthUnknownFailClosed is used.
+	EtcdHealthUnknownPolicy EtcdHealthUnknownPolicy
+
+	// EtcdHealthCheckMode defines how thoroughly EtcdIsHealthy checks the etcd health; if not set, EtcdHealthCheckFull is used.
+	// NOTE: EtcdHealthDetails always performs the full check.
+	EtcdHealthCheckMode EtcdHealthCheckMode
+
+	// DefaultTimeout is the timeout applied to operations on the management cluster and on etcd
+	// when the context passed by the caller has no deadline; 0 disables the default timeout.
+	DefaultTimeout time.Duration
+
+	// EtcdClientCertCommonName is the CommonName of the client certificate generated for connecting to the etcd
+	// members of the workload clusters; if not set, cluster-api.x-k8s.io is used.
+	EtcdClientCertCommonName string
+
+	// EtcdClientCertOrganization is the Organization of the client certificate generated for connecting to the etcd
+	// members of the workload clusters, e.g. to make etcd access attributable to a specific management cluster.
+	EtcdClientCertOrganization []string
+
+	etcdHealthCache etcdHealthCache
+	etcdClientPools etcdClientPools
 }
 
 // RemoteClusterConnectionError represents a failure to connect to a remote cluster.
@@ -67,6 +223,39 @@ func (e *RemoteClusterConnectionError) Error() string { return e.Name + ": " + e
 // Unwrap satisfies the unwrap error inteface.
 func (e *RemoteClusterConnectionError) Unwrap() error { return e.Err }
 
+// ScalingPrecondition is a precondition that must be satisfied before scaling a control plane.
+type ScalingPrecondition string
+
+const (
+	// ControlPlaneMachinesHaveNodesPrecondition requires all the control plane machines to have a node.
+	ControlPlaneMachinesHaveNodesPrecondition ScalingPrecondition = "ControlPlaneMachinesHaveNodes"
+
+	// EtcdHealthyPrecondition requires the etcd cluster and its members to be healthy.
+	EtcdHealthyPrecondition ScalingPrecondition = "EtcdHealthy"
+)
+
+// ScalingPreconditionError represents a precondition not satisfied when checking if a control plane can be scaled.
+type ScalingPreconditionError struct {
+	Precondition ScalingPrecondition
+	Err          error
+}
+
+// Error satisfies the error interface.
+func (e *ScalingPreconditionError) Error() string {
+	return fmt.Sprintf("precondition %s not satisfied: %v", e.Precondition, e.Err)
+}
+
+// Unwrap satisfies the unwrap error inteface.
+func (e *ScalingPreconditionError) Unwrap() error { return e.Err }
+
+// withDefaultTimeout returns a child context with DefaultTimeout as a deadline, if the given context has no deadline.
+func (m *Management) withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || m.DefaultTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, m.DefaultTimeout)
+}
+
 // Get implements client.Reader.
 func (m *Management) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
 	return m.Client.Get(ctx, key, obj)
@@ -79,12 +268,38 @@ func (m *Management) List(ctx context.Context, list client.ObjectList, opts ...c
 
 // GetMachinesForCluster returns a list of machines that can be filtered or not.
 // If no filter is supplied then all machines associated with the target cluster are returned.
+// The returned machines are unordered; use SortedByCreationTimestamp or Names when a deterministic order is required.
 func (m *Management) GetMachinesForCluster(ctx context.Context, cluster *clusterv1.Cluster, filters ...collections.Func) (collections.Machines, error) {
-	return collections.GetFilteredMachinesForCluster(ctx, m.Client, cluster, filters...)
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if !m.IncludeUnlabeledMachines {
+		return collections.GetFilteredMachinesForCluster(ctx, m.Client, cluster, filters...)
+	}
+
+	machines, err := collections.GetFilteredMachinesForCluster(ctx, m.Client, cluster)
+	if err != nil {
+		return nil, err
+	}
+	unlabeled, err := collections.GetUnlabeledMachinesForCluster(ctx, m.Client, cluster)
+	if err != nil {
+		return nil, err
+	}
+	for _, machine := range unlabeled {
+		if machine.Labels == nil {
+			machine.Labels = map[string]string{}
+		}
+		machine.Labels[clusterv1.ClusterLabelName] = cluster.Name
+		machines.Insert(machine)
+	}
+	return machines.Filter(filters...), nil
 }
 
 // GetMachinePoolsForCluster returns a list of machine pools owned by the cluster.
 func (m *Management) GetMachinePoolsForCluster(ctx context.Context, cluster *clusterv1.Cluster) (*expv1.MachinePoolList, error) {
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
 	selectors := []client.ListOption{
 		client.InNamespace(cluster.GetNamespace()),
 		client.MatchingLabels{
@@ -129,7 +344,7 @@ func (m *Management) GetWorkloadCluster(ctx context.Context, clusterKey client.O
 	// TODO: consider if we can detect if we are using external etcd in a more explicit way (e.g. looking at the config instead of deriving from the existing certificates)
 	var clientCert tls.Certificate
 	if keyData != nil {
-		clientCert, err = generateClientCert(crtData, keyData)
+		clientCert, err = generateClientCert(crtData, keyData, m.etcdClientCertSubject())
 		if err != nil {
 			return nil, err
 		}
@@ -140,39 +355,152 @@ func (m *Management) GetWorkloadCluster(ctx context.Context, clusterKey client.O
 		}
 	}
 
+	tlsConfig, err := etcdTLSConfig(crtData, clientCert)
+	if err != nil {
+		return nil, err
+	}
+
+	etcdClientGenerator := NewEtcdClientGenerator(restConfig, tlsConfig, m.EtcdDialTimeout)
+	if m.SystemNamespace != "" {
+		etcdClientGenerator.etcdNamespace = m.SystemNamespace
+	}
+	if m.EtcdNamespace != "" {
+		etcdClientGenerator.etcdNamespace = m.EtcdNamespace
+	}
+	if m.EtcdConnectionStrategy != "" {
+		etcdClientGenerator.connectionStrategy = m.EtcdConnectionStrategy
+	}
+	etcdClientGenerator.getNode = func(ctx context.Context, nodeName string) (*corev1.Node, error) {
+		node := &corev1.Node{}
+		if err := c.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	etcdClientGenerator.nodeAddressResolver = m.EtcdNodeAddressResolver
+	if m.EtcdConnectionStrategy == EtcdConnectionDirectToEndpoints {
+		etcdClientGenerator.endpoints, err = m.getEtcdEndpoints(ctx, clusterKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if keyData != nil {
+		etcdClientGenerator.generateClientCert = func() (tls.Certificate, error) {
+			return generateClientCert(crtData, keyData, m.etcdClientCertSubject())
+		}
+	}
+	etcdClientGenerator.pool = m.etcdClientPools.get(clusterKey)
+	return &Workload{
+		Client:                      c,
+		CoreDNSMigrator:             &CoreDNSMigrator{},
+		etcdClientGenerator:         etcdClientGenerator,
+		etcdDBSizeWarningThreshold:  m.EtcdDBSizeWarningThreshold,
+		etcdDialRetries:             m.EtcdDialRetries,
+		etcdLearnerPromotionTimeout: m.EtcdLearnerPromotionTimeout,
+		etcdMemberConsistencyMode:   m.EtcdMemberConsistencyMode,
+		controlPlaneNodeLabels:      m.ControlPlaneNodeLabels,
+		systemNamespace:             m.SystemNamespace,
+		clusterKey:                  clusterKey,
+		onEtcdMembersChanged: func() {
+			m.etcdHealthCache.delete(clusterKey)
+		},
+	}, nil
+}
+
+// CloseEtcdClients closes the etcd clients kept open for a cluster, e.g. when the cluster is deleted.
+func (m *Management) CloseEtcdClients(clusterKey client.ObjectKey) error {
+	pool := m.etcdClientPools.delete(clusterKey)
+	if pool == nil {
+		return nil
+	}
+	return pool.Close()
+}
+
+// getEtcdEndpoints returns the etcd endpoints configured for a cluster with the EtcdEndpointsAnnotation on
+// its KubeadmControlPlane.
+func (m *Management) getEtcdEndpoints(ctx context.Context, clusterKey client.ObjectKey) ([]string, error) {
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	cluster := &clusterv1.Cluster{}
+	if err := m.Client.Get(ctx, clusterKey, cluster); err != nil {
+		return nil, errors.Wrapf(err, "failed to get cluster %s/%s", clusterKey.Namespace, clusterKey.Name)
+	}
+	if cluster.Spec.ControlPlaneRef == nil {
+		return nil, errors.Errorf("cluster %s/%s does not have a control plane", clusterKey.Namespace, clusterKey.Name)
+	}
+
+	kcp := &controlplanev1.KubeadmControlPlane{}
+	if err := m.Client.Get(ctx, client.ObjectKey{Namespace: clusterKey.Namespace, Name: cluster.Spec.ControlPlaneRef.Name}, kcp); err != nil {
+		return nil, errors.Wrapf(err, "failed to get KubeadmControlPlane %s/%s", clusterKey.Namespace, cluster.Spec.ControlPlaneRef.Name)
+	}
+
+	var endpoints []string
+	for _, endpoint := range strings.Split(kcp.Annotations[controlplanev1.EtcdEndpointsAnnotation], ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, errors.Errorf("unable to connect directly to etcd: the %s annotation is not set on KubeadmControlPlane %s/%s", controlplanev1.EtcdEndpointsAnnotation, kcp.Namespace, kcp.Name)
+	}
+	return endpoints, nil
+}
+
+// etcdClientCertSubject returns the subject of the client certificates generated for connecting to etcd.
+func (m *Management) etcdClientCertSubject() pkix.Name {
+	subject := pkix.Name{
+		CommonName:   defaultEtcdClientCertCommonName,
+		Organization: m.EtcdClientCertOrganization,
+	}
+	if m.EtcdClientCertCommonName != "" {
+		subject.CommonName = m.EtcdClientCertCommonName
+	}
+	return subject
+}
+
+// etcdTLSConfig returns the TLS config for connecting to etcd with the given etcd CA certificate and client certificate.
+// NOTE: The etcd serving certificate is verified by each client against the host it dials, see EtcdClientGenerator.
+func etcdTLSConfig(caData []byte, clientCert tls.Certificate) (*tls.Config, error) {
 	caPool := x509.NewCertPool()
-	caPool.AppendCertsFromPEM(crtData)
-	tlsConfig := &tls.Config{
+	if !caPool.AppendCertsFromPEM(caData) {
+		return nil, errors.Wrap(ErrEtcdCACertInvalid, "etcd CA PEM contained no valid certificates")
+	}
+	return &tls.Config{
 		RootCAs:      caPool,
 		Certificates: []tls.Certificate{clientCert},
 		MinVersion:   tls.VersionTLS12,
-	}
-	tlsConfig.InsecureSkipVerify = true
-	return &Workload{
-		Client:              c,
-		CoreDNSMigrator:     &CoreDNSMigrator{},
-		etcdClientGenerator: NewEtcdClientGenerator(restConfig, tlsConfig, m.EtcdDialTimeout),
 	}, nil
 }
 
 func (m *Management) getEtcdCAKeyPair(ctx context.Context, clusterKey client.ObjectKey) ([]byte, []byte, error) {
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
 	etcdCASecret := &corev1.Secret{}
 	etcdCAObjectKey := client.ObjectKey{
 		Namespace: clusterKey.Namespace,
 		Name:      fmt.Sprintf("%s-etcd", clusterKey.Name),
 	}
 	if err := m.Client.Get(ctx, etcdCAObjectKey, etcdCASecret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, errors.Wrapf(ErrEtcdCASecretNotFound, "failed to get secret; etcd CA bundle %s/%s", etcdCAObjectKey.Namespace, etcdCAObjectKey.Name)
+		}
 		return nil, nil, errors.Wrapf(err, "failed to get secret; etcd CA bundle %s/%s", etcdCAObjectKey.Namespace, etcdCAObjectKey.Name)
 	}
 	crtData, ok := etcdCASecret.Data[secret.TLSCrtDataName]
 	if !ok {
-		return nil, nil, errors.Errorf("etcd tls crt does not exist for cluster %s/%s", clusterKey.Namespace, clusterKey.Name)
+		return nil, nil, errors.Wrapf(ErrEtcdCACertMissing, "etcd tls crt does not exist for cluster %s/%s", clusterKey.Namespace, clusterKey.Name)
 	}
+	// NOTE: The CA key is not required, given that it is missing when using an external etcd.
 	keyData := etcdCASecret.Data[secret.TLSKeyDataName]
 	return crtData, keyData, nil
 }
 
 func (m *Management) getAPIServerEtcdClientCert(ctx context.Context, clusterKey client.ObjectKey) (tls.Certificate, error) {
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
 	apiServerEtcdClientCertificateSecret := &corev1.Secret{}
 	apiServerEtcdClientCertificateObjectKey := client.ObjectKey{
 		Namespace: clusterKey.Namespace,
@@ -191,3 +519,520 @@ func (m *Management) getAPIServerEtcdClientCert(ctx context.Context, clusterKey
 	}
 	return tls.X509KeyPair(crtData, keyData)
 }
+
+const (
+	// etcdHealthSummaryConcurrency is the maximum number of clusters checked in parallel by EtcdHealthSummary.
+	etcdHealthSummaryConcurrency = 10
+
+	// etcdHealthSummaryTimeout is the overall deadline for EtcdHealthSummary; clusters not checked
+	// within this deadline are reported with the context error.
+	etcdHealthSummaryTimeout = 1 * time.Minute
+)
+
+// EtcdHealthSummary checks etcd health for all the clusters with a KubeadmControlPlane, and returns
+// the result keyed by cluster; a nil error means the etcd cluster is healthy.
+// Clusters are checked with a bounded concurrency and within an overall deadline, so a single unresponsive
+// cluster does not block the summary.
+func (m *Management) EtcdHealthSummary(ctx context.Context) (map[client.ObjectKey]error, error) {
+	kcpList := &controlplanev1.KubeadmControlPlaneList{}
+	if err := m.Client.List(ctx, kcpList); err != nil {
+		return nil, errors.Wrap(err, "failed to list KubeadmControlPlanes")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdHealthSummaryTimeout)
+	defer cancel()
+
+	// Assume the check did not complete for all the clusters; this is overridden once the check for each cluster completes.
+	targets := map[client.ObjectKey]*controlplanev1.KubeadmControlPlane{}
+	results := map[client.ObjectKey]error{}
+	for i := range kcpList.Items {
+		kcp := &kcpList.Items[i]
+		clusterName := ownerClusterName(kcp.ObjectMeta)
+		if clusterName == "" {
+			continue
+		}
+		clusterKey := client.ObjectKey{Namespace: kcp.Namespace, Name: clusterName}
+		targets[clusterKey] = kcp
+		results[clusterKey] = errors.New("etcd health check did not complete")
+	}
+
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, etcdHealthSummaryConcurrency)
+	)
+	for clusterKey, kcp := range targets {
+		clusterKey, kcp := clusterKey, kcp
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var err error
+			select {
+			case sem <- struct{}{}:
+				err = m.EtcdIsHealthy(ctx, kcp, clusterKey)
+				<-sem
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			if results != nil {
+				results[clusterKey] = err
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	// Return a snapshot of the results; checks still running after the deadline are not recorded.
+	lock.Lock()
+	defer lock.Unlock()
+	summary := results
+	results = nil
+	return summary, nil
+}
+
+// RemoveEtcdMemberForMachine removes the etcd member hosted on a control plane machine of a cluster, forwarding etcd
+// leadership and checking that etcd quorum is preserved before removing the member, and checking etcd health after it;
+// this is meant to be called before deleting a control plane machine.
+// See Workload.SafelyRemoveEtcdMemberForMachine for details.
+func (m *Management) RemoveEtcdMemberForMachine(ctx context.Context, clusterKey client.ObjectKey, machine *clusterv1.Machine) error {
+	// NOTE: The default timeout is not applied when getting the workload cluster, because the context
+	// determines the lifecycle of the cached client to the workload cluster, if created by this call.
+	workloadCluster, err := m.GetWorkloadCluster(ctx, clusterKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to create client to workload cluster")
+	}
+
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	return workloadCluster.SafelyRemoveEtcdMemberForMachine(ctx, machine)
+}
+
+// DumpEtcdState returns a snapshot of the state of the etcd cluster of a cluster, including the list of members,
+// the alarms, the leader and the database size of each member, so it can be included in bug reports and
+// support cases; see Workload.EtcdState for details.
+func (m *Management) DumpEtcdState(ctx context.Context, clusterKey client.ObjectKey) (*EtcdState, error) {
+	// NOTE: The default timeout is not applied when getting the workload cluster, because the context
+	// determines the lifecycle of the cached client to the workload cluster, if created by this call.
+	workloadCluster, err := m.GetWorkloadCluster(ctx, clusterKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create client to workload cluster")
+	}
+
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	return workloadCluster.EtcdState(ctx)
+}
+
+// EtcdIsHealthy checks etcd health for a cluster; a nil error means the etcd cluster is healthy.
+// If EtcdHealthCacheTTL is set, a result for the same cluster computed within the TTL is returned instead
+// of checking again, thus avoiding repeated connections to the etcd members in a short time frame; this does
+// not apply to EtcdHealthCheckQuorumOnly, which is cheap enough to be checked every time.
+// If the etcd health could not be determined, an error wrapping ErrEtcdHealthUnknown is returned, unless
+// EtcdHealthUnknownPolicy is EtcdHealthUnknownFailOpen.
+func (m *Management) EtcdIsHealthy(ctx context.Context, kcp *controlplanev1.KubeadmControlPlane, clusterKey client.ObjectKey) error {
+	return m.EtcdHealthUnknownPolicy.apply(m.etcdHealth(ctx, kcp, clusterKey))
+}
+
+// etcdHealth checks the etcd cluster for the given KubeadmControlPlane, using the same checks used to
+// compute the EtcdClusterHealthy condition, or only checking etcd quorum if required by EtcdHealthCheckMode.
+func (m *Management) etcdHealth(ctx context.Context, kcp *controlplanev1.KubeadmControlPlane, clusterKey client.ObjectKey) error {
+	if m.EtcdHealthCheckMode == EtcdHealthCheckQuorumOnly {
+		return m.etcdQuorumHealth(ctx, clusterKey)
+	}
+
+	details, err := m.EtcdHealthDetails(ctx, kcp, clusterKey)
+	if err != nil {
+		return err
+	}
+	return details.Cluster
+}
+
+// etcdQuorumHealth checks that the etcd cluster for the given cluster has a leader and a quorum of voting members.
+// See Workload.EtcdQuorumIsHealthy for details.
+func (m *Management) etcdQuorumHealth(ctx context.Context, clusterKey client.ObjectKey) error {
+	defer observeEtcdHealthCheckDuration(clusterKey, time.Now())
+
+	// NOTE: The default timeout is not applied when getting the workload cluster, because the context
+	// determines the lifecycle of the cached client to the workload cluster, if created by this call.
+	workloadCluster, err := m.GetWorkloadCluster(ctx, clusterKey)
+	if err != nil {
+		return &etcdHealthUnknownError{Err: errors.Wrap(err, "failed to create client to workload cluster")}
+	}
+
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	return workloadCluster.EtcdQuorumIsHealthy(ctx)
+}
+
+// EtcdHealthDetails contains the detailed result of an etcd health check for a cluster.
+type EtcdHealthDetails struct {
+	// Members contains the health of the etcd member hosted on each control plane node, keyed by node name;
+	// a nil error means the etcd member is healthy.
+	Members map[string]error
+
+	// Cluster contains the health of the etcd cluster as a whole, including the problems not related to a single member,
+	// e.g. etcd members and control plane nodes not corresponding; a nil error means the etcd cluster is healthy.
+	Cluster error
+
+	// MemberNames contains the names of the etcd members as reported by the etcd members during the health check;
+	// it is nil if no etcd member could be reached.
+	MemberNames []string
+
+	// LeaderNodeName is the name of the node hosting the etcd leader, as reported by the etcd members during
+	// the health check; it is empty if the leader is unknown.
+	LeaderNodeName string
+}
+
+// EtcdHealthDetails checks etcd health for a cluster, returning the health of each etcd member, so callers
+// can decide on which specific node to act without connecting to etcd again.
+// If the etcd health could not be determined, e.g. because the workload cluster can't be reached, the error
+// wraps ErrEtcdHealthUnknown, so callers can tell it apart from etcd being known to be unhealthy.
+// If EtcdHealthCacheTTL is set, a result for the same cluster computed within the TTL is returned instead
+// of checking again; the returned details are shared with other callers and must not be modified.
+func (m *Management) EtcdHealthDetails(ctx context.Context, kcp *controlplanev1.KubeadmControlPlane, clusterKey client.ObjectKey) (*EtcdHealthDetails, error) {
+	if m.EtcdHealthCacheTTL <= 0 {
+		return m.etcdHealthDetails(ctx, kcp, clusterKey)
+	}
+
+	if entry, ok := m.etcdHealthCache.get(clusterKey, m.EtcdHealthCacheTTL); ok {
+		return entry.details, entry.err
+	}
+	details, err := m.etcdHealthDetails(ctx, kcp, clusterKey)
+	// NOTE: Results of interrupted checks are not cached, given that they don't reflect the etcd health.
+	if ctx.Err() == nil && !errors.Is(err, context.DeadlineExceeded) {
+		m.etcdHealthCache.set(clusterKey, details, err)
+	}
+	return details, err
+}
+
+// etcdHealthDetails checks etcd health for a cluster, without using the cache.
+func (m *Management) etcdHealthDetails(ctx context.Context, kcp *controlplanev1.KubeadmControlPlane, clusterKey client.ObjectKey) (*EtcdHealthDetails, error) {
+	defer observeEtcdHealthCheckDuration(clusterKey, time.Now())
+
+	// NOTE: The default timeout is not applied when getting the workload cluster, because the context
+	// determines the lifecycle of the cached client to the workload cluster, if created by this call.
+	workloadCluster, err := m.GetWorkloadCluster(ctx, clusterKey)
+	if err != nil {
+		return nil, &etcdHealthUnknownError{Err: errors.Wrap(err, "failed to create client to workload cluster")}
+	}
+
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: clusterKey.Namespace, Name: clusterKey.Name}}
+	machines, err := m.GetMachinesForCluster(ctx, cluster, collections.ControlPlaneMachines(clusterKey.Name))
+	if err != nil {
+		return nil, &etcdHealthUnknownError{Err: errors.Wrap(err, "failed to get control plane machines")}
+	}
+
+	// NOTE: conditions are computed on copies and never persisted.
+	controlPlane := &ControlPlane{KCP: kcp.DeepCopy(), Machines: collections.New()}
+	for _, machine := range machines {
+		controlPlane.Machines.Insert(machine.DeepCopy())
+	}
+	workloadCluster.UpdateEtcdConditions(ctx, controlPlane)
+	if err := ctx.Err(); err != nil {
+		return nil, &etcdHealthUnknownError{Err: errors.Wrap(err, "etcd health check did not complete")}
+	}
+
+	return etcdHealthDetailsFromConditions(controlPlane), nil
+}
+
+// ForMachines returns the health of the etcd members keyed by the name of the given machines, using the node
+// referenced by each machine to find the etcd member it is hosting; machines without a node, or whose node
+// is not hosting any of the known etcd members, are reported with an error.
+func (d *EtcdHealthDetails) ForMachines(machines collections.Machines) map[string]error {
+	results := make(map[string]error, machines.Len())
+	for _, machine := range machines {
+		if machine.Status.NodeRef == nil {
+			results[machine.Name] = errors.New("machine does not have a node yet")
+			continue
+		}
+		err, ok := d.Members[machine.Status.NodeRef.Name]
+		if !ok {
+			results[machine.Name] = errors.Errorf("etcd member health for the %s node is unknown", machine.Status.NodeRef.Name)
+			continue
+		}
+		results[machine.Name] = err
+	}
+	return results
+}
+
+// String returns a human-readable summary of the etcd health check, with one "<node>: ok" or "<node>: <error>" line
+// for each etcd member, sorted by node name, preceded by a line for the etcd cluster as a whole.
+// The summary is stable across checks with the same results, so it can be used in logs, events and condition messages.
+func (d *EtcdHealthDetails) String() string {
+	nodeNames := make([]string, 0, len(d.Members))
+	for nodeName := range d.Members {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	lines := make([]string, 0, len(nodeNames)+1)
+	lines = append(lines, fmt.Sprintf("etcd cluster: %s", healthSummary(d.Cluster)))
+	for _, nodeName := range nodeNames {
+		lines = append(lines, fmt.Sprintf("%s: %s", nodeName, healthSummary(d.Members[nodeName])))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// healthSummary returns "ok" for a nil health check error, the error message otherwise.
+func healthSummary(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return err.Error()
+}
+
+// etcdHealthDetailsFromConditions returns the etcd health details from the etcd conditions of a control plane.
+func etcdHealthDetailsFromConditions(controlPlane *ControlPlane) *EtcdHealthDetails {
+	details := &EtcdHealthDetails{
+		Members:        map[string]error{},
+		Cluster:        conditionError(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition, "etcd cluster"),
+		MemberNames:    controlPlane.etcdMemberNames,
+		LeaderNodeName: controlPlane.etcdLeaderNodeName,
+	}
+	// If the etcd cluster could not be inspected, e.g. because the nodes of the workload cluster can't be listed,
+	// its health is unknown rather than unhealthy.
+	if conditions.GetReason(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition) == controlplanev1.EtcdClusterInspectionFailedReason {
+		details.Cluster = &etcdHealthUnknownError{Err: errors.New(conditions.GetMessage(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition))}
+	}
+	for _, machine := range controlPlane.Machines {
+		// Machines without a node are not hosting an etcd member yet.
+		if machine.Status.NodeRef == nil {
+			continue
+		}
+		details.Members[machine.Status.NodeRef.Name] = conditionError(machine, controlplanev1.MachineEtcdMemberHealthyCondition, "etcd member")
+	}
+	return details
+}
+
+// conditionError returns an error if a health condition is unknown, or false with error severity, nil otherwise.
+// NOTE: A false condition with warning or info severity, e.g. the etcd database approaching its quota or etcd members
+// running different versions, does not make etcd unhealthy; this allows KCP to keep scaling, e.g. to roll out a fix.
+func conditionError(from conditions.Getter, conditionType clusterv1.ConditionType, subject string) error {
+	condition := conditions.Get(from, conditionType)
+	if condition == nil || condition.Status == corev1.ConditionUnknown {
+		return errors.Errorf("%s health is unknown", subject)
+	}
+	if condition.Status == corev1.ConditionFalse && condition.Severity == clusterv1.ConditionSeverityError {
+		return errors.Errorf("%s is not healthy: %s", subject, condition.Message)
+	}
+	return nil
+}
+
+// ControlPlaneIsHealthyForScaling checks if it is safe to scale the given KubeadmControlPlane, i.e. if all the
+// control plane machines have a node and, if etcd is managed by KCP, the etcd cluster and its members are healthy.
+// If a precondition is not satisfied, a ScalingPreconditionError describing the first failing precondition is returned.
+// Machines in excludeFor, e.g. the machine being deleted when scaling down, are not considered by the checks.
+// NOTE: This gets the KubeadmControlPlane and its machines, so it is meant for callers not having them at hand;
+// the KubeadmControlPlane controller uses ControlPlaneHealthyForScaling with the machines it already has instead.
+func (m *Management) ControlPlaneIsHealthyForScaling(ctx context.Context, clusterKey client.ObjectKey, controlPlaneName string, excludeFor ...*clusterv1.Machine) error {
+	kcp := &controlplanev1.KubeadmControlPlane{}
+	getCtx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+	if err := m.Client.Get(getCtx, client.ObjectKey{Namespace: clusterKey.Namespace, Name: controlPlaneName}, kcp); err != nil {
+		return errors.Wrapf(err, "failed to get KubeadmControlPlane %s/%s", clusterKey.Namespace, controlPlaneName)
+	}
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: clusterKey.Namespace, Name: clusterKey.Name}}
+	machines, err := m.GetMachinesForCluster(ctx, cluster, collections.ControlPlaneMachines(clusterKey.Name), collections.OwnedMachines(kcp))
+	if err != nil {
+		return errors.Wrap(err, "failed to get control plane machines")
+	}
+
+	// If etcd is not managed by KCP, its health is not a precondition for scaling.
+	var etcdHealth func() (*EtcdHealthDetails, error)
+	if kcp.Spec.KubeadmConfigSpec.ClusterConfiguration == nil || kcp.Spec.KubeadmConfigSpec.ClusterConfiguration.Etcd.External == nil {
+		etcdHealth = func() (*EtcdHealthDetails, error) {
+			return m.EtcdHealthDetails(ctx, kcp, clusterKey)
+		}
+	}
+	return ControlPlaneHealthyForScaling(machines, etcdHealth, excludeFor...)
+}
+
+// ControlPlaneHealthyForScaling checks the preconditions for scaling a control plane with the given machines, i.e. that
+// all the machines have a node and, if etcdHealth is not nil, that the etcd cluster and its members are healthy;
+// etcdHealth is nil when etcd is not managed by KCP, and it is called only if all the machines have a node.
+// This allows to evaluate the same preconditions as ControlPlaneIsHealthyForScaling against machines and etcd health
+// details the caller already has, e.g. the cached ones returned by EtcdHealthDetails.
+func ControlPlaneHealthyForScaling(machines collections.Machines, etcdHealth func() (*EtcdHealthDetails, error), excludeFor ...*clusterv1.Machine) error {
+	// If there are no control plane machines, the control plane has not been initialized yet,
+	// so it is considered ok to proceed.
+	if machines.Len() == 0 {
+		return nil
+	}
+
+	excludedMachines := sets.NewString()
+	for _, machine := range excludeFor {
+		excludedMachines.Insert(machine.Name)
+	}
+
+	machinesWithoutNode := machines.Filter(func(machine *clusterv1.Machine) bool {
+		return !excludedMachines.Has(machine.Name) && machine.Status.NodeRef == nil
+	})
+	if machinesWithoutNode.Len() > 0 {
+		return &ScalingPreconditionError{
+			Precondition: ControlPlaneMachinesHaveNodesPrecondition,
+			Err:          errors.Errorf("machines %s do not have a node yet", strings.Join(machinesWithoutNode.Names(), ", ")),
+		}
+	}
+
+	if etcdHealth == nil {
+		return nil
+	}
+
+	details, err := etcdHealth()
+	if err != nil {
+		return errors.Wrap(err, "failed to check etcd health")
+	}
+	if err := etcdHealthyForScaling(details.ForMachines(machines), details.Cluster, excludedMachines); err != nil {
+		return &ScalingPreconditionError{
+			Precondition: EtcdHealthyPrecondition,
+			Err:          err,
+		}
+	}
+	return nil
+}
+
+// etcdHealthyForScaling returns an error if the etcd member hosted on any machine, except the excluded ones,
+// or the etcd cluster is not healthy.
+func etcdHealthyForScaling(machineHealth map[string]error, clusterHealth error, excludedMachines sets.String) error {
+	machineNames := make([]string, 0, len(machineHealth))
+	for machineName := range machineHealth {
+		machineNames = append(machineNames, machineName)
+	}
+	sort.Strings(machineNames)
+
+	excludedMemberUnhealthy := false
+	memberErrors := []error{}
+	for _, machineName := range machineNames {
+		err := machineHealth[machineName]
+		if err == nil {
+			continue
+		}
+		if excludedMachines.Has(machineName) {
+			excludedMemberUnhealthy = true
+			continue
+		}
+		memberErrors = append(memberErrors, errors.Wrapf(err, "machine %s", machineName))
+	}
+	if len(memberErrors) > 0 {
+		return kerrors.NewAggregate(memberErrors)
+	}
+
+	// NOTE: The etcd cluster health accounts for the health of all the members, so it is ignored
+	// when the only unhealthy members are the excluded ones.
+	if clusterHealth != nil && !excludedMemberUnhealthy {
+		return clusterHealth
+	}
+	return nil
+}
+
+// etcdHealthCache caches the results of etcd health checks by cluster.
+type etcdHealthCache struct {
+	lock    sync.Mutex
+	entries map[client.ObjectKey]etcdHealthCacheEntry
+}
+
+type etcdHealthCacheEntry struct {
+	details   *EtcdHealthDetails
+	err       error
+	timestamp time.Time
+}
+
+// get returns the cached result for a cluster, if it has been computed within the ttl.
+func (c *etcdHealthCache) get(clusterKey client.ObjectKey, ttl time.Duration) (etcdHealthCacheEntry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[clusterKey]
+	if !ok || time.Since(entry.timestamp) > ttl {
+		return etcdHealthCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// delete drops the result for a cluster, if any.
+func (c *etcdHealthCache) delete(clusterKey client.ObjectKey) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.entries, clusterKey)
+}
+
+// set stores the result for a cluster.
+func (c *etcdHealthCache) set(clusterKey client.ObjectKey, details *EtcdHealthDetails, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[client.ObjectKey]etcdHealthCacheEntry{}
+	}
+	c.entries[clusterKey] = etcdHealthCacheEntry{details: details, err: err, timestamp: time.Now()}
+}
+
+// etcdClientPools holds the pools of etcd clients for the workload clusters.
+type etcdClientPools struct {
+	lock  sync.Mutex
+	pools map[client.ObjectKey]*etcd.ClientPool
+}
+
+// get returns the pool for a cluster, creating it if it does not exist yet.
+func (p *etcdClientPools) get(clusterKey client.ObjectKey) *etcd.ClientPool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.pools == nil {
+		p.pools = map[client.ObjectKey]*etcd.ClientPool{}
+	}
+	pool, ok := p.pools[clusterKey]
+	if !ok {
+		pool = etcd.NewClientPool()
+		p.pools[clusterKey] = pool
+	}
+	return pool
+}
+
+// delete drops the pool for a cluster, if any, and returns it.
+func (p *etcdClientPools) delete(clusterKey client.ObjectKey) *etcd.ClientPool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pool := p.pools[clusterKey]
+	delete(p.pools, clusterKey)
+	return pool
+}
+
+// ownerClusterName returns the name of the Cluster owning the object, if any.
+func ownerClusterName(obj metav1.ObjectMeta) string {
+	for _, ref := range obj.OwnerReferences {
+		if ref.Kind != "Cluster" {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		if gv.Group == clusterv1.GroupVersion.Group {
+			return ref.Name
+		}
+	}
+	return ""
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"fmt"
@@ -28,19 +29,25 @@ import (
 	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controllers/remote"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/certs"
 	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/kubeconfig"
 	"sigs.k8s.io/cluster-api/util/secret"
 )
@@ -75,6 +82,71 @@ func TestGetMachinesForCluster(t *testing.T) {
 	g.Expect(machines).To(HaveLen(1))
 }
 
+func TestGetMachinesForClusterIncludingUnlabeledMachines(t *testing.T) {
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      "my-cluster",
+		},
+	}
+	labeledMachine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      "labeled-machine",
+			Labels: map[string]string{
+				clusterv1.ClusterLabelName:             "my-cluster",
+				clusterv1.MachineControlPlaneLabelName: "",
+			},
+		},
+	}
+	// The unlabeled machine belongs to the cluster via the owner reference of the KubeadmControlPlane owning it.
+	kcp := &controlplanev1.KubeadmControlPlane{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      "my-control-plane",
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster", Name: "my-cluster"},
+			},
+		},
+	}
+	unlabeledMachine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      "unlabeled-machine",
+			Labels: map[string]string{
+				clusterv1.MachineControlPlaneLabelName: "",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: controlplanev1.GroupVersion.String(), Kind: "KubeadmControlPlane", Name: "my-control-plane"},
+			},
+		},
+	}
+
+	t.Run("does not return unlabeled machines by default", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := &Management{Client: fake.NewClientBuilder().WithObjects(kcp.DeepCopy(), labeledMachine.DeepCopy(), unlabeledMachine.DeepCopy()).Build()}
+		machines, err := m.GetMachinesForCluster(ctx, cluster)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(machines.Names()).To(ConsistOf("labeled-machine"))
+	})
+
+	t.Run("returns unlabeled machines owned by the cluster, matching label based filters", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := fake.NewClientBuilder().WithObjects(kcp.DeepCopy(), labeledMachine.DeepCopy(), unlabeledMachine.DeepCopy()).Build()
+		m := &Management{Client: c, IncludeUnlabeledMachines: true}
+		machines, err := m.GetMachinesForCluster(ctx, cluster, collections.ControlPlaneMachines("my-cluster"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(machines.Names()).To(ConsistOf("labeled-machine", "unlabeled-machine"))
+
+		// The label is not backfilled.
+		got := &clusterv1.Machine{}
+		g.Expect(c.Get(ctx, client.ObjectKeyFromObject(unlabeledMachine), got)).To(Succeed())
+		g.Expect(got.Labels).ToNot(HaveKey(clusterv1.ClusterLabelName))
+	})
+}
+
 func TestGetWorkloadCluster(t *testing.T) {
 	g := NewWithT(t)
 
@@ -139,16 +211,28 @@ func TestGetWorkloadCluster(t *testing.T) {
 	}
 
 	tests := []struct {
-		name       string
-		clusterKey client.ObjectKey
-		objs       []client.Object
-		expectErr  bool
+		name                  string
+		clusterKey            client.ObjectKey
+		systemNamespace       string
+		objs                  []client.Object
+		expectErr             bool
+		expectedErr           error
+		expectedEtcdNamespace string
 	}{
 		{
-			name:       "returns a workload cluster",
-			clusterKey: clusterKey,
-			objs:       []client.Object{etcdSecret.DeepCopy(), kubeconfigSecret.DeepCopy()},
-			expectErr:  false,
+			name:                  "returns a workload cluster",
+			clusterKey:            clusterKey,
+			objs:                  []client.Object{etcdSecret.DeepCopy(), kubeconfigSecret.DeepCopy()},
+			expectErr:             false,
+			expectedEtcdNamespace: metav1.NamespaceSystem,
+		},
+		{
+			name:                  "returns a workload cluster using the configured system namespace",
+			clusterKey:            clusterKey,
+			systemNamespace:       "control-plane-system",
+			objs:                  []client.Object{etcdSecret.DeepCopy(), kubeconfigSecret.DeepCopy()},
+			expectErr:             false,
+			expectedEtcdNamespace: "control-plane-system",
 		},
 		{
 			name:       "returns error if cannot get rest.Config from kubeconfigSecret",
@@ -157,16 +241,18 @@ func TestGetWorkloadCluster(t *testing.T) {
 			expectErr:  true,
 		},
 		{
-			name:       "returns error if unable to find the etcd secret",
-			clusterKey: clusterKey,
-			objs:       []client.Object{kubeconfigSecret.DeepCopy()},
-			expectErr:  true,
+			name:        "returns error if unable to find the etcd secret",
+			clusterKey:  clusterKey,
+			objs:        []client.Object{kubeconfigSecret.DeepCopy()},
+			expectErr:   true,
+			expectedErr: ErrEtcdCASecretNotFound,
 		},
 		{
-			name:       "returns error if unable to find the certificate in the etcd secret",
-			clusterKey: clusterKey,
-			objs:       []client.Object{emptyCrtEtcdSecret.DeepCopy(), kubeconfigSecret.DeepCopy()},
-			expectErr:  true,
+			name:        "returns error if unable to find the certificate in the etcd secret",
+			clusterKey:  clusterKey,
+			objs:        []client.Object{emptyCrtEtcdSecret.DeepCopy(), kubeconfigSecret.DeepCopy()},
+			expectErr:   true,
+			expectedErr: ErrEtcdCACertMissing,
 		},
 		{
 			name:       "returns error if unable to find the key in the etcd secret",
@@ -196,18 +282,757 @@ func TestGetWorkloadCluster(t *testing.T) {
 			// Note: The API reader is intentionally used instead of the regular (cached) client
 			// to avoid test failures when the local cache isn't able to catch up in time.
 			m := Management{
-				Client:  env.GetAPIReader(),
-				Tracker: tracker,
+				Client:          env.GetAPIReader(),
+				Tracker:         tracker,
+				SystemNamespace: tt.systemNamespace,
 			}
 
 			workloadCluster, err := m.GetWorkloadCluster(ctx, tt.clusterKey)
 			if tt.expectErr {
 				g.Expect(err).To(HaveOccurred())
+				if tt.expectedErr != nil {
+					g.Expect(errors.Is(err, tt.expectedErr)).To(BeTrue())
+				}
 				g.Expect(workloadCluster).To(BeNil())
 				return
 			}
 			g.Expect(err).ToNot(HaveOccurred())
 			g.Expect(workloadCluster).ToNot(BeNil())
+
+			// Changing the etcd members through the workload cluster drops the cached etcd health.
+			m.etcdHealthCache.set(tt.clusterKey, &EtcdHealthDetails{}, nil)
+			workloadCluster.(*Workload).etcdMembersChanged()
+			_, ok := m.etcdHealthCache.get(tt.clusterKey, time.Minute)
+			g.Expect(ok).To(BeFalse())
+
+			// The etcd pods are proxied in the system namespace.
+			w := workloadCluster.(*Workload)
+			g.Expect(w.namespace()).To(Equal(tt.expectedEtcdNamespace))
+			etcdClientGenerator := w.etcdClientGenerator.(*EtcdClientGenerator)
+			g.Expect(etcdClientGenerator.proxy(etcdClientGenerator.tlsConfig).Namespace).To(Equal(tt.expectedEtcdNamespace))
+		})
+	}
+}
+
+func TestEtcdIsHealthyCache(t *testing.T) {
+	clusterKey := client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "my-cluster"}
+	kcp := &controlplanev1.KubeadmControlPlane{}
+
+	t.Run("returns the cached result within the TTL", func(t *testing.T) {
+		g := NewWithT(t)
+
+		// NOTE: The management cluster has no client, so any attempt to actually check etcd health fails the test.
+		m := &Management{EtcdHealthCacheTTL: time.Minute}
+		unhealthy := &EtcdHealthDetails{Cluster: errors.New("etcd cluster is not healthy")}
+		m.etcdHealthCache.set(clusterKey, unhealthy, nil)
+		g.Expect(m.EtcdIsHealthy(ctx, kcp, clusterKey)).To(MatchError("etcd cluster is not healthy"))
+		details, err := m.EtcdHealthDetails(ctx, kcp, clusterKey)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(details).To(BeIdenticalTo(unhealthy))
+
+		m.etcdHealthCache.set(clusterKey, &EtcdHealthDetails{}, nil)
+		g.Expect(m.EtcdIsHealthy(ctx, kcp, clusterKey)).To(Succeed())
+
+		m.etcdHealthCache.set(clusterKey, nil, errors.New("etcd health is unknown"))
+		g.Expect(m.EtcdIsHealthy(ctx, kcp, clusterKey)).To(MatchError("etcd health is unknown"))
+		_, err = m.EtcdHealthDetails(ctx, kcp, clusterKey)
+		g.Expect(err).To(MatchError("etcd health is unknown"))
+	})
+
+	t.Run("cached results expire after the TTL", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := &etcdHealthCache{}
+		_, ok := c.get(clusterKey, time.Minute)
+		g.Expect(ok).To(BeFalse())
+
+		c.set(clusterKey, &EtcdHealthDetails{}, nil)
+		_, ok = c.get(clusterKey, time.Minute)
+		g.Expect(ok).To(BeTrue())
+
+		// Other clusters are not affected.
+		_, ok = c.get(client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "another-cluster"}, time.Minute)
+		g.Expect(ok).To(BeFalse())
+
+		c.entries[clusterKey] = etcdHealthCacheEntry{timestamp: time.Now().Add(-2 * time.Minute)}
+		_, ok = c.get(clusterKey, time.Minute)
+		g.Expect(ok).To(BeFalse())
+	})
+}
+
+func TestEtcdHealthDetailsFromConditions(t *testing.T) {
+	g := NewWithT(t)
+
+	machineWithNode := func(name string, healthy bool) *clusterv1.Machine {
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: clusterv1.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: name + "-node"},
+			},
+		}
+		if healthy {
+			conditions.MarkTrue(m, controlplanev1.MachineEtcdMemberHealthyCondition)
+		} else {
+			conditions.MarkFalse(m, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "etcd member has alarms")
+		}
+		return m
+	}
+	withoutCondition := machineWithNode("m3", true)
+	conditions.Delete(withoutCondition, controlplanev1.MachineEtcdMemberHealthyCondition)
+	provisioning := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "m4"}}
+
+	kcp := &controlplanev1.KubeadmControlPlane{}
+	conditions.MarkFalse(kcp, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: m2")
+
+	controlPlane := &ControlPlane{
+		KCP:      kcp,
+		Machines: collections.FromMachines(machineWithNode("m1", true), machineWithNode("m2", false), withoutCondition, provisioning),
+	}
+
+	details := etcdHealthDetailsFromConditions(controlPlane)
+	g.Expect(details.Cluster).To(MatchError("etcd cluster is not healthy: Following machines are reporting etcd member errors: m2"))
+	g.Expect(details.Members).To(HaveLen(3))
+	g.Expect(details.Members).To(HaveKeyWithValue("m1-node", BeNil()))
+	g.Expect(details.Members).To(HaveKeyWithValue("m2-node", MatchError("etcd member is not healthy: etcd member has alarms")))
+	g.Expect(details.Members).To(HaveKeyWithValue("m3-node", MatchError("etcd member health is unknown")))
+
+	conditions.MarkTrue(kcp, controlplanev1.EtcdClusterHealthyCondition)
+	g.Expect(etcdHealthDetailsFromConditions(controlPlane).Cluster).ToNot(HaveOccurred())
+
+	// Warnings, e.g. the etcd database approaching its quota, do not make the etcd cluster unhealthy.
+	conditions.MarkFalse(kcp, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityWarning, "etcd member m1-node database size is approaching the quota")
+	g.Expect(etcdHealthDetailsFromConditions(controlPlane).Cluster).ToNot(HaveOccurred())
+
+	// If the etcd cluster could not be inspected, its health is unknown rather than unhealthy.
+	conditions.MarkUnknown(kcp, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterInspectionFailedReason, "Failed to list nodes which are hosting the etcd members")
+	details = etcdHealthDetailsFromConditions(controlPlane)
+	g.Expect(errors.Is(details.Cluster, ErrEtcdHealthUnknown)).To(BeTrue())
+	g.Expect(details.Cluster).To(MatchError("etcd health is unknown: Failed to list nodes which are hosting the etcd members"))
+}
+
+func TestEtcdIsHealthyUnknownPolicy(t *testing.T) {
+	clusterKey := client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "my-cluster"}
+	kcp := &controlplanev1.KubeadmControlPlane{}
+
+	tests := []struct {
+		name      string
+		policy    EtcdHealthUnknownPolicy
+		expectErr bool
+	}{
+		{
+			name:      "unknown health is treated as unhealthy by default",
+			expectErr: true,
+		},
+		{
+			name:      "unknown health is treated as unhealthy when failing closed",
+			policy:    EtcdHealthUnknownFailClosed,
+			expectErr: true,
+		},
+		{
+			name:      "unknown health is treated as healthy when failing open",
+			policy:    EtcdHealthUnknownFailOpen,
+			expectErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// NOTE: There is no kubeconfig secret for the cluster, so the workload cluster can't be reached.
+			m := &Management{Client: fake.NewClientBuilder().Build(), EtcdHealthUnknownPolicy: tt.policy}
+			err := m.EtcdIsHealthy(ctx, kcp, clusterKey)
+			if tt.expectErr {
+				g.Expect(errors.Is(err, ErrEtcdHealthUnknown)).To(BeTrue())
+				g.Expect(err.Error()).To(HavePrefix("etcd health is unknown: failed to create client to workload cluster"))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+
+	t.Run("known unhealthy etcd is reported when failing open", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := &Management{EtcdHealthCacheTTL: time.Minute, EtcdHealthUnknownPolicy: EtcdHealthUnknownFailOpen}
+		m.etcdHealthCache.set(clusterKey, &EtcdHealthDetails{Cluster: errors.New("etcd cluster is not healthy")}, nil)
+		g.Expect(m.EtcdIsHealthy(ctx, kcp, clusterKey)).To(MatchError("etcd cluster is not healthy"))
+	})
+}
+
+func TestControlPlaneIsHealthyForScaling(t *testing.T) {
+	clusterKey := client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "my-cluster"}
+
+	newKCP := func() *controlplanev1.KubeadmControlPlane {
+		return &controlplanev1.KubeadmControlPlane{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterKey.Namespace,
+				Name:      "my-control-plane",
+				UID:       "kcp-uid",
+			},
+			Spec: controlplanev1.KubeadmControlPlaneSpec{
+				KubeadmConfigSpec: bootstrapv1.KubeadmConfigSpec{
+					ClusterConfiguration: &bootstrapv1.ClusterConfiguration{
+						Etcd: bootstrapv1.Etcd{External: &bootstrapv1.ExternalEtcd{}},
+					},
+				},
+			},
+		}
+	}
+	newMachine := func(kcp *controlplanev1.KubeadmControlPlane, name string, nodeName string) *clusterv1.Machine {
+		machine := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterKey.Namespace,
+				Name:      name,
+				Labels: map[string]string{
+					clusterv1.ClusterLabelName:             clusterKey.Name,
+					clusterv1.MachineControlPlaneLabelName: "",
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(kcp, controlplanev1.GroupVersion.WithKind("KubeadmControlPlane")),
+				},
+			},
+		}
+		if nodeName != "" {
+			machine.Status.NodeRef = &corev1.ObjectReference{Name: nodeName}
+		}
+		return machine
+	}
+
+	t.Run("fails if the KubeadmControlPlane does not exist", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := &Management{Client: fake.NewClientBuilder().Build()}
+		err := m.ControlPlaneIsHealthyForScaling(ctx, clusterKey, "my-control-plane")
+		g.Expect(err).To(HaveOccurred())
+		var preconditionErr *ScalingPreconditionError
+		g.Expect(errors.As(err, &preconditionErr)).To(BeFalse())
+	})
+
+	t.Run("succeeds if there are no control plane machines", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := &Management{Client: fake.NewClientBuilder().WithObjects(newKCP()).Build()}
+		g.Expect(m.ControlPlaneIsHealthyForScaling(ctx, clusterKey, "my-control-plane")).To(Succeed())
+	})
+
+	t.Run("succeeds if all the control plane machines have a node", func(t *testing.T) {
+		g := NewWithT(t)
+
+		kcp := newKCP()
+		m := &Management{Client: fake.NewClientBuilder().WithObjects(
+			kcp,
+			newMachine(kcp, "machine-1", "node-1"),
+			newMachine(kcp, "machine-2", "node-2"),
+		).Build()}
+		g.Expect(m.ControlPlaneIsHealthyForScaling(ctx, clusterKey, "my-control-plane")).To(Succeed())
+	})
+
+	t.Run("fails if a control plane machine does not have a node", func(t *testing.T) {
+		g := NewWithT(t)
+
+		kcp := newKCP()
+		m := &Management{Client: fake.NewClientBuilder().WithObjects(
+			kcp,
+			newMachine(kcp, "machine-1", "node-1"),
+			newMachine(kcp, "machine-2", ""),
+		).Build()}
+		err := m.ControlPlaneIsHealthyForScaling(ctx, clusterKey, "my-control-plane")
+		var preconditionErr *ScalingPreconditionError
+		g.Expect(errors.As(err, &preconditionErr)).To(BeTrue())
+		g.Expect(preconditionErr.Precondition).To(Equal(ControlPlaneMachinesHaveNodesPrecondition))
+		g.Expect(preconditionErr.Error()).To(ContainSubstring("machine-2"))
+	})
+
+	t.Run("ignores excluded machines", func(t *testing.T) {
+		g := NewWithT(t)
+
+		kcp := newKCP()
+		machineWithoutNode := newMachine(kcp, "machine-2", "")
+		m := &Management{Client: fake.NewClientBuilder().WithObjects(
+			kcp,
+			newMachine(kcp, "machine-1", "node-1"),
+			machineWithoutNode,
+		).Build()}
+		g.Expect(m.ControlPlaneIsHealthyForScaling(ctx, clusterKey, "my-control-plane", machineWithoutNode)).To(Succeed())
+	})
+}
+
+func TestControlPlaneHealthyForScalingWithEtcdWarnings(t *testing.T) {
+	newMachine := func(name string) *clusterv1.Machine {
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: clusterv1.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: name + "-node"},
+			},
+		}
+		conditions.MarkTrue(m, controlplanev1.MachineEtcdMemberHealthyCondition)
+		return m
+	}
+	machines := collections.FromMachines(newMachine("m1"), newMachine("m2"), newMachine("m3"))
+
+	tests := []struct {
+		name     string
+		severity clusterv1.ConditionSeverity
+		wantErr  bool
+	}{
+		{
+			name:     "etcd cluster reporting a warning allows scaling",
+			severity: clusterv1.ConditionSeverityWarning,
+			wantErr:  false,
+		},
+		{
+			name:     "etcd cluster reporting an error prevents scaling",
+			severity: clusterv1.ConditionSeverityError,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			kcp := &controlplanev1.KubeadmControlPlane{}
+			conditions.MarkFalse(kcp, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, tt.severity, "etcd members are running different versions")
+			controlPlane := &ControlPlane{KCP: kcp, Machines: machines}
+			etcdHealth := func() (*EtcdHealthDetails, error) {
+				return etcdHealthDetailsFromConditions(controlPlane), nil
+			}
+
+			err := ControlPlaneHealthyForScaling(machines, etcdHealth)
+			if !tt.wantErr {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			var preconditionErr *ScalingPreconditionError
+			g.Expect(errors.As(err, &preconditionErr)).To(BeTrue())
+			g.Expect(preconditionErr.Precondition).To(Equal(EtcdHealthyPrecondition))
+		})
+	}
+}
+
+func TestEtcdHealthyForScaling(t *testing.T) {
+	tests := []struct {
+		name             string
+		machineHealth    map[string]error
+		clusterHealth    error
+		excludedMachines sets.String
+		wantErr          bool
+	}{
+		{
+			name:             "healthy",
+			machineHealth:    map[string]error{"machine-1": nil, "machine-2": nil},
+			excludedMachines: sets.NewString(),
+			wantErr:          false,
+		},
+		{
+			name:             "unhealthy member",
+			machineHealth:    map[string]error{"machine-1": nil, "machine-2": errors.New("etcd member is not healthy")},
+			clusterHealth:    errors.New("etcd cluster is not healthy"),
+			excludedMachines: sets.NewString(),
+			wantErr:          true,
+		},
+		{
+			name:             "unhealthy excluded member",
+			machineHealth:    map[string]error{"machine-1": nil, "machine-2": errors.New("etcd member is not healthy")},
+			clusterHealth:    errors.New("etcd cluster is not healthy"),
+			excludedMachines: sets.NewString("machine-2"),
+			wantErr:          false,
+		},
+		{
+			name:             "unhealthy cluster",
+			machineHealth:    map[string]error{"machine-1": nil, "machine-2": nil},
+			clusterHealth:    errors.New("etcd cluster has alarms"),
+			excludedMachines: sets.NewString("machine-2"),
+			wantErr:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := etcdHealthyForScaling(tt.machineHealth, tt.clusterHealth, tt.excludedMachines)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestEtcdHealthDetailsForMachines(t *testing.T) {
+	g := NewWithT(t)
+
+	newMachine := func(name, nodeName string) *clusterv1.Machine {
+		machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if nodeName != "" {
+			machine.Status.NodeRef = &corev1.ObjectReference{Name: nodeName}
+		}
+		return machine
+	}
+
+	details := &EtcdHealthDetails{
+		Members: map[string]error{
+			"node-1": nil,
+			"node-2": errors.New("etcd member is not healthy"),
+		},
+	}
+	results := details.ForMachines(collections.FromMachines(
+		newMachine("machine-1", "node-1"),
+		newMachine("machine-2", "node-2"),
+		newMachine("machine-3", "node-3"),
+		newMachine("machine-4", ""),
+	))
+
+	g.Expect(results).To(HaveLen(4))
+	g.Expect(results["machine-1"]).ToNot(HaveOccurred())
+	g.Expect(results["machine-2"]).To(MatchError("etcd member is not healthy"))
+	g.Expect(results["machine-3"]).To(MatchError(ContainSubstring("is unknown")))
+	g.Expect(results["machine-4"]).To(MatchError(ContainSubstring("does not have a node")))
+}
+
+func TestEtcdHealthDetailsString(t *testing.T) {
+	tests := []struct {
+		name    string
+		details *EtcdHealthDetails
+		want    string
+	}{
+		{
+			name:    "no members",
+			details: &EtcdHealthDetails{},
+			want:    "etcd cluster: ok",
+		},
+		{
+			name: "healthy members are sorted by node name",
+			details: &EtcdHealthDetails{
+				Members: map[string]error{
+					"node-2": nil,
+					"node-1": nil,
+				},
+			},
+			want: "etcd cluster: ok\nnode-1: ok\nnode-2: ok",
+		},
+		{
+			name: "unhealthy members and cluster report the error",
+			details: &EtcdHealthDetails{
+				Members: map[string]error{
+					"node-1": nil,
+					"node-3": errors.New("etcd member is not healthy: alarm NOSPACE"),
+					"node-2": nil,
+				},
+				Cluster: errors.New("etcd cluster is not healthy: node-3 is not healthy"),
+			},
+			want: "etcd cluster: etcd cluster is not healthy: node-3 is not healthy\n" +
+				"node-1: ok\n" +
+				"node-2: ok\n" +
+				"node-3: etcd member is not healthy: alarm NOSPACE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(tt.details.String()).To(Equal(tt.want))
+			// The summary must be stable, regardless of the map iteration order.
+			g.Expect(tt.details.String()).To(Equal(tt.details.String()))
+		})
+	}
+}
+
+func TestGetEtcdEndpoints(t *testing.T) {
+	clusterKey := client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "my-cluster"}
+	newCluster := func() *clusterv1.Cluster {
+		return &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: clusterKey.Namespace, Name: clusterKey.Name},
+			Spec: clusterv1.ClusterSpec{
+				ControlPlaneRef: &corev1.ObjectReference{Namespace: clusterKey.Namespace, Name: "my-kcp"},
+			},
+		}
+	}
+	newKCP := func(annotations map[string]string) *controlplanev1.KubeadmControlPlane {
+		return &controlplanev1.KubeadmControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Namespace: clusterKey.Namespace, Name: "my-kcp", Annotations: annotations},
+		}
+	}
+
+	t.Run("returns the endpoints from the KubeadmControlPlane annotation", func(t *testing.T) {
+		g := NewWithT(t)
+
+		kcp := newKCP(map[string]string{controlplanev1.EtcdEndpointsAnnotation: "node-1.etcd.example.com, node-2.etcd.example.com:2379,"})
+		m := &Management{Client: fake.NewClientBuilder().WithObjects(newCluster(), kcp).Build()}
+
+		endpoints, err := m.getEtcdEndpoints(ctx, clusterKey)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(endpoints).To(Equal([]string{"node-1.etcd.example.com", "node-2.etcd.example.com:2379"}))
+	})
+
+	t.Run("fails if the annotation is not set", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := &Management{Client: fake.NewClientBuilder().WithObjects(newCluster(), newKCP(nil)).Build()}
+
+		_, err := m.getEtcdEndpoints(ctx, clusterKey)
+		g.Expect(err).To(MatchError(ContainSubstring(controlplanev1.EtcdEndpointsAnnotation)))
+	})
+}
+
+func TestManagementDefaultTimeout(t *testing.T) {
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "my-cluster"}}
+
+	t.Run("sets a deadline when the context has none", func(t *testing.T) {
+		g := NewWithT(t)
+
+		reader := &deadlineRecordingReader{Reader: fake.NewClientBuilder().Build()}
+		m := &Management{Client: reader, DefaultTimeout: time.Minute}
+
+		_, err := m.GetMachinesForCluster(context.Background(), cluster)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(reader.hasDeadline).To(BeTrue())
+		g.Expect(time.Until(reader.deadline)).To(BeNumerically("<=", time.Minute))
+	})
+
+	t.Run("preserves the deadline of the context", func(t *testing.T) {
+		g := NewWithT(t)
+
+		reader := &deadlineRecordingReader{Reader: fake.NewClientBuilder().Build()}
+		m := &Management{Client: reader, DefaultTimeout: time.Minute}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		deadline, _ := ctx.Deadline()
+
+		_, err := m.GetMachinesForCluster(ctx, cluster)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(reader.deadline).To(Equal(deadline))
+	})
+
+	t.Run("does not set a deadline if DefaultTimeout is not set", func(t *testing.T) {
+		g := NewWithT(t)
+
+		reader := &deadlineRecordingReader{Reader: fake.NewClientBuilder().Build()}
+		m := &Management{Client: reader}
+
+		_, err := m.GetMachinesForCluster(context.Background(), cluster)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(reader.hasDeadline).To(BeFalse())
+	})
+}
+
+// deadlineRecordingReader is a client.Reader recording the deadline of the context of the last list call.
+type deadlineRecordingReader struct {
+	client.Reader
+	deadline    time.Time
+	hasDeadline bool
+}
+
+func (r *deadlineRecordingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	r.deadline, r.hasDeadline = ctx.Deadline()
+	return r.Reader.List(ctx, list, opts...)
+}
+
+func TestInternalIPAddress(t *testing.T) {
+	tests := []struct {
+		name            string
+		node            *corev1.Node
+		expectedErr     bool
+		expectedAddress string
+	}{
+		{
+			name: "returns the node InternalIP",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+				Status: corev1.NodeStatus{
+					Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeHostName, Address: "node-1"},
+						{Type: corev1.NodeExternalIP, Address: "1.2.3.4"},
+						{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+					},
+				},
+			},
+			expectedAddress: "10.0.0.1",
+		},
+		{
+			name: "fails if the node has no InternalIP",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+				Status: corev1.NodeStatus{
+					Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeExternalIP, Address: "1.2.3.4"},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			address, err := internalIPAddress(*tt.node)
+			if tt.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(address).To(Equal(tt.expectedAddress))
+		})
+	}
+}
+
+func TestEtcdHealthSummary(t *testing.T) {
+	g := NewWithT(t)
+
+	ns, err := env.CreateNamespace(ctx, "etcd-health-summary")
+	g.Expect(err).ToNot(HaveOccurred())
+	defer func() {
+		g.Expect(env.Cleanup(ctx, ns)).To(Succeed())
+	}()
+
+	newKCP := func(name string, ownerRefs ...metav1.OwnerReference) *controlplanev1.KubeadmControlPlane {
+		return &controlplanev1.KubeadmControlPlane{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       ns.Name,
+				OwnerReferences: ownerRefs,
+			},
+			Spec: controlplanev1.KubeadmControlPlaneSpec{
+				Version: "v1.22.0",
+				MachineTemplate: controlplanev1.KubeadmControlPlaneMachineTemplate{
+					InfrastructureRef: corev1.ObjectReference{
+						APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+						Kind:       "GenericInfrastructureMachineTemplate",
+						Name:       "infra-foo",
+					},
+				},
+			},
+		}
+	}
+	owned := newKCP("owned", metav1.OwnerReference{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       "Cluster",
+		Name:       "my-cluster",
+		UID:        "uid",
+	})
+	notOwned := newKCP("not-owned")
+	for _, o := range []client.Object{owned, notOwned} {
+		g.Expect(env.Client.Create(ctx, o)).To(Succeed())
+	}
+
+	// Note: The API reader is intentionally used instead of the regular (cached) client
+	// to avoid test failures when the local cache isn't able to catch up in time.
+	m := Management{
+		Client: env.GetAPIReader(),
+	}
+
+	summary, err := m.EtcdHealthSummary(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Only clusters with a KubeadmControlPlane are reported; given that there is no kubeconfig
+	// for my-cluster, the check is expected to fail.
+	clusterKey := client.ObjectKey{Namespace: ns.Name, Name: "my-cluster"}
+	g.Expect(summary).To(HaveLen(1))
+	g.Expect(summary).To(HaveKey(clusterKey))
+	g.Expect(summary[clusterKey]).To(HaveOccurred())
+}
+
+func TestEtcdTLSConfig(t *testing.T) {
+	key, err := certs.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := getTestCACert(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		caData      []byte
+		expectedErr error
+	}{
+		{
+			name:   "returns a tls config with the CA in the root CA pool",
+			caData: certs.EncodeCertPEM(cert),
+		},
+		{
+			name:        "returns error if the CA is not PEM encoded",
+			caData:      []byte("bad cert"),
+			expectedErr: ErrEtcdCACertInvalid,
+		},
+		{
+			name:        "returns error if the CA PEM block does not contain a valid certificate",
+			caData:      []byte("-----BEGIN CERTIFICATE-----\nbm90IGEgY2VydGlmaWNhdGU=\n-----END CERTIFICATE-----\n"),
+			expectedErr: ErrEtcdCACertInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			tlsConfig, err := etcdTLSConfig(tt.caData, tls.Certificate{})
+			if tt.expectedErr != nil {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(errors.Is(err, tt.expectedErr)).To(BeTrue())
+				g.Expect(err.Error()).To(ContainSubstring("etcd CA PEM contained no valid certificates"))
+				g.Expect(tlsConfig).To(BeNil())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tlsConfig.RootCAs).ToNot(BeNil())
+			g.Expect(tlsConfig.RootCAs.Subjects()).To(HaveLen(1)) //nolint:staticcheck
+			g.Expect(tlsConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+			g.Expect(tlsConfig.InsecureSkipVerify).To(BeFalse())
+		})
+	}
+}
+
+func TestEtcdClientCertSubject(t *testing.T) {
+	key, err := certs.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := getTestCACert(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                 string
+		m                    *Management
+		expectedCommonName   string
+		expectedOrganization []string
+	}{
+		{
+			name:               "uses the default common name if none is configured",
+			m:                  &Management{},
+			expectedCommonName: "cluster-api.x-k8s.io",
+		},
+		{
+			name: "uses the configured common name and organization",
+			m: &Management{
+				EtcdClientCertCommonName:   "capi-management-1",
+				EtcdClientCertOrganization: []string{"tenant-a"},
+			},
+			expectedCommonName:   "capi-management-1",
+			expectedOrganization: []string{"tenant-a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			clientCert, err := generateClientCert(certs.EncodeCertPEM(caCert), certs.EncodePrivateKeyPEM(key), tt.m.etcdClientCertSubject())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(clientCert.Certificate).To(HaveLen(1))
+
+			cert, err := x509.ParseCertificate(clientCert.Certificate[0])
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(cert.Subject.CommonName).To(Equal(tt.expectedCommonName))
+			g.Expect(cert.Subject.Organization).To(Equal(tt.expectedOrganization))
+			g.Expect(cert.CheckSignatureFrom(caCert)).To(Succeed())
 		})
 	}
 }
@@ -54,6 +54,14 @@ type ControlPlane struct {
 	// reconciliationTime is the time of the current reconciliation, and should be used for all "now" calculations
 	reconciliationTime metav1.Time
 
+	// etcdMemberNames is the list of etcd members observed while updating the etcd conditions, if any;
+	// it allows to reuse the member list gathered during the health check without connecting to etcd again.
+	etcdMemberNames []string
+
+	// etcdLeaderNodeName is the name of the node hosting the etcd leader, as observed while updating the etcd conditions;
+	// it is empty if the leader is unknown.
+	etcdLeaderNodeName string
+
 	// TODO: we should see if we can combine these with the Machine objects so we don't have all these separate lookups
 	// See discussion on https://github.com/kubernetes-sigs/cluster-api/pull/3405
 	kubeadmConfigs map[string]*bootstrapv1.KubeadmConfig
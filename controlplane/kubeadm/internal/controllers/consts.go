@@ -30,4 +30,12 @@ const (
 	// dependentCertRequeueAfter is how long to wait before checking again to see if
 	// dependent certificates have been created.
 	dependentCertRequeueAfter = 30 * time.Second
+
+	// etcdMemberPromotionRequeueAfter is how long to wait before checking again to see if
+	// a re-created etcd member can be promoted to voting member.
+	etcdMemberPromotionRequeueAfter = 15 * time.Second
+
+	// managementClusterDefaultTimeout is the timeout for operations on the management cluster and on etcd
+	// invoked with a context without a deadline.
+	managementClusterDefaultTimeout = 1 * time.Minute
 )
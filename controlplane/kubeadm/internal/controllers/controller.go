@@ -58,6 +58,7 @@ import (
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io;bootstrap.cluster.x-k8s.io;controlplane.cluster.x-k8s.io,resources=*,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines;machines/status,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinesets;machinedeployments,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
 
 // KubeadmControlPlaneReconciler reconciles a KubeadmControlPlane object.
@@ -69,6 +70,52 @@ type KubeadmControlPlaneReconciler struct {
 	Tracker         *remote.ClusterCacheTracker
 	EtcdDialTimeout time.Duration
 
+	// EtcdNamespace is the namespace where the etcd pods are running in the workload clusters;
+	// if not set, kube-system is used.
+	EtcdNamespace string
+
+	// EtcdConnectionStrategy defines how to connect to the etcd members of the workload clusters;
+	// if not set, the etcd pods are reached by port-forwarding via the workload cluster API server.
+	EtcdConnectionStrategy internal.EtcdConnectionStrategy
+
+	// EtcdDBSizeWarningThreshold is the percentage of the etcd backend database quota above which
+	// a warning is reported for an etcd member; 0 disables the check.
+	EtcdDBSizeWarningThreshold int
+
+	// EtcdDialRetries is the number of times connecting to an etcd member is retried when checking its health,
+	// before reporting the member as unhealthy; 0 disables retries.
+	EtcdDialRetries int
+
+	// EtcdLearnerPromotionTimeout is how long an etcd member can be a learner before it is reported as unhealthy,
+	// given that a learner which is never promoted to voting member usually means a stuck scale up; 0 disables the check.
+	EtcdLearnerPromotionTimeout time.Duration
+
+	// EtcdMemberConsistencyMode defines how the etcd members are required to agree on the list of members for being
+	// considered consistent; if not set, EtcdMemberConsistencyUnanimous is used.
+	EtcdMemberConsistencyMode internal.EtcdMemberConsistencyMode
+
+	// EtcdHealthCacheTTL is how long the result of an etcd health check for a cluster is re-used; 0 disables caching.
+	EtcdHealthCacheTTL time.Duration
+
+	// EtcdClientCertCommonName is the CommonName of the client certificate generated for connecting to etcd;
+	// if not set, cluster-api.x-k8s.io is used.
+	EtcdClientCertCommonName string
+
+	// EtcdClientCertOrganization is the Organization of the client certificate generated for connecting to etcd.
+	EtcdClientCertOrganization []string
+
+	// IncludeUnlabeledMachines, if set, also considers the machines lacking the cluster name label whose owner chain
+	// resolves to the cluster, e.g. adopted or legacy machines.
+	IncludeUnlabeledMachines bool
+
+	// BackfillClusterNameLabel, if set together with IncludeUnlabeledMachines, sets the cluster name label on
+	// the unlabeled machines once per reconcile, before getting the machines, so they are found by label afterwards.
+	BackfillClusterNameLabel bool
+
+	// RecreateMissingEtcdMembers, if set, re-creates the etcd member of control plane nodes whose member has been
+	// removed while the node is still there, instead of only reporting it on the EtcdClusterHealthy condition.
+	RecreateMissingEtcdMembers bool
+
 	// WatchFilterValue is the label value used to filter events prior to reconciliation.
 	WatchFilterValue string
 
@@ -77,6 +124,17 @@ type KubeadmControlPlaneReconciler struct {
 }
 
 func (r *KubeadmControlPlaneReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
+	switch r.EtcdConnectionStrategy {
+	case "", internal.EtcdConnectionProxyViaPod, internal.EtcdConnectionDirectToNode, internal.EtcdConnectionDirectToEndpoints:
+	default:
+		return errors.Errorf("invalid etcd connection strategy %q, must be one of %s, %s or %s", r.EtcdConnectionStrategy, internal.EtcdConnectionProxyViaPod, internal.EtcdConnectionDirectToNode, internal.EtcdConnectionDirectToEndpoints)
+	}
+	switch r.EtcdMemberConsistencyMode {
+	case "", internal.EtcdMemberConsistencyUnanimous, internal.EtcdMemberConsistencyQuorum:
+	default:
+		return errors.Errorf("invalid etcd member consistency mode %q, must be one of %s or %s", r.EtcdMemberConsistencyMode, internal.EtcdMemberConsistencyUnanimous, internal.EtcdMemberConsistencyQuorum)
+	}
+
 	c, err := ctrl.NewControllerManagedBy(mgr).
 		For(&controlplanev1.KubeadmControlPlane{}).
 		Owns(&clusterv1.Machine{}).
@@ -106,15 +164,31 @@ func (r *KubeadmControlPlaneReconciler) SetupWithManager(ctx context.Context, mg
 		if r.Tracker == nil {
 			return errors.New("cluster cache tracker is nil, cannot create the internal management cluster resource")
 		}
-		r.managementCluster = &internal.Management{
-			Client:          r.Client,
-			Tracker:         r.Tracker,
-			EtcdDialTimeout: r.EtcdDialTimeout,
+		managementCluster := &internal.Management{
+			Client:                      r.Client,
+			Tracker:                     r.Tracker,
+			EtcdDialTimeout:             r.EtcdDialTimeout,
+			EtcdNamespace:               r.EtcdNamespace,
+			EtcdConnectionStrategy:      r.EtcdConnectionStrategy,
+			EtcdDBSizeWarningThreshold:  r.EtcdDBSizeWarningThreshold,
+			EtcdDialRetries:             r.EtcdDialRetries,
+			EtcdLearnerPromotionTimeout: r.EtcdLearnerPromotionTimeout,
+			EtcdMemberConsistencyMode:   r.EtcdMemberConsistencyMode,
+			EtcdHealthCacheTTL:          r.EtcdHealthCacheTTL,
+			EtcdClientCertCommonName:    r.EtcdClientCertCommonName,
+			EtcdClientCertOrganization:  r.EtcdClientCertOrganization,
+			IncludeUnlabeledMachines:    r.IncludeUnlabeledMachines,
+			DefaultTimeout:              managementClusterDefaultTimeout,
 		}
+		r.managementCluster = managementCluster
 	}
 
 	if r.managementClusterUncached == nil {
-		r.managementClusterUncached = &internal.Management{Client: mgr.GetAPIReader()}
+		r.managementClusterUncached = &internal.Management{
+			Client:                   mgr.GetAPIReader(),
+			IncludeUnlabeledMachines: r.IncludeUnlabeledMachines,
+			DefaultTimeout:           managementClusterDefaultTimeout,
+		}
 	}
 
 	return nil
@@ -240,6 +314,7 @@ func patchKubeadmControlPlane(ctx context.Context, patchHelper *patch.Helper, kc
 			controlplanev1.MachinesReadyCondition,
 			controlplanev1.AvailableCondition,
 			controlplanev1.CertificatesAvailableCondition,
+			controlplanev1.MachinesConfigurationTrackedCondition,
 		}},
 		patch.WithStatusObservedGeneration{},
 	)
@@ -284,6 +359,14 @@ func (r *KubeadmControlPlaneReconciler) reconcile(ctx context.Context, cluster *
 		return result, err
 	}
 
+	// Backfill the cluster name label before getting the machines, so the backfilled machines are found by label.
+	if r.IncludeUnlabeledMachines && r.BackfillClusterNameLabel {
+		if err := r.backfillClusterNameLabel(ctx, cluster); err != nil {
+			log.Error(err, "failed to backfill the cluster name label on machines")
+			return ctrl.Result{}, err
+		}
+	}
+
 	controlPlaneMachines, err := r.managementClusterUncached.GetMachinesForCluster(ctx, cluster, collections.ControlPlaneMachines(cluster.Name))
 	if err != nil {
 		log.Error(err, "failed to retrieve control plane machines for cluster")
@@ -419,6 +502,10 @@ func (r *KubeadmControlPlaneReconciler) reconcileDelete(ctx context.Context, clu
 
 	// If no control plane machines remain, remove the finalizer
 	if len(ownedMachines) == 0 {
+		// The etcd clients kept open for the cluster are not needed anymore.
+		if err := r.managementCluster.CloseEtcdClients(util.ObjectKey(cluster)); err != nil {
+			log.Error(err, "Failed to close etcd clients")
+		}
 		controllerutil.RemoveFinalizer(kcp, controlplanev1.KubeadmControlPlaneFinalizer)
 		return ctrl.Result{}, nil
 	}
@@ -504,6 +591,12 @@ func (r *KubeadmControlPlaneReconciler) reconcileControlPlaneConditions(ctx cont
 
 	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(controlPlane.Cluster))
 	if err != nil {
+		// If the etcd CA secret does not exist yet, e.g. because it is being restored during a move, wait for it;
+		// instead, if the secret exists but it is not valid, surface the error.
+		if errors.Is(err, internal.ErrEtcdCASecretNotFound) {
+			ctrl.LoggerFrom(ctx).Info("Waiting for the etcd CA secret to be available", "err", err.Error())
+			return ctrl.Result{RequeueAfter: dependentCertRequeueAfter}, nil
+		}
 		return ctrl.Result{}, errors.Wrap(err, "cannot get remote client to workload cluster")
 	}
 
@@ -573,9 +666,41 @@ func (r *KubeadmControlPlaneReconciler) reconcileEtcdMembers(ctx context.Context
 		log.Info("Etcd members without nodes removed from the cluster", "members", removedMembers)
 	}
 
+	if r.RecreateMissingEtcdMembers {
+		for _, nodeName := range nodeNames {
+			if err := workloadCluster.EnsureEtcdMemberForNode(ctx, nodeName); err != nil {
+				if errors.Is(err, internal.ErrEtcdMemberPromotionPending) {
+					log.Info("Waiting for the re-created etcd member to be promoted", "node", nodeName)
+					return ctrl.Result{RequeueAfter: etcdMemberPromotionRequeueAfter}, nil
+				}
+				return ctrl.Result{}, errors.Wrapf(err, "failed to re-create the etcd member for node %s", nodeName)
+			}
+		}
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// backfillClusterNameLabel sets the cluster name label on the machines lacking it whose owner chain resolves to
+// the cluster, e.g. adopted or legacy machines, so their owners don't have to be walked to find them anymore.
+func (r *KubeadmControlPlaneReconciler) backfillClusterNameLabel(ctx context.Context, cluster *clusterv1.Cluster) error {
+	machines, err := collections.GetUnlabeledMachinesForCluster(ctx, r.APIReader, cluster)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the machines lacking the cluster name label")
+	}
+	for _, machine := range machines {
+		patch := client.MergeFrom(machine.DeepCopy())
+		if machine.Labels == nil {
+			machine.Labels = map[string]string{}
+		}
+		machine.Labels[clusterv1.ClusterLabelName] = cluster.Name
+		if err := r.Client.Patch(ctx, machine, patch); err != nil {
+			return errors.Wrapf(err, "failed to backfill the cluster name label on machine %s", machine.Name)
+		}
+	}
+	return nil
+}
+
 func (r *KubeadmControlPlaneReconciler) adoptMachines(ctx context.Context, kcp *controlplanev1.KubeadmControlPlane, machines collections.Machines, cluster *clusterv1.Cluster) error {
 	// We do an uncached full quorum read against the KCP to avoid re-adopting Machines the garbage collector just intentionally orphaned
 	// See https://github.com/kubernetes/kubernetes/issues/42639
@@ -25,6 +25,7 @@ import (
 
 	"github.com/blang/semver"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -745,6 +746,59 @@ func TestKubeadmControlPlaneReconciler_adoption(t *testing.T) {
 	})
 }
 
+func TestKubeadmControlPlaneReconciler_backfillClusterNameLabel(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "my-cluster"}}
+	// The unlabeled machine belongs to the cluster via the owner reference of the KubeadmControlPlane owning it.
+	kcp := &controlplanev1.KubeadmControlPlane{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      "my-control-plane",
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster", Name: "my-cluster"},
+			},
+		},
+	}
+	ownedMachine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      "owned-machine",
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: controlplanev1.GroupVersion.String(), Kind: "KubeadmControlPlane", Name: "my-control-plane"},
+			},
+		},
+	}
+	foreignMachine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      "foreign-machine",
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster", Name: "another-cluster"},
+			},
+		},
+	}
+
+	fakeClient := newFakeClient(kcp.DeepCopy(), ownedMachine.DeepCopy(), foreignMachine.DeepCopy())
+	r := &KubeadmControlPlaneReconciler{
+		Client:    fakeClient,
+		APIReader: fakeClient,
+	}
+	g.Expect(r.backfillClusterNameLabel(ctx, cluster)).To(Succeed())
+
+	got := &clusterv1.Machine{}
+	g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(ownedMachine), got)).To(Succeed())
+	g.Expect(got.Labels).To(HaveKeyWithValue(clusterv1.ClusterLabelName, "my-cluster"))
+	g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(foreignMachine), got)).To(Succeed())
+	g.Expect(got.Labels).ToNot(HaveKey(clusterv1.ClusterLabelName))
+
+	// The backfilled machine is found by label from now on.
+	m := &internal.Management{Client: fakeClient}
+	machines, err := m.GetMachinesForCluster(ctx, cluster)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machines.Names()).To(ConsistOf("owned-machine"))
+}
+
 func TestReconcileInitializeControlPlane(t *testing.T) {
 	g := NewWithT(t)
 
@@ -1351,19 +1405,108 @@ func TestKubeadmControlPlaneReconciler_reconcileDelete(t *testing.T) {
 
 		fakeClient := newFakeClient(cluster.DeepCopy(), kcp.DeepCopy())
 
+		managementCluster := &fakeManagementCluster{
+			Management: &internal.Management{Client: fakeClient},
+			Workload:   fakeWorkloadCluster{},
+		}
 		r := &KubeadmControlPlaneReconciler{
-			Client: fakeClient,
-			managementCluster: &fakeManagementCluster{
-				Management: &internal.Management{Client: fakeClient},
-				Workload:   fakeWorkloadCluster{},
-			},
-			recorder: record.NewFakeRecorder(32),
+			Client:            fakeClient,
+			managementCluster: managementCluster,
+			recorder:          record.NewFakeRecorder(32),
 		}
 
 		result, err := r.reconcileDelete(ctx, cluster, kcp)
 		g.Expect(result).To(Equal(ctrl.Result{}))
 		g.Expect(err).NotTo(HaveOccurred())
 		g.Expect(kcp.Finalizers).To(BeEmpty())
+		g.Expect(managementCluster.ClosedEtcdClients).To(ConsistOf(util.ObjectKey(cluster)))
+	})
+}
+
+func TestKubeadmControlPlaneReconciler_reconcileEtcdMembers(t *testing.T) {
+	setup := func(recreateMissingEtcdMembers bool, ensureEtcdMemberErr error) (*KubeadmControlPlaneReconciler, *internal.ControlPlane) {
+		cluster, kcp, _ := createClusterWithControlPlane(metav1.NamespaceDefault)
+		m, _ := createMachineNodePair("test-0", cluster, kcp, true)
+
+		r := &KubeadmControlPlaneReconciler{
+			managementCluster: &fakeManagementCluster{
+				Workload: fakeWorkloadCluster{EnsureEtcdMemberErr: ensureEtcdMemberErr},
+			},
+			RecreateMissingEtcdMembers: recreateMissingEtcdMembers,
+			recorder:                   record.NewFakeRecorder(32),
+		}
+		controlPlane := &internal.ControlPlane{Cluster: cluster, KCP: kcp, Machines: collections.FromMachines(m)}
+		return r, controlPlane
+	}
+
+	t.Run("does not re-create missing etcd members by default", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r, controlPlane := setup(false, errors.New("should not be called"))
+		result, err := r.reconcileEtcdMembers(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{}))
+	})
+
+	t.Run("requeues while a re-created etcd member is waiting to be promoted", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r, controlPlane := setup(true, internal.ErrEtcdMemberPromotionPending)
+		result, err := r.reconcileEtcdMembers(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{RequeueAfter: etcdMemberPromotionRequeueAfter}))
+	})
+
+	t.Run("returns an error if an etcd member can't be re-created", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r, controlPlane := setup(true, errors.New("etcd pod not found"))
+		_, err := r.reconcileEtcdMembers(ctx, controlPlane)
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("succeeds once all the etcd members exist", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r, controlPlane := setup(true, nil)
+		result, err := r.reconcileEtcdMembers(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{}))
+	})
+}
+
+func TestKubeadmControlPlaneReconciler_reconcileControlPlaneConditions(t *testing.T) {
+	cluster, kcp, _ := createClusterWithControlPlane(metav1.NamespaceDefault)
+	kcp.Status.Initialized = true
+
+	t.Run("requeues if the etcd CA secret is not found", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := &KubeadmControlPlaneReconciler{
+			managementCluster: &fakeManagementCluster{
+				WorkloadErr: errors.Wrap(internal.ErrEtcdCASecretNotFound, "failed to get secret"),
+			},
+		}
+		controlPlane := &internal.ControlPlane{Cluster: cluster, KCP: kcp}
+
+		result, err := r.reconcileControlPlaneConditions(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{RequeueAfter: dependentCertRequeueAfter}))
+	})
+
+	t.Run("returns an error if the etcd CA secret does not contain the CA certificate", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := &KubeadmControlPlaneReconciler{
+			managementCluster: &fakeManagementCluster{
+				WorkloadErr: errors.Wrap(internal.ErrEtcdCACertMissing, "etcd tls crt does not exist"),
+			},
+		}
+		controlPlane := &internal.ControlPlane{Cluster: cluster, KCP: kcp}
+
+		_, err := r.reconcileControlPlaneConditions(ctx, controlPlane)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(errors.Is(err, internal.ErrEtcdCACertMissing)).To(BeTrue())
 	})
 }
 
@@ -1533,6 +1676,9 @@ func createMachineNodePair(name string, cluster *clusterv1.Cluster, kcp *control
 }
 
 func setMachineHealthy(m *clusterv1.Machine) {
+	if m.Status.NodeRef == nil {
+		m.Status.NodeRef = &corev1.ObjectReference{Kind: "Node", Name: m.Name}
+	}
 	conditions.MarkTrue(m, controlplanev1.MachineAPIServerPodHealthyCondition)
 	conditions.MarkTrue(m, controlplanev1.MachineControllerManagerPodHealthyCondition)
 	conditions.MarkTrue(m, controlplanev1.MachineSchedulerPodHealthyCondition)
@@ -20,9 +20,11 @@ import (
 	"context"
 
 	"github.com/blang/semver"
+	"github.com/pkg/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
 	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/collections"
@@ -35,6 +37,55 @@ type fakeManagementCluster struct {
 	MachinePools *expv1.MachinePoolList
 	Workload     fakeWorkloadCluster
 	Reader       client.Reader
+
+	// WorkloadErr is returned by GetWorkloadCluster.
+	WorkloadErr error
+
+	// EtcdHealth is the canned etcd health of the cluster returned by EtcdHealthDetails; if not set,
+	// the etcd members hosted on the nodes of Machines are reported as healthy.
+	EtcdHealth *internal.EtcdHealthDetails
+
+	// EtcdHealthErr is returned by EtcdHealthDetails.
+	EtcdHealthErr error
+
+	// ClosedEtcdClients records the clusters whose etcd clients have been closed.
+	ClosedEtcdClients []client.ObjectKey
+}
+
+// newFakeManagementCluster returns a fakeManagementCluster for the given control plane machines,
+// with all the etcd members hosted on the machines' nodes healthy.
+func newFakeManagementCluster(machines ...*clusterv1.Machine) *fakeManagementCluster {
+	f := &fakeManagementCluster{
+		Machines: collections.FromMachines(machines...),
+		EtcdHealth: &internal.EtcdHealthDetails{
+			Members: map[string]error{},
+		},
+	}
+	for _, m := range machines {
+		if m.Status.NodeRef != nil {
+			f.EtcdHealth.Members[m.Status.NodeRef.Name] = nil
+			f.EtcdHealth.MemberNames = append(f.EtcdHealth.MemberNames, m.Status.NodeRef.Name)
+		}
+	}
+	return f
+}
+
+// withEtcdUnhealthyOnNode marks the etcd member hosted on the given node as unhealthy, and thus also the etcd cluster.
+func (f *fakeManagementCluster) withEtcdUnhealthyOnNode(nodeName string) *fakeManagementCluster {
+	f.EtcdHealth.Members[nodeName] = errors.Errorf("etcd member is not healthy: member on node %s is not responding", nodeName)
+	f.EtcdHealth.Cluster = errors.New("etcd cluster is not healthy")
+	return f
+}
+
+// withMachineMissingNodeRef removes the node reference from the given machine, like for a machine still provisioning.
+func (f *fakeManagementCluster) withMachineMissingNodeRef(machineName string) *fakeManagementCluster {
+	m := f.Machines[machineName].DeepCopy()
+	if m.Status.NodeRef != nil {
+		delete(f.EtcdHealth.Members, m.Status.NodeRef.Name)
+	}
+	m.Status.NodeRef = nil
+	f.Machines.Insert(m)
+	return f
 }
 
 func (f *fakeManagementCluster) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
@@ -46,9 +97,24 @@ func (f *fakeManagementCluster) List(ctx context.Context, list client.ObjectList
 }
 
 func (f *fakeManagementCluster) GetWorkloadCluster(_ context.Context, _ client.ObjectKey) (internal.WorkloadCluster, error) {
+	if f.WorkloadErr != nil {
+		return nil, f.WorkloadErr
+	}
 	return f.Workload, nil
 }
 
+func (f *fakeManagementCluster) CloseEtcdClients(clusterKey client.ObjectKey) error {
+	f.ClosedEtcdClients = append(f.ClosedEtcdClients, clusterKey)
+	return nil
+}
+
+func (f *fakeManagementCluster) RemoveEtcdMemberForMachine(ctx context.Context, _ client.ObjectKey, machine *clusterv1.Machine) error {
+	if f.WorkloadErr != nil {
+		return f.WorkloadErr
+	}
+	return f.Workload.SafelyRemoveEtcdMemberForMachine(ctx, machine)
+}
+
 func (f *fakeManagementCluster) GetMachinesForCluster(c context.Context, cluster *clusterv1.Cluster, filters ...collections.Func) (collections.Machines, error) {
 	if f.Management != nil {
 		return f.Management.GetMachinesForCluster(c, cluster, filters...)
@@ -63,10 +129,26 @@ func (f *fakeManagementCluster) GetMachinePoolsForCluster(c context.Context, clu
 	return f.MachinePools, nil
 }
 
+func (f *fakeManagementCluster) EtcdHealthDetails(_ context.Context, _ *controlplanev1.KubeadmControlPlane, _ client.ObjectKey) (*internal.EtcdHealthDetails, error) {
+	if f.EtcdHealthErr != nil {
+		return nil, f.EtcdHealthErr
+	}
+	if f.EtcdHealth != nil {
+		return f.EtcdHealth, nil
+	}
+	return newFakeManagementCluster(f.Machines.UnsortedList()...).EtcdHealth, nil
+}
+
 type fakeWorkloadCluster struct {
 	*internal.Workload
 	Status            internal.ClusterStatus
 	EtcdMembersResult []string
+
+	// EnsureEtcdMemberErr is returned by EnsureEtcdMemberForNode.
+	EnsureEtcdMemberErr error
+
+	// SafelyRemoveEtcdMemberErr is returned by SafelyRemoveEtcdMemberForMachine.
+	SafelyRemoveEtcdMemberErr error
 }
 
 func (f fakeWorkloadCluster) ForwardEtcdLeadership(_ context.Context, _ *clusterv1.Machine, _ *clusterv1.Machine) error {
@@ -109,6 +191,14 @@ func (f fakeWorkloadCluster) RemoveEtcdMemberForMachine(ctx context.Context, mac
 	return nil
 }
 
+func (f fakeWorkloadCluster) SafelyRemoveEtcdMemberForMachine(ctx context.Context, machine *clusterv1.Machine) error {
+	return f.SafelyRemoveEtcdMemberErr
+}
+
+func (f fakeWorkloadCluster) EnsureEtcdMemberForNode(_ context.Context, _ string) error {
+	return f.EnsureEtcdMemberErr
+}
+
 func (f fakeWorkloadCluster) RemoveMachineFromKubeadmConfigMap(ctx context.Context, machine *clusterv1.Machine, version semver.Version) error {
 	return nil
 }
@@ -117,6 +207,14 @@ func (f fakeWorkloadCluster) EtcdMembers(_ context.Context) ([]string, error) {
 	return f.EtcdMembersResult, nil
 }
 
+func (f fakeWorkloadCluster) EtcdQuorumIsHealthy(_ context.Context) error {
+	return nil
+}
+
+func (f fakeWorkloadCluster) EtcdState(_ context.Context) (*internal.EtcdState, error) {
+	return &internal.EtcdState{}, nil
+}
+
 type fakeMigrator struct {
 	migrateCalled    bool
 	migrateErr       error
@@ -29,6 +29,7 @@ import (
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	etcdutil "sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd/util"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
@@ -152,14 +153,16 @@ func (r *KubeadmControlPlaneReconciler) reconcileUnhealthyMachines(ctx context.C
 		return ctrl.Result{}, errors.Wrapf(err, "failed to create client to workload cluster")
 	}
 
-	// If the machine that is about to be deleted is the etcd leader, move it to the newest member available.
+	// Remove the etcd member of the machine that is about to be deleted, moving the etcd leadership away from it first
+	// if required; the member health is re-assessed right before removing it, so remediation is skipped if removing it
+	// would make etcd lose quorum.
 	if controlPlane.IsEtcdManaged() {
-		etcdLeaderCandidate := controlPlane.HealthyMachines().Newest()
-		if err := workloadCluster.ForwardEtcdLeadership(ctx, machineToBeRemediated, etcdLeaderCandidate); err != nil {
-			log.Error(err, "Failed to move leadership to candidate machine", "candidate", etcdLeaderCandidate.Name)
-			return ctrl.Result{}, err
-		}
-		if err := workloadCluster.RemoveEtcdMemberForMachine(ctx, machineToBeRemediated); err != nil {
+		if err := r.managementCluster.RemoveEtcdMemberForMachine(ctx, util.ObjectKey(controlPlane.Cluster), machineToBeRemediated); err != nil {
+			if errors.Is(err, internal.ErrEtcdQuorumWouldBeLost) {
+				log.Info("A control plane machine needs remediation, but removing this machine could result in etcd quorum loss. Skipping remediation", "UnhealthyMachine", machineToBeRemediated.Name)
+				conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "KCP can't remediate this machine because this could result in etcd loosing quorum")
+				return ctrl.Result{}, nil
+			}
 			log.Error(err, "Failed to remove etcd member for machine")
 			return ctrl.Result{}, err
 		}
@@ -214,6 +217,7 @@ func (r *KubeadmControlPlaneReconciler) canSafelyRemoveEtcdMember(ctx context.Co
 	// Gets the etcd status
 
 	// This makes it possible to have a set of etcd members status different from the MHC unhealthy/unhealthy conditions.
+	// NOTE: Only voting members are returned, given that learners don't count for quorum.
 	etcdMembers, err := workloadCluster.EtcdMembers(ctx)
 	if err != nil {
 		return false, errors.Wrapf(err, "failed to get etcdStatus for workload cluster %s", controlPlane.Cluster.Name)
@@ -270,8 +274,7 @@ func (r *KubeadmControlPlaneReconciler) canSafelyRemoveEtcdMember(ctx context.Co
 		healthyMembers = append(healthyMembers, fmt.Sprintf("%s (%s)", etcdMember, machine.Name))
 	}
 
-	// See https://etcd.io/docs/v3.3/faq/#what-is-failure-tolerance for fault tolerance formula explanation.
-	targetQuorum := (targetTotalMembers / 2.0) + 1
+	targetQuorum := etcdutil.EtcdQuorum(targetTotalMembers)
 	canSafelyRemediate := targetTotalMembers-targetUnhealthyMembers >= targetQuorum
 
 	log.Info(fmt.Sprintf("etcd cluster projected after remediation of %s", machineToBeRemediated.Name),
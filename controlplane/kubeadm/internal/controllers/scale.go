@@ -71,6 +71,9 @@ func (r *KubeadmControlPlaneReconciler) scaleUpControlPlane(ctx context.Context,
 	if result, err := r.preflightChecks(ctx, controlPlane); err != nil || !result.IsZero() {
 		return result, err
 	}
+	if result, err := r.checkControlPlaneHealthyForScaling(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
 
 	// Create the bootstrap configuration
 	bootstrapSpec := controlPlane.JoinControlPlaneConfig()
@@ -105,6 +108,9 @@ func (r *KubeadmControlPlaneReconciler) scaleDownControlPlane(
 	if result, err := r.preflightChecks(ctx, controlPlane, machineToDelete); err != nil || !result.IsZero() {
 		return result, err
 	}
+	if result, err := r.checkControlPlaneHealthyForScaling(ctx, controlPlane, machineToDelete); err != nil || !result.IsZero() {
+		return result, err
+	}
 
 	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(cluster))
 	if err != nil {
@@ -117,14 +123,14 @@ func (r *KubeadmControlPlaneReconciler) scaleDownControlPlane(
 		return ctrl.Result{}, errors.New("failed to pick control plane Machine to delete")
 	}
 
-	// If KCP should manage etcd, If etcd leadership is on machine that is about to be deleted, move it to the newest member available.
+	// If KCP should manage etcd, remove the etcd member of the machine that is about to be deleted, moving the etcd
+	// leadership away from it first if required; if removing it would make etcd lose quorum, wait.
 	if controlPlane.IsEtcdManaged() {
-		etcdLeaderCandidate := controlPlane.Machines.Newest()
-		if err := workloadCluster.ForwardEtcdLeadership(ctx, machineToDelete, etcdLeaderCandidate); err != nil {
-			logger.Error(err, "Failed to move leadership to candidate machine", "candidate", etcdLeaderCandidate.Name)
-			return ctrl.Result{}, err
-		}
-		if err := workloadCluster.RemoveEtcdMemberForMachine(ctx, machineToDelete); err != nil {
+		if err := r.managementCluster.RemoveEtcdMemberForMachine(ctx, util.ObjectKey(cluster), machineToDelete); err != nil {
+			if errors.Is(err, internal.ErrEtcdQuorumWouldBeLost) {
+				logger.Info("Waiting for etcd to be able to tolerate removing the member of the machine to delete", "reason", err.Error())
+				return ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}, nil
+			}
 			logger.Error(err, "Failed to remove etcd member for machine")
 			return ctrl.Result{}, err
 		}
@@ -217,6 +223,36 @@ loopmachines:
 	return ctrl.Result{}, nil
 }
 
+// checkControlPlaneHealthyForScaling checks if the control plane is healthy for scaling, i.e. all the control plane
+// machines have a node and etcd is healthy. If a precondition is not satisfied, it requeues.
+// NOTE: differently from preflightChecks, this func checks the current state of etcd instead of relying on KCP conditions.
+func (r *KubeadmControlPlaneReconciler) checkControlPlaneHealthyForScaling(ctx context.Context, controlPlane *internal.ControlPlane, excludeFor ...*clusterv1.Machine) (ctrl.Result, error) {
+	logger := controlPlane.Logger()
+
+	// If etcd is not managed by KCP, its health is not a precondition for scaling.
+	var etcdHealth func() (*internal.EtcdHealthDetails, error)
+	if controlPlane.IsEtcdManaged() {
+		etcdHealth = func() (*internal.EtcdHealthDetails, error) {
+			return r.managementCluster.EtcdHealthDetails(ctx, controlPlane.KCP, util.ObjectKey(controlPlane.Cluster))
+		}
+	}
+
+	err := internal.ControlPlaneHealthyForScaling(controlPlane.Machines, etcdHealth, excludeFor...)
+	if err == nil {
+		return ctrl.Result{}, nil
+	}
+
+	var preconditionErr *internal.ScalingPreconditionError
+	if !errors.As(err, &preconditionErr) {
+		return ctrl.Result{}, errors.Wrap(err, "failed to check if the control plane is healthy for scaling")
+	}
+
+	r.recorder.Eventf(controlPlane.KCP, corev1.EventTypeWarning, "ControlPlaneUnhealthy",
+		"Waiting for control plane to be healthy for scaling to continue reconciliation: %v", preconditionErr)
+	logger.Info("Waiting for control plane to be healthy for scaling", "precondition", preconditionErr.Precondition, "failure", preconditionErr.Err.Error())
+	return ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}, nil
+}
+
 func preflightCheckCondition(kind string, obj conditions.Getter, condition clusterv1.ConditionType) error {
 	c := conditions.Get(obj, condition)
 	if c == nil {
@@ -170,6 +170,44 @@ func TestKubeadmControlPlaneReconciler_scaleUpControlPlane(t *testing.T) {
 			g.Expect(m).To(Equal(bm))
 		}
 	})
+	t.Run("does not create a control plane Machine if the control plane is not healthy for scaling", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cluster, kcp, genericMachineTemplate := createClusterWithControlPlane(metav1.NamespaceDefault)
+		setKCPHealthy(kcp)
+		initObjs := []client.Object{cluster.DeepCopy(), kcp.DeepCopy(), genericMachineTemplate.DeepCopy()}
+
+		var machines []*clusterv1.Machine
+		for i := 0; i < 2; i++ {
+			m, _ := createMachineNodePair(fmt.Sprintf("test-%d", i), cluster, kcp, true)
+			setMachineHealthy(m)
+			machines = append(machines, m)
+			initObjs = append(initObjs, m.DeepCopy())
+		}
+		fmc := newFakeManagementCluster(machines...).withEtcdUnhealthyOnNode(machines[0].Status.NodeRef.Name)
+
+		fakeClient := newFakeClient(initObjs...)
+
+		r := &KubeadmControlPlaneReconciler{
+			Client:                    fakeClient,
+			managementCluster:         fmc,
+			managementClusterUncached: fmc,
+			recorder:                  record.NewFakeRecorder(32),
+		}
+		controlPlane := &internal.ControlPlane{
+			KCP:      kcp,
+			Cluster:  cluster,
+			Machines: fmc.Machines,
+		}
+
+		result, err := r.scaleUpControlPlane(ctx, cluster, kcp, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}))
+
+		controlPlaneMachines := clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, &controlPlaneMachines)).To(Succeed())
+		g.Expect(controlPlaneMachines.Items).To(HaveLen(2))
+	})
 }
 
 func TestKubeadmControlPlaneReconciler_scaleDownControlPlane_NoError(t *testing.T) {
@@ -211,6 +249,44 @@ func TestKubeadmControlPlaneReconciler_scaleDownControlPlane_NoError(t *testing.
 		g.Expect(fakeClient.List(context.Background(), &controlPlaneMachines)).To(Succeed())
 		g.Expect(controlPlaneMachines.Items).To(HaveLen(0))
 	})
+	t.Run("does not delete the control plane Machine if removing its etcd member would result in etcd losing quorum", func(t *testing.T) {
+		g := NewWithT(t)
+
+		machines := map[string]*clusterv1.Machine{
+			"one": machine("one"),
+		}
+		setMachineHealthy(machines["one"])
+		fakeClient := newFakeClient(machines["one"])
+
+		r := &KubeadmControlPlaneReconciler{
+			recorder: record.NewFakeRecorder(32),
+			Client:   fakeClient,
+			managementCluster: &fakeManagementCluster{
+				Workload: fakeWorkloadCluster{SafelyRemoveEtcdMemberErr: internal.ErrEtcdQuorumWouldBeLost},
+			},
+		}
+
+		cluster := &clusterv1.Cluster{}
+		kcp := &controlplanev1.KubeadmControlPlane{
+			Spec: controlplanev1.KubeadmControlPlaneSpec{
+				Version: "v1.19.1",
+			},
+		}
+		setKCPHealthy(kcp)
+		controlPlane := &internal.ControlPlane{
+			KCP:      kcp,
+			Cluster:  cluster,
+			Machines: machines,
+		}
+
+		result, err := r.scaleDownControlPlane(context.Background(), cluster, kcp, controlPlane, controlPlane.Machines)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}))
+
+		controlPlaneMachines := clusterv1.MachineList{}
+		g.Expect(fakeClient.List(context.Background(), &controlPlaneMachines)).To(Succeed())
+		g.Expect(controlPlaneMachines.Items).To(HaveLen(1))
+	})
 	t.Run("deletes the oldest control plane Machine even if preflight checks fails", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -223,12 +299,12 @@ func TestKubeadmControlPlaneReconciler_scaleDownControlPlane_NoError(t *testing.
 		setMachineHealthy(machines["three"])
 		fakeClient := newFakeClient(machines["one"], machines["two"], machines["three"])
 
+		fmc := newFakeManagementCluster(machines["two"], machines["three"])
+		fmc.Workload = fakeWorkloadCluster{}
 		r := &KubeadmControlPlaneReconciler{
-			recorder: record.NewFakeRecorder(32),
-			Client:   fakeClient,
-			managementCluster: &fakeManagementCluster{
-				Workload: fakeWorkloadCluster{},
-			},
+			recorder:          record.NewFakeRecorder(32),
+			Client:            fakeClient,
+			managementCluster: fmc,
 		}
 
 		cluster := &clusterv1.Cluster{}
@@ -491,6 +567,68 @@ func TestPreflightChecks(t *testing.T) {
 	}
 }
 
+func TestCheckControlPlaneHealthyForScaling(t *testing.T) {
+	cluster, kcp, _ := createClusterWithControlPlane(metav1.NamespaceDefault)
+	m1, _ := createMachineNodePair("m1", cluster, kcp, true)
+	m2, _ := createMachineNodePair("m2", cluster, kcp, true)
+	m3, _ := createMachineNodePair("m3", cluster, kcp, true)
+
+	testCases := []struct {
+		name              string
+		managementCluster *fakeManagementCluster
+		excludeFor        []*clusterv1.Machine
+		expectResult      ctrl.Result
+	}{
+		{
+			name:              "control plane healthy",
+			managementCluster: newFakeManagementCluster(m1, m2, m3),
+			expectResult:      ctrl.Result{},
+		},
+		{
+			name:              "etcd unhealthy on a node",
+			managementCluster: newFakeManagementCluster(m1, m2, m3).withEtcdUnhealthyOnNode("m2"),
+			expectResult:      ctrl.Result{RequeueAfter: preflightFailedRequeueAfter},
+		},
+		{
+			name:              "etcd unhealthy on the node of an excluded machine",
+			managementCluster: newFakeManagementCluster(m1, m2, m3).withEtcdUnhealthyOnNode("m2"),
+			excludeFor:        []*clusterv1.Machine{m2},
+			expectResult:      ctrl.Result{},
+		},
+		{
+			name:              "machine missing node ref",
+			managementCluster: newFakeManagementCluster(m1, m2, m3).withMachineMissingNodeRef("m3"),
+			expectResult:      ctrl.Result{RequeueAfter: preflightFailedRequeueAfter},
+		},
+		{
+			name:              "excluded machine missing node ref",
+			managementCluster: newFakeManagementCluster(m1, m2, m3).withMachineMissingNodeRef("m3"),
+			excludeFor:        []*clusterv1.Machine{m3},
+			expectResult:      ctrl.Result{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &KubeadmControlPlaneReconciler{
+				managementCluster: tc.managementCluster,
+				recorder:          record.NewFakeRecorder(32),
+			}
+			controlPlane := &internal.ControlPlane{
+				KCP:      kcp,
+				Cluster:  cluster,
+				Machines: tc.managementCluster.Machines,
+			}
+
+			result, err := r.checkControlPlaneHealthyForScaling(ctx, controlPlane, tc.excludeFor...)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result).To(Equal(tc.expectResult))
+		})
+	}
+}
+
 func TestPreflightCheckCondition(t *testing.T) {
 	condition := clusterv1.ConditionType("fooCondition")
 	testCases := []struct {
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"strings"
 
 	"github.com/pkg/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -52,6 +53,16 @@ func (r *KubeadmControlPlaneReconciler) updateStatus(ctx context.Context, kcp *c
 	}
 	kcp.Status.UpdatedReplicas = int32(len(controlPlane.UpToDateMachines()))
 
+	// Report the machines KCP can't detect configuration changes for, which are not outdated as far as KCP can tell.
+	untrackedMachines := ownedMachines.Filter(internal.MissingConfigurationHash(), collections.Not(collections.HasAnnotationKey(controlplanev1.KubeadmClusterConfigurationAnnotation)))
+	switch {
+	case len(untrackedMachines) > 0:
+		names := untrackedMachines.Names()
+		conditions.MarkFalse(kcp, controlplanev1.MachinesConfigurationTrackedCondition, controlplanev1.MissingConfigurationHashReason, clusterv1.ConditionSeverityInfo, "%d machines are missing the configuration hash, changes to the ClusterConfiguration are not rolled out to them: %s", len(names), strings.Join(names, ", "))
+	case conditions.Has(kcp, controlplanev1.MachinesConfigurationTrackedCondition):
+		conditions.MarkTrue(kcp, controlplanev1.MachinesConfigurationTrackedCondition)
+	}
+
 	replicas := int32(len(ownedMachines))
 	desiredReplicas := *kcp.Spec.Replicas
 
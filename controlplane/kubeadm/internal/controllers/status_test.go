@@ -379,3 +379,89 @@ func kubeadmConfigMap() *corev1.ConfigMap {
 		},
 	}
 }
+
+func TestKubeadmControlPlaneReconciler_updateStatusMachinesMissingConfigurationHash(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      "foo",
+		},
+	}
+
+	kcp := &controlplanev1.KubeadmControlPlane{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "KubeadmControlPlane",
+			APIVersion: controlplanev1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      "foo",
+		},
+		Spec: controlplanev1.KubeadmControlPlaneSpec{
+			Version: "v1.16.6",
+			MachineTemplate: controlplanev1.KubeadmControlPlaneMachineTemplate{
+				InfrastructureRef: corev1.ObjectReference{
+					APIVersion: "test/v1alpha1",
+					Kind:       "UnknownInfraMachine",
+					Name:       "foo",
+				},
+			},
+		},
+	}
+	kcp.Default()
+	g.Expect(kcp.ValidateCreate()).To(Succeed())
+
+	objs := []client.Object{cluster.DeepCopy(), kcp.DeepCopy(), kubeadmConfigMap()}
+	machines := map[string]*clusterv1.Machine{}
+
+	// A machine created by KCP, with the ClusterConfiguration annotation.
+	m, n := createMachineNodePair("created", cluster, kcp, true)
+	m.Annotations = map[string]string{controlplanev1.KubeadmClusterConfigurationAnnotation: "{}"}
+	objs = append(objs, n, m)
+	machines[m.Name] = m
+
+	// A machine created by an older KCP, with the configuration hash label.
+	m, n = createMachineNodePair("legacy", cluster, kcp, true)
+	m.Labels["kubeadm.controlplane.cluster.x-k8s.io/hash"] = "1234"
+	objs = append(objs, n, m)
+	machines[m.Name] = m
+
+	// Machines adopted by KCP, with neither.
+	for _, name := range []string{"adopted-1", "adopted-0"} {
+		m, n = createMachineNodePair(name, cluster, kcp, true)
+		objs = append(objs, n, m)
+		machines[m.Name] = m
+	}
+
+	fakeClient := newFakeClient(objs...)
+	log.SetLogger(klogr.New())
+
+	r := &KubeadmControlPlaneReconciler{
+		Client: fakeClient,
+		managementCluster: &fakeManagementCluster{
+			Machines: machines,
+			Workload: fakeWorkloadCluster{
+				Status: internal.ClusterStatus{
+					Nodes:            4,
+					ReadyNodes:       4,
+					HasKubeadmConfig: true,
+				},
+			},
+		},
+		recorder: record.NewFakeRecorder(32),
+	}
+
+	g.Expect(r.updateStatus(ctx, kcp, cluster)).To(Succeed())
+	g.Expect(conditions.IsFalse(kcp, controlplanev1.MachinesConfigurationTrackedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(kcp, controlplanev1.MachinesConfigurationTrackedCondition)).To(Equal(controlplanev1.MissingConfigurationHashReason))
+	g.Expect(conditions.GetMessage(kcp, controlplanev1.MachinesConfigurationTrackedCondition)).To(HaveSuffix(": adopted-0, adopted-1"))
+
+	// Once the adopted machines are gone, the condition is reported as true.
+	delete(machines, "adopted-0")
+	delete(machines, "adopted-1")
+
+	g.Expect(r.updateStatus(ctx, kcp, cluster)).To(Succeed())
+	g.Expect(conditions.IsTrue(kcp, controlplanev1.MachinesConfigurationTrackedCondition)).To(BeTrue())
+}
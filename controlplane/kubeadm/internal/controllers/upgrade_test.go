@@ -91,6 +91,7 @@ func TestKubeadmControlPlaneReconciler_RolloutStrategy_ScaleUp(t *testing.T) {
 	// run upgrade the first time, expect we scale up
 	needingUpgrade := collections.FromMachineList(initialMachine)
 	controlPlane.Machines = needingUpgrade
+	r.managementCluster.(*fakeManagementCluster).EtcdHealth = newFakeManagementCluster(needingUpgrade.UnsortedList()...).EtcdHealth
 	result, err = r.upgradeControlPlane(ctx, cluster, kcp, controlPlane, needingUpgrade)
 	g.Expect(result).To(Equal(ctrl.Result{Requeue: true}))
 	g.Expect(err).To(BeNil())
@@ -113,6 +114,7 @@ func TestKubeadmControlPlaneReconciler_RolloutStrategy_ScaleUp(t *testing.T) {
 		setMachineHealthy(&bothMachines.Items[i])
 	}
 	controlPlane.Machines = collections.FromMachineList(bothMachines)
+	r.managementCluster.(*fakeManagementCluster).EtcdHealth = newFakeManagementCluster(controlPlane.Machines.UnsortedList()...).EtcdHealth
 
 	// run upgrade the second time, expect we scale down
 	result, err = r.upgradeControlPlane(ctx, cluster, kcp, controlPlane, controlPlane.Machines)
@@ -205,6 +207,7 @@ func TestKubeadmControlPlaneReconciler_RolloutStrategy_ScaleDown(t *testing.T) {
 	// run upgrade, expect we scale down
 	needingUpgrade := collections.FromMachineList(machineList)
 	controlPlane.Machines = needingUpgrade
+	fmc.EtcdHealth = newFakeManagementCluster(needingUpgrade.UnsortedList()...).EtcdHealth
 	result, err = r.upgradeControlPlane(ctx, cluster, kcp, controlPlane, needingUpgrade)
 	g.Expect(result).To(Equal(ctrl.Result{Requeue: true}))
 	g.Expect(err).To(BeNil())
@@ -30,30 +30,89 @@ import (
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/proxy"
 )
 
+const (
+	// keepAliveTime is the interval after which the etcd client pings the server, if there is no activity,
+	// to check the connection is still alive, e.g. when the etcd pod being proxied to has been restarted.
+	keepAliveTime = 10 * time.Second
+
+	// keepAliveTimeout is how long the etcd client waits for a response to a ping before closing the connection.
+	keepAliveTimeout = 5 * time.Second
+)
+
 // GRPCDial is a function that creates a connection to a given endpoint.
 type GRPCDial func(ctx context.Context, addr string) (net.Conn, error)
 
 // etcd wraps the etcd client from etcd's clientv3 package.
-// This interface is implemented by both the clientv3 package and the backoff adapter that adds retries to the client.
+// This interface is implemented by both the clientv3 package, extended by clientv3Client, and the backoff adapter
+// that adds retries to the client.
 type etcd interface {
 	AlarmList(ctx context.Context) (*clientv3.AlarmResponse, error)
 	Close() error
 	Endpoints() []string
+	MemberAddAsLearner(ctx context.Context, peerAddrs []string) (*clientv3.MemberAddResponse, error)
 	MemberList(ctx context.Context) (*clientv3.MemberListResponse, error)
+	MemberPromote(ctx context.Context, id uint64) (*clientv3.MemberPromoteResponse, error)
+	SerializableMemberList(ctx context.Context) (*clientv3.MemberListResponse, error)
 	MemberRemove(ctx context.Context, id uint64) (*clientv3.MemberRemoveResponse, error)
 	MemberUpdate(ctx context.Context, id uint64, peerURLs []string) (*clientv3.MemberUpdateResponse, error)
 	MoveLeader(ctx context.Context, id uint64) (*clientv3.MoveLeaderResponse, error)
 	Status(ctx context.Context, endpoint string) (*clientv3.StatusResponse, error)
 }
 
+// clientv3Client extends the clientv3 client with the operations not exposed by the clientv3 API.
+type clientv3Client struct {
+	*clientv3.Client
+}
+
+// SerializableMemberList lists the etcd members from the local state of the member the client is connected to;
+// this is required because the clientv3 MemberList always requests a linearizable read.
+func (c clientv3Client) SerializableMemberList(ctx context.Context) (*clientv3.MemberListResponse, error) {
+	response, err := clientv3.RetryClusterClient(c.Client).MemberList(ctx, &etcdserverpb.MemberListRequest{Linearizable: false})
+	if err != nil {
+		return nil, err
+	}
+	return (*clientv3.MemberListResponse)(response), nil
+}
+
+// MemberListConsistency defines the consistency of the reads of the list of etcd members.
+type MemberListConsistency string
+
+const (
+	// MemberListLinearizable reads the list of members through the raft consensus, which proves that the etcd cluster
+	// has a quorum; this is the default, and it is required for health checks and membership changes.
+	MemberListLinearizable MemberListConsistency = "Linearizable"
+
+	// MemberListSerializable reads the list of members from the local state of the member the client is connected to,
+	// which is cheaper and does not require a quorum, but could be stale; this is intended for informational reads,
+	// e.g. for dumping the etcd state for debugging.
+	MemberListSerializable MemberListConsistency = "Serializable"
+)
+
 // Client wraps an etcd client formatting its output to something more consumable.
 type Client struct {
 	EtcdClient etcd
 	Endpoint   string
 	LeaderID   uint64
 	Errors     []string
+
+	// DBSize is the size of the backend database physically allocated, in bytes, as reported by the endpoint.
+	DBSize int64
+
+	// DBSizeInUse is the size of the backend database logically in use, in bytes, as reported by the endpoint.
+	DBSizeInUse int64
+
+	// Version is the version of etcd running on the endpoint, as reported by the endpoint.
+	Version string
+
+	// shared is set on clients handed out by a ClientPool; closing them is a no-op given that
+	// the underlying connection is owned by the pool.
+	shared bool
 }
 
+// DefaultQuotaBackendBytes is the etcd default for the backend database size quota (2GiB);
+// once a member exceeds the quota etcd raises a NOSPACE alarm and the cluster goes into maintenance mode.
+const DefaultQuotaBackendBytes int64 = 2 * 1024 * 1024 * 1024
+
 // MemberAlarm represents an alarm type association with a cluster member.
 type MemberAlarm struct {
 	// MemberID is the ID of the member associated with the raised alarm.
@@ -125,33 +184,46 @@ func pbMemberToMember(m *etcdserverpb.Member) *Member {
 
 // ClientConfiguration describes the configuration for an etcd client.
 type ClientConfiguration struct {
-	Endpoints   []string
-	Proxy       proxy.Proxy
+	Endpoints []string
+	// Proxy is used for dialing the endpoints; if nil, the endpoints are dialed directly.
+	Proxy       *proxy.Proxy
 	TLSConfig   *tls.Config
 	DialTimeout time.Duration
 }
 
 // NewClient creates a new etcd client with the given configuration.
 func NewClient(ctx context.Context, config ClientConfiguration) (*Client, error) {
-	dialer, err := proxy.NewDialer(config.Proxy)
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to create a dialer for etcd client")
-	}
-
-	etcdClient, err := clientv3.New(clientv3.Config{
-		Endpoints:   config.Endpoints,
-		DialTimeout: config.DialTimeout,
-		DialOptions: []grpc.DialOption{
-			grpc.WithBlock(), // block until the underlying connection is up
-			grpc.WithContextDialer(dialer.DialContextWithAddr),
-		},
-		TLS: config.TLSConfig,
-	})
+	dialOptions := []grpc.DialOption{
+		grpc.WithBlock(), // block until the underlying connection is up
+	}
+	if config.Proxy != nil {
+		dialer, err := proxy.NewDialer(*config.Proxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to create a dialer for etcd client")
+		}
+		dialOptions = append(dialOptions, grpc.WithContextDialer(dialer.DialContextWithAddr))
+	}
+
+	etcdClient, err := clientv3.New(clientv3Config(config, dialOptions))
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to create etcd client")
 	}
 
-	return newEtcdClient(ctx, etcdClient)
+	return newEtcdClient(ctx, clientv3Client{Client: etcdClient})
+}
+
+// clientv3Config returns the configuration of the etcd client for the given configuration and dial options.
+// NOTE: Keepalive is configured so a connection which died, e.g. because the etcd pod has been restarted,
+// is detected and the operations using it fail fast instead of hanging until the context is done.
+func clientv3Config(config ClientConfiguration, dialOptions []grpc.DialOption) clientv3.Config {
+	return clientv3.Config{
+		Endpoints:            config.Endpoints,
+		DialTimeout:          config.DialTimeout,
+		DialKeepAliveTime:    keepAliveTime,
+		DialKeepAliveTimeout: keepAliveTimeout,
+		DialOptions:          dialOptions,
+		TLS:                  config.TLSConfig,
+	}
 }
 
 func newEtcdClient(ctx context.Context, etcdClient etcd) (*Client, error) {
@@ -160,27 +232,54 @@ func newEtcdClient(ctx context.Context, etcdClient etcd) (*Client, error) {
 		return nil, errors.New("etcd client was not configured with any endpoints")
 	}
 
-	status, err := etcdClient.Status(ctx, endpoints[0])
-	if err != nil {
+	c := &Client{
+		Endpoint:   endpoints[0],
+		EtcdClient: etcdClient,
+	}
+	if err := c.refreshStatus(ctx); err != nil {
 		return nil, err
 	}
+	return c, nil
+}
 
-	return &Client{
-		Endpoint:   endpoints[0],
-		EtcdClient: etcdClient,
-		LeaderID:   status.Leader,
-		Errors:     status.Errors,
-	}, nil
+// refreshStatus reads the status of the endpoint and updates the corresponding fields of the client.
+func (c *Client) refreshStatus(ctx context.Context) error {
+	status, err := c.EtcdClient.Status(ctx, c.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	c.LeaderID = status.Leader
+	c.Errors = status.Errors
+	c.DBSize = status.DbSize
+	c.DBSizeInUse = status.DbSizeInUse
+	c.Version = status.Version
+	return nil
 }
 
-// Close closes the etcd client.
+// Close closes the etcd client; clients handed out by a ClientPool are closed by the pool instead.
 func (c *Client) Close() error {
+	if c.shared {
+		return nil
+	}
 	return c.EtcdClient.Close()
 }
 
-// Members retrieves a list of etcd members.
+// Members retrieves a list of etcd members; the list is read through the raft consensus, see MemberListLinearizable.
 func (c *Client) Members(ctx context.Context) ([]*Member, error) {
-	response, err := c.EtcdClient.MemberList(ctx)
+	return c.MembersWithConsistency(ctx, MemberListLinearizable)
+}
+
+// MembersWithConsistency retrieves a list of etcd members, reading it with the given consistency.
+func (c *Client) MembersWithConsistency(ctx context.Context, consistency MemberListConsistency) ([]*Member, error) {
+	var response *clientv3.MemberListResponse
+	var err error
+	switch consistency {
+	case MemberListSerializable:
+		response, err = c.EtcdClient.SerializableMemberList(ctx)
+	default:
+		response, err = c.EtcdClient.MemberList(ctx)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get list of members for etcd cluster")
 	}
@@ -212,6 +311,23 @@ func (c *Client) MoveLeader(ctx context.Context, newLeaderID uint64) error {
 	return errors.Wrapf(err, "failed to move etcd leader: %v", newLeaderID)
 }
 
+// AddLearnerMember adds a new member with the given peer URLs as a learner, i.e. a non-voting member
+// which gets promoted to voting member with PromoteMember once it is in sync with the leader.
+func (c *Client) AddLearnerMember(ctx context.Context, peerURLs []string) (*Member, error) {
+	response, err := c.EtcdClient.MemberAddAsLearner(ctx, peerURLs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to add etcd learner member with peer URLs %v", peerURLs)
+	}
+	return pbMemberToMember(response.Member), nil
+}
+
+// PromoteMember promotes a learner member to voting member; it fails with rpctypes.ErrMemberLearnerNotReady
+// while the learner is not in sync with the leader yet.
+func (c *Client) PromoteMember(ctx context.Context, id uint64) error {
+	_, err := c.EtcdClient.MemberPromote(ctx, id)
+	return errors.Wrapf(err, "failed to promote etcd member: %v", id)
+}
+
 // RemoveMember removes a given member.
 func (c *Client) RemoveMember(ctx context.Context, id uint64) error {
 	_, err := c.EtcdClient.MemberRemove(ctx, id)
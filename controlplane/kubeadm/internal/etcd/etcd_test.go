@@ -18,6 +18,7 @@ package etcd
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
@@ -104,3 +105,72 @@ func TestEtcdMembers_WithSuccess(t *testing.T) {
 	g.Expect(len(updatedMembers[0].PeerURLs)).To(Equal(2))
 	g.Expect(updatedMembers[0].PeerURLs).To(Equal([]string{"https://1.2.3.4:2000", "https://4.5.6.7:2000"}))
 }
+
+func TestEtcdMembers_WithConsistency(t *testing.T) {
+	newFakeEtcdClient := func() *etcdfake.FakeEtcdClient {
+		return &etcdfake.FakeEtcdClient{
+			EtcdEndpoints: []string{"https://etcd-instance:2379"},
+			MemberListResponse: &clientv3.MemberListResponse{
+				Header: &etcdserverpb.ResponseHeader{},
+				Members: []*etcdserverpb.Member{
+					{ID: 1234, Name: "foo", PeerURLs: []string{"https://1.2.3.4:2000"}},
+				},
+			},
+			AlarmResponse:  &clientv3.AlarmResponse{},
+			StatusResponse: &clientv3.StatusResponse{},
+		}
+	}
+
+	tests := []struct {
+		name                 string
+		list                 func(client *Client) ([]*Member, error)
+		expectedSerializable bool
+	}{
+		{
+			name:                 "Members reads linearizable",
+			list:                 func(client *Client) ([]*Member, error) { return client.Members(ctx) },
+			expectedSerializable: false,
+		},
+		{
+			name: "MembersWithConsistency reads linearizable when requested",
+			list: func(client *Client) ([]*Member, error) {
+				return client.MembersWithConsistency(ctx, MemberListLinearizable)
+			},
+			expectedSerializable: false,
+		},
+		{
+			name: "MembersWithConsistency reads serializable when requested",
+			list: func(client *Client) ([]*Member, error) {
+				return client.MembersWithConsistency(ctx, MemberListSerializable)
+			},
+			expectedSerializable: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			fakeEtcdClient := newFakeEtcdClient()
+			client, err := newEtcdClient(ctx, fakeEtcdClient)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			members, err := tt.list(client)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(members).To(HaveLen(1))
+			g.Expect(fakeEtcdClient.MemberListSerializable).To(Equal(tt.expectedSerializable))
+		})
+	}
+}
+
+func TestClientv3Config(t *testing.T) {
+	g := NewWithT(t)
+
+	config := clientv3Config(ClientConfiguration{
+		Endpoints:   []string{"https://etcd-instance:2379"},
+		DialTimeout: time.Second,
+	}, nil)
+	g.Expect(config.Endpoints).To(Equal([]string{"https://etcd-instance:2379"}))
+	g.Expect(config.DialTimeout).To(Equal(time.Second))
+	g.Expect(config.DialKeepAliveTime).To(Equal(keepAliveTime))
+	g.Expect(config.DialKeepAliveTimeout).To(Equal(keepAliveTimeout))
+}
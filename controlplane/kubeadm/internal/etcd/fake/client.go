@@ -24,16 +24,32 @@ import (
 )
 
 type FakeEtcdClient struct { //nolint:revive
-	AlarmResponse        *clientv3.AlarmResponse
-	EtcdEndpoints        []string
-	MemberListResponse   *clientv3.MemberListResponse
-	MemberRemoveResponse *clientv3.MemberRemoveResponse
-	MemberUpdateResponse *clientv3.MemberUpdateResponse
-	MoveLeaderResponse   *clientv3.MoveLeaderResponse
-	StatusResponse       *clientv3.StatusResponse
-	ErrorResponse        error
-	MovedLeader          uint64
-	RemovedMember        uint64
+	AlarmResponse         *clientv3.AlarmResponse
+	EtcdEndpoints         []string
+	MemberAddResponse     *clientv3.MemberAddResponse
+	MemberListResponse    *clientv3.MemberListResponse
+	MemberPromoteResponse *clientv3.MemberPromoteResponse
+	MemberRemoveResponse  *clientv3.MemberRemoveResponse
+	MemberUpdateResponse  *clientv3.MemberUpdateResponse
+	MoveLeaderResponse    *clientv3.MoveLeaderResponse
+	StatusResponse        *clientv3.StatusResponse
+	ErrorResponse         error
+	MovedLeader           uint64
+	RemovedMember         uint64
+	AddedLearnerPeerURLs  []string
+	PromotedMember        uint64
+
+	// MemberPromoteError is returned by MemberPromote, e.g. to simulate a learner not in sync with the leader yet.
+	MemberPromoteError error
+
+	// MemberListSerializable is set when the members have been listed with a serializable read.
+	MemberListSerializable bool
+
+	// StatusError is returned by Status, e.g. to simulate a connection that is no longer alive.
+	StatusError error
+
+	// Closed is set when the client has been closed.
+	Closed bool
 }
 
 func (c *FakeEtcdClient) Endpoints() []string {
@@ -46,6 +62,7 @@ func (c *FakeEtcdClient) MoveLeader(_ context.Context, i uint64) (*clientv3.Move
 }
 
 func (c *FakeEtcdClient) Close() error {
+	c.Closed = true
 	return nil
 }
 
@@ -53,9 +70,26 @@ func (c *FakeEtcdClient) AlarmList(_ context.Context) (*clientv3.AlarmResponse,
 	return c.AlarmResponse, c.ErrorResponse
 }
 
+func (c *FakeEtcdClient) MemberAddAsLearner(_ context.Context, peerAddrs []string) (*clientv3.MemberAddResponse, error) {
+	c.AddedLearnerPeerURLs = peerAddrs
+	return c.MemberAddResponse, c.ErrorResponse
+}
+
+func (c *FakeEtcdClient) MemberPromote(_ context.Context, i uint64) (*clientv3.MemberPromoteResponse, error) {
+	if c.MemberPromoteError != nil {
+		return nil, c.MemberPromoteError
+	}
+	c.PromotedMember = i
+	return c.MemberPromoteResponse, c.ErrorResponse
+}
+
 func (c *FakeEtcdClient) MemberList(_ context.Context) (*clientv3.MemberListResponse, error) {
 	return c.MemberListResponse, c.ErrorResponse
 }
+func (c *FakeEtcdClient) SerializableMemberList(_ context.Context) (*clientv3.MemberListResponse, error) {
+	c.MemberListSerializable = true
+	return c.MemberListResponse, c.ErrorResponse
+}
 func (c *FakeEtcdClient) MemberRemove(_ context.Context, i uint64) (*clientv3.MemberRemoveResponse, error) {
 	c.RemovedMember = i
 	return c.MemberRemoveResponse, c.ErrorResponse
@@ -64,5 +98,5 @@ func (c *FakeEtcdClient) MemberUpdate(_ context.Context, _ uint64, _ []string) (
 	return c.MemberUpdateResponse, c.ErrorResponse
 }
 func (c *FakeEtcdClient) Status(_ context.Context, _ string) (*clientv3.StatusResponse, error) {
-	return c.StatusResponse, nil
+	return c.StatusResponse, c.StatusError
 }
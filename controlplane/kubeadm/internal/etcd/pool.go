@@ -0,0 +1,115 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// livenessCheckTimeout is how long the liveness check of a pooled client waits for the endpoint to reply.
+const livenessCheckTimeout = 5 * time.Second
+
+// ClientPool keeps etcd clients open across reconciles, so connections to the members of a
+// workload cluster are not re-established every time they are needed.
+type ClientPool struct {
+	lock    sync.Mutex
+	clients map[string]*Client
+}
+
+// NewClientPool returns an empty ClientPool.
+func NewClientPool() *ClientPool {
+	return &ClientPool{
+		clients: map[string]*Client{},
+	}
+}
+
+// Get returns the client stored in the pool for the given key, refreshing its status; if there is
+// no client for the key, or the stored one is not alive anymore, e.g. because the etcd member has been
+// restarted, a new one is created with newClient and stored in the pool.
+// The returned client is owned by the pool, so calling Close on it has no effect.
+// NOTE: The pool is not locked while checking liveness or creating clients, so a slow etcd member doesn't block
+// callers for other keys; if concurrent callers create a client for the same key, only the first one stored is kept.
+func (p *ClientPool) Get(ctx context.Context, key string, newClient func(context.Context) (*Client, error)) (*Client, error) {
+	p.lock.Lock()
+	c, ok := p.clients[key]
+	p.lock.Unlock()
+
+	if ok {
+		// Reading the status doubles as a liveness check for the pooled connection; it is done on a copy
+		// given that the pooled client can be used concurrently.
+		shared := c.sharedCopy()
+		if err := shared.checkLiveness(ctx); err == nil {
+			return shared, nil
+		}
+		p.lock.Lock()
+		if p.clients[key] == c {
+			delete(p.clients, key)
+			_ = c.EtcdClient.Close()
+		}
+		p.lock.Unlock()
+	}
+
+	created, err := newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if stored, ok := p.clients[key]; ok {
+		_ = created.EtcdClient.Close()
+		return stored.sharedCopy(), nil
+	}
+	p.clients[key] = created
+	return created.sharedCopy(), nil
+}
+
+// Close closes all the clients in the pool; the pool can still be used afterwards.
+func (p *ClientPool) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var errs []error
+	for key, c := range p.clients {
+		if err := c.EtcdClient.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(p.clients, key)
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// checkLiveness refreshes the status of the client, failing if the endpoint does not reply in time.
+func (c *Client) checkLiveness(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, livenessCheckTimeout)
+	defer cancel()
+
+	return c.refreshStatus(ctx)
+}
+
+// sharedCopy returns a copy of the client which does not close the underlying connection.
+func (c *Client) sharedCopy() *Client {
+	shared := *c
+	shared.Errors = append([]string(nil), c.Errors...)
+	shared.shared = true
+	return &shared
+}
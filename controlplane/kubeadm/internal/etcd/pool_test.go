@@ -0,0 +1,126 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	etcdfake "sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd/fake"
+)
+
+func TestClientPool(t *testing.T) {
+	newFakeClient := func() *etcdfake.FakeEtcdClient {
+		return &etcdfake.FakeEtcdClient{
+			EtcdEndpoints:  []string{"https://etcd-instance:2379"},
+			StatusResponse: &clientv3.StatusResponse{Version: "3.5.1"},
+		}
+	}
+
+	t.Run("reuses the client for the same key", func(t *testing.T) {
+		g := NewWithT(t)
+
+		pool := NewClientPool()
+		fakeClient := newFakeClient()
+		created := 0
+		newClient := func(ctx context.Context) (*Client, error) {
+			created++
+			return newEtcdClient(ctx, fakeClient)
+		}
+
+		c1, err := pool.Get(ctx, "node-1", newClient)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(c1.Close()).To(Succeed())
+		g.Expect(fakeClient.Closed).To(BeFalse())
+
+		fakeClient.StatusResponse = &clientv3.StatusResponse{Version: "3.5.2"}
+		c2, err := pool.Get(ctx, "node-1", newClient)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(c2.EtcdClient).To(BeIdenticalTo(fakeClient))
+		g.Expect(c2.Version).To(Equal("3.5.2"))
+		g.Expect(created).To(Equal(1))
+
+		g.Expect(pool.Close()).To(Succeed())
+		g.Expect(fakeClient.Closed).To(BeTrue())
+	})
+
+	t.Run("reconnects a client whose connection died", func(t *testing.T) {
+		g := NewWithT(t)
+
+		pool := NewClientPool()
+		deadClient := newFakeClient()
+		newClient := func(ctx context.Context) (*Client, error) {
+			return newEtcdClient(ctx, deadClient)
+		}
+
+		_, err := pool.Get(ctx, "node-1", newClient)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		deadClient.StatusError = errors.New("rpc error: code = Unavailable desc = connection closed")
+		liveClient := newFakeClient()
+		c, err := pool.Get(ctx, "node-1", func(ctx context.Context) (*Client, error) {
+			return newEtcdClient(ctx, liveClient)
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(c.EtcdClient).To(BeIdenticalTo(liveClient))
+		g.Expect(deadClient.Closed).To(BeTrue())
+		g.Expect(pool.clients).To(HaveKey("node-1"))
+	})
+
+	t.Run("drops a client which can't be reconnected", func(t *testing.T) {
+		g := NewWithT(t)
+
+		pool := NewClientPool()
+		deadClient := newFakeClient()
+		_, err := pool.Get(ctx, "node-1", func(ctx context.Context) (*Client, error) {
+			return newEtcdClient(ctx, deadClient)
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		deadClient.StatusError = errors.New("rpc error: code = Unavailable desc = connection closed")
+		_, err = pool.Get(ctx, "node-1", func(ctx context.Context) (*Client, error) {
+			return nil, errors.New("etcd member is not reachable")
+		})
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(deadClient.Closed).To(BeTrue())
+		g.Expect(pool.clients).To(BeEmpty())
+	})
+	t.Run("keeps the client stored first by a concurrent caller", func(t *testing.T) {
+		g := NewWithT(t)
+
+		pool := NewClientPool()
+		storedClient := newFakeClient()
+		duplicateClient := newFakeClient()
+		c, err := pool.Get(ctx, "node-1", func(ctx context.Context) (*Client, error) {
+			// The pool is not locked while creating the client, so a concurrent caller can store one in the meantime.
+			_, err := pool.Get(ctx, "node-1", func(ctx context.Context) (*Client, error) {
+				return newEtcdClient(ctx, storedClient)
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+			return newEtcdClient(ctx, duplicateClient)
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(c.EtcdClient).To(BeIdenticalTo(storedClient))
+		g.Expect(duplicateClient.Closed).To(BeTrue())
+		g.Expect(storedClient.Closed).To(BeFalse())
+		g.Expect(pool.clients).To(HaveLen(1))
+	})
+}
@@ -18,11 +18,29 @@ limitations under the License.
 package util
 
 import (
+	"fmt"
+	"net/url"
+
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd"
+	"sigs.k8s.io/cluster-api/util/collections"
 )
 
+// NoMachineForMemberError is returned when an etcd member is not hosted on the node of any control plane machine,
+// e.g. a phantom member left behind by a machine which has been deleted without removing its etcd member.
+type NoMachineForMemberError struct {
+	MemberID   uint64
+	MemberName string
+}
+
+// Error satisfies the error interface.
+func (e *NoMachineForMemberError) Error() string {
+	return fmt.Sprintf("no control plane machine found for etcd member %x with name %q", e.MemberID, e.MemberName)
+}
+
 // MemberForName returns the etcd member with the matching name.
 func MemberForName(members []*etcd.Member, name string) *etcd.Member {
 	for _, m := range members {
@@ -33,6 +51,51 @@ func MemberForName(members []*etcd.Member, name string) *etcd.Member {
 	return nil
 }
 
+// MemberForID returns the etcd member with the matching ID.
+func MemberForID(members []*etcd.Member, id uint64) *etcd.Member {
+	for _, m := range members {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// MemberForPeerAddresses returns the etcd member advertising a peer URL whose host is one of the given addresses,
+// or nil if there is no such member; this allows to find the member hosted on a node when the member name
+// doesn't match the node name, e.g. after the node has been renamed.
+func MemberForPeerAddresses(members []*etcd.Member, addresses []string) *etcd.Member {
+	hosts := sets.NewString(addresses...)
+	for _, m := range members {
+		for _, peerURL := range m.PeerURLs {
+			u, err := url.Parse(peerURL)
+			if err != nil {
+				continue
+			}
+			if hosts.Has(u.Hostname()) {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// MachineForMemberID returns the control plane machine hosting the etcd member with the given ID, i.e. the machine whose
+// node has the same name as the etcd member. A NoMachineForMemberError is returned if no machine is hosting the member.
+func MachineForMemberID(members []*etcd.Member, machines collections.Machines, id uint64) (*clusterv1.Machine, error) {
+	member := MemberForID(members, id)
+	if member == nil {
+		return nil, errors.Errorf("etcd member %x not found", id)
+	}
+
+	for _, machine := range machines {
+		if machine.Status.NodeRef != nil && machine.Status.NodeRef.Name == member.Name {
+			return machine, nil
+		}
+	}
+	return nil, &NoMachineForMemberError{MemberID: member.ID, MemberName: member.Name}
+}
+
 // MemberNames returns a list of all the etcd member names.
 func MemberNames(members []*etcd.Member) []string {
 	names := make([]string, 0, len(members))
@@ -42,6 +105,31 @@ func MemberNames(members []*etcd.Member) []string {
 	return names
 }
 
+// VotingMemberCount returns the number of voting members, i.e. excluding learners, which don't vote
+// and therefore don't count for quorum.
+func VotingMemberCount(members []*etcd.Member) int {
+	count := 0
+	for _, m := range members {
+		if !m.IsLearner {
+			count++
+		}
+	}
+	return count
+}
+
+// EtcdQuorum returns the number of voting members required for an etcd cluster with the given number
+// of voting members to have quorum, i.e. a majority of them.
+// See https://etcd.io/docs/v3.3/faq/#what-is-failure-tolerance for fault tolerance formula explanation.
+func EtcdQuorum(votingMembers int) int {
+	return votingMembers/2 + 1
+}
+
+// EtcdQuorumForMembers returns the number of voting members required for the etcd cluster with the given
+// members to have quorum; learners are not considered, given that they don't vote.
+func EtcdQuorumForMembers(members []*etcd.Member) int {
+	return EtcdQuorum(VotingMemberCount(members))
+}
+
 // MemberEqual returns true if the lists of members match.
 //
 // This function only checks that set of names of each member
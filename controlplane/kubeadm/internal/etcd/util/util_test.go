@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd"
+	"sigs.k8s.io/cluster-api/util/collections"
+)
+
+func TestVotingMemberCount(t *testing.T) {
+	g := NewWithT(t)
+
+	members := []*etcd.Member{
+		{Name: "m1", ID: 1},
+		{Name: "m2", ID: 2},
+		{Name: "m3", ID: 3, IsLearner: true},
+	}
+	g.Expect(VotingMemberCount(members)).To(Equal(2))
+	g.Expect(VotingMemberCount(nil)).To(Equal(0))
+}
+
+func TestEtcdQuorum(t *testing.T) {
+	tests := []struct {
+		votingMembers  int
+		expectedQuorum int
+	}{
+		{votingMembers: 1, expectedQuorum: 1},
+		{votingMembers: 3, expectedQuorum: 2},
+		{votingMembers: 5, expectedQuorum: 3},
+		{votingMembers: 7, expectedQuorum: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d voting members", tt.votingMembers), func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(EtcdQuorum(tt.votingMembers)).To(Equal(tt.expectedQuorum))
+
+			var members []*etcd.Member
+			for i := 1; i <= tt.votingMembers; i++ {
+				members = append(members, &etcd.Member{Name: fmt.Sprintf("m%d", i), ID: uint64(i)})
+			}
+			g.Expect(EtcdQuorumForMembers(members)).To(Equal(tt.expectedQuorum))
+
+			// A learner does not vote, so it does not change the quorum.
+			members = append(members, &etcd.Member{Name: "learner", ID: 100, IsLearner: true})
+			g.Expect(EtcdQuorumForMembers(members)).To(Equal(tt.expectedQuorum))
+		})
+	}
+}
+
+func TestMemberForID(t *testing.T) {
+	g := NewWithT(t)
+
+	members := []*etcd.Member{
+		{Name: "m1", ID: 1},
+		{Name: "m2", ID: 2},
+	}
+	g.Expect(MemberForID(members, 2)).To(Equal(members[1]))
+	g.Expect(MemberForID(members, 3)).To(BeNil())
+}
+
+func TestMemberForPeerAddresses(t *testing.T) {
+	g := NewWithT(t)
+
+	members := []*etcd.Member{
+		{Name: "m1", ID: 1, PeerURLs: []string{"https://10.0.0.1:2380"}},
+		{Name: "m2", ID: 2, PeerURLs: []string{"https://10.0.0.2:2380"}},
+		{Name: "m3", ID: 3, PeerURLs: []string{"%"}},
+	}
+	g.Expect(MemberForPeerAddresses(members, []string{"192.168.0.2", "10.0.0.2"})).To(Equal(members[1]))
+	g.Expect(MemberForPeerAddresses(members, []string{"10.0.0.3"})).To(BeNil())
+	g.Expect(MemberForPeerAddresses(members, nil)).To(BeNil())
+}
+
+func TestMachineForMemberID(t *testing.T) {
+	newMachine := func(name, nodeName string) *clusterv1.Machine {
+		machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if nodeName != "" {
+			machine.Status.NodeRef = &corev1.ObjectReference{Name: nodeName}
+		}
+		return machine
+	}
+
+	members := []*etcd.Member{
+		{Name: "node-1", ID: 1},
+		{Name: "node-2", ID: 2},
+		{Name: "node-3", ID: 3},
+	}
+	machines := collections.FromMachines(
+		newMachine("machine-1", "node-1"),
+		newMachine("machine-2", "node-2"),
+		newMachine("machine-4", ""),
+	)
+
+	t.Run("returns the machine hosting the member", func(t *testing.T) {
+		g := NewWithT(t)
+
+		machine, err := MachineForMemberID(members, machines, 2)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(machine.Name).To(Equal("machine-2"))
+	})
+
+	t.Run("returns a NoMachineForMemberError for a phantom member", func(t *testing.T) {
+		g := NewWithT(t)
+
+		machine, err := MachineForMemberID(members, machines, 3)
+		g.Expect(machine).To(BeNil())
+		var noMachineErr *NoMachineForMemberError
+		g.Expect(errors.As(err, &noMachineErr)).To(BeTrue())
+		g.Expect(noMachineErr.MemberID).To(Equal(uint64(3)))
+		g.Expect(noMachineErr.MemberName).To(Equal("node-3"))
+	})
+
+	t.Run("returns an error for an unknown member", func(t *testing.T) {
+		g := NewWithT(t)
+
+		machine, err := MachineForMemberID(members, machines, 5)
+		g.Expect(machine).To(BeNil())
+		g.Expect(err).To(HaveOccurred())
+		var noMachineErr *NoMachineForMemberError
+		g.Expect(errors.As(err, &noMachineErr)).To(BeFalse())
+	})
+}
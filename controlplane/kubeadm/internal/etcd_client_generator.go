@@ -19,9 +19,16 @@ package internal
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -31,11 +38,64 @@ import (
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/proxy"
 )
 
+// etcdClientCertRenewalThreshold is the remaining validity below which the etcd client certificate gets regenerated before use.
+const etcdClientCertRenewalThreshold = 5 * time.Minute
+
+// etcdClientPort is the port etcd is listening on for client connections.
+const etcdClientPort = 2379
+
+// EtcdConnectionStrategy defines how to connect to the etcd members of a workload cluster.
+type EtcdConnectionStrategy string
+
+const (
+	// EtcdConnectionProxyViaPod connects to the etcd members by port-forwarding into the etcd pods
+	// via the workload cluster API server. This is the default.
+	EtcdConnectionProxyViaPod EtcdConnectionStrategy = "ProxyViaPod"
+
+	// EtcdConnectionDirectToNode connects to the etcd members by dialing the InternalIP of the nodes hosting them.
+	// NOTE: This requires the management cluster to reach the etcd client port (2379) of the workload
+	// cluster control plane nodes, but avoids the API server load of port-forwarding.
+	EtcdConnectionDirectToNode EtcdConnectionStrategy = "DirectToNode"
+
+	// EtcdConnectionDirectToEndpoints connects to the etcd members by dialing the DNS names configured for the
+	// workload cluster, validating the etcd server certificate against them.
+	// NOTE: This is an interop path for clusters whose etcd members are fronted by stable DNS names and are not
+	// reachable via the etcd pods or the nodes hosting them.
+	EtcdConnectionDirectToEndpoints EtcdConnectionStrategy = "DirectToEndpoints"
+)
+
 // EtcdClientGenerator generates etcd clients that connect to specific etcd members on particular control plane nodes.
 type EtcdClientGenerator struct {
+	lock         sync.Mutex
 	restConfig   *rest.Config
 	tlsConfig    *tls.Config
 	createClient clientCreator
+
+	// etcdNamespace is the namespace where the etcd pods are running; it defaults to defaultSystemNamespace.
+	etcdNamespace string
+
+	// connectionStrategy defines how to connect to the etcd members; it defaults to EtcdConnectionProxyViaPod.
+	connectionStrategy EtcdConnectionStrategy
+
+	// getNode returns a node of the workload cluster; it is required when dialing the etcd members directly
+	// at the nodes hosting them, i.e. when using EtcdConnectionDirectToNode or a nodeAddressResolver.
+	getNode func(ctx context.Context, nodeName string) (*corev1.Node, error)
+
+	// nodeAddressResolver, if set, returns the host and port to dial for the etcd member hosted on a node, e.g. for
+	// providers exposing etcd on a custom address or port; the member is then dialed directly, regardless of the
+	// connection strategy.
+	nodeAddressResolver func(node corev1.Node) (string, int, error)
+
+	// endpoints are the DNS names, optionally with a port, of the etcd members; they are required when using
+	// EtcdConnectionDirectToEndpoints, and the first label of each DNS name is the name of the node hosting the member.
+	endpoints []string
+
+	// generateClientCert generates a new etcd client certificate; it is nil when the client certificate
+	// can't be regenerated, e.g. when re-using the apiserver-etcd-client certificate for external etcd.
+	generateClientCert func() (tls.Certificate, error)
+
+	// pool, if set, keeps the clients open across reconciles; clients are keyed by the endpoint they connect to.
+	pool *etcd.ClientPool
 }
 
 type clientCreator func(ctx context.Context, endpoints []string) (*etcd.Client, error)
@@ -44,28 +104,288 @@ var errEtcdNodeConnection = errors.New("failed to connect to etcd node")
 
 // NewEtcdClientGenerator returns a new etcdClientGenerator instance.
 func NewEtcdClientGenerator(restConfig *rest.Config, tlsConfig *tls.Config, etcdDialTimeout time.Duration) *EtcdClientGenerator {
-	ecg := &EtcdClientGenerator{restConfig: restConfig, tlsConfig: tlsConfig}
+	ecg := &EtcdClientGenerator{restConfig: restConfig, tlsConfig: tlsConfig, etcdNamespace: defaultSystemNamespace, connectionStrategy: EtcdConnectionProxyViaPod}
 
 	ecg.createClient = func(ctx context.Context, endpoints []string) (*etcd.Client, error) {
-		p := proxy.Proxy{
-			Kind:       "pods",
-			Namespace:  metav1.NamespaceSystem,
-			KubeConfig: ecg.restConfig,
-			TLSConfig:  ecg.tlsConfig,
-			Port:       2379,
+		// Make sure the client certificate does not expire while in use, e.g. during long running
+		// control plane operations holding the same workload cluster for several etcd operations.
+		tlsConfig, err := ecg.getTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig, verifier, err := ecg.tlsConfigForEndpoints(tlsConfig, endpoints)
+		if err != nil {
+			return nil, err
 		}
-		return etcd.NewClient(ctx, etcd.ClientConfiguration{
+
+		client, err := etcd.NewClient(ctx, etcd.ClientConfiguration{
 			Endpoints:   endpoints,
-			Proxy:       p,
+			Proxy:       ecg.proxy(tlsConfig),
 			TLSConfig:   tlsConfig,
 			DialTimeout: etcdDialTimeout,
 		})
+		if err != nil {
+			if dialAddressErr := verifier.dialAddressError(); dialAddressErr != nil {
+				return nil, dialAddressErr
+			}
+		}
+		return client, err
 	}
 
 	return ecg
 }
 
+// proxy returns the proxy configuration used for connecting to the etcd pods;
+// it is nil when connecting directly to the nodes.
+func (c *EtcdClientGenerator) proxy(tlsConfig *tls.Config) *proxy.Proxy {
+	if c.dialsDirectly() {
+		return nil
+	}
+	return &proxy.Proxy{
+		Kind:       "pods",
+		Namespace:  c.etcdNamespace,
+		KubeConfig: c.restConfig,
+		TLSConfig:  tlsConfig,
+		Port:       etcdClientPort,
+	}
+}
+
+// dialsDirectly reports if the etcd members are dialed directly instead of port-forwarding into the etcd pods.
+func (c *EtcdClientGenerator) dialsDirectly() bool {
+	return c.nodeAddressResolver != nil || c.connectionStrategy == EtcdConnectionDirectToNode || c.connectionStrategy == EtcdConnectionDirectToEndpoints
+}
+
+// endpointForNode returns the etcd endpoint for the etcd member hosted on a node.
+func (c *EtcdClientGenerator) endpointForNode(ctx context.Context, nodeName string) (string, error) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+	if c.getNode != nil && (c.nodeAddressResolver != nil || c.connectionStrategy == EtcdConnectionDirectToNode) {
+		var err error
+		if node, err = c.getNode(ctx, nodeName); err != nil {
+			return "", errors.Wrapf(err, "failed to get node %s", nodeName)
+		}
+	}
+
+	host, port, err := c.resolveNodeAddress(*node)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the address of node %s", nodeName)
+	}
+	if !c.dialsDirectly() {
+		// The etcd pod is dialed by name via the proxy.
+		return host, nil
+	}
+	return "https://" + net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// resolveNodeAddress returns the host and port to dial for the etcd member hosted on a node, using the
+// nodeAddressResolver if set, or else the connection strategy.
+func (c *EtcdClientGenerator) resolveNodeAddress(node corev1.Node) (string, int, error) {
+	if c.nodeAddressResolver != nil {
+		return c.nodeAddressResolver(node)
+	}
+	switch c.connectionStrategy {
+	case EtcdConnectionDirectToEndpoints:
+		return c.configuredEndpointForNode(node.Name)
+	case EtcdConnectionDirectToNode:
+		address, err := internalIPAddress(node)
+		if err != nil {
+			return "", 0, err
+		}
+		return address, etcdClientPort, nil
+	default:
+		return staticPodName("etcd", node.Name), etcdClientPort, nil
+	}
+}
+
+// configuredEndpointForNode returns the host and port of the configured etcd endpoint whose DNS name has the node
+// name as a first label.
+func (c *EtcdClientGenerator) configuredEndpointForNode(nodeName string) (string, int, error) {
+	for _, endpoint := range c.endpoints {
+		host, port := endpoint, strconv.Itoa(etcdClientPort)
+		if h, p, err := net.SplitHostPort(endpoint); err == nil {
+			host, port = h, p
+		}
+		if strings.SplitN(host, ".", 2)[0] != nodeName {
+			continue
+		}
+		portNumber, err := strconv.Atoi(port)
+		if err != nil {
+			return "", 0, errors.Wrapf(err, "invalid port in etcd endpoint %q", endpoint)
+		}
+		return host, portNumber, nil
+	}
+	return "", 0, errors.Errorf("unable to connect directly to etcd: no endpoint configured for node %s", nodeName)
+}
+
+// internalIPAddress returns the InternalIP address of a node.
+func internalIPAddress(node corev1.Node) (string, error) {
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP && address.Address != "" {
+			return address.Address, nil
+		}
+	}
+	return "", errors.Errorf("node %s has no InternalIP address", node.Name)
+}
+
+// etcdProxyDialHost is the host the etcd members are dialed at when port-forwarding into the etcd pods.
+const etcdProxyDialHost = "127.0.0.1"
+
+// tlsConfigForEndpoints returns a copy of the TLS config which verifies the etcd server certificate against the host
+// being dialed for the endpoint, i.e. the host of the endpoint when dialing directly, or else the host the etcd pods
+// are dialed at via port-forwarding, together with the verifier keeping track of the verification errors.
+func (c *EtcdClientGenerator) tlsConfigForEndpoints(tlsConfig *tls.Config, endpoints []string) (*tls.Config, *servingCertVerifier, error) {
+	if len(endpoints) != 1 {
+		return nil, nil, errors.Errorf("invalid argument: exactly one etcd endpoint is expected, got %d", len(endpoints))
+	}
+
+	dialHost := etcdProxyDialHost
+	if c.dialsDirectly() {
+		u, err := url.Parse(endpoints[0])
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to parse etcd endpoint %q", endpoints[0])
+		}
+		dialHost = u.Hostname()
+	}
+
+	verifier := &servingCertVerifier{}
+	return verifier.wrap(tlsConfig, dialHost), verifier, nil
+}
+
+// servingCertVerifier verifies the etcd serving certificate during the TLS handshakes of an etcd client, keeping track
+// of the last verification error. This allows to report SAN mismatches explicitly, given that the etcd client retries
+// failing TLS handshakes until the dial timeout expires, and then fails with a generic timeout error.
+type servingCertVerifier struct {
+	lock       sync.Mutex
+	serverName string
+	err        error
+}
+
+// wrap returns a copy of the TLS config which verifies the serving certificate against the server name with the verifier.
+// NOTE: The TLS config is copied, given that it is shared by clients connecting to different endpoints.
+func (v *servingCertVerifier) wrap(tlsConfig *tls.Config, serverName string) *tls.Config {
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.ServerName = serverName
+	v.serverName = serverName
+	rootCAs := tlsConfig.RootCAs
+
+	// NOTE: The standard verification is replaced by an equivalent one, given that VerifyConnection is not
+	// invoked when the standard verification fails.
+	tlsConfig.InsecureSkipVerify = true //nolint:gosec
+	tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		err := verifyServingCert(cs, rootCAs, serverName)
+
+		v.lock.Lock()
+		defer v.lock.Unlock()
+		v.err = err
+		return err
+	}
+	return tlsConfig
+}
+
+// dialAddressError returns an error if the last verified serving certificate did not cover the host being dialed.
+func (v *servingCertVerifier) dialAddressError() error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	var hostnameErr x509.HostnameError
+	if errors.As(v.err, &hostnameErr) {
+		return errors.Wrapf(v.err, "etcd serving cert does not cover dial address %s", v.serverName)
+	}
+	return nil
+}
+
+// verifyServingCert verifies the certificate chain presented by the server against the root CAs and the server name,
+// like the standard verification of crypto/tls.
+func verifyServingCert(cs tls.ConnectionState, rootCAs *x509.CertPool, serverName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("etcd did not present a serving cert")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         rootCAs,
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// getTLSConfig returns the TLS config to be used for connecting to etcd, regenerating the client certificate
+// if it is near expiry.
+func (c *EtcdClientGenerator) getTLSConfig() (*tls.Config, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.generateClientCert == nil || len(c.tlsConfig.Certificates) == 0 {
+		return c.tlsConfig, nil
+	}
+
+	notAfter, err := certificateNotAfter(c.tlsConfig.Certificates[0])
+	if err != nil {
+		return nil, err
+	}
+	if time.Until(notAfter) > etcdClientCertRenewalThreshold {
+		return c.tlsConfig, nil
+	}
+
+	if err := c.refreshClientCertLocked(); err != nil {
+		return nil, err
+	}
+	return c.tlsConfig, nil
+}
+
+// refreshClientCert regenerates the etcd client certificate, if possible.
+func (c *EtcdClientGenerator) refreshClientCert() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.refreshClientCertLocked(); err != nil {
+		return err
+	}
+
+	// Pooled clients are still using the previous certificate, so they are closed and re-created on next use.
+	if c.pool != nil {
+		return c.pool.Close()
+	}
+	return nil
+}
+
+func (c *EtcdClientGenerator) refreshClientCertLocked() error {
+	if c.generateClientCert == nil {
+		return errors.New("etcd client certificate can't be regenerated")
+	}
+
+	clientCert, err := c.generateClientCert()
+	if err != nil {
+		return errors.Wrap(err, "failed to regenerate etcd client certificate")
+	}
+
+	// NOTE: The TLS config is replaced instead of modified, given that it could be still in use by existing clients.
+	tlsConfig := c.tlsConfig.Clone()
+	tlsConfig.Certificates = []tls.Certificate{clientCert}
+	c.tlsConfig = tlsConfig
+	return nil
+}
+
+// certificateNotAfter returns the expiry time of a tls.Certificate.
+func certificateNotAfter(cert tls.Certificate) (time.Time, error) {
+	if cert.Leaf != nil {
+		return cert.Leaf.NotAfter, nil
+	}
+	if len(cert.Certificate) == 0 {
+		return time.Time{}, errors.New("etcd client certificate is empty")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to parse etcd client certificate")
+	}
+	return leaf.NotAfter, nil
+}
+
 // forFirstAvailableNode takes a list of nodes and returns a client for the first one that connects.
+// NOTE: Callers are responsible for closing the returned client; pooled clients are not closed by this, but
+// they carry an up-to-date snapshot of the member status (e.g. leader, errors, database size) nevertheless.
 func (c *EtcdClientGenerator) forFirstAvailableNode(ctx context.Context, nodeNames []string) (*etcd.Client, error) {
 	// This is an additional safeguard for avoiding this func to return nil, nil.
 	if len(nodeNames) == 0 {
@@ -75,8 +395,12 @@ func (c *EtcdClientGenerator) forFirstAvailableNode(ctx context.Context, nodeNam
 	// Loop through the existing control plane nodes.
 	var errs []error
 	for _, name := range nodeNames {
-		endpoints := []string{staticPodName("etcd", name)}
-		client, err := c.createClient(ctx, endpoints)
+		endpoint, err := c.endpointForNode(ctx, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		client, err := c.getClient(ctx, endpoint)
 		if err != nil {
 			errs = append(errs, err)
 			continue
@@ -86,6 +410,16 @@ func (c *EtcdClientGenerator) forFirstAvailableNode(ctx context.Context, nodeNam
 	return nil, errors.Wrap(kerrors.NewAggregate(errs), "could not establish a connection to any etcd node")
 }
 
+// getClient returns a client for the etcd endpoint, reusing the pooled one if any.
+func (c *EtcdClientGenerator) getClient(ctx context.Context, endpoint string) (*etcd.Client, error) {
+	if c.pool == nil {
+		return c.createClient(ctx, []string{endpoint})
+	}
+	return c.pool.Get(ctx, endpoint, func(ctx context.Context) (*etcd.Client, error) {
+		return c.createClient(ctx, []string{endpoint})
+	})
+}
+
 // forLeader takes a list of nodes and returns a client to the leader node.
 func (c *EtcdClientGenerator) forLeader(ctx context.Context, nodeNames []string) (*etcd.Client, error) {
 	// This is an additional safeguard for avoiding this func to return nil, nil.
@@ -18,18 +18,25 @@ package internal
 
 import (
 	"context"
+	"crypto/rsa"
 	"crypto/tls"
+	"crypto/x509"
+	"net"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/api/v3/etcdserverpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
 
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd"
 	etcdfake "sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd/fake"
+	"sigs.k8s.io/cluster-api/util/certs"
 )
 
 var (
@@ -42,6 +49,331 @@ func TestNewEtcdClientGenerator(t *testing.T) {
 	g.Expect(subject.createClient).To(Not(BeNil()))
 }
 
+func TestEtcdClientGeneratorProxy(t *testing.T) {
+	g := NewWithT(t)
+	subject = NewEtcdClientGenerator(&rest.Config{}, &tls.Config{MinVersion: tls.VersionTLS12}, 0)
+	g.Expect(subject.proxy(subject.tlsConfig).Namespace).To(Equal(metav1.NamespaceSystem))
+
+	subject.etcdNamespace = "etcd-system"
+	g.Expect(subject.proxy(subject.tlsConfig).Namespace).To(Equal("etcd-system"))
+
+	subject.connectionStrategy = EtcdConnectionDirectToNode
+	g.Expect(subject.proxy(subject.tlsConfig)).To(BeNil())
+
+	subject.connectionStrategy = EtcdConnectionDirectToEndpoints
+	g.Expect(subject.proxy(subject.tlsConfig)).To(BeNil())
+
+	subject.connectionStrategy = EtcdConnectionProxyViaPod
+	subject.nodeAddressResolver = func(node corev1.Node) (string, int, error) { return node.Name, 2379, nil }
+	g.Expect(subject.proxy(subject.tlsConfig)).To(BeNil())
+}
+
+func TestEtcdClientGeneratorEndpointForNode(t *testing.T) {
+	getNode := func(ctx context.Context, nodeName string) (*corev1.Node, error) {
+		if nodeName != "node-1" {
+			return nil, errors.New("node not found")
+		}
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Status: corev1.NodeStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+			},
+		}, nil
+	}
+	customResolver := func(node corev1.Node) (string, int, error) {
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeInternalIP {
+				return address.Address, 12379, nil
+			}
+		}
+		return "", 0, errors.New("no address")
+	}
+
+	tests := []struct {
+		name               string
+		connectionStrategy EtcdConnectionStrategy
+		getNode            func(ctx context.Context, nodeName string) (*corev1.Node, error)
+		resolver           func(node corev1.Node) (string, int, error)
+		endpoints          []string
+		nodeName           string
+
+		expectedErr      bool
+		expectedEndpoint string
+	}{
+		{
+			name:               "Returns the etcd pod name when proxying via pod",
+			connectionStrategy: EtcdConnectionProxyViaPod,
+			nodeName:           "node-1",
+			expectedEndpoint:   "etcd-node-1",
+		},
+		{
+			name:               "Returns the node address when connecting directly to the node",
+			connectionStrategy: EtcdConnectionDirectToNode,
+			getNode:            getNode,
+			nodeName:           "node-1",
+			expectedEndpoint:   "https://10.0.0.1:2379",
+		},
+		{
+			name:               "Fails when the node address can't be resolved",
+			connectionStrategy: EtcdConnectionDirectToNode,
+			getNode:            getNode,
+			nodeName:           "node-2",
+			expectedErr:        true,
+		},
+		{
+			name:               "Fails when connecting directly to the node without a way to get nodes",
+			connectionStrategy: EtcdConnectionDirectToNode,
+			nodeName:           "node-1",
+			expectedErr:        true,
+		},
+		{
+			name:               "Returns the configured endpoint for the node when connecting directly to endpoints",
+			connectionStrategy: EtcdConnectionDirectToEndpoints,
+			endpoints:          []string{"node-1.etcd.example.com", "node-2.etcd.example.com"},
+			nodeName:           "node-2",
+			expectedEndpoint:   "https://node-2.etcd.example.com:2379",
+		},
+		{
+			name:               "Returns the configured endpoint with its port when connecting directly to endpoints",
+			connectionStrategy: EtcdConnectionDirectToEndpoints,
+			endpoints:          []string{"node-1.etcd.example.com:12379"},
+			nodeName:           "node-1",
+			expectedEndpoint:   "https://node-1.etcd.example.com:12379",
+		},
+		{
+			name:               "Fails when no endpoint is configured for the node",
+			connectionStrategy: EtcdConnectionDirectToEndpoints,
+			endpoints:          []string{"node-10.etcd.example.com"},
+			nodeName:           "node-1",
+			expectedErr:        true,
+		},
+		{
+			name:               "Returns the address resolved by a custom resolver, regardless of the connection strategy",
+			connectionStrategy: EtcdConnectionProxyViaPod,
+			getNode:            getNode,
+			resolver:           customResolver,
+			nodeName:           "node-1",
+			expectedEndpoint:   "https://10.0.0.1:12379",
+		},
+		{
+			name:               "Fails when the node to be passed to a custom resolver can't be read",
+			connectionStrategy: EtcdConnectionProxyViaPod,
+			getNode:            getNode,
+			resolver:           customResolver,
+			nodeName:           "node-2",
+			expectedErr:        true,
+		},
+		{
+			name:               "Fails when a custom resolver fails",
+			connectionStrategy: EtcdConnectionDirectToNode,
+			resolver:           customResolver,
+			nodeName:           "node-1",
+			expectedErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			subject = NewEtcdClientGenerator(&rest.Config{}, &tls.Config{MinVersion: tls.VersionTLS12}, 0)
+			subject.connectionStrategy = tt.connectionStrategy
+			subject.getNode = tt.getNode
+			subject.nodeAddressResolver = tt.resolver
+			subject.endpoints = tt.endpoints
+
+			endpoint, err := subject.endpointForNode(ctx, tt.nodeName)
+
+			if tt.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(endpoint).To(Equal(tt.expectedEndpoint))
+		})
+	}
+}
+
+func TestTLSConfigForEndpoints(t *testing.T) {
+	tests := []struct {
+		name               string
+		connectionStrategy EtcdConnectionStrategy
+		endpoints          []string
+		expectedServerName string
+		expectedErr        bool
+	}{
+		{
+			name:               "verifies the serving cert against the host the etcd pods are dialed at via port-forwarding",
+			connectionStrategy: EtcdConnectionProxyViaPod,
+			endpoints:          []string{"etcd-node-1"},
+			expectedServerName: "127.0.0.1",
+		},
+		{
+			name:               "verifies the serving cert against the node address when dialing nodes",
+			connectionStrategy: EtcdConnectionDirectToNode,
+			endpoints:          []string{"https://10.0.0.1:2379"},
+			expectedServerName: "10.0.0.1",
+		},
+		{
+			name:               "verifies the serving cert against the DNS name when dialing endpoints",
+			connectionStrategy: EtcdConnectionDirectToEndpoints,
+			endpoints:          []string{"https://node-1.etcd.example.com:2379"},
+			expectedServerName: "node-1.etcd.example.com",
+		},
+		{
+			name:               "fails with more than one endpoint",
+			connectionStrategy: EtcdConnectionDirectToEndpoints,
+			endpoints:          []string{"https://node-1.etcd.example.com:2379", "https://node-2.etcd.example.com:2379"},
+			expectedErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+			subject = NewEtcdClientGenerator(&rest.Config{}, tlsConfig, 0)
+			subject.connectionStrategy = tt.connectionStrategy
+
+			endpointTLSConfig, verifier, err := subject.tlsConfigForEndpoints(tlsConfig, tt.endpoints)
+			if tt.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(verifier).ToNot(BeNil())
+			g.Expect(endpointTLSConfig.ServerName).To(Equal(tt.expectedServerName))
+			g.Expect(endpointTLSConfig.VerifyConnection).ToNot(BeNil())
+
+			// The shared TLS config is not modified.
+			g.Expect(tlsConfig.ServerName).To(BeEmpty())
+			g.Expect(tlsConfig.VerifyConnection).To(BeNil())
+		})
+	}
+}
+
+func TestEtcdClientGeneratorReportsServingCertNotCoveringDialAddress(t *testing.T) {
+	caKey, err := certs.NewPrivateKey()
+	NewWithT(t).Expect(err).ToNot(HaveOccurred())
+	caCert, err := getTestCACert(caKey)
+	NewWithT(t).Expect(err).ToNot(HaveOccurred())
+	caPool := x509.NewCertPool()
+	caPool.AddCert(caCert)
+
+	tests := []struct {
+		name        string
+		altNames    certs.AltNames
+		expectedErr string
+	}{
+		{
+			name:     "serving cert covering the dial address",
+			altNames: certs.AltNames{DNSNames: []string{"localhost"}, IPs: []net.IP{net.ParseIP("127.0.0.1")}},
+		},
+		{
+			name:        "serving cert not covering the dial address",
+			altNames:    certs.AltNames{DNSNames: []string{"node-1.etcd.example.com"}},
+			expectedErr: "etcd serving cert does not cover dial address 127.0.0.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			listener := newTestEtcdServingListener(g, caCert, caKey, tt.altNames)
+			defer listener.Close()
+
+			// NOTE: The listener is not serving etcd, so connecting fails anyway after the TLS handshake.
+			subject = NewEtcdClientGenerator(&rest.Config{}, &tls.Config{RootCAs: caPool, MinVersion: tls.VersionTLS12}, time.Second)
+			subject.connectionStrategy = EtcdConnectionDirectToEndpoints
+			_, err = subject.createClient(ctx, []string{"https://" + listener.Addr().String()})
+			g.Expect(err).To(HaveOccurred())
+			if tt.expectedErr == "" {
+				g.Expect(err.Error()).ToNot(ContainSubstring("etcd serving cert does not cover dial address"))
+				return
+			}
+			g.Expect(err.Error()).To(ContainSubstring(tt.expectedErr))
+		})
+	}
+}
+
+func TestEtcdClientGeneratorReportsServingCertNotCoveringProxyDialAddress(t *testing.T) {
+	caKey, err := certs.NewPrivateKey()
+	NewWithT(t).Expect(err).ToNot(HaveOccurred())
+	caCert, err := getTestCACert(caKey)
+	NewWithT(t).Expect(err).ToNot(HaveOccurred())
+	caPool := x509.NewCertPool()
+	caPool.AddCert(caCert)
+
+	tests := []struct {
+		name        string
+		altNames    certs.AltNames
+		expectedErr string
+	}{
+		{
+			name:     "serving cert covering 127.0.0.1",
+			altNames: certs.AltNames{DNSNames: []string{"localhost"}, IPs: []net.IP{net.ParseIP("127.0.0.1")}},
+		},
+		{
+			name:        "serving cert not covering 127.0.0.1",
+			altNames:    certs.AltNames{DNSNames: []string{"localhost", "node-1"}},
+			expectedErr: "etcd serving cert does not cover dial address 127.0.0.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			listener := newTestEtcdServingListener(g, caCert, caKey, tt.altNames)
+			defer listener.Close()
+
+			subject = NewEtcdClientGenerator(&rest.Config{}, &tls.Config{RootCAs: caPool, MinVersion: tls.VersionTLS12}, time.Second)
+			tlsConfig, verifier, err := subject.tlsConfigForEndpoints(subject.tlsConfig, []string{staticPodName("etcd", "node-1")})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			// NOTE: The listener stands for the etcd pod, which is reached at 127.0.0.1 when port-forwarding into it.
+			conn, err := tls.Dial("tcp", listener.Addr().String(), tlsConfig)
+			if tt.expectedErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(conn.Close()).To(Succeed())
+				g.Expect(verifier.dialAddressError()).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(verifier.dialAddressError()).To(MatchError(ContainSubstring(tt.expectedErr)))
+		})
+	}
+}
+
+// newTestEtcdServingListener returns a TLS listener on 127.0.0.1 presenting a serving cert signed by the CA
+// with the given alt names; it completes the TLS handshake of each connection, then closes it.
+func newTestEtcdServingListener(g *WithT, caCert *x509.Certificate, caKey *rsa.PrivateKey, altNames certs.AltNames) net.Listener {
+	key, err := certs.NewPrivateKey()
+	g.Expect(err).ToNot(HaveOccurred())
+	cfg := certs.Config{
+		CommonName: "etcd",
+		AltNames:   altNames,
+		Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	cert, err := cfg.NewSignedCert(key, caCert, caKey)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+	return listener
+}
+
 func TestFirstAvailableNode(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -105,6 +437,34 @@ func TestFirstAvailableNode(t *testing.T) {
 	}
 }
 
+func TestFirstAvailableNodeReusesPooledClient(t *testing.T) {
+	g := NewWithT(t)
+
+	fakeClient := &etcdfake.FakeEtcdClient{
+		EtcdEndpoints:  []string{"etcd-node-1"},
+		StatusResponse: &clientv3.StatusResponse{},
+	}
+	created := 0
+	subject = NewEtcdClientGenerator(&rest.Config{}, &tls.Config{MinVersion: tls.VersionTLS12}, 0)
+	subject.pool = etcd.NewClientPool()
+	subject.createClient = func(ctx context.Context, endpoints []string) (*etcd.Client, error) {
+		created++
+		return &etcd.Client{EtcdClient: fakeClient, Endpoint: endpoints[0]}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		client, err := subject.forFirstAvailableNode(ctx, []string{"node-1"})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(client.Endpoint).To(Equal("etcd-node-1"))
+		g.Expect(client.Close()).To(Succeed())
+	}
+	g.Expect(created).To(Equal(1))
+	g.Expect(fakeClient.Closed).To(BeFalse())
+
+	g.Expect(subject.pool.Close()).To(Succeed())
+	g.Expect(fakeClient.Closed).To(BeTrue())
+}
+
 func TestForLeader(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -226,3 +586,60 @@ func TestForLeader(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTLSConfig(t *testing.T) {
+	certExpiringIn := func(d time.Duration) tls.Certificate {
+		return tls.Certificate{Leaf: &x509.Certificate{NotAfter: time.Now().Add(d)}}
+	}
+	regeneratedCert := certExpiringIn(time.Hour)
+
+	tests := []struct {
+		name               string
+		cert               tls.Certificate
+		generateClientCert func() (tls.Certificate, error)
+		expectedCert       tls.Certificate
+		expectErr          bool
+	}{
+		{
+			name:               "Returns the existing client certificate if it is not near expiry",
+			cert:               certExpiringIn(time.Hour),
+			generateClientCert: func() (tls.Certificate, error) { return regeneratedCert, nil },
+			expectedCert:       certExpiringIn(time.Hour),
+		},
+		{
+			name:               "Regenerates the client certificate if it is near expiry",
+			cert:               certExpiringIn(time.Minute),
+			generateClientCert: func() (tls.Certificate, error) { return regeneratedCert, nil },
+			expectedCert:       regeneratedCert,
+		},
+		{
+			name:         "Returns the existing client certificate if it can't be regenerated",
+			cert:         certExpiringIn(time.Minute),
+			expectedCert: certExpiringIn(time.Minute),
+		},
+		{
+			name:               "Returns error if regenerating the client certificate fails",
+			cert:               certExpiringIn(time.Minute),
+			generateClientCert: func() (tls.Certificate, error) { return tls.Certificate{}, errors.New("failed") },
+			expectErr:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			subject = NewEtcdClientGenerator(&rest.Config{}, &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{tt.cert}}, 0)
+			subject.generateClientCert = tt.generateClientCert
+
+			tlsConfig, err := subject.getTLSConfig()
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tlsConfig.Certificates).To(HaveLen(1))
+			g.Expect(tlsConfig.Certificates[0].Leaf.NotAfter).To(BeTemporally("~", tt.expectedCert.Leaf.NotAfter, time.Second))
+		})
+	}
+}
@@ -115,6 +115,24 @@ func MatchesKubeadmBootstrapConfig(machineConfigs map[string]*bootstrapv1.Kubead
 	}
 }
 
+// configurationHashLabelKey is the label which was used by KubeadmControlPlane v1alpha3 to record the hash of the
+// configuration used to generate a control plane machine.
+const configurationHashLabelKey = "kubeadm.controlplane.cluster.x-k8s.io/hash"
+
+// MissingConfigurationHash returns a filter to find all machines without the configuration hash label.
+// NOTE: Machines created by KCP have either the configuration hash label or the KubeadmClusterConfigurationAnnotation;
+// machines missing both are adopted or manually created, and this filter allows reporting them separately from
+// machines that are actually outdated.
+func MissingConfigurationHash() collections.Func {
+	return func(machine *clusterv1.Machine) bool {
+		if machine == nil {
+			return false
+		}
+		_, ok := machine.GetLabels()[configurationHashLabelKey]
+		return !ok
+	}
+}
+
 // matchClusterConfiguration verifies if KCP and machine ClusterConfiguration matches.
 // NOTE: Machines that have KubeadmClusterConfigurationAnnotation will have to match with KCP ClusterConfiguration.
 // If the annotation is not present (machine is either old or adopted), we won't roll out on any possible changes
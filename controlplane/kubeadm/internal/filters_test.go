@@ -106,6 +106,40 @@ func TestMatchClusterConfiguration(t *testing.T) {
 	})
 }
 
+func TestMissingConfigurationHash(t *testing.T) {
+	t.Run("returns true if the machine does not have the configuration hash label", func(t *testing.T) {
+		g := NewWithT(t)
+		m := &clusterv1.Machine{}
+		g.Expect(MissingConfigurationHash()(m)).To(BeTrue())
+	})
+	t.Run("returns true if the machine has other labels only", func(t *testing.T) {
+		g := NewWithT(t)
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					"foo": "bar",
+				},
+			},
+		}
+		g.Expect(MissingConfigurationHash()(m)).To(BeTrue())
+	})
+	t.Run("returns false if the machine has the configuration hash label, even if empty", func(t *testing.T) {
+		g := NewWithT(t)
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					configurationHashLabelKey: "",
+				},
+			},
+		}
+		g.Expect(MissingConfigurationHash()(m)).To(BeFalse())
+	})
+	t.Run("returns false if the machine is nil", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(MissingConfigurationHash()(nil)).To(BeFalse())
+	})
+}
+
 func TestGetAdjustedKcpConfig(t *testing.T) {
 	t.Run("if the machine is the first control plane, kcp config should get InitConfiguration", func(t *testing.T) {
 		g := NewWithT(t)
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	etcdHealthCheckDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "etcd_health_check_duration_seconds",
+			Help:    "Duration of the etcd health checks of the workload clusters, including the checks which failed.",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+		},
+		[]string{"cluster"},
+	)
+
+	etcdDialDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "etcd_dial_duration_seconds",
+			Help:    "Duration of connecting to the etcd member hosted on a control plane node while checking the etcd health, including the attempts which failed.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 10),
+		},
+		[]string{"cluster", "node"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(etcdHealthCheckDuration, etcdDialDuration)
+}
+
+// clusterMetricLabel returns the value of the cluster label of the metrics, in the namespace/name format
+// given that names are unique only within a namespace.
+func clusterMetricLabel(clusterKey client.ObjectKey) string {
+	return clusterKey.Namespace + "/" + clusterKey.Name
+}
+
+// observeEtcdHealthCheckDuration records the duration of an etcd health check started at the given time.
+func observeEtcdHealthCheckDuration(clusterKey client.ObjectKey, start time.Time) {
+	etcdHealthCheckDuration.WithLabelValues(clusterMetricLabel(clusterKey)).Observe(time.Since(start).Seconds())
+}
+
+// observeEtcdDialDuration records the duration of connecting to the etcd member hosted on a node, started at the given time.
+func observeEtcdDialDuration(clusterKey client.ObjectKey, nodeName string, start time.Time) {
+	etcdDialDuration.WithLabelValues(clusterMetricLabel(clusterKey), nodeName).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+)
+
+func TestEtcdHealthCheckDurationMetric(t *testing.T) {
+	g := NewWithT(t)
+
+	clusterKey := client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "etcd-health-check-duration"}
+	kcp := &controlplanev1.KubeadmControlPlane{ObjectMeta: metav1.ObjectMeta{Namespace: clusterKey.Namespace, Name: "kcp"}}
+	m := &Management{Client: fake.NewClientBuilder().Build()}
+
+	// The health check fails, given that there is no client to the workload cluster, but its duration is observed anyway.
+	series := testutil.CollectAndCount(etcdHealthCheckDuration)
+	g.Expect(m.EtcdIsHealthy(ctx, kcp, clusterKey)).ToNot(Succeed())
+	g.Expect(testutil.CollectAndCount(etcdHealthCheckDuration)).To(Equal(series + 1))
+}
+
+func TestEtcdDialDurationMetric(t *testing.T) {
+	g := NewWithT(t)
+
+	clusterKey := client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "etcd-dial-duration"}
+	w := &Workload{
+		etcdClientGenerator: &fakeEtcdClientGenerator{forNodesErr: errors.New("failed to dial")},
+		clusterKey:          clusterKey,
+	}
+	machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Namespace: clusterKey.Namespace, Name: "machine"}}
+
+	// Connecting to the etcd member fails, but the dial duration is observed anyway.
+	series := testutil.CollectAndCount(etcdDialDuration)
+	_, _, _, err := w.tryGetCurrentEtcdMembers(ctx, machine, "node1", 0)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(testutil.CollectAndCount(etcdDialDuration)).To(Equal(series + 1))
+}
@@ -60,9 +60,16 @@ const (
 	labelNodeRoleControlPlane    = "node-role.kubernetes.io/control-plane"
 	clusterStatusKey             = "ClusterStatus"
 	clusterConfigurationKey      = "ClusterConfiguration"
+
+	// defaultSystemNamespace is the namespace where the control plane components and the kubeadm, kubelet,
+	// kube-proxy and CoreDNS objects live in the workload clusters, by Kubernetes convention.
+	defaultSystemNamespace = metav1.NamespaceSystem
 )
 
 var (
+	// defaultControlPlaneNodeLabels are the labels used by default to identify the control plane nodes.
+	defaultControlPlaneNodeLabels = []string{labelNodeRoleOldControlPlane, labelNodeRoleControlPlane}
+
 	// Starting from v1.22.0 kubeadm dropped the usage of the ClusterStatus entry from the kubeadm-config ConfigMap
 	// so we're not anymore required to remove API endpoints for control plane nodes after deletion.
 	//
@@ -84,6 +91,14 @@ var (
 	// ErrControlPlaneMinNodes signals that a cluster doesn't meet the minimum required nodes
 	// to remove an etcd member.
 	ErrControlPlaneMinNodes = errors.New("cluster has fewer than 2 control plane nodes; removing an etcd member is not supported")
+
+	// ErrEtcdQuorumWouldBeLost signals that removing an etcd member would leave the etcd cluster without
+	// a majority of healthy members.
+	ErrEtcdQuorumWouldBeLost = errors.New("removing the etcd member would result in etcd losing quorum")
+
+	// ErrEtcdMemberPromotionPending signals that an etcd member has been re-added as a learner, and it is not
+	// yet in sync with the leader for being promoted to voting member.
+	ErrEtcdMemberPromotionPending = errors.New("etcd learner member is not yet in sync with the leader")
 )
 
 // WorkloadCluster defines all behaviors necessary to upgrade kubernetes on a workload cluster
@@ -95,6 +110,8 @@ type WorkloadCluster interface {
 	UpdateStaticPodConditions(ctx context.Context, controlPlane *ControlPlane)
 	UpdateEtcdConditions(ctx context.Context, controlPlane *ControlPlane)
 	EtcdMembers(ctx context.Context) ([]string, error)
+	EtcdQuorumIsHealthy(ctx context.Context) error
+	EtcdState(ctx context.Context) (*EtcdState, error)
 
 	// Upgrade related tasks.
 	ReconcileKubeletRBACBinding(ctx context.Context, version semver.Version) error
@@ -113,6 +130,9 @@ type WorkloadCluster interface {
 	RemoveMachineFromKubeadmConfigMap(ctx context.Context, machine *clusterv1.Machine, version semver.Version) error
 	RemoveNodeFromKubeadmConfigMap(ctx context.Context, nodeName string, version semver.Version) error
 	ForwardEtcdLeadership(ctx context.Context, machine *clusterv1.Machine, leaderCandidate *clusterv1.Machine) error
+	SafelyRemoveEtcdMemberForMachine(ctx context.Context, machine *clusterv1.Machine) error
+	EnsureEtcdMemberForNode(ctx context.Context, nodeName string) error
+	RefreshEtcdClientBundle() error
 	AllowBootstrapTokensToGetNodes(ctx context.Context) error
 
 	// State recovery tasks.
@@ -124,15 +144,65 @@ type Workload struct {
 	Client              ctrlclient.Client
 	CoreDNSMigrator     coreDNSMigrator
 	etcdClientGenerator etcdClientFor
+
+	// etcdDBSizeWarningThreshold is the percentage of the etcd backend database quota above which
+	// a warning is reported for an etcd member; 0 disables the check.
+	etcdDBSizeWarningThreshold int
+
+	// etcdDialRetries is the number of times connecting to an etcd member and listing members is retried
+	// when checking the etcd member health, before reporting the member as unhealthy; 0 disables retries.
+	etcdDialRetries int
+
+	// etcdLearnerPromotionTimeout is how long an etcd member can be a learner before it is reported as unhealthy;
+	// 0 disables the check.
+	etcdLearnerPromotionTimeout time.Duration
+
+	// etcdMemberConsistencyMode defines how the etcd members are required to agree on the list of members;
+	// if not set, EtcdMemberConsistencyUnanimous is used.
+	etcdMemberConsistencyMode EtcdMemberConsistencyMode
+
+	// controlPlaneNodeLabels are the labels used to identify the control plane nodes; if not set,
+	// defaultControlPlaneNodeLabels are used.
+	controlPlaneNodeLabels []string
+
+	// systemNamespace is the namespace where the control plane components live; if not set,
+	// defaultSystemNamespace is used.
+	systemNamespace string
+
+	// clusterKey identifies the workload cluster, e.g. in metrics.
+	clusterKey ctrlclient.ObjectKey
+
+	// onEtcdMembersChanged, if set, is called when the etcd members are changed, e.g. to drop cached etcd health.
+	onEtcdMembersChanged func()
 }
 
 var _ WorkloadCluster = &Workload{}
 
+// etcdMembersChanged notifies that the etcd members have been changed, or at least attempted to.
+func (w *Workload) etcdMembersChanged() {
+	if w.onEtcdMembersChanged != nil {
+		w.onEtcdMembersChanged()
+	}
+}
+
+// namespace returns the namespace where the control plane components live in the workload cluster.
+func (w *Workload) namespace() string {
+	if w.systemNamespace == "" {
+		return defaultSystemNamespace
+	}
+	return w.systemNamespace
+}
+
 func (w *Workload) getControlPlaneNodes(ctx context.Context) (*corev1.NodeList, error) {
 	controlPlaneNodes := &corev1.NodeList{}
 	controlPlaneNodeNames := sets.NewString()
 
-	for _, label := range []string{labelNodeRoleOldControlPlane, labelNodeRoleControlPlane} {
+	labels := w.controlPlaneNodeLabels
+	if len(labels) == 0 {
+		labels = defaultControlPlaneNodeLabels
+	}
+
+	for _, label := range labels {
 		nodes := &corev1.NodeList{}
 		if err := w.Client.List(ctx, nodes, ctrlclient.MatchingLabels(map[string]string{
 			label: "",
@@ -186,7 +256,7 @@ func (w *Workload) UpdateKubernetesVersionInKubeadmConfigMap(ctx context.Context
 func (w *Workload) UpdateKubeletConfigMap(ctx context.Context, version semver.Version) error {
 	// Check if the desired configmap already exists
 	desiredKubeletConfigMapName := generateKubeletConfigName(version)
-	configMapKey := ctrlclient.ObjectKey{Name: desiredKubeletConfigMapName, Namespace: metav1.NamespaceSystem}
+	configMapKey := ctrlclient.ObjectKey{Name: desiredKubeletConfigMapName, Namespace: w.namespace()}
 	_, err := w.getConfigMap(ctx, configMapKey)
 	if err == nil {
 		// Nothing to do, the configmap already exists
@@ -204,7 +274,7 @@ func (w *Workload) UpdateKubeletConfigMap(ctx context.Context, version semver.Ve
 		return nil
 	}
 
-	configMapKey = ctrlclient.ObjectKey{Name: previousMinorVersionKubeletConfigMapName, Namespace: metav1.NamespaceSystem}
+	configMapKey = ctrlclient.ObjectKey{Name: previousMinorVersionKubeletConfigMapName, Namespace: w.namespace()}
 	// Returns a copy
 	cm, err := w.getConfigMap(ctx, configMapKey)
 	if apierrors.IsNotFound(errors.Cause(err)) {
@@ -309,7 +379,7 @@ func (w *Workload) RemoveNodeFromKubeadmConfigMap(ctx context.Context, name stri
 // kubeadm-config ConfigMap updated.
 func (w *Workload) updateClusterStatus(ctx context.Context, mutator func(status *bootstrapv1.ClusterStatus), version semver.Version) error {
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-		key := ctrlclient.ObjectKey{Name: kubeadmConfigKey, Namespace: metav1.NamespaceSystem}
+		key := ctrlclient.ObjectKey{Name: kubeadmConfigKey, Namespace: w.namespace()}
 		configMap, err := w.getConfigMap(ctx, key)
 		if err != nil {
 			return errors.Wrap(err, "failed to get kubeadmConfigMap")
@@ -348,7 +418,7 @@ func (w *Workload) updateClusterStatus(ctx context.Context, mutator func(status
 // kubeadm-config ConfigMap updated.
 func (w *Workload) updateClusterConfiguration(ctx context.Context, mutator func(*bootstrapv1.ClusterConfiguration), version semver.Version) error {
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-		key := ctrlclient.ObjectKey{Name: kubeadmConfigKey, Namespace: metav1.NamespaceSystem}
+		key := ctrlclient.ObjectKey{Name: kubeadmConfigKey, Namespace: w.namespace()}
 		configMap, err := w.getConfigMap(ctx, key)
 		if err != nil {
 			return errors.Wrap(err, "failed to get kubeadmConfigMap")
@@ -412,7 +482,7 @@ func (w *Workload) ClusterStatus(ctx context.Context) (ClusterStatus, error) {
 	// find the kubeadm conifg
 	key := ctrlclient.ObjectKey{
 		Name:      kubeadmConfigKey,
-		Namespace: metav1.NamespaceSystem,
+		Namespace: w.namespace(),
 	}
 	err = w.Client.Get(ctx, key, &corev1.ConfigMap{})
 	// TODO: Consider if this should only return false if the error is IsNotFound.
@@ -421,7 +491,11 @@ func (w *Workload) ClusterStatus(ctx context.Context) (ClusterStatus, error) {
 	return status, nil
 }
 
-func generateClientCert(caCertEncoded, caKeyEncoded []byte) (tls.Certificate, error) {
+// defaultEtcdClientCertCommonName is the CommonName of the etcd client certificate generated by the controllers
+// when no other CommonName is configured.
+const defaultEtcdClientCertCommonName = "cluster-api.x-k8s.io"
+
+func generateClientCert(caCertEncoded, caKeyEncoded []byte, subject pkix.Name) (tls.Certificate, error) {
 	privKey, err := certs.NewPrivateKey()
 	if err != nil {
 		return tls.Certificate{}, err
@@ -434,30 +508,23 @@ func generateClientCert(caCertEncoded, caKeyEncoded []byte) (tls.Certificate, er
 	if err != nil {
 		return tls.Certificate{}, err
 	}
-	x509Cert, err := newClientCert(caCert, privKey, caKey)
+	x509Cert, err := newClientCert(caCert, privKey, caKey, subject)
 	if err != nil {
 		return tls.Certificate{}, err
 	}
 	return tls.X509KeyPair(certs.EncodeCertPEM(x509Cert), certs.EncodePrivateKeyPEM(privKey))
 }
 
-func newClientCert(caCert *x509.Certificate, key *rsa.PrivateKey, caKey crypto.Signer) (*x509.Certificate, error) {
-	cfg := certs.Config{
-		CommonName: "cluster-api.x-k8s.io",
-	}
-
+func newClientCert(caCert *x509.Certificate, key *rsa.PrivateKey, caKey crypto.Signer, subject pkix.Name) (*x509.Certificate, error) {
 	now := time.Now().UTC()
 
 	tmpl := x509.Certificate{
 		SerialNumber: new(big.Int).SetInt64(0),
-		Subject: pkix.Name{
-			CommonName:   cfg.CommonName,
-			Organization: cfg.Organization,
-		},
-		NotBefore:   now.Add(time.Minute * -5),
-		NotAfter:    now.Add(time.Hour * 24 * 365 * 10), // 10 years
-		KeyUsage:    x509.KeyUsageDigitalSignature,
-		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		Subject:      subject,
+		NotBefore:    now.Add(time.Minute * -5),
+		NotAfter:     now.Add(time.Hour * 24 * 365 * 10), // 10 years
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 	}
 
 	b, err := x509.CreateCertificate(rand.Reader, &tmpl, caCert, key.Public(), caKey)
@@ -482,7 +549,7 @@ func (w *Workload) UpdateKubeProxyImageInfo(ctx context.Context, kcp *controlpla
 
 	ds := &appsv1.DaemonSet{}
 
-	if err := w.Client.Get(ctx, ctrlclient.ObjectKey{Name: kubeProxyKey, Namespace: metav1.NamespaceSystem}, ds); err != nil {
+	if err := w.Client.Get(ctx, ctrlclient.ObjectKey{Name: kubeProxyKey, Namespace: w.namespace()}, ds); err != nil {
 		if apierrors.IsNotFound(err) {
 			// if kube-proxy is missing, return without errors
 			return nil
@@ -19,7 +19,12 @@ package internal
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -36,6 +41,23 @@ import (
 	"sigs.k8s.io/cluster-api/util/conditions"
 )
 
+// EtcdMemberConsistencyMode defines how the etcd members are required to agree on the list of members
+// for being considered consistent.
+type EtcdMemberConsistencyMode string
+
+const (
+	// EtcdMemberConsistencyUnanimous requires all the etcd members to report the same list of members. This is the default.
+	EtcdMemberConsistencyUnanimous EtcdMemberConsistencyMode = "Unanimous"
+
+	// EtcdMemberConsistencyQuorum requires a quorum of the etcd members to report the same list of members; members
+	// reporting a different list, e.g. because they are lagging behind during a membership change, are tolerated.
+	EtcdMemberConsistencyQuorum EtcdMemberConsistencyMode = "Quorum"
+)
+
+// etcdDialRetryInterval is the base interval between retries when connecting to an etcd member for checking its health;
+// the interval grows linearly with the number of attempts.
+const etcdDialRetryInterval = 200 * time.Millisecond
+
 // UpdateEtcdConditions is responsible for updating machine conditions reflecting the status of all the etcd members.
 // This operation is best effort, in the sense that in case of problems in retrieving member status, it sets
 // the condition to Unknown state without returning any error.
@@ -68,14 +90,22 @@ func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane
 		return
 	}
 
+	quotaBackendBytes := etcdQuotaBackendBytes(controlPlane.KCP)
+
 	// Update conditions for etcd members on the nodes.
 	var (
 		// kcpErrors is used to store errors that can't be reported on any machine.
 		kcpErrors []string
-		// clusterID is used to store and compare the etcd's cluster id.
-		clusterID *uint64
-		// members is used to store the list of etcd members and compare with all the other nodes in the cluster.
-		members []*etcd.Member
+		// kcpWarnings is used to store warnings that should not affect the health of a single machine.
+		kcpWarnings []string
+		// perNodeMembers is used to store the list of etcd members as seen by the member hosted on each node.
+		perNodeMembers = map[string][]*etcd.Member{}
+		// machinesToCheck is used to store the machines hosting the etcd members to be checked, in the order of the nodes.
+		machinesToCheck []*clusterv1.Machine
+		// memberVersions is used to store the etcd version reported by the member hosted on each node.
+		memberVersions = map[string]string{}
+		// leaderIDs is used to store the ID of the etcd leader as seen by the member hosted on each node.
+		leaderIDs = map[string]uint64{}
 	)
 
 	for _, node := range controlPlaneNodes.Items {
@@ -103,24 +133,58 @@ func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane
 			continue
 		}
 
-		currentMembers, err := w.getCurrentEtcdMembers(ctx, machine, node.Name)
-		if err != nil {
+		// If the node has not been initialized by the cloud provider yet, there is no point in connecting to the etcd member;
+		// report it as unknown, given that this is a transient state and not a problem with the etcd member.
+		if node.Spec.ProviderID == "" {
+			conditions.MarkUnknown(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberNodeNotInitializedReason, "Waiting for the %s node to be initialized by the cloud provider", node.Name)
 			continue
 		}
 
-		// Check if the list of members IDs reported is the same as all other members.
-		// NOTE: the first member reporting this information is the baseline for this information.
-		if members == nil {
-			members = currentMembers
+		currentMembers, memberStatus, err := w.getCurrentEtcdMembers(ctx, machine, node.Name, quotaBackendBytes)
+		if err != nil {
+			continue
+		}
+		if memberStatus.dbSizeWarning != "" {
+			kcpWarnings = append(kcpWarnings, memberStatus.dbSizeWarning)
 		}
-		if !etcdutil.MemberEqual(members, currentMembers) {
-			conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "etcd member reports the cluster is composed by members %s, but all previously seen etcd members are reporting %s", etcdutil.MemberNames(currentMembers), etcdutil.MemberNames(members))
+		memberVersions[node.Name] = memberStatus.version
+		leaderIDs[node.Name] = memberStatus.leaderID
+		perNodeMembers[node.Name] = currentMembers
+		machinesToCheck = append(machinesToCheck, machine)
+	}
+
+	// Detect etcd members still named after the previous name of the node hosting them, e.g. while a node is being
+	// renamed; this is tolerated for a short period of time, so the rename doesn't immediately fail etcd health.
+	now := time.Now()
+	renamedMembers := renamedEtcdMembers(perNodeMembers, controlPlaneNodes, now)
+
+	// Check if the etcd members agree on the list of members and on the cluster they belong to.
+	consistencyErrors := evaluateEtcdConsistency(perNodeMembers, renamedMembers, w.etcdMemberConsistencyMode)
+
+	// The list of members and the leader reported by the baseline node are used as a reference for the etcd cluster.
+	var members []*etcd.Member
+	var leaderID uint64
+	if baseline := etcdBaselineNode(perNodeMembers, w.etcdMemberConsistencyMode); baseline != "" {
+		members = perNodeMembers[baseline]
+		leaderID = leaderIDs[baseline]
+	}
+
+	// Keep track of how long etcd members have been learners, so learners which are never promoted can be detected.
+	// NOTE: This is done only if the list of members is known, otherwise the learners tracked so far would be lost.
+	if members != nil {
+		controlPlane.KCP.Status.EtcdLearners = trackEtcdLearners(controlPlane.KCP.Status.EtcdLearners, members, now)
+	}
+
+	for _, machine := range machinesToCheck {
+		node := nodeForName(controlPlaneNodes, machine.Status.NodeRef.Name)
+		if err := consistencyErrors[node.Name]; err != nil {
+			conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "%s", err.Error())
 			continue
 		}
 
 		// Retrieve the member and check for alarms.
-		// NB. The member for this node always exists given forFirstAvailableNode(node) used above
-		member := etcdutil.MemberForName(currentMembers, node.Name)
+		// NB. The member for this node always exists, given that it is checked by evaluateEtcdConsistency.
+		member := etcdutil.MemberForName(perNodeMembers[node.Name], etcdMemberName(renamedMembers, node.Name))
 		if len(member.Alarms) > 0 {
 			alarmList := []string{}
 			for _, alarm := range member.Alarms {
@@ -137,47 +201,299 @@ func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane
 			}
 		}
 
-		// Check if the member belongs to the same cluster as all other members.
-		// NOTE: the first member reporting this information is the baseline for this information.
-		if clusterID == nil {
-			clusterID = &member.ClusterID
+		// Check if the member is a learner which has not been promoted to voting member for longer than expected,
+		// which usually means promotion keeps failing and the scale up is stuck.
+		if member.IsLearner {
+			if msg := stuckEtcdLearnerMessage(controlPlane.KCP.Status.EtcdLearners, member.Name, w.etcdLearnerPromotionTimeout, now); msg != "" {
+				conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "%s", msg)
+				continue
+			}
 		}
-		if *clusterID != member.ClusterID {
-			conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "etcd member has cluster ID %d, but all previously seen etcd members have cluster ID %d", member.ClusterID, *clusterID)
-			continue
+
+		// Check if the URLs advertised by the member are consistent with the addresses of the node hosting it,
+		// e.g. they are not stale after a change of the node IP; this is not detected by the checks above
+		// because the member ID doesn't change.
+		// NOTE: This is reported as a warning that does not affect the health of the machine, given that members can
+		// legitimately advertise addresses not reported by the node, e.g. on a dedicated etcd network or behind NAT.
+		if mismatchingURLs := memberURLsNotMatchingNode(member, *node); len(mismatchingURLs) > 0 {
+			kcpWarnings = append(kcpWarnings, fmt.Sprintf("etcd member on the %s node advertises URLs %s, which do not match any of the node addresses", node.Name, strings.Join(mismatchingURLs, ", ")))
 		}
 
 		conditions.MarkTrue(machine, controlplanev1.MachineEtcdMemberHealthyCondition)
 	}
 
+	// Check if etcd members are running different versions for longer than expected.
+	if versionSkewWarning := etcdVersionSkewWarning(controlPlane, memberVersions, now); versionSkewWarning != "" {
+		kcpWarnings = append(kcpWarnings, versionSkewWarning)
+	}
+
 	// Make sure that the list of etcd members and machines is consistent.
-	kcpErrors = compareMachinesAndMembers(controlPlane, members, kcpErrors)
+	kcpErrors = compareMachinesAndMembers(controlPlane, members, renamedMembers, kcpErrors)
+
+	// Keep track of the list of etcd members, so it can be used without connecting to etcd again.
+	if members != nil {
+		controlPlane.etcdMemberNames = etcdutil.MemberNames(members)
+	}
+
+	// Keep track of the node hosting the etcd leader, so e.g. scale down can avoid removing the leader first.
+	// NOTE: etcd members are named after the node hosting them, except for members of renamed nodes.
+	controlPlane.etcdLeaderNodeName = ""
+	for _, member := range members {
+		if member.ID == leaderID {
+			controlPlane.etcdLeaderNodeName = nodeNameForEtcdMember(renamedMembers, member.Name)
+			break
+		}
+	}
 
 	// Aggregate components error from machines at KCP level
 	aggregateFromMachinesToKCP(aggregateFromMachinesToKCPInput{
 		controlPlane:      controlPlane,
 		machineConditions: []clusterv1.ConditionType{controlplanev1.MachineEtcdMemberHealthyCondition},
 		kcpErrors:         kcpErrors,
+		kcpWarnings:       kcpWarnings,
 		condition:         controlplanev1.EtcdClusterHealthyCondition,
 		unhealthyReason:   controlplanev1.EtcdClusterUnhealthyReason,
 		unknownReason:     controlplanev1.EtcdClusterUnknownReason,
 		note:              "etcd member",
+		includeNodeNames:  true,
 	})
 }
 
-func (w *Workload) getCurrentEtcdMembers(ctx context.Context, machine *clusterv1.Machine, nodeName string) ([]*etcd.Member, error) {
+// evaluateEtcdConsistency checks if the etcd members agree on the list of members and on the cluster they belong to,
+// given the list of members as seen by the member hosted on each node, keyed by node name; it returns an error for
+// each node whose member is not consistent with the others.
+// NOTE: The members reported by the baseline node are the reference for the list of members; the first consistent
+// member is the reference for the cluster ID. This makes the results deterministic, e.g. in case of split brain
+// the nodes agreeing with the baseline node are considered healthy.
+// With EtcdMemberConsistencyQuorum, members reporting a list of members different from the baseline node are
+// tolerated if a quorum of the members agrees with the baseline node.
+// The member hosted on a node is the member named after the node or, for renamed nodes, the member named after
+// the previous name of the node, as returned by renamedEtcdMembers.
+func evaluateEtcdConsistency(perNodeMembers map[string][]*etcd.Member, renamedMembers map[string]string, mode EtcdMemberConsistencyMode) map[string]error {
+	errs := map[string]error{}
+
+	baseline := etcdBaselineNode(perNodeMembers, mode)
+	if baseline == "" {
+		return errs
+	}
+	members := perNodeMembers[baseline]
+	tolerateDivergingMembers := mode == EtcdMemberConsistencyQuorum && countNodesReporting(perNodeMembers, members) >= etcdutil.EtcdQuorumForMembers(members)
+
+	var clusterID *uint64
+	for _, nodeName := range sortedNodeNames(perNodeMembers) {
+		currentMembers := perNodeMembers[nodeName]
+
+		// Check if the list of members reported is the same as the one reported by the baseline node.
+		if !etcdutil.MemberEqual(members, currentMembers) && !tolerateDivergingMembers {
+			errs[nodeName] = errors.Errorf("etcd member reports the cluster is composed by members %s, but all previously seen etcd members are reporting %s", etcdutil.MemberNames(currentMembers), etcdutil.MemberNames(members))
+			continue
+		}
+
+		member := etcdutil.MemberForName(currentMembers, etcdMemberName(renamedMembers, nodeName))
+		if member == nil {
+			errs[nodeName] = errors.Errorf("etcd member reports the cluster is composed by members %s, which do not include the member hosted on the %s node", etcdutil.MemberNames(currentMembers), nodeName)
+			continue
+		}
+
+		// Check if the member belongs to the same cluster as all other members.
+		if clusterID == nil {
+			clusterID = &member.ClusterID
+		}
+		if *clusterID != member.ClusterID {
+			errs[nodeName] = errors.Errorf("etcd member has cluster ID %d, but all previously seen etcd members have cluster ID %d", member.ClusterID, *clusterID)
+			continue
+		}
+	}
+	return errs
+}
+
+// etcdMemberRenameGracePeriod is how long an etcd member is allowed to be named after the previous name of the node
+// hosting it, after the node has been renamed, i.e. after the node with the new name has been created.
+const etcdMemberRenameGracePeriod = 10 * time.Minute
+
+// renamedEtcdMembers returns the names of the etcd members which are still named after the previous name of the node
+// hosting them, keyed by the current node name; when there is no member named after a node, the member hosted on it
+// is found by matching the member peer URLs with the node addresses.
+// NOTE: Only a single renamed node, created less than etcdMemberRenameGracePeriod ago, is tolerated; a persistent
+// mismatch, or many renamed nodes at the same time, are reported as errors by the consistency checks.
+func renamedEtcdMembers(perNodeMembers map[string][]*etcd.Member, nodes *corev1.NodeList, now time.Time) map[string]string {
+	renamed := map[string]string{}
+	for _, nodeName := range sortedNodeNames(perNodeMembers) {
+		members := perNodeMembers[nodeName]
+		if etcdutil.MemberForName(members, nodeName) != nil {
+			continue
+		}
+		node := nodeForName(nodes, nodeName)
+		if node == nil || now.Sub(node.CreationTimestamp.Time) > etcdMemberRenameGracePeriod {
+			continue
+		}
+		addresses := make([]string, 0, len(node.Status.Addresses))
+		for _, address := range node.Status.Addresses {
+			addresses = append(addresses, address.Address)
+		}
+		// NOTE: A member named after another existing node is not a renamed member.
+		member := etcdutil.MemberForPeerAddresses(members, addresses)
+		if member == nil || member.Name == "" || nodeForName(nodes, member.Name) != nil {
+			continue
+		}
+		renamed[nodeName] = member.Name
+	}
+	if len(renamed) != 1 {
+		return nil
+	}
+	return renamed
+}
+
+// etcdMemberName returns the name of the etcd member hosted on the node with the given name.
+func etcdMemberName(renamedMembers map[string]string, nodeName string) string {
+	if memberName, ok := renamedMembers[nodeName]; ok {
+		return memberName
+	}
+	return nodeName
+}
+
+// nodeNameForEtcdMember returns the name of the node hosting the etcd member with the given name.
+func nodeNameForEtcdMember(renamedMembers map[string]string, memberName string) string {
+	for nodeName, renamedMemberName := range renamedMembers {
+		if renamedMemberName == memberName {
+			return nodeName
+		}
+	}
+	return memberName
+}
+
+// etcdBaselineNode returns the node whose list of members is used as a reference when checking etcd consistency,
+// i.e. the first node in alphabetical order or, with EtcdMemberConsistencyQuorum, the first node in alphabetical
+// order reporting the list of members reported by most of the nodes; it returns an empty string if there are no nodes.
+func etcdBaselineNode(perNodeMembers map[string][]*etcd.Member, mode EtcdMemberConsistencyMode) string {
+	nodeNames := sortedNodeNames(perNodeMembers)
+	if len(nodeNames) == 0 {
+		return ""
+	}
+	if mode != EtcdMemberConsistencyQuorum {
+		return nodeNames[0]
+	}
+
+	baseline, baselineCount := "", 0
+	for _, nodeName := range nodeNames {
+		if count := countNodesReporting(perNodeMembers, perNodeMembers[nodeName]); count > baselineCount {
+			baseline, baselineCount = nodeName, count
+		}
+	}
+	return baseline
+}
+
+// countNodesReporting returns the number of nodes whose member reports the given list of members.
+func countNodesReporting(perNodeMembers map[string][]*etcd.Member, members []*etcd.Member) int {
+	count := 0
+	for _, currentMembers := range perNodeMembers {
+		if etcdutil.MemberEqual(members, currentMembers) {
+			count++
+		}
+	}
+	return count
+}
+
+func sortedNodeNames(perNodeMembers map[string][]*etcd.Member) []string {
+	nodeNames := make([]string, 0, len(perNodeMembers))
+	for nodeName := range perNodeMembers {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	return nodeNames
+}
+
+// nodeForName returns the node with the given name from a list of nodes, or nil if it doesn't exist.
+func nodeForName(nodes *corev1.NodeList, name string) *corev1.Node {
+	for i := range nodes.Items {
+		if nodes.Items[i].Name == name {
+			return &nodes.Items[i]
+		}
+	}
+	return nil
+}
+
+// memberURLsNotMatchingNode returns the peer and client URLs advertised by an etcd member whose host does not match
+// any of the addresses of the node hosting the member; URLs with a loopback host are ignored, as well as all the URLs
+// if the node does not report any address.
+func memberURLsNotMatchingNode(member *etcd.Member, node corev1.Node) []string {
+	if len(node.Status.Addresses) == 0 {
+		return nil
+	}
+	nodeAddresses := sets.NewString()
+	for _, address := range node.Status.Addresses {
+		nodeAddresses.Insert(address.Address)
+	}
+
+	mismatchingURLs := []string{}
+	for _, memberURL := range append(append([]string{}, member.PeerURLs...), member.ClientURLs...) {
+		u, err := url.Parse(memberURL)
+		if err != nil {
+			mismatchingURLs = append(mismatchingURLs, memberURL)
+			continue
+		}
+		host := u.Hostname()
+		if host == "localhost" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+			continue
+		}
+		if !nodeAddresses.Has(host) {
+			mismatchingURLs = append(mismatchingURLs, memberURL)
+		}
+	}
+	return mismatchingURLs
+}
+
+// etcdMemberStatus contains the information about an etcd member derived from its status.
+type etcdMemberStatus struct {
+	// dbSizeWarning is a non empty warning if the backend database of the member is approaching the quota.
+	dbSizeWarning string
+
+	// version is the version of etcd running on the member.
+	version string
+
+	// leaderID is the ID of the etcd leader, as seen by the member.
+	leaderID uint64
+}
+
+// getCurrentEtcdMembers returns the list of etcd members as seen by the member hosted on the given node; additionally,
+// it returns the status of this member.
+// Connecting to the member and listing members are retried up to etcdDialRetries times, so transient network
+// problems don't flap the member to unhealthy; errors reported by the member itself are not retried.
+func (w *Workload) getCurrentEtcdMembers(ctx context.Context, machine *clusterv1.Machine, nodeName string, quotaBackendBytes int64) ([]*etcd.Member, etcdMemberStatus, error) {
+	for attempt := 1; ; attempt++ {
+		members, status, retriable, err := w.tryGetCurrentEtcdMembers(ctx, machine, nodeName, quotaBackendBytes)
+		if err == nil || !retriable || attempt > w.etcdDialRetries {
+			return members, status, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return members, status, err
+		case <-time.After(time.Duration(attempt) * etcdDialRetryInterval):
+		}
+	}
+}
+
+// tryGetCurrentEtcdMembers implements a single attempt of getCurrentEtcdMembers, and additionally returns
+// whether it failed because of a problem worth retrying.
+func (w *Workload) tryGetCurrentEtcdMembers(ctx context.Context, machine *clusterv1.Machine, nodeName string, quotaBackendBytes int64) ([]*etcd.Member, etcdMemberStatus, bool, error) {
 	// Create the etcd Client for the etcd Pod scheduled on the Node
+	dialStart := time.Now()
 	etcdClient, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, []string{nodeName})
+	observeEtcdDialDuration(w.clusterKey, nodeName, dialStart)
 	if err != nil {
 		conditions.MarkUnknown(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberInspectionFailedReason, "Failed to connect to the etcd pod on the %s node: %s", nodeName, err)
-		return nil, errors.Wrapf(err, "failed to get current etcd members: failed to connect to the etcd pod on the %s node", nodeName)
+		return nil, etcdMemberStatus{}, true, errors.Wrapf(err, "failed to get current etcd members: failed to connect to the etcd pod on the %s node", nodeName)
 	}
 	defer etcdClient.Close()
 
 	// While creating a new client, forFirstAvailableNode retrieves the status for the endpoint; check if the endpoint has errors.
 	if len(etcdClient.Errors) > 0 {
 		conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "Etcd member status reports errors: %s", strings.Join(etcdClient.Errors, ", "))
-		return nil, errors.Errorf("failed to get current etcd members: etcd member status reports errors: %s", strings.Join(etcdClient.Errors, ", "))
+		return nil, etcdMemberStatus{}, false, errors.Errorf("failed to get current etcd members: etcd member status reports errors: %s", strings.Join(etcdClient.Errors, ", "))
 	}
 
 	// Gets the list etcd members known by this member.
@@ -186,13 +502,128 @@ func (w *Workload) getCurrentEtcdMembers(ctx context.Context, machine *clusterv1
 		// NB. We should never be in here, given that we just received answer to the etcd calls included in forFirstAvailableNode;
 		// however, we are considering the calls to Members a signal of etcd not being stable.
 		conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "Failed get answer from the etcd member on the %s node", nodeName)
-		return nil, errors.Errorf("failed to get current etcd members: failed get answer from the etcd member on the %s node", nodeName)
+		return nil, etcdMemberStatus{}, true, errors.Errorf("failed to get current etcd members: failed get answer from the etcd member on the %s node", nodeName)
 	}
 
-	return currentMembers, nil
+	return currentMembers, etcdMemberStatus{
+		dbSizeWarning: w.etcdDBSizeWarning(etcdClient, nodeName, quotaBackendBytes),
+		version:       etcdClient.Version,
+		leaderID:      etcdClient.LeaderID,
+	}, false, nil
 }
 
-func compareMachinesAndMembers(controlPlane *ControlPlane, members []*etcd.Member, kcpErrors []string) []string {
+// etcdDBSizeWarning checks the backend database size reported by the etcd member status against the configured
+// percentage of the quota, and returns a warning message if the member is over the threshold.
+// NOTE: This is a proactive check surfaced as a warning; the member is not considered unhealthy until etcd raises a NOSPACE alarm.
+func (w *Workload) etcdDBSizeWarning(etcdClient *etcd.Client, nodeName string, quotaBackendBytes int64) string {
+	if w.etcdDBSizeWarningThreshold <= 0 || quotaBackendBytes <= 0 {
+		return ""
+	}
+	if etcdClient.DBSize*100 < quotaBackendBytes*int64(w.etcdDBSizeWarningThreshold) {
+		return ""
+	}
+	return fmt.Sprintf("etcd member on the %s node has a database size of %d bytes (%d bytes in use), which exceeds %d%% of the %d bytes quota",
+		nodeName, etcdClient.DBSize, etcdClient.DBSizeInUse, w.etcdDBSizeWarningThreshold, quotaBackendBytes)
+}
+
+// etcdVersionSkewGracePeriod is how long etcd members are allowed to run different versions after the newest
+// control plane machine has been created, e.g. while the last etcd member of a rolling upgrade is joining.
+const etcdVersionSkewGracePeriod = 15 * time.Minute
+
+// etcdVersionSkewWarning returns a warning message if the etcd members are running different versions, and this is
+// not the transient consequence of a rolling upgrade, i.e. a rolling upgrade is not in progress and the newest machine
+// has been created more than etcdVersionSkewGracePeriod ago; this usually means an etcd member failed to roll.
+func etcdVersionSkewWarning(controlPlane *ControlPlane, memberVersions map[string]string, now time.Time) string {
+	versions := sets.NewString()
+	for _, version := range memberVersions {
+		if version != "" {
+			versions.Insert(version)
+		}
+	}
+	if versions.Len() <= 1 {
+		return ""
+	}
+
+	// While a rolling upgrade is in progress, etcd members are expected to run different versions.
+	if conditions.IsFalse(controlPlane.KCP, controlplanev1.MachinesSpecUpToDateCondition) {
+		return ""
+	}
+	if newest := controlPlane.Machines.Newest(); newest != nil && now.Sub(newest.CreationTimestamp.Time) < etcdVersionSkewGracePeriod {
+		return ""
+	}
+
+	nodeNames := make([]string, 0, len(memberVersions))
+	for nodeName := range memberVersions {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	memberList := make([]string, 0, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		memberList = append(memberList, fmt.Sprintf("%s: %s", nodeName, memberVersions[nodeName]))
+	}
+	return fmt.Sprintf("etcd members are running different versions (%s), but no rolling upgrade is in progress", strings.Join(memberList, ", "))
+}
+
+// trackEtcdLearners returns the etcd members which are learners, preserving the time they have been first observed
+// as learners from the previously tracked learners; members which have been promoted or removed are dropped.
+func trackEtcdLearners(previous []controlplanev1.EtcdLearner, members []*etcd.Member, now time.Time) []controlplanev1.EtcdLearner {
+	var learners []controlplanev1.EtcdLearner
+	for _, member := range members {
+		// NOTE: A member which has not started yet has no name, and it can't be linked to a node; it is tracked
+		// as soon as it starts.
+		if !member.IsLearner || member.Name == "" {
+			continue
+		}
+		learner := controlplanev1.EtcdLearner{Name: member.Name, Since: metav1.NewTime(now)}
+		for _, p := range previous {
+			if p.Name == member.Name {
+				learner.Since = p.Since
+				break
+			}
+		}
+		learners = append(learners, learner)
+	}
+	sort.Slice(learners, func(i, j int) bool { return learners[i].Name < learners[j].Name })
+	return learners
+}
+
+// stuckEtcdLearnerMessage returns an error message if the etcd member with the given name has been a learner for
+// longer than the given timeout; a timeout lower or equal to zero disables the check.
+func stuckEtcdLearnerMessage(learners []controlplanev1.EtcdLearner, name string, timeout time.Duration, now time.Time) string {
+	if timeout <= 0 {
+		return ""
+	}
+	for _, learner := range learners {
+		if learner.Name != name {
+			continue
+		}
+		if now.Sub(learner.Since.Time) <= timeout {
+			return ""
+		}
+		return fmt.Sprintf("etcd member has been a learner since %s, for longer than %s; promotion to voting member is likely failing", learner.Since.UTC().Format(time.RFC3339), timeout)
+	}
+	return ""
+}
+
+// etcdQuotaBackendBytes returns the backend database size quota for the local etcd managed by KCP,
+// as defined by the quota-backend-bytes extra arg, or the etcd default if not set.
+func etcdQuotaBackendBytes(kcp *controlplanev1.KubeadmControlPlane) int64 {
+	clusterConfiguration := kcp.Spec.KubeadmConfigSpec.ClusterConfiguration
+	if clusterConfiguration == nil || clusterConfiguration.Etcd.Local == nil {
+		return etcd.DefaultQuotaBackendBytes
+	}
+	value, ok := clusterConfiguration.Etcd.Local.ExtraArgs["quota-backend-bytes"]
+	if !ok {
+		return etcd.DefaultQuotaBackendBytes
+	}
+	quotaBackendBytes, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || quotaBackendBytes <= 0 {
+		return etcd.DefaultQuotaBackendBytes
+	}
+	return quotaBackendBytes
+}
+
+func compareMachinesAndMembers(controlPlane *ControlPlane, members []*etcd.Member, renamedMembers map[string]string, kcpErrors []string) []string {
 	// NOTE: We run this check only if we actually know the list of members, otherwise the first for loop
 	// could generate a false negative when reporting missing etcd members.
 	if members == nil {
@@ -206,7 +637,7 @@ func compareMachinesAndMembers(controlPlane *ControlPlane, members []*etcd.Membe
 		}
 		found := false
 		for _, member := range members {
-			if machine.Status.NodeRef.Name == member.Name {
+			if etcdMemberName(renamedMembers, machine.Status.NodeRef.Name) == member.Name {
 				found = true
 				break
 			}
@@ -220,7 +651,7 @@ func compareMachinesAndMembers(controlPlane *ControlPlane, members []*etcd.Membe
 	for _, member := range members {
 		found := false
 		for _, machine := range controlPlane.Machines {
-			if machine.Status.NodeRef != nil && machine.Status.NodeRef.Name == member.Name {
+			if machine.Status.NodeRef != nil && etcdMemberName(renamedMembers, machine.Status.NodeRef.Name) == member.Name {
 				found = true
 				break
 			}
@@ -376,7 +807,7 @@ func (w *Workload) updateStaticPodCondition(ctx context.Context, machine *cluste
 	}
 
 	podKey := ctrlclient.ObjectKey{
-		Namespace: metav1.NamespaceSystem,
+		Namespace: w.namespace(),
 		Name:      staticPodName(component, node.Name),
 	}
 
@@ -502,10 +933,15 @@ type aggregateFromMachinesToKCPInput struct {
 	controlPlane      *ControlPlane
 	machineConditions []clusterv1.ConditionType
 	kcpErrors         []string
+	kcpWarnings       []string
 	condition         clusterv1.ConditionType
 	unhealthyReason   string
 	unknownReason     string
 	note              string
+
+	// includeNodeNames adds the name of the node hosting each machine to the messages, e.g. for etcd members,
+	// which are identified by the node hosting them.
+	includeNodeNames bool
 }
 
 // aggregateFromMachinesToKCP aggregates a group of conditions from machines to KCP.
@@ -522,22 +958,26 @@ func aggregateFromMachinesToKCP(input aggregateFromMachinesToKCPInput) {
 
 	for i := range input.controlPlane.Machines {
 		machine := input.controlPlane.Machines[i]
+		name := machine.Name
+		if input.includeNodeNames && machine.Status.NodeRef != nil {
+			name = fmt.Sprintf("%s (node %s)", machine.Name, machine.Status.NodeRef.Name)
+		}
 		for _, condition := range input.machineConditions {
 			if machineCondition := conditions.Get(machine, condition); machineCondition != nil {
 				switch machineCondition.Status {
 				case corev1.ConditionTrue:
-					kcpMachinesWithTrue.Insert(machine.Name)
+					kcpMachinesWithTrue.Insert(name)
 				case corev1.ConditionFalse:
 					switch machineCondition.Severity {
 					case clusterv1.ConditionSeverityInfo:
-						kcpMachinesWithInfo.Insert(machine.Name)
+						kcpMachinesWithInfo.Insert(name)
 					case clusterv1.ConditionSeverityWarning:
-						kcpMachinesWithWarnings.Insert(machine.Name)
+						kcpMachinesWithWarnings.Insert(name)
 					case clusterv1.ConditionSeverityError:
-						kcpMachinesWithErrors.Insert(machine.Name)
+						kcpMachinesWithErrors.Insert(name)
 					}
 				case corev1.ConditionUnknown:
-					kcpMachinesWithUnknown.Insert(machine.Name)
+					kcpMachinesWithUnknown.Insert(name)
 				}
 			}
 		}
@@ -552,9 +992,12 @@ func aggregateFromMachinesToKCP(input aggregateFromMachinesToKCPInput) {
 		return
 	}
 
-	// In case of no errors and at least one machine with warnings, report false, warnings.
+	// In case of no errors and at least one machine with warnings or KCP level warnings, report false, warnings.
 	if len(kcpMachinesWithWarnings) > 0 {
-		conditions.MarkFalse(input.controlPlane.KCP, input.condition, input.unhealthyReason, clusterv1.ConditionSeverityWarning, "Following machines are reporting %s warnings: %s", input.note, strings.Join(kcpMachinesWithWarnings.List(), ", "))
+		input.kcpWarnings = append(input.kcpWarnings, fmt.Sprintf("Following machines are reporting %s warnings: %s", input.note, strings.Join(kcpMachinesWithWarnings.List(), ", ")))
+	}
+	if len(input.kcpWarnings) > 0 {
+		conditions.MarkFalse(input.controlPlane.KCP, input.condition, input.unhealthyReason, clusterv1.ConditionSeverityWarning, "%s", strings.Join(input.kcpWarnings, "; "))
 		return
 	}
 
@@ -577,5 +1020,7 @@ func aggregateFromMachinesToKCP(input aggregateFromMachinesToKCPInput) {
 	}
 
 	// This last case should happen only if there are no provisioned machines, and thus without conditions.
-	// So there will be no condition at KCP level too.
+	// So there will be no condition at KCP level too; drop the condition possibly computed by a previous reconcile,
+	// so it does not report a stale status.
+	conditions.Delete(input.controlPlane.KCP, input.condition)
 }
@@ -18,6 +18,7 @@ package internal
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
@@ -28,6 +29,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
@@ -45,8 +47,10 @@ func TestUpdateEtcdConditions(t *testing.T) {
 		machines                  []*clusterv1.Machine
 		injectClient              client.Client // This test is injecting a fake client because it is required to create nodes with a controlled Status or to fail with a specific error.
 		injectEtcdClientGenerator etcdClientFor // This test is injecting a fake etcdClientGenerator because it is required to nodes with a controlled Status or to fail with a specific error.
+		dbSizeWarningThreshold    int
 		expectedKCPCondition      *clusterv1.Condition
 		expectedMachineConditions map[string]clusterv1.Conditions
+		expectedEtcdMemberNames   []string
 	}{
 		{
 			name: "if list nodes return an error should report all the conditions Unknown",
@@ -101,13 +105,33 @@ func TestUpdateEtcdConditions(t *testing.T) {
 			injectEtcdClientGenerator: &fakeEtcdClientGenerator{
 				forNodesErr: errors.New("failed to get client for node"),
 			},
-			expectedKCPCondition: conditions.UnknownCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnknownReason, "Following machines are reporting unknown etcd member status: m1"),
+			expectedKCPCondition: conditions.UnknownCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnknownReason, "Following machines are reporting unknown etcd member status: m1 (node n1)"),
 			expectedMachineConditions: map[string]clusterv1.Conditions{
 				"m1": {
 					*conditions.UnknownCondition(controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberInspectionFailedReason, "Failed to connect to the etcd pod on the %s node: failed to get client for node", "n1"),
 				},
 			},
 		},
+		{
+			name: "node not yet initialized by the cloud provider should report unknown condition without connecting to etcd",
+			machines: []*clusterv1.Machine{
+				fakeMachine("m1", withNodeRef("n1")),
+			},
+			injectClient: &fakeClient{
+				list: &corev1.NodeList{
+					Items: []corev1.Node{*fakeNode("n1", withoutProviderID())},
+				},
+			},
+			injectEtcdClientGenerator: &fakeEtcdClientGenerator{
+				forNodesErr: errors.New("etcd should not be dialed"),
+			},
+			expectedKCPCondition: conditions.UnknownCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnknownReason, "Following machines are reporting unknown etcd member status: m1 (node n1)"),
+			expectedMachineConditions: map[string]clusterv1.Conditions{
+				"m1": {
+					*conditions.UnknownCondition(controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberNodeNotInitializedReason, "Waiting for the n1 node to be initialized by the cloud provider"),
+				},
+			},
+		},
 		{
 			name: "etcd client reporting status errors should be reflected into a false condition",
 			machines: []*clusterv1.Machine{
@@ -126,7 +150,7 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					Errors: []string{"some errors"},
 				},
 			},
-			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m1"),
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m1 (node n1)"),
 			expectedMachineConditions: map[string]clusterv1.Conditions{
 				"m1": {
 					*conditions.FalseCondition(controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "Etcd member status reports errors: %s", "some errors"),
@@ -151,7 +175,7 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					},
 				},
 			},
-			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m1"),
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m1 (node n1)"),
 			expectedMachineConditions: map[string]clusterv1.Conditions{
 				"m1": {
 					*conditions.FalseCondition(controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "Failed get answer from the etcd member on the %s node", "n1"),
@@ -185,12 +209,13 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					},
 				},
 			},
-			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m1"),
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m1 (node n1)"),
 			expectedMachineConditions: map[string]clusterv1.Conditions{
 				"m1": {
 					*conditions.FalseCondition(controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "Etcd member reports alarms: %s", "NOSPACE"),
 				},
 			},
+			expectedEtcdMemberNames: []string{"n1"},
 		},
 		{
 			name: "etcd members with different Cluster ID should report false condition",
@@ -250,7 +275,7 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					}
 				},
 			},
-			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m2"),
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m2 (node n2)"),
 			expectedMachineConditions: map[string]clusterv1.Conditions{
 				"m1": {
 					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
@@ -259,6 +284,7 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					*conditions.FalseCondition(controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "etcd member has cluster ID %d, but all previously seen etcd members have cluster ID %d", uint64(2), uint64(1)),
 				},
 			},
+			expectedEtcdMemberNames: []string{"n1", "n2"},
 		},
 		{
 			name: "etcd members with different member list should report false condition",
@@ -318,7 +344,7 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					}
 				},
 			},
-			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m2"),
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m2 (node n2)"),
 			expectedMachineConditions: map[string]clusterv1.Conditions{
 				"m1": {
 					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
@@ -327,6 +353,7 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					*conditions.FalseCondition(controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "etcd member reports the cluster is composed by members [n2 n3], but all previously seen etcd members are reporting [n1 n2]"),
 				},
 			},
+			expectedEtcdMemberNames: []string{"n1", "n2"},
 		},
 		{
 			name: "a machine without a member should report false condition",
@@ -368,7 +395,7 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					}
 				},
 			},
-			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m2"),
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: %s", "m2 (node n2)"),
 			expectedMachineConditions: map[string]clusterv1.Conditions{
 				"m1": {
 					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
@@ -377,6 +404,7 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					*conditions.FalseCondition(controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "Missing etcd member"),
 				},
 			},
+			expectedEtcdMemberNames: []string{"n1"},
 		},
 		{
 			name: "healthy etcd members should report true",
@@ -445,6 +473,175 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
 				},
 			},
+			expectedEtcdMemberNames: []string{"n1", "n2"},
+		},
+		{
+			name: "etcd member advertising URLs not matching the node addresses should report a warning at KCP level",
+			machines: []*clusterv1.Machine{
+				fakeMachine("m1", withNodeRef("n1")),
+			},
+			injectClient: &fakeClient{
+				list: &corev1.NodeList{
+					Items: []corev1.Node{
+						*fakeNode("n1", withNodeAddress("10.0.0.2")),
+					},
+				},
+			},
+			injectEtcdClientGenerator: &fakeEtcdClientGenerator{
+				forNodesClient: &etcd.Client{
+					EtcdClient: &fake2.FakeEtcdClient{
+						EtcdEndpoints: []string{},
+						MemberListResponse: &clientv3.MemberListResponse{
+							Header: &pb.ResponseHeader{
+								ClusterId: uint64(1),
+							},
+							Members: []*pb.Member{
+								{Name: "n1", ID: uint64(1), PeerURLs: []string{"https://10.0.0.1:2380"}, ClientURLs: []string{"https://10.0.0.2:2379", "https://127.0.0.1:2379"}},
+							},
+						},
+						AlarmResponse: &clientv3.AlarmResponse{
+							Alarms: []*pb.AlarmMember{},
+						},
+					},
+				},
+			},
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityWarning,
+				"etcd member on the n1 node advertises URLs https://10.0.0.1:2380, which do not match any of the node addresses"),
+			expectedMachineConditions: map[string]clusterv1.Conditions{
+				"m1": {
+					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
+				},
+			},
+			expectedEtcdMemberNames: []string{"n1"},
+		},
+		{
+			name: "etcd member exceeding the database size threshold should report a warning at KCP level",
+			kcp: &controlplanev1.KubeadmControlPlane{
+				Spec: controlplanev1.KubeadmControlPlaneSpec{
+					KubeadmConfigSpec: bootstrapv1.KubeadmConfigSpec{
+						ClusterConfiguration: &bootstrapv1.ClusterConfiguration{
+							Etcd: bootstrapv1.Etcd{
+								Local: &bootstrapv1.LocalEtcd{
+									ExtraArgs: map[string]string{"quota-backend-bytes": "1000"},
+								},
+							},
+						},
+					},
+				},
+			},
+			machines: []*clusterv1.Machine{
+				fakeMachine("m1", withNodeRef("n1")),
+				fakeMachine("m2", withNodeRef("n2")),
+			},
+			injectClient: &fakeClient{
+				list: &corev1.NodeList{
+					Items: []corev1.Node{
+						*fakeNode("n1"),
+						*fakeNode("n2"),
+					},
+				},
+			},
+			injectEtcdClientGenerator: &fakeEtcdClientGenerator{
+				forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+					var dbSize, dbSizeInUse int64
+					switch n[0] {
+					case "n1":
+						dbSize, dbSizeInUse = 900, 600
+					case "n2":
+						dbSize, dbSizeInUse = 700, 600
+					default:
+						return nil, errors.New("no client for this node")
+					}
+					return &etcd.Client{
+						EtcdClient: &fake2.FakeEtcdClient{
+							EtcdEndpoints: []string{},
+							MemberListResponse: &clientv3.MemberListResponse{
+								Header: &pb.ResponseHeader{
+									ClusterId: uint64(1),
+								},
+								Members: []*pb.Member{
+									{Name: "n1", ID: uint64(1)},
+									{Name: "n2", ID: uint64(2)},
+								},
+							},
+							AlarmResponse: &clientv3.AlarmResponse{
+								Alarms: []*pb.AlarmMember{},
+							},
+						},
+						DBSize:      dbSize,
+						DBSizeInUse: dbSizeInUse,
+					}, nil
+				},
+			},
+			dbSizeWarningThreshold: 80,
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityWarning,
+				"etcd member on the n1 node has a database size of 900 bytes (600 bytes in use), which exceeds 80%% of the 1000 bytes quota"),
+			expectedMachineConditions: map[string]clusterv1.Conditions{
+				"m1": {
+					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
+				},
+				"m2": {
+					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
+				},
+			},
+			expectedEtcdMemberNames: []string{"n1", "n2"},
+		},
+		{
+			name: "etcd members running different versions without a rolling upgrade in progress should report a warning at KCP level",
+			machines: []*clusterv1.Machine{
+				fakeMachine("m1", withNodeRef("n1")),
+				fakeMachine("m2", withNodeRef("n2")),
+			},
+			injectClient: &fakeClient{
+				list: &corev1.NodeList{
+					Items: []corev1.Node{
+						*fakeNode("n1"),
+						*fakeNode("n2"),
+					},
+				},
+			},
+			injectEtcdClientGenerator: &fakeEtcdClientGenerator{
+				forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+					var version string
+					switch n[0] {
+					case "n1":
+						version = "3.5.1"
+					case "n2":
+						version = "3.4.13"
+					default:
+						return nil, errors.New("no client for this node")
+					}
+					return &etcd.Client{
+						EtcdClient: &fake2.FakeEtcdClient{
+							EtcdEndpoints: []string{},
+							MemberListResponse: &clientv3.MemberListResponse{
+								Header: &pb.ResponseHeader{
+									ClusterId: uint64(1),
+								},
+								Members: []*pb.Member{
+									{Name: "n1", ID: uint64(1)},
+									{Name: "n2", ID: uint64(2)},
+								},
+							},
+							AlarmResponse: &clientv3.AlarmResponse{
+								Alarms: []*pb.AlarmMember{},
+							},
+						},
+						Version: version,
+					}, nil
+				},
+			},
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityWarning,
+				"etcd members are running different versions (n1: 3.5.1, n2: 3.4.13), but no rolling upgrade is in progress"),
+			expectedMachineConditions: map[string]clusterv1.Conditions{
+				"m1": {
+					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
+				},
+				"m2": {
+					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
+				},
+			},
+			expectedEtcdMemberNames: []string{"n1", "n2"},
 		},
 		{
 			name: "Eternal etcd should set a condition at KCP level",
@@ -470,8 +667,9 @@ func TestUpdateEtcdConditions(t *testing.T) {
 				tt.kcp = &controlplanev1.KubeadmControlPlane{}
 			}
 			w := &Workload{
-				Client:              tt.injectClient,
-				etcdClientGenerator: tt.injectEtcdClientGenerator,
+				Client:                     tt.injectClient,
+				etcdClientGenerator:        tt.injectEtcdClientGenerator,
+				etcdDBSizeWarningThreshold: tt.dbSizeWarningThreshold,
 			}
 			controlPane := &ControlPlane{
 				KCP:      tt.kcp,
@@ -486,6 +684,623 @@ func TestUpdateEtcdConditions(t *testing.T) {
 				g.Expect(tt.expectedMachineConditions).To(HaveKey(m.Name))
 				g.Expect(m.GetConditions()).To(conditions.MatchConditions(tt.expectedMachineConditions[m.Name]), "unexpected conditions for machine %s", m.Name)
 			}
+			// The member list observed during the health check must be available without connecting to etcd again.
+			g.Expect(controlPane.etcdMemberNames).To(Equal(tt.expectedEtcdMemberNames))
+			g.Expect(etcdHealthDetailsFromConditions(controlPane).MemberNames).To(Equal(tt.expectedEtcdMemberNames))
+		})
+	}
+}
+
+func TestUpdateEtcdConditionsControlPlaneNodeLabels(t *testing.T) {
+	g := NewWithT(t)
+
+	customLabelNode := fakeNode("n1")
+	customLabelNode.Labels = map[string]string{"example.com/etcd": ""}
+	defaultLabelNode := fakeNode("n2")
+	workerNode := fakeNode("n3")
+	workerNode.Labels = map[string]string{}
+
+	var checkedNodes []string
+	w := &Workload{
+		Client: fake.NewClientBuilder().WithObjects(customLabelNode, defaultLabelNode, workerNode).Build(),
+		etcdClientGenerator: &fakeEtcdClientGenerator{
+			forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+				checkedNodes = append(checkedNodes, n...)
+				return &etcd.Client{
+					EtcdClient: &fake2.FakeEtcdClient{
+						EtcdEndpoints: []string{},
+						MemberListResponse: &clientv3.MemberListResponse{
+							Header: &pb.ResponseHeader{
+								ClusterId: uint64(1),
+							},
+							Members: []*pb.Member{
+								{Name: "n1", ID: uint64(1)},
+							},
+						},
+						AlarmResponse: &clientv3.AlarmResponse{
+							Alarms: []*pb.AlarmMember{},
+						},
+					},
+				}, nil
+			},
+		},
+		controlPlaneNodeLabels: []string{"example.com/etcd"},
+	}
+	controlPlane := &ControlPlane{
+		KCP:      &controlplanev1.KubeadmControlPlane{},
+		Machines: collections.FromMachines(fakeMachine("m1", withNodeRef("n1"))),
+	}
+	w.UpdateEtcdConditions(ctx, controlPlane)
+
+	// Only the nodes with the configured labels are considered as hosting etcd members.
+	g.Expect(checkedNodes).To(ConsistOf("n1"))
+	g.Expect(conditions.IsTrue(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition)).To(BeTrue())
+}
+
+func TestUpdateEtcdConditionsLeaderNodeName(t *testing.T) {
+	g := NewWithT(t)
+
+	w := &Workload{
+		Client: fake.NewClientBuilder().WithObjects(fakeNode("n1"), fakeNode("n2")).Build(),
+		etcdClientGenerator: &fakeEtcdClientGenerator{
+			forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+				return &etcd.Client{
+					EtcdClient: &fake2.FakeEtcdClient{
+						EtcdEndpoints: []string{},
+						MemberListResponse: &clientv3.MemberListResponse{
+							Header: &pb.ResponseHeader{
+								ClusterId: uint64(1),
+							},
+							Members: []*pb.Member{
+								{Name: "n1", ID: uint64(1)},
+								{Name: "n2", ID: uint64(2)},
+							},
+						},
+						AlarmResponse: &clientv3.AlarmResponse{
+							Alarms: []*pb.AlarmMember{},
+						},
+					},
+					LeaderID: uint64(2),
+				}, nil
+			},
+		},
+	}
+	controlPlane := &ControlPlane{
+		KCP:      &controlplanev1.KubeadmControlPlane{},
+		Machines: collections.FromMachines(fakeMachine("m1", withNodeRef("n1")), fakeMachine("m2", withNodeRef("n2"))),
+	}
+	w.UpdateEtcdConditions(ctx, controlPlane)
+
+	g.Expect(controlPlane.etcdLeaderNodeName).To(Equal("n2"))
+	g.Expect(etcdHealthDetailsFromConditions(controlPlane).LeaderNodeName).To(Equal("n2"))
+}
+
+func TestUpdateEtcdConditionsDropsStaleCondition(t *testing.T) {
+	g := NewWithT(t)
+
+	// The condition has been computed by a previous reconcile, when the control plane machines were provisioned.
+	kcp := &controlplanev1.KubeadmControlPlane{}
+	conditions.MarkFalse(kcp, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Following machines are reporting etcd member errors: m1 (node n1)")
+
+	w := &Workload{
+		Client: &fakeClient{
+			list: &corev1.NodeList{
+				Items: []corev1.Node{*fakeNode("n1")},
+			},
+		},
+	}
+	controlPlane := &ControlPlane{
+		KCP:      kcp,
+		Machines: collections.FromMachines(fakeMachine("m2")), // without NodeRef (provisioning)
+	}
+	w.UpdateEtcdConditions(ctx, controlPlane)
+
+	g.Expect(conditions.Get(kcp, controlplanev1.EtcdClusterHealthyCondition)).To(BeNil())
+}
+
+func TestUpdateEtcdConditionsStuckLearner(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name             string
+		learnersSince    []controlplanev1.EtcdLearner
+		expectLearnerErr bool
+	}{
+		{
+			name:             "learner observed for the first time is tolerated",
+			expectLearnerErr: false,
+		},
+		{
+			name:             "learner within the promotion timeout is tolerated",
+			learnersSince:    []controlplanev1.EtcdLearner{{Name: "n2", Since: metav1.NewTime(now.Add(-time.Minute))}},
+			expectLearnerErr: false,
+		},
+		{
+			name:             "learner exceeding the promotion timeout is reported as unhealthy",
+			learnersSince:    []controlplanev1.EtcdLearner{{Name: "n2", Since: metav1.NewTime(now.Add(-time.Hour))}},
+			expectLearnerErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			w := &Workload{
+				Client: fake.NewClientBuilder().WithObjects(fakeNode("n1"), fakeNode("n2")).Build(),
+				etcdClientGenerator: &fakeEtcdClientGenerator{
+					forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+						return &etcd.Client{
+							EtcdClient: &fake2.FakeEtcdClient{
+								EtcdEndpoints: []string{},
+								MemberListResponse: &clientv3.MemberListResponse{
+									Header: &pb.ResponseHeader{
+										ClusterId: uint64(1),
+									},
+									Members: []*pb.Member{
+										{Name: "n1", ID: uint64(1)},
+										{Name: "n2", ID: uint64(2), IsLearner: true},
+									},
+								},
+								AlarmResponse: &clientv3.AlarmResponse{
+									Alarms: []*pb.AlarmMember{},
+								},
+							},
+						}, nil
+					},
+				},
+				etcdLearnerPromotionTimeout: 10 * time.Minute,
+			}
+			kcp := &controlplanev1.KubeadmControlPlane{}
+			kcp.Status.EtcdLearners = tt.learnersSince
+			m1 := fakeMachine("m1", withNodeRef("n1"))
+			m2 := fakeMachine("m2", withNodeRef("n2"))
+			controlPlane := &ControlPlane{
+				KCP:      kcp,
+				Machines: collections.FromMachines(m1, m2),
+			}
+			w.UpdateEtcdConditions(ctx, controlPlane)
+
+			// The learner is tracked in the KCP status, preserving the time it has been first observed as a learner.
+			g.Expect(kcp.Status.EtcdLearners).To(HaveLen(1))
+			g.Expect(kcp.Status.EtcdLearners[0].Name).To(Equal("n2"))
+			if len(tt.learnersSince) > 0 {
+				g.Expect(kcp.Status.EtcdLearners[0].Since).To(Equal(tt.learnersSince[0].Since))
+			}
+
+			g.Expect(conditions.IsTrue(m1, controlplanev1.MachineEtcdMemberHealthyCondition)).To(BeTrue())
+			if tt.expectLearnerErr {
+				g.Expect(conditions.IsFalse(m2, controlplanev1.MachineEtcdMemberHealthyCondition)).To(BeTrue())
+				g.Expect(*conditions.GetSeverity(m2, controlplanev1.MachineEtcdMemberHealthyCondition)).To(Equal(clusterv1.ConditionSeverityError))
+				g.Expect(conditions.GetMessage(m2, controlplanev1.MachineEtcdMemberHealthyCondition)).To(ContainSubstring("learner"))
+				g.Expect(conditions.IsFalse(kcp, controlplanev1.EtcdClusterHealthyCondition)).To(BeTrue())
+				return
+			}
+			g.Expect(conditions.IsTrue(m2, controlplanev1.MachineEtcdMemberHealthyCondition)).To(BeTrue())
+		})
+	}
+}
+
+func TestUpdateEtcdConditionsRenamedNode(t *testing.T) {
+	tests := []struct {
+		name              string
+		nodeCreated       time.Time
+		otherNodeRenamed  bool
+		expectRenameError bool
+	}{
+		{
+			name:              "member named after the previous name of a recently renamed node is tolerated",
+			nodeCreated:       time.Now().Add(-time.Minute),
+			expectRenameError: false,
+		},
+		{
+			name:              "member named after the previous name of a node renamed long ago is reported as unhealthy",
+			nodeCreated:       time.Now().Add(-time.Hour),
+			expectRenameError: true,
+		},
+		{
+			name:              "many members not matching renamed nodes are reported as unhealthy",
+			nodeCreated:       time.Now().Add(-time.Minute),
+			otherNodeRenamed:  true,
+			expectRenameError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// The n2 node has been renamed to n2-renamed, but the etcd member hosted on it is still named n2.
+			n1Name := "n1"
+			if tt.otherNodeRenamed {
+				n1Name = "n1-renamed"
+			}
+			n1 := fakeNode(n1Name, withNodeAddress("10.0.0.1"), withCreationTimestamp(tt.nodeCreated))
+			n2 := fakeNode("n2-renamed", withNodeAddress("10.0.0.2"), withCreationTimestamp(tt.nodeCreated))
+
+			w := &Workload{
+				Client: fake.NewClientBuilder().WithObjects(n1, n2).Build(),
+				etcdClientGenerator: &fakeEtcdClientGenerator{
+					forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+						return &etcd.Client{
+							EtcdClient: &fake2.FakeEtcdClient{
+								EtcdEndpoints: []string{},
+								MemberListResponse: &clientv3.MemberListResponse{
+									Header: &pb.ResponseHeader{
+										ClusterId: uint64(1),
+									},
+									Members: []*pb.Member{
+										{Name: "n1", ID: uint64(1), PeerURLs: []string{"https://10.0.0.1:2380"}},
+										{Name: "n2", ID: uint64(2), PeerURLs: []string{"https://10.0.0.2:2380"}},
+									},
+								},
+								AlarmResponse: &clientv3.AlarmResponse{
+									Alarms: []*pb.AlarmMember{},
+								},
+							},
+							LeaderID: uint64(2),
+						}, nil
+					},
+				},
+			}
+			m1 := fakeMachine("m1", withNodeRef(n1Name))
+			m2 := fakeMachine("m2", withNodeRef("n2-renamed"))
+			controlPlane := &ControlPlane{
+				KCP:      &controlplanev1.KubeadmControlPlane{},
+				Machines: collections.FromMachines(m1, m2),
+			}
+			w.UpdateEtcdConditions(ctx, controlPlane)
+
+			if tt.expectRenameError {
+				g.Expect(conditions.IsFalse(m2, controlplanev1.MachineEtcdMemberHealthyCondition)).To(BeTrue())
+				g.Expect(conditions.IsFalse(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition)).To(BeTrue())
+				return
+			}
+			g.Expect(conditions.IsTrue(m1, controlplanev1.MachineEtcdMemberHealthyCondition)).To(BeTrue())
+			g.Expect(conditions.IsTrue(m2, controlplanev1.MachineEtcdMemberHealthyCondition)).To(BeTrue())
+			g.Expect(conditions.IsTrue(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition)).To(BeTrue())
+			g.Expect(controlPlane.etcdLeaderNodeName).To(Equal("n2-renamed"))
+		})
+	}
+}
+
+func TestTrackEtcdLearners(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	since := metav1.NewTime(now.Add(-time.Hour))
+	previous := []controlplanev1.EtcdLearner{
+		{Name: "n2", Since: since},
+		{Name: "promoted", Since: since},
+	}
+	members := []*etcd.Member{
+		{Name: "n1"},
+		{Name: "n3", IsLearner: true},
+		{Name: "n2", IsLearner: true},
+		{Name: "promoted"},
+		{Name: "", IsLearner: true}, // not started yet
+	}
+
+	g.Expect(trackEtcdLearners(previous, members, now)).To(Equal([]controlplanev1.EtcdLearner{
+		{Name: "n2", Since: since},
+		{Name: "n3", Since: metav1.NewTime(now)},
+	}))
+	g.Expect(trackEtcdLearners(previous, []*etcd.Member{{Name: "n2"}}, now)).To(BeEmpty())
+}
+
+func TestUpdateEtcdConditionsRetriesEtcdDial(t *testing.T) {
+	tests := []struct {
+		name            string
+		etcdDialRetries int
+		expectHealthy   bool
+	}{
+		{
+			name:            "a member failing to connect once is reported healthy when retries are enabled",
+			etcdDialRetries: 2,
+			expectHealthy:   true,
+		},
+		{
+			name:            "a member failing to connect once is reported unhealthy when retries are disabled",
+			etcdDialRetries: 0,
+			expectHealthy:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			attempts := 0
+			w := &Workload{
+				Client: fake.NewClientBuilder().WithObjects(fakeNode("n1")).Build(),
+				etcdClientGenerator: &fakeEtcdClientGenerator{
+					forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+						attempts++
+						if attempts == 1 {
+							return nil, errors.New("failed to dial etcd")
+						}
+						return &etcd.Client{
+							EtcdClient: &fake2.FakeEtcdClient{
+								EtcdEndpoints: []string{},
+								MemberListResponse: &clientv3.MemberListResponse{
+									Header: &pb.ResponseHeader{
+										ClusterId: uint64(1),
+									},
+									Members: []*pb.Member{
+										{Name: "n1", ID: uint64(1)},
+									},
+								},
+								AlarmResponse: &clientv3.AlarmResponse{
+									Alarms: []*pb.AlarmMember{},
+								},
+							},
+						}, nil
+					},
+				},
+				etcdDialRetries: tt.etcdDialRetries,
+			}
+			controlPlane := &ControlPlane{
+				KCP:      &controlplanev1.KubeadmControlPlane{},
+				Machines: collections.FromMachines(fakeMachine("m1", withNodeRef("n1"))),
+			}
+			w.UpdateEtcdConditions(ctx, controlPlane)
+
+			g.Expect(conditions.IsTrue(controlPlane.Machines["m1"], controlplanev1.MachineEtcdMemberHealthyCondition)).To(Equal(tt.expectHealthy))
+			g.Expect(conditions.IsTrue(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition)).To(Equal(tt.expectHealthy))
+		})
+	}
+}
+
+func TestEvaluateEtcdConsistency(t *testing.T) {
+	member := func(name string, clusterID uint64) *etcd.Member {
+		return &etcd.Member{Name: name, ClusterID: clusterID}
+	}
+
+	tests := []struct {
+		name           string
+		mode           EtcdMemberConsistencyMode
+		perNodeMembers map[string][]*etcd.Member
+		expectedErrors map[string]string
+	}{
+		{
+			name:           "no members",
+			perNodeMembers: map[string][]*etcd.Member{},
+			expectedErrors: map[string]string{},
+		},
+		{
+			name: "consistent members",
+			perNodeMembers: map[string][]*etcd.Member{
+				"n1": {member("n1", 1), member("n2", 1), member("n3", 1)},
+				"n2": {member("n1", 1), member("n2", 1), member("n3", 1)},
+				"n3": {member("n1", 1), member("n2", 1), member("n3", 1)},
+			},
+			expectedErrors: map[string]string{},
+		},
+		{
+			name: "member set diverging from the baseline node",
+			perNodeMembers: map[string][]*etcd.Member{
+				"n1": {member("n1", 1), member("n2", 1), member("n3", 1)},
+				"n2": {member("n1", 1), member("n2", 1), member("n3", 1)},
+				"n3": {member("n1", 1), member("n3", 1)},
+			},
+			expectedErrors: map[string]string{
+				"n3": "etcd member reports the cluster is composed by members [n1 n3], but all previously seen etcd members are reporting [n1 n2 n3]",
+			},
+		},
+		{
+			name: "member list not including the member hosted on the node",
+			perNodeMembers: map[string][]*etcd.Member{
+				"n1": {member("n1", 1), member("n2", 1)},
+				"n3": {member("n1", 1), member("n2", 1)},
+			},
+			expectedErrors: map[string]string{
+				"n3": "etcd member reports the cluster is composed by members [n1 n2], which do not include the member hosted on the n3 node",
+			},
+		},
+		{
+			name: "member with a different cluster ID",
+			perNodeMembers: map[string][]*etcd.Member{
+				"n1": {member("n1", 1), member("n2", 1)},
+				"n2": {member("n1", 2), member("n2", 2)},
+			},
+			expectedErrors: map[string]string{
+				"n2": "etcd member has cluster ID 2, but all previously seen etcd members have cluster ID 1",
+			},
+		},
+		{
+			name: "split brain",
+			perNodeMembers: map[string][]*etcd.Member{
+				"n1": {member("n1", 1), member("n2", 1)},
+				"n2": {member("n1", 1), member("n2", 1)},
+				"n3": {member("n3", 2), member("n4", 2)},
+				"n4": {member("n3", 2), member("n4", 2)},
+			},
+			expectedErrors: map[string]string{
+				"n3": "etcd member reports the cluster is composed by members [n3 n4], but all previously seen etcd members are reporting [n1 n2]",
+				"n4": "etcd member reports the cluster is composed by members [n3 n4], but all previously seen etcd members are reporting [n1 n2]",
+			},
+		},
+		{
+			name: "stale member set with unanimous consistency",
+			mode: EtcdMemberConsistencyUnanimous,
+			perNodeMembers: map[string][]*etcd.Member{
+				"n1": {member("n1", 1), member("n2", 1)},
+				"n2": {member("n1", 1), member("n2", 1), member("n3", 1)},
+				"n3": {member("n1", 1), member("n2", 1), member("n3", 1)},
+			},
+			expectedErrors: map[string]string{
+				"n2": "etcd member reports the cluster is composed by members [n1 n2 n3], but all previously seen etcd members are reporting [n1 n2]",
+				"n3": "etcd member reports the cluster is composed by members [n1 n2 n3], but all previously seen etcd members are reporting [n1 n2]",
+			},
+		},
+		{
+			name: "stale member set with quorum consistency",
+			mode: EtcdMemberConsistencyQuorum,
+			perNodeMembers: map[string][]*etcd.Member{
+				"n1": {member("n1", 1), member("n2", 1)},
+				"n2": {member("n1", 1), member("n2", 1), member("n3", 1)},
+				"n3": {member("n1", 1), member("n2", 1), member("n3", 1)},
+			},
+			expectedErrors: map[string]string{},
+		},
+		{
+			name: "member sets without quorum with quorum consistency",
+			mode: EtcdMemberConsistencyQuorum,
+			perNodeMembers: map[string][]*etcd.Member{
+				"n1": {member("n1", 1), member("n2", 1), member("n3", 1), member("n4", 1)},
+				"n2": {member("n1", 1), member("n2", 1), member("n3", 1)},
+				"n3": {member("n1", 1), member("n2", 1), member("n3", 1), member("n4", 1)},
+				"n4": {member("n1", 1), member("n2", 1), member("n4", 1)},
+			},
+			expectedErrors: map[string]string{
+				"n2": "etcd member reports the cluster is composed by members [n1 n2 n3], but all previously seen etcd members are reporting [n1 n2 n3 n4]",
+				"n4": "etcd member reports the cluster is composed by members [n1 n2 n4], but all previously seen etcd members are reporting [n1 n2 n3 n4]",
+			},
+		},
+		{
+			name: "stale member set not including the member hosted on the node with quorum consistency",
+			mode: EtcdMemberConsistencyQuorum,
+			perNodeMembers: map[string][]*etcd.Member{
+				"n1": {member("n1", 1), member("n2", 1), member("n3", 1)},
+				"n2": {member("n1", 1), member("n2", 1), member("n3", 1)},
+				"n3": {member("n1", 1), member("n2", 1)},
+			},
+			expectedErrors: map[string]string{
+				"n3": "etcd member reports the cluster is composed by members [n1 n2], which do not include the member hosted on the n3 node",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := evaluateEtcdConsistency(tt.perNodeMembers, nil, tt.mode)
+			g.Expect(errs).To(HaveLen(len(tt.expectedErrors)))
+			for nodeName, expectedError := range tt.expectedErrors {
+				g.Expect(errs).To(HaveKey(nodeName))
+				g.Expect(errs[nodeName]).To(MatchError(expectedError))
+			}
+		})
+	}
+}
+
+func TestMemberURLsNotMatchingNode(t *testing.T) {
+	node := *fakeNode("n1", withNodeAddress("10.0.0.1"))
+	node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeHostName, Address: "n1.example.com"})
+
+	tests := []struct {
+		name     string
+		member   *etcd.Member
+		node     corev1.Node
+		expected []string
+	}{
+		{
+			name: "URLs matching the node addresses",
+			member: &etcd.Member{
+				PeerURLs:   []string{"https://10.0.0.1:2380"},
+				ClientURLs: []string{"https://n1.example.com:2379"},
+			},
+			node:     node,
+			expected: []string{},
+		},
+		{
+			name: "loopback URLs are ignored",
+			member: &etcd.Member{
+				ClientURLs: []string{"https://127.0.0.1:2379", "https://localhost:2379", "https://[::1]:2379"},
+			},
+			node:     node,
+			expected: []string{},
+		},
+		{
+			name: "URLs not matching the node addresses",
+			member: &etcd.Member{
+				PeerURLs:   []string{"https://10.0.0.2:2380"},
+				ClientURLs: []string{"https://10.0.0.1:2379", "https://10.0.0.2:2379"},
+			},
+			node:     node,
+			expected: []string{"https://10.0.0.2:2380", "https://10.0.0.2:2379"},
+		},
+		{
+			name: "node without addresses",
+			member: &etcd.Member{
+				PeerURLs: []string{"https://10.0.0.2:2380"},
+			},
+			node:     *fakeNode("n1"),
+			expected: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(memberURLsNotMatchingNode(tt.member, tt.node)).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestEtcdVersionSkewWarning(t *testing.T) {
+	now := time.Now()
+	oldMachine := fakeMachine("m1", withNodeRef("n1"))
+	oldMachine.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+	newMachine := fakeMachine("m2", withNodeRef("n2"))
+	newMachine.CreationTimestamp = metav1.NewTime(now.Add(-time.Minute))
+
+	rollingUpdateKCP := &controlplanev1.KubeadmControlPlane{}
+	conditions.MarkFalse(rollingUpdateKCP, controlplanev1.MachinesSpecUpToDateCondition, controlplanev1.RollingUpdateInProgressReason, clusterv1.ConditionSeverityWarning, "")
+
+	tests := []struct {
+		name           string
+		kcp            *controlplanev1.KubeadmControlPlane
+		machines       []*clusterv1.Machine
+		memberVersions map[string]string
+		wantWarning    bool
+	}{
+		{
+			name:           "members running the same version",
+			kcp:            &controlplanev1.KubeadmControlPlane{},
+			machines:       []*clusterv1.Machine{oldMachine},
+			memberVersions: map[string]string{"n1": "3.5.1", "n2": "3.5.1"},
+			wantWarning:    false,
+		},
+		{
+			name:           "members with unknown version",
+			kcp:            &controlplanev1.KubeadmControlPlane{},
+			machines:       []*clusterv1.Machine{oldMachine},
+			memberVersions: map[string]string{"n1": "3.5.1", "n2": ""},
+			wantWarning:    false,
+		},
+		{
+			name:           "members running different versions during a rolling upgrade",
+			kcp:            rollingUpdateKCP,
+			machines:       []*clusterv1.Machine{oldMachine},
+			memberVersions: map[string]string{"n1": "3.5.1", "n2": "3.4.13"},
+			wantWarning:    false,
+		},
+		{
+			name:           "members running different versions shortly after a machine has been created",
+			kcp:            &controlplanev1.KubeadmControlPlane{},
+			machines:       []*clusterv1.Machine{oldMachine, newMachine},
+			memberVersions: map[string]string{"n1": "3.5.1", "n2": "3.4.13"},
+			wantWarning:    false,
+		},
+		{
+			name:           "members running different versions without a rolling upgrade in progress",
+			kcp:            &controlplanev1.KubeadmControlPlane{},
+			machines:       []*clusterv1.Machine{oldMachine},
+			memberVersions: map[string]string{"n1": "3.5.1", "n2": "3.4.13"},
+			wantWarning:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			controlPlane := &ControlPlane{
+				KCP:      tt.kcp,
+				Machines: collections.FromMachines(tt.machines...),
+			}
+			warning := etcdVersionSkewWarning(controlPlane, tt.memberVersions, now)
+			if tt.wantWarning {
+				g.Expect(warning).ToNot(BeEmpty())
+				return
+			}
+			g.Expect(warning).To(BeEmpty())
 		})
 	}
 }
@@ -984,6 +1799,9 @@ func fakeNode(name string, options ...fakeNodeOption) *corev1.Node {
 				labelNodeRoleControlPlane: "",
 			},
 		},
+		Spec: corev1.NodeSpec{
+			ProviderID: "test://" + name,
+		},
 	}
 	for _, opt := range options {
 		opt(p)
@@ -991,6 +1809,18 @@ func fakeNode(name string, options ...fakeNodeOption) *corev1.Node {
 	return p
 }
 
+func withNodeAddress(address string) fakeNodeOption {
+	return func(node *corev1.Node) {
+		node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: address})
+	}
+}
+
+func withCreationTimestamp(t time.Time) fakeNodeOption {
+	return func(node *corev1.Node) {
+		node.CreationTimestamp = metav1.NewTime(t)
+	}
+}
+
 func withUnreachableTaint() fakeNodeOption {
 	return func(node *corev1.Node) {
 		node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{
@@ -1000,6 +1830,12 @@ func withUnreachableTaint() fakeNodeOption {
 	}
 }
 
+func withoutProviderID() fakeNodeOption {
+	return func(node *corev1.Node) {
+		node.Spec.ProviderID = ""
+	}
+}
+
 func withReadyCondition(status corev1.ConditionStatus) fakeNodeOption {
 	return func(node *corev1.Node) {
 		node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{
@@ -154,7 +154,7 @@ func (w *Workload) UpdateCoreDNS(ctx context.Context, kcp *controlplanev1.Kubead
 // getCoreDNSInfo returns all necessary coredns based information.
 func (w *Workload) getCoreDNSInfo(ctx context.Context, clusterConfig *bootstrapv1.ClusterConfiguration) (*coreDNSInfo, error) {
 	// Get the coredns configmap and corefile.
-	key := ctrlclient.ObjectKey{Name: coreDNSKey, Namespace: metav1.NamespaceSystem}
+	key := ctrlclient.ObjectKey{Name: coreDNSKey, Namespace: w.namespace()}
 	cm, err := w.getConfigMap(ctx, key)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error getting %v config map from target cluster", key)
@@ -273,7 +273,7 @@ func (w *Workload) updateCoreDNSClusterRole(ctx context.Context, kubernetesVersi
 		return nil
 	}
 
-	key := ctrlclient.ObjectKey{Name: coreDNSClusterRoleName, Namespace: metav1.NamespaceSystem}
+	key := ctrlclient.ObjectKey{Name: coreDNSClusterRoleName, Namespace: w.namespace()}
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
 		currentClusterRole := &rbacv1.ClusterRole{}
 		if err := w.Client.Get(ctx, key, currentClusterRole); err != nil {
@@ -334,7 +334,7 @@ func (w *Workload) updateCoreDNSCorefile(ctx context.Context, info *coreDNSInfo)
 	if err := w.Client.Update(ctx, &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      coreDNSKey,
-			Namespace: metav1.NamespaceSystem,
+			Namespace: w.namespace(),
 		},
 		Data: map[string]string{
 			corefileKey:       info.Corefile,
@@ -358,7 +358,7 @@ func (w *Workload) updateCoreDNSCorefile(ctx context.Context, info *coreDNSInfo)
 	if err := w.Client.Update(ctx, &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      coreDNSKey,
-			Namespace: metav1.NamespaceSystem,
+			Namespace: w.namespace(),
 		},
 		Data: map[string]string{
 			corefileKey:       updatedCorefile,
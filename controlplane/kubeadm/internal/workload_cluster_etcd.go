@@ -18,10 +18,14 @@ package internal
 
 import (
 	"context"
+	"strings"
 
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
@@ -32,6 +36,7 @@ import (
 type etcdClientFor interface {
 	forFirstAvailableNode(ctx context.Context, nodeNames []string) (*etcd.Client, error)
 	forLeader(ctx context.Context, nodeNames []string) (*etcd.Client, error)
+	refreshClientCert() error
 }
 
 // ReconcileEtcdMembers iterates over all etcd members and finds members that do not have corresponding nodes.
@@ -155,6 +160,7 @@ func (w *Workload) removeMemberForNode(ctx context.Context, name string) error {
 		return nil
 	}
 
+	defer w.etcdMembersChanged()
 	if err := etcdClient.RemoveMember(ctx, member.ID); err != nil {
 		return errors.Wrap(err, "failed to remove member from etcd")
 	}
@@ -210,13 +216,225 @@ func (w *Workload) ForwardEtcdLeadership(ctx context.Context, machine *clusterv1
 	return nil
 }
 
+// SafelyRemoveEtcdMemberForMachine removes the etcd member hosted on the machine's node from the target cluster's
+// etcd cluster, taking care of all the steps required before deleting a control plane machine:
+// - if the member is the etcd leader, leadership is forwarded to a healthy member first;
+// - the member is removed only if the remaining members would still have quorum, otherwise ErrEtcdQuorumWouldBeLost is returned;
+// - after the removal, the remaining members are checked to still be able to serve requests.
+//
+// NOTE: Member health is assessed by connecting to each of the etcd members, so this does not depend on
+// the conditions on the control plane machines being up to date.
+func (w *Workload) SafelyRemoveEtcdMemberForMachine(ctx context.Context, machine *clusterv1.Machine) error {
+	if machine == nil || machine.Status.NodeRef == nil {
+		// Nothing to do, no node for Machine
+		return nil
+	}
+	nodeName := machine.Status.NodeRef.Name
+
+	controlPlaneNodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return err
+	}
+	if len(controlPlaneNodes.Items) < 2 {
+		return ErrControlPlaneMinNodes
+	}
+
+	// Connect to each etcd member to assess its health; members that can't be reached, or that are not
+	// able to list the members of the cluster, are considered unhealthy.
+	var allClients []*etcd.Client
+	defer func() {
+		for _, c := range allClients {
+			c.Close()
+		}
+	}()
+	clients := map[string]*etcd.Client{}
+	var members []*etcd.Member
+	for _, n := range controlPlaneNodes.Items {
+		c, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, []string{n.Name})
+		if err != nil {
+			continue
+		}
+		allClients = append(allClients, c)
+
+		m, err := c.Members(ctx)
+		if err != nil || len(c.Errors) > 0 {
+			continue
+		}
+		clients[n.Name] = c
+		if members == nil {
+			members = m
+		}
+	}
+	if members == nil {
+		return errors.New("failed to list etcd members: none of the etcd members is healthy")
+	}
+
+	member := etcdutil.MemberForName(members, nodeName)
+
+	// The member has already been removed, return immediately
+	if member == nil {
+		return nil
+	}
+
+	// Project the etcd cluster after the member is removed, and check there is still a majority of healthy voting members;
+	// learners are not considered, given that they don't vote.
+	var healthyRemainingMembers []*etcd.Member
+	for _, m := range members {
+		if m.ID == member.ID || m.IsLearner {
+			continue
+		}
+		if _, ok := clients[m.Name]; ok {
+			healthyRemainingMembers = append(healthyRemainingMembers, m)
+		}
+	}
+	remainingVotingMembers := etcdutil.VotingMemberCount(members)
+	if !member.IsLearner {
+		remainingVotingMembers--
+	}
+	targetQuorum := etcdutil.EtcdQuorum(remainingVotingMembers)
+	if len(healthyRemainingMembers) < targetQuorum {
+		return errors.Wrapf(ErrEtcdQuorumWouldBeLost, "%d healthy voting members would remain out of %d, while %d are required", len(healthyRemainingMembers), remainingVotingMembers, targetQuorum)
+	}
+	remainingClient := clients[healthyRemainingMembers[0].Name]
+
+	// If the member is the etcd leader, move leadership to a healthy member before removing it.
+	if remainingClient.LeaderID == member.ID {
+		leaderClient, ok := clients[nodeName]
+		if !ok {
+			return errors.Errorf("failed to move etcd leadership: the leader %s is not reachable", nodeName)
+		}
+		if err := leaderClient.MoveLeader(ctx, healthyRemainingMembers[0].ID); err != nil {
+			return errors.Wrapf(err, "failed to move leader")
+		}
+	}
+
+	defer w.etcdMembersChanged()
+	if err := remainingClient.RemoveMember(ctx, member.ID); err != nil {
+		return errors.Wrap(err, "failed to remove member from etcd")
+	}
+
+	// Check the remaining members are still able to serve requests; listing members goes through consensus.
+	if _, err := remainingClient.Members(ctx); err != nil {
+		return errors.Wrap(err, "etcd cluster is not healthy after removing member")
+	}
+	return nil
+}
+
+// EnsureEtcdMemberForNode re-creates the etcd member for a control plane node whose member has been removed, e.g.
+// accidentally, while the node is still there; this is the inverse of removeMemberForNode. The member is re-added as
+// a learner using the peer URL the etcd pod on the node advertises, and it is promoted to voting member once it is
+// in sync with the leader; until then ErrEtcdMemberPromotionPending is returned, and callers are expected to call
+// this again later. It is a no-op if the node already has a voting member.
+// NOTE: The etcd instance on the node still has the data directory of the removed member, which must be wiped for
+// it to join the cluster again as the new member, e.g. by restarting it after moving the data directory away.
+func (w *Workload) EnsureEtcdMemberForNode(ctx context.Context, nodeName string) error {
+	controlPlaneNodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Exclude the node from the etcd client node list, given that its member might be missing.
+	var otherNodes []string
+	for _, n := range controlPlaneNodes.Items {
+		if n.Name != nodeName {
+			otherNodes = append(otherNodes, n.Name)
+		}
+	}
+	if len(otherNodes) == 0 {
+		return errors.Errorf("failed to re-create the etcd member for node %s: no other control plane node to connect to etcd", nodeName)
+	}
+	etcdClient, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, otherNodes)
+	if err != nil {
+		return errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	// List etcd members. This checks that the cluster is healthy, because the request goes through consensus.
+	members, err := etcdClient.Members(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list etcd members using etcd client")
+	}
+
+	member := etcdutil.MemberForName(members, nodeName)
+	if member == nil {
+		// The etcd pod is created by kubeadm only after adding the member while joining the node, so it not being
+		// there means that the node did not join yet, and the member must not be added on its behalf.
+		peerURL, err := w.etcdPeerURLForNode(ctx, nodeName)
+		if err != nil {
+			return err
+		}
+
+		// NOTE: A re-added member has an empty name until it starts, so it is also looked up by its peer URL.
+		if member = memberForPeerURL(members, peerURL); member == nil {
+			defer w.etcdMembersChanged()
+			if member, err = etcdClient.AddLearnerMember(ctx, []string{peerURL}); err != nil {
+				return err
+			}
+		}
+	}
+	if !member.IsLearner {
+		return nil
+	}
+
+	defer w.etcdMembersChanged()
+	if err := etcdClient.PromoteMember(ctx, member.ID); err != nil {
+		if errors.Is(err, rpctypes.ErrMemberLearnerNotReady) {
+			return errors.Wrapf(ErrEtcdMemberPromotionPending, "etcd member for node %s", nodeName)
+		}
+		return err
+	}
+	return nil
+}
+
+// etcdPeerURLForNode returns the peer URL advertised by the etcd pod hosted on a node.
+func (w *Workload) etcdPeerURLForNode(ctx context.Context, nodeName string) (string, error) {
+	podKey := ctrlclient.ObjectKey{
+		Namespace: w.namespace(),
+		Name:      staticPodName("etcd", nodeName),
+	}
+	pod := &corev1.Pod{}
+	if err := w.Client.Get(ctx, podKey, pod); err != nil {
+		return "", errors.Wrapf(err, "failed to get etcd pod for node %s", nodeName)
+	}
+
+	const peerURLsFlag = "--initial-advertise-peer-urls="
+	for _, container := range pod.Spec.Containers {
+		for _, arg := range append(container.Command, container.Args...) {
+			if strings.HasPrefix(arg, peerURLsFlag) {
+				return strings.Split(strings.TrimPrefix(arg, peerURLsFlag), ",")[0], nil
+			}
+		}
+	}
+	return "", errors.Errorf("etcd pod %s does not advertise a peer URL", podKey.Name)
+}
+
+// memberForPeerURL returns the etcd member with the given peer URL, if any.
+func memberForPeerURL(members []*etcd.Member, peerURL string) *etcd.Member {
+	for _, m := range members {
+		for _, u := range m.PeerURLs {
+			if u == peerURL {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// RefreshEtcdClientBundle regenerates the client certificate used for connecting to etcd.
+// NOTE: The client certificate is regenerated automatically when near expiry before connecting to etcd;
+// this allows callers holding a workload cluster during long running operations to force a refresh.
+func (w *Workload) RefreshEtcdClientBundle() error {
+	return w.etcdClientGenerator.refreshClientCert()
+}
+
 // EtcdMemberStatus contains status information for a single etcd member.
 type EtcdMemberStatus struct {
 	Name       string
 	Responsive bool
 }
 
-// EtcdMembers returns the current set of members in an etcd cluster.
+// EtcdMembers returns the current set of voting members in an etcd cluster; learners are not included,
+// given that they don't vote and therefore don't count for quorum.
 //
 // NOTE: This methods uses control plane machines/nodes only to get in contact with etcd,
 // but then it relies on etcd as ultimate source of truth for the list of members.
@@ -243,7 +461,144 @@ func (w *Workload) EtcdMembers(ctx context.Context) ([]string, error) {
 
 	names := []string{}
 	for _, member := range members {
+		if member.IsLearner {
+			continue
+		}
 		names = append(names, member.Name)
 	}
 	return names, nil
 }
+
+// EtcdQuorumIsHealthy checks that the etcd cluster has a leader and a quorum of voting members, connecting only to the
+// first reachable etcd member; unlike UpdateEtcdConditions, alarms, learners, the consistency of the members and the
+// correspondence between members and machines are not checked.
+// NOTE: Listing members is a linearizable request, so it succeeds only if the etcd cluster has quorum.
+func (w *Workload) EtcdQuorumIsHealthy(ctx context.Context) error {
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return &etcdHealthUnknownError{Err: errors.Wrap(err, "failed to list control plane nodes")}
+	}
+	nodeNames := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	if len(nodeNames) == 0 {
+		return errors.New("etcd cluster is not healthy: there are no control plane nodes hosting etcd members")
+	}
+
+	etcdClient, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, nodeNames)
+	if err != nil {
+		return errors.Wrap(err, "etcd cluster is not healthy: failed to connect to any etcd member")
+	}
+	defer etcdClient.Close()
+
+	members, err := etcdClient.Members(ctx)
+	if err != nil {
+		return errors.Wrap(err, "etcd cluster is not healthy: failed to list etcd members")
+	}
+
+	// Check the leader is a known voting member.
+	leader := etcdutil.MemberForID(members, etcdClient.LeaderID)
+	if leader == nil || leader.IsLearner {
+		return errors.New("etcd cluster is not healthy: etcd cluster does not have a leader")
+	}
+
+	// Check a quorum of the voting members is started; members which are not started yet have no name.
+	votingMembers, startedVotingMembers := 0, 0
+	for _, member := range members {
+		if member.IsLearner {
+			continue
+		}
+		votingMembers++
+		if member.Name != "" {
+			startedVotingMembers++
+		}
+	}
+	if quorum := etcdutil.EtcdQuorum(votingMembers); startedVotingMembers < quorum {
+		return errors.Errorf("etcd cluster is not healthy: %d of %d voting members are started, below the quorum of %d", startedVotingMembers, votingMembers, quorum)
+	}
+	return nil
+}
+
+// EtcdState is a snapshot of the state of an etcd cluster, meant to be included in bug reports and support cases.
+type EtcdState struct {
+	// Members is the list of etcd members, including learners and the alarms raised for each member,
+	// as reported by the first reachable member; it is nil if no etcd member could be reached.
+	Members []*etcd.Member `json:"members,omitempty"`
+
+	// LeaderID is the ID of the etcd leader, as reported by the first reachable member.
+	LeaderID uint64 `json:"leaderID,omitempty"`
+
+	// Endpoints contains the status of the etcd member hosted on each control plane node, keyed by node name.
+	Endpoints map[string]EtcdEndpointState `json:"endpoints,omitempty"`
+}
+
+// EtcdEndpointState is the status of the etcd member hosted on a control plane node.
+type EtcdEndpointState struct {
+	// Version is the version of etcd running on the member.
+	Version string `json:"version,omitempty"`
+
+	// LeaderID is the ID of the etcd leader, as seen by the member.
+	LeaderID uint64 `json:"leaderID,omitempty"`
+
+	// DBSize is the size of the backend database physically allocated, in bytes.
+	DBSize int64 `json:"dbSize,omitempty"`
+
+	// DBSizeInUse is the size of the backend database logically in use, in bytes.
+	DBSizeInUse int64 `json:"dbSizeInUse,omitempty"`
+
+	// Errors is the list of errors reported by the member status.
+	Errors []string `json:"errors,omitempty"`
+
+	// Error is the error which occurred while inspecting the member, e.g. connecting to it; it is empty on success.
+	Error string `json:"error,omitempty"`
+}
+
+// EtcdState returns a snapshot of the state of the etcd cluster, connecting to the etcd member hosted on each
+// control plane node in turn; this is best effort, in the sense that members which can't be reached are reported
+// in the snapshot instead of failing.
+// The list of members is read with serializable consistency, so it is available even if etcd lost quorum, while
+// all the other reads of the list of members, e.g. for health checks and membership changes, are linearizable.
+// NOTE: This is a diagnostic aid; the snapshot is not meant to be used for taking decisions, see UpdateEtcdConditions.
+func (w *Workload) EtcdState(ctx context.Context) (*EtcdState, error) {
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list control plane nodes")
+	}
+
+	state := &EtcdState{Endpoints: map[string]EtcdEndpointState{}}
+	for _, node := range nodes.Items {
+		state.Endpoints[node.Name] = w.etcdEndpointState(ctx, node.Name, state)
+	}
+	return state, nil
+}
+
+// etcdEndpointState returns the status of the etcd member hosted on the given node; additionally, if the list
+// of members is not yet known, it sets the members and the leader reported by this member on the etcd state.
+func (w *Workload) etcdEndpointState(ctx context.Context, nodeName string, state *EtcdState) EtcdEndpointState {
+	etcdClient, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, []string{nodeName})
+	if err != nil {
+		return EtcdEndpointState{Error: errors.Wrapf(err, "failed to connect to the etcd pod on the %s node", nodeName).Error()}
+	}
+	defer etcdClient.Close()
+
+	endpointState := EtcdEndpointState{
+		Version:     etcdClient.Version,
+		LeaderID:    etcdClient.LeaderID,
+		DBSize:      etcdClient.DBSize,
+		DBSizeInUse: etcdClient.DBSizeInUse,
+		Errors:      etcdClient.Errors,
+	}
+	if state.Members != nil {
+		return endpointState
+	}
+
+	members, err := etcdClient.MembersWithConsistency(ctx, etcd.MemberListSerializable)
+	if err != nil {
+		endpointState.Error = err.Error()
+		return endpointState
+	}
+	state.Members = members
+	state.LeaderID = etcdClient.LeaderID
+	return endpointState
+}
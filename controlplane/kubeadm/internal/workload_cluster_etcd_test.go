@@ -25,6 +25,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	. "github.com/onsi/gomega"
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -32,8 +33,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd"
 	fake2 "sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd/fake"
+	"sigs.k8s.io/cluster-api/util/collections"
 	"sigs.k8s.io/cluster-api/util/yaml"
 )
 
@@ -336,6 +339,333 @@ func TestRemoveEtcdMemberForMachine(t *testing.T) {
 	}
 }
 
+func TestSafelyRemoveEtcdMemberForMachine(t *testing.T) {
+	machine := &clusterv1.Machine{
+		Status: clusterv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{
+				Name: "cp1",
+			},
+		},
+	}
+	nodes := []client.Object{}
+	for _, name := range []string{"cp1", "cp2", "cp3"} {
+		nodes = append(nodes, &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{labelNodeRoleControlPlane: ""},
+			},
+		})
+	}
+	allMembers := []*pb.Member{
+		{Name: "cp1", ID: uint64(1)},
+		{Name: "cp2", ID: uint64(2)},
+		{Name: "cp3", ID: uint64(3)},
+	}
+	nodesWithLearner := append([]client.Object{}, nodes...)
+	nodesWithLearner = append(nodesWithLearner, &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "cp4",
+			Labels: map[string]string{labelNodeRoleControlPlane: ""},
+		},
+	})
+	allMembersWithLearner := append([]*pb.Member{}, allMembers...)
+	allMembersWithLearner = append(allMembersWithLearner, &pb.Member{Name: "cp4", ID: uint64(4), IsLearner: true})
+	learnerMachine := &clusterv1.Machine{
+		Status: clusterv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{
+				Name: "cp4",
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		machine *clusterv1.Machine
+		objs    []client.Object
+		members []*pb.Member
+		// leaderID is the etcd leader, as reported by all the etcd members.
+		leaderID uint64
+		// unreachable are the nodes hosting etcd members that can't be connected to.
+		unreachable []string
+		// withErrors are the nodes hosting etcd members reporting errors.
+		withErrors        []string
+		expectErr         error
+		expectMovedLeader uint64
+		expectRemoved     uint64
+	}{
+		{
+			name:    "does nothing if the machine has no node",
+			machine: &clusterv1.Machine{},
+			objs:    nodes,
+			members: allMembers,
+		},
+		{
+			name:      "returns an error if there are less than 2 control plane nodes",
+			machine:   machine,
+			objs:      nodes[:1],
+			members:   allMembers,
+			expectErr: ErrControlPlaneMinNodes,
+		},
+		{
+			name:     "does nothing if the member has already been removed",
+			machine:  machine,
+			objs:     nodes,
+			members:  allMembers[1:],
+			leaderID: 2,
+		},
+		{
+			name:          "removes a member which is not the leader",
+			machine:       machine,
+			objs:          nodes,
+			members:       allMembers,
+			leaderID:      2,
+			expectRemoved: 1,
+		},
+		{
+			name:              "forwards leadership before removing a member which is the leader",
+			machine:           machine,
+			objs:              nodes,
+			members:           allMembers,
+			leaderID:          1,
+			expectMovedLeader: 2,
+			expectRemoved:     1,
+		},
+		{
+			name:          "removes a member if the remaining members have quorum",
+			machine:       machine,
+			objs:          nodes,
+			members:       allMembers,
+			leaderID:      2,
+			unreachable:   []string{"cp1"},
+			expectRemoved: 1,
+		},
+		{
+			name:        "does not remove a member if a remaining member is unreachable",
+			machine:     machine,
+			objs:        nodes,
+			members:     allMembers,
+			leaderID:    2,
+			unreachable: []string{"cp3"},
+			expectErr:   ErrEtcdQuorumWouldBeLost,
+		},
+		{
+			name:       "does not remove a member if a remaining member is reporting errors",
+			machine:    machine,
+			objs:       nodes,
+			members:    allMembers,
+			leaderID:   2,
+			withErrors: []string{"cp3"},
+			expectErr:  ErrEtcdQuorumWouldBeLost,
+		},
+		{
+			name:        "does not count learners as healthy members when assessing quorum",
+			machine:     machine,
+			objs:        nodesWithLearner,
+			members:     allMembersWithLearner,
+			leaderID:    2,
+			unreachable: []string{"cp3"},
+			expectErr:   ErrEtcdQuorumWouldBeLost,
+		},
+		{
+			name:          "removes a learner if the voting members have quorum",
+			machine:       learnerMachine,
+			objs:          nodesWithLearner,
+			members:       allMembersWithLearner,
+			leaderID:      2,
+			unreachable:   []string{"cp3"},
+			expectRemoved: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			fakeEtcdClients := map[string]*fake2.FakeEtcdClient{}
+			etcdClientGenerator := &fakeEtcdClientGenerator{
+				forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+					name := n[0]
+					for _, u := range tt.unreachable {
+						if u == name {
+							return nil, errors.New("no client")
+						}
+					}
+					fakeEtcdClients[name] = &fake2.FakeEtcdClient{
+						MemberListResponse: &clientv3.MemberListResponse{Members: tt.members},
+						AlarmResponse:      &clientv3.AlarmResponse{},
+					}
+					c := &etcd.Client{EtcdClient: fakeEtcdClients[name], LeaderID: tt.leaderID}
+					for _, e := range tt.withErrors {
+						if e == name {
+							c.Errors = []string{"etcd member is unhealthy"}
+						}
+					}
+					return c, nil
+				},
+			}
+
+			var membersChanged bool
+			w := &Workload{
+				Client:               fake.NewClientBuilder().WithObjects(tt.objs...).Build(),
+				etcdClientGenerator:  etcdClientGenerator,
+				onEtcdMembersChanged: func() { membersChanged = true },
+			}
+			err := w.SafelyRemoveEtcdMemberForMachine(ctx, tt.machine)
+			if tt.expectErr != nil {
+				g.Expect(errors.Is(err, tt.expectErr)).To(BeTrue(), "unexpected error: %v", err)
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+
+			var movedLeader, removed uint64
+			for _, c := range fakeEtcdClients {
+				if c.MovedLeader != 0 {
+					movedLeader = c.MovedLeader
+				}
+				if c.RemovedMember != 0 {
+					removed = c.RemovedMember
+				}
+			}
+			g.Expect(movedLeader).To(Equal(tt.expectMovedLeader))
+			g.Expect(removed).To(Equal(tt.expectRemoved))
+			g.Expect(membersChanged).To(Equal(removed != 0))
+		})
+	}
+}
+
+func TestEnsureEtcdMemberForNode(t *testing.T) {
+	controlPlaneNode := func(name string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{labelNodeRoleControlPlane: ""},
+			},
+		}
+	}
+	etcdPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "etcd-cp1",
+			Namespace: metav1.NamespaceSystem,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:    "etcd",
+				Command: []string{"etcd", "--name=cp1", "--initial-advertise-peer-urls=https://10.0.0.1:2380"},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name                    string
+		objs                    []client.Object
+		members                 []*pb.Member
+		memberPromoteError      error
+		expectErr               error
+		expectAnyErr            bool
+		expectAddedLearnerPeers []string
+		expectPromotedMember    uint64
+		expectMembersChanged    bool
+	}{
+		{
+			name: "does nothing if the node has a voting member",
+			objs: []client.Object{controlPlaneNode("cp1"), controlPlaneNode("cp2")},
+			members: []*pb.Member{
+				{Name: "cp1", ID: uint64(1)},
+				{Name: "cp2", ID: uint64(2)},
+			},
+		},
+		{
+			name: "returns an error if there is no other control plane node to connect to etcd",
+			objs: []client.Object{controlPlaneNode("cp1"), etcdPod},
+			members: []*pb.Member{
+				{Name: "cp2", ID: uint64(2)},
+			},
+			expectAnyErr: true,
+		},
+		{
+			name: "does not add the member if the node has no etcd pod, e.g. because it did not join yet",
+			objs: []client.Object{controlPlaneNode("cp1"), controlPlaneNode("cp2")},
+			members: []*pb.Member{
+				{Name: "cp2", ID: uint64(2)},
+			},
+			expectAnyErr: true,
+		},
+		{
+			name: "re-adds the missing member as a learner and waits for it to be in sync",
+			objs: []client.Object{controlPlaneNode("cp1"), controlPlaneNode("cp2"), etcdPod},
+			members: []*pb.Member{
+				{Name: "cp2", ID: uint64(2)},
+			},
+			memberPromoteError:      rpctypes.ErrMemberLearnerNotReady,
+			expectErr:               ErrEtcdMemberPromotionPending,
+			expectAddedLearnerPeers: []string{"https://10.0.0.1:2380"},
+			expectMembersChanged:    true,
+		},
+		{
+			name: "promotes the re-added member once it is in sync",
+			objs: []client.Object{controlPlaneNode("cp1"), controlPlaneNode("cp2"), etcdPod},
+			members: []*pb.Member{
+				{Name: "cp2", ID: uint64(2)},
+				{ID: uint64(3), PeerURLs: []string{"https://10.0.0.1:2380"}, IsLearner: true},
+			},
+			expectPromotedMember: uint64(3),
+			expectMembersChanged: true,
+		},
+		{
+			name: "returns an error if promoting the member fails",
+			objs: []client.Object{controlPlaneNode("cp1"), controlPlaneNode("cp2"), etcdPod},
+			members: []*pb.Member{
+				{Name: "cp1", ID: uint64(3), PeerURLs: []string{"https://10.0.0.1:2380"}, IsLearner: true},
+				{Name: "cp2", ID: uint64(2)},
+			},
+			memberPromoteError:   errors.New("cannot promote etcd member"),
+			expectAnyErr:         true,
+			expectMembersChanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			fakeEtcdClient := &fake2.FakeEtcdClient{
+				MemberListResponse: &clientv3.MemberListResponse{Members: tt.members},
+				AlarmResponse:      &clientv3.AlarmResponse{},
+				MemberAddResponse: &clientv3.MemberAddResponse{
+					Member: &pb.Member{ID: uint64(3), PeerURLs: []string{"https://10.0.0.1:2380"}, IsLearner: true},
+				},
+				MemberPromoteError: tt.memberPromoteError,
+			}
+			var etcdClientNodes []string
+			var membersChanged bool
+			w := &Workload{
+				Client: fake.NewClientBuilder().WithObjects(tt.objs...).Build(),
+				etcdClientGenerator: &fakeEtcdClientGenerator{
+					forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+						etcdClientNodes = n
+						return &etcd.Client{EtcdClient: fakeEtcdClient}, nil
+					},
+				},
+				onEtcdMembersChanged: func() { membersChanged = true },
+			}
+
+			err := w.EnsureEtcdMemberForNode(ctx, "cp1")
+			switch {
+			case tt.expectErr != nil:
+				g.Expect(errors.Is(err, tt.expectErr)).To(BeTrue(), "unexpected error: %v", err)
+			case tt.expectAnyErr:
+				g.Expect(err).To(HaveOccurred())
+			default:
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(etcdClientNodes).ToNot(ContainElement("cp1"))
+			g.Expect(fakeEtcdClient.AddedLearnerPeerURLs).To(Equal(tt.expectAddedLearnerPeers))
+			g.Expect(fakeEtcdClient.PromotedMember).To(Equal(tt.expectPromotedMember))
+			g.Expect(membersChanged).To(Equal(tt.expectMembersChanged))
+		})
+	}
+}
+
 func TestForwardEtcdLeadership(t *testing.T) {
 	t.Run("handles errors correctly", func(t *testing.T) {
 		tests := []struct {
@@ -773,6 +1103,188 @@ func TestRemoveNodeFromKubeadmConfigMap(t *testing.T) {
 	}
 }
 
+func TestEtcdQuorumIsHealthy(t *testing.T) {
+	tests := []struct {
+		name      string
+		members   []*pb.Member
+		leaderID  uint64
+		expectErr string
+	}{
+		{
+			name: "etcd cluster with a leader and all the voting members started is healthy",
+			members: []*pb.Member{
+				{Name: "n1", ID: uint64(1)},
+				{Name: "n2", ID: uint64(2)},
+				{Name: "n3", ID: uint64(3)},
+			},
+			leaderID: uint64(1),
+		},
+		{
+			name: "etcd cluster with a quorum of the voting members started is healthy",
+			members: []*pb.Member{
+				{Name: "n1", ID: uint64(1)},
+				{Name: "n2", ID: uint64(2)},
+				{ID: uint64(3)},
+			},
+			leaderID: uint64(1),
+		},
+		{
+			name: "etcd cluster without a leader is not healthy",
+			members: []*pb.Member{
+				{Name: "n1", ID: uint64(1)},
+				{Name: "n2", ID: uint64(2)},
+			},
+			leaderID:  uint64(0),
+			expectErr: "does not have a leader",
+		},
+		{
+			name: "etcd cluster with less than a quorum of the voting members started is not healthy",
+			members: []*pb.Member{
+				{Name: "n1", ID: uint64(1)},
+				{ID: uint64(2)},
+				{ID: uint64(3)},
+				{Name: "n4", ID: uint64(4), IsLearner: true},
+			},
+			leaderID:  uint64(1),
+			expectErr: "1 of 3 voting members are started, below the quorum of 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			w := &Workload{
+				Client: fake.NewClientBuilder().WithObjects(fakeNode("n1")).Build(),
+				etcdClientGenerator: &fakeEtcdClientGenerator{
+					forNodesClient: &etcd.Client{
+						EtcdClient: &fake2.FakeEtcdClient{
+							EtcdEndpoints: []string{},
+							MemberListResponse: &clientv3.MemberListResponse{
+								Header:  &pb.ResponseHeader{},
+								Members: tt.members,
+							},
+							AlarmResponse: &clientv3.AlarmResponse{},
+						},
+						LeaderID: tt.leaderID,
+					},
+				},
+			}
+			err := w.EtcdQuorumIsHealthy(ctx)
+			if tt.expectErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.expectErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestEtcdQuorumIsHealthyComparedToFullCheck(t *testing.T) {
+	g := NewWithT(t)
+
+	// The etcd cluster has quorum, but the member hosted on the n2 node has raised an alarm.
+	dialedNodes := []string{}
+	w := &Workload{
+		Client: fake.NewClientBuilder().WithObjects(fakeNode("n1"), fakeNode("n2"), fakeNode("n3")).Build(),
+		etcdClientGenerator: &fakeEtcdClientGenerator{
+			forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+				dialedNodes = append(dialedNodes, n[0])
+				return &etcd.Client{
+					EtcdClient: &fake2.FakeEtcdClient{
+						EtcdEndpoints: []string{},
+						MemberListResponse: &clientv3.MemberListResponse{
+							Header: &pb.ResponseHeader{
+								ClusterId: uint64(1),
+							},
+							Members: []*pb.Member{
+								{Name: "n1", ID: uint64(1)},
+								{Name: "n2", ID: uint64(2)},
+								{Name: "n3", ID: uint64(3)},
+							},
+						},
+						AlarmResponse: &clientv3.AlarmResponse{
+							Alarms: []*pb.AlarmMember{{MemberID: uint64(2), Alarm: pb.AlarmType_NOSPACE}},
+						},
+					},
+					LeaderID: uint64(1),
+				}, nil
+			},
+		},
+	}
+
+	// The full check connects to the member hosted on each node, and it detects the alarm.
+	controlPlane := &ControlPlane{
+		KCP:      &controlplanev1.KubeadmControlPlane{},
+		Machines: collections.FromMachines(fakeMachine("m1", withNodeRef("n1")), fakeMachine("m2", withNodeRef("n2")), fakeMachine("m3", withNodeRef("n3"))),
+	}
+	w.UpdateEtcdConditions(ctx, controlPlane)
+	g.Expect(etcdHealthDetailsFromConditions(controlPlane).Cluster).To(HaveOccurred())
+	g.Expect(dialedNodes).To(HaveLen(3))
+
+	// The quorum only check connects to a single member, and it ignores the alarm.
+	dialedNodes = []string{}
+	g.Expect(w.EtcdQuorumIsHealthy(ctx)).To(Succeed())
+	g.Expect(dialedNodes).To(HaveLen(1))
+}
+
+func TestEtcdState(t *testing.T) {
+	g := NewWithT(t)
+
+	var etcdClients []*fake2.FakeEtcdClient
+	w := &Workload{
+		Client: fake.NewClientBuilder().WithObjects(fakeNode("n1"), fakeNode("n2"), fakeNode("n3")).Build(),
+		etcdClientGenerator: &fakeEtcdClientGenerator{
+			forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+				if n[0] == "n1" {
+					return nil, errors.New("connection refused")
+				}
+				etcdClient := &fake2.FakeEtcdClient{
+					EtcdEndpoints: []string{},
+					MemberListResponse: &clientv3.MemberListResponse{
+						Header: &pb.ResponseHeader{
+							ClusterId: uint64(1),
+						},
+						Members: []*pb.Member{
+							{Name: "n1", ID: uint64(1), PeerURLs: []string{"https://10.0.0.1:2380"}},
+							{Name: "n2", ID: uint64(2), PeerURLs: []string{"https://10.0.0.2:2380"}},
+							{Name: "n3", ID: uint64(3), PeerURLs: []string{"https://10.0.0.3:2380"}, IsLearner: true},
+						},
+					},
+					AlarmResponse: &clientv3.AlarmResponse{
+						Alarms: []*pb.AlarmMember{{MemberID: uint64(2), Alarm: pb.AlarmType_NOSPACE}},
+					},
+				}
+				etcdClients = append(etcdClients, etcdClient)
+				return &etcd.Client{
+					EtcdClient: etcdClient,
+					LeaderID:   uint64(2),
+					Version:    "3.5.1",
+					DBSize:     int64(1024),
+				}, nil
+			},
+		},
+	}
+	state, err := w.EtcdState(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// The members, the alarms and the leader are reported by the first reachable member.
+	g.Expect(state.LeaderID).To(Equal(uint64(2)))
+	g.Expect(state.Members).To(HaveLen(3))
+	g.Expect(state.Members[1].Alarms).To(ConsistOf(etcd.AlarmNoSpace))
+	g.Expect(state.Members[2].IsLearner).To(BeTrue())
+	g.Expect(state.Members[2].PeerURLs).To(ConsistOf("https://10.0.0.3:2380"))
+
+	// The members are read with serializable consistency.
+	g.Expect(etcdClients).ToNot(BeEmpty())
+	g.Expect(etcdClients[0].MemberListSerializable).To(BeTrue())
+
+	// The status of each member is reported, including the members which can't be reached.
+	g.Expect(state.Endpoints).To(HaveLen(3))
+	g.Expect(state.Endpoints["n1"].Error).To(ContainSubstring("connection refused"))
+	g.Expect(state.Endpoints["n2"]).To(Equal(EtcdEndpointState{Version: "3.5.1", LeaderID: uint64(2), DBSize: int64(1024)}))
+	g.Expect(state.Endpoints["n3"].Error).To(BeEmpty())
+}
+
 type fakeEtcdClientGenerator struct {
 	forNodesClient     *etcd.Client
 	forNodesClientFunc func([]string) (*etcd.Client, error)
@@ -792,6 +1304,10 @@ func (c *fakeEtcdClientGenerator) forLeader(_ context.Context, _ []string) (*etc
 	return c.forLeaderClient, c.forLeaderErr
 }
 
+func (c *fakeEtcdClientGenerator) refreshClientCert() error {
+	return nil
+}
+
 func defaultMachine(transforms ...func(m *clusterv1.Machine)) *clusterv1.Machine {
 	m := &clusterv1.Machine{
 		Status: clusterv1.MachineStatus{
@@ -71,7 +71,7 @@ func (w *Workload) AllowBootstrapTokensToGetNodes(ctx context.Context) error {
 	if err := w.EnsureResource(ctx, &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      GetNodesClusterRoleName,
-			Namespace: metav1.NamespaceSystem,
+			Namespace: w.namespace(),
 		},
 		Rules: []rbacv1.PolicyRule{
 			{
@@ -87,7 +87,7 @@ func (w *Workload) AllowBootstrapTokensToGetNodes(ctx context.Context) error {
 	return w.EnsureResource(ctx, &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      GetNodesClusterRoleName,
-			Namespace: metav1.NamespaceSystem,
+			Namespace: w.namespace(),
 		},
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: rbacv1.GroupName,
@@ -121,7 +121,7 @@ func (w *Workload) ReconcileKubeletRBACBinding(ctx context.Context, version semv
 	roleName := generateKubeletConfigRoleName(version)
 	return w.EnsureResource(ctx, &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: metav1.NamespaceSystem,
+			Namespace: w.namespace(),
 			Name:      roleName,
 		},
 		Subjects: []rbacv1.Subject{
@@ -150,7 +150,7 @@ func (w *Workload) ReconcileKubeletRBACRole(ctx context.Context, version semver.
 	return w.EnsureResource(ctx, &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      generateKubeletConfigRoleName(version),
-			Namespace: metav1.NamespaceSystem,
+			Namespace: w.namespace(),
 		},
 		Rules: []rbacv1.PolicyRule{
 			{
@@ -39,9 +39,10 @@ import (
 
 func TestGetControlPlaneNodes(t *testing.T) {
 	tests := []struct {
-		name          string
-		nodes         []corev1.Node
-		expectedNodes []string
+		name                   string
+		controlPlaneNodeLabels []string
+		nodes                  []corev1.Node
+		expectedNodes          []string
 	}{
 		{
 			name: "Return control plane nodes",
@@ -84,6 +85,31 @@ func TestGetControlPlaneNodes(t *testing.T) {
 				"control-plane-node-with-new-label",
 			},
 		},
+		{
+			name:                   "Return control plane nodes using custom labels",
+			controlPlaneNodeLabels: []string{"example.com/control-plane"},
+			nodes: []corev1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "control-plane-node-with-new-label",
+						Labels: map[string]string{
+							labelNodeRoleControlPlane: "",
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "control-plane-node-with-custom-label",
+						Labels: map[string]string{
+							"example.com/control-plane": "",
+						},
+					},
+				},
+			},
+			expectedNodes: []string{
+				"control-plane-node-with-custom-label",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -96,7 +122,8 @@ func TestGetControlPlaneNodes(t *testing.T) {
 			fakeClient := fake.NewClientBuilder().WithObjects(objs...).Build()
 
 			w := &Workload{
-				Client: fakeClient,
+				Client:                 fakeClient,
+				controlPlaneNodeLabels: tt.controlPlaneNodeLabels,
 			}
 			nodes, err := w.getControlPlaneNodes(ctx)
 			g.Expect(err).ToNot(HaveOccurred())
@@ -31,6 +31,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
@@ -88,6 +89,18 @@ var (
 	webhookCertDir                 string
 	healthAddr                     string
 	etcdDialTimeout                time.Duration
+	etcdNamespace                  string
+	etcdConnectionStrategy         string
+	etcdDBSizeWarningThreshold     int
+	etcdDialRetries                int
+	etcdLearnerPromotionTimeout    time.Duration
+	etcdMemberConsistencyMode      string
+	etcdHealthCacheTTL             time.Duration
+	etcdClientCertCommonName       string
+	etcdClientCertOrganization     []string
+	includeUnlabeledMachines       bool
+	backfillClusterNameLabel       bool
+	recreateMissingEtcdMembers     bool
 	logOptions                     = logs.NewOptions()
 )
 
@@ -138,6 +151,42 @@ func InitFlags(fs *pflag.FlagSet) {
 	fs.DurationVar(&etcdDialTimeout, "etcd-dial-timeout-duration", 10*time.Second,
 		"Duration that the etcd client waits at most to establish a connection with etcd")
 
+	fs.StringVar(&etcdNamespace, "etcd-namespace", metav1.NamespaceSystem,
+		"Namespace where the etcd pods are running in the workload clusters.")
+
+	fs.StringVar(&etcdConnectionStrategy, "etcd-connection-strategy", "ProxyViaPod",
+		"How to connect to the etcd members of the workload clusters, either ProxyViaPod (port-forwarding via the workload cluster API server), DirectToNode (dialing the InternalIP of the control plane nodes, which requires network reachability to the etcd client port) or DirectToEndpoints (dialing the DNS names listed in the controlplane.cluster.x-k8s.io/etcd-endpoints annotation of the KubeadmControlPlane).")
+
+	fs.IntVar(&etcdDBSizeWarningThreshold, "etcd-db-size-warning-threshold", 80,
+		"Percentage of the etcd backend database quota above which a warning is reported on the EtcdClusterHealthy condition. Set to 0 to disable the check.")
+
+	fs.IntVar(&etcdDialRetries, "etcd-dial-retries", 2,
+		"Number of times connecting to an etcd member is retried when checking its health, before reporting the member as unhealthy. Set to 0 to disable retries.")
+
+	fs.DurationVar(&etcdLearnerPromotionTimeout, "etcd-learner-promotion-timeout", 10*time.Minute,
+		"Duration an etcd member can be a learner before it is reported as unhealthy, given that a learner which is never promoted to voting member usually means a stuck scale up. Set to 0 to disable the check.")
+
+	fs.StringVar(&etcdMemberConsistencyMode, "etcd-member-consistency-mode", "Unanimous",
+		"How the etcd members are required to agree on the list of members for being considered consistent, either Unanimous (all the members report the same list of members) or Quorum (a quorum of the members report the same list of members, tolerating members lagging behind during membership changes).")
+
+	fs.DurationVar(&etcdHealthCacheTTL, "etcd-health-cache-ttl", 5*time.Second,
+		"Duration the result of an etcd health check for a workload cluster is re-used before checking again; the result is dropped as soon as the etcd members are changed. Set to 0 to disable caching.")
+
+	fs.StringVar(&etcdClientCertCommonName, "etcd-client-cert-common-name", "cluster-api.x-k8s.io",
+		"CommonName of the client certificate generated for connecting to the etcd members of the workload clusters.")
+
+	fs.BoolVar(&includeUnlabeledMachines, "include-unlabeled-machines", false,
+		"Also consider the machines lacking the cluster.x-k8s.io/cluster-name label whose owner chain resolves to the cluster, e.g. adopted or legacy machines.")
+
+	fs.BoolVar(&backfillClusterNameLabel, "backfill-cluster-name-label", false,
+		"Set the cluster.x-k8s.io/cluster-name label on the unlabeled machines considered because of --include-unlabeled-machines.")
+
+	fs.BoolVar(&recreateMissingEtcdMembers, "recreate-missing-etcd-members", false,
+		"Re-create the etcd member of control plane nodes whose member has been removed while the node is still there, by re-adding it as a learner and promoting it once in sync. The etcd data directory on the node must be wiped for the etcd instance to join again.")
+
+	fs.StringSliceVar(&etcdClientCertOrganization, "etcd-client-cert-organization", nil,
+		"Comma-separated list of Organizations of the client certificate generated for connecting to the etcd members of the workload clusters, e.g. to make etcd access attributable to this management cluster.")
+
 	feature.MutableGates.AddFlag(fs)
 }
 func main() {
@@ -247,11 +296,23 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) {
 	}
 
 	if err := (&kubeadmcontrolplanecontrollers.KubeadmControlPlaneReconciler{
-		Client:           mgr.GetClient(),
-		APIReader:        mgr.GetAPIReader(),
-		Tracker:          tracker,
-		WatchFilterValue: watchFilterValue,
-		EtcdDialTimeout:  etcdDialTimeout,
+		Client:                      mgr.GetClient(),
+		APIReader:                   mgr.GetAPIReader(),
+		Tracker:                     tracker,
+		WatchFilterValue:            watchFilterValue,
+		EtcdDialTimeout:             etcdDialTimeout,
+		EtcdNamespace:               etcdNamespace,
+		EtcdConnectionStrategy:      etcdConnectionStrategy,
+		EtcdDBSizeWarningThreshold:  etcdDBSizeWarningThreshold,
+		EtcdDialRetries:             etcdDialRetries,
+		EtcdLearnerPromotionTimeout: etcdLearnerPromotionTimeout,
+		EtcdMemberConsistencyMode:   etcdMemberConsistencyMode,
+		EtcdHealthCacheTTL:          etcdHealthCacheTTL,
+		EtcdClientCertCommonName:    etcdClientCertCommonName,
+		EtcdClientCertOrganization:  etcdClientCertOrganization,
+		IncludeUnlabeledMachines:    includeUnlabeledMachines,
+		BackfillClusterNameLabel:    backfillClusterNameLabel,
+		RecreateMissingEtcdMembers:  recreateMissingEtcdMembers,
 	}).SetupWithManager(ctx, mgr, concurrency(kubeadmControlPlaneConcurrency)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "KubeadmControlPlane")
 		os.Exit(1)
@@ -90,6 +90,12 @@ func (r *Reconciler) reconcileNode(ctx context.Context, cluster *clusterv1.Clust
 		}
 		log.Info("Set Machine's NodeRef", "noderef", machine.Status.NodeRef.Name)
 		r.recorder.Event(machine, corev1.EventTypeNormal, "SuccessfulSetNodeRef", machine.Status.NodeRef.Name)
+	} else if machine.Status.NodeRef.Name == node.Name && machine.Status.NodeRef.UID != node.UID {
+		// The Node has been re-registered by the same instance, e.g. because the Node object has been deleted and
+		// recreated by the kubelet; given that it has been found by the Machine's providerID, update the NodeRef.
+		log.Info("Updating Machine's NodeRef UID for re-registered Node", "noderef", node.Name, "uid", node.UID)
+		machine.Status.NodeRef.UID = node.UID
+		r.recorder.Event(machine, corev1.EventTypeNormal, "SuccessfulUpdateNodeRef", machine.Status.NodeRef.Name)
 	}
 
 	// Set the NodeSystemInfo.
@@ -17,13 +17,17 @@ limitations under the License.
 package machine
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -164,6 +168,56 @@ func TestGetNode(t *testing.T) {
 	}
 }
 
+func TestReconcileNodeUpdatesNodeRefOfReRegisteredNode(t *testing.T) {
+	g := NewWithT(t)
+
+	testCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: metav1.NamespaceDefault,
+		},
+	}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+			UID:  "new-uid",
+		},
+		Spec: corev1.NodeSpec{ProviderID: "test://id-1"},
+	}
+
+	machine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-machine",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: clusterv1.MachineSpec{
+			ClusterName: testCluster.Name,
+			ProviderID:  pointer.StringPtr("test://id-1"),
+		},
+		Status: clusterv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{
+				Kind:       "Node",
+				APIVersion: "v1",
+				Name:       "test-node",
+				UID:        "old-uid",
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithObjects(node).Build()
+	r := &Reconciler{
+		Client:   fakeClient,
+		Tracker:  remote.NewTestClusterCacheTracker(ctrl.Log, fakeClient, fakeScheme, client.ObjectKeyFromObject(testCluster)),
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	_, err := r.reconcileNode(context.Background(), testCluster, machine)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machine.Status.NodeRef.Name).To(Equal(node.Name))
+	g.Expect(machine.Status.NodeRef.UID).To(Equal(node.UID))
+}
+
 func TestSummarizeNodeConditions(t *testing.T) {
 	testCases := []struct {
 		name       string
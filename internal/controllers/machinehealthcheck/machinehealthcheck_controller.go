@@ -27,13 +27,20 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -48,6 +55,7 @@ import (
 	"sigs.k8s.io/cluster-api/internal/controllers/machine"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/collections"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
@@ -60,15 +68,71 @@ const (
 	// is restricted by remediation circuit shorting logic.
 	EventRemediationRestricted string = "RemediationRestricted"
 
+	// EventAllTargetsUnhealthy is emitted in case when all the targets of the MachineHealthCheck
+	// are unhealthy, e.g. during a cluster wide outage, which usually requires manual intervention.
+	EventAllTargetsUnhealthy string = "AllTargetsUnhealthy"
+
+	// EventRemediationGloballyDisabled is emitted in case when machine remediation is skipped
+	// because remediation has been disabled for all the MachineHealthChecks by the kill switch.
+	EventRemediationGloballyDisabled string = "RemediationGloballyDisabled"
+
+	// EventClusterNotFound is emitted in case when the Cluster referenced by the MachineHealthCheck
+	// does not exist, and so no machine is health checked.
+	EventClusterNotFound string = "ClusterNotFound"
+
+	// EventRemediationSkippedClusterDeleting is emitted in case when machine remediation
+	// is skipped because the Cluster is being deleted.
+	EventRemediationSkippedClusterDeleting string = "RemediationSkippedClusterDeleting"
+
+	// EventRemediationConcurrencyLimited is emitted in case when machine remediation
+	// is delayed because the maximum number of remediations in progress has been reached.
+	EventRemediationConcurrencyLimited string = "RemediationConcurrencyLimited"
+
+	// EventRemediationSkippedScalingDown is emitted in case when machine remediation
+	// is skipped because the MachineSet owning the machine is scaling down.
+	EventRemediationSkippedScalingDown string = "RemediationSkippedScalingDown"
+
+	// EventRemediationSkippedNodeRecovered is emitted in case when machine remediation
+	// is skipped because the node recovered after the machine has been health checked.
+	EventRemediationSkippedNodeRecovered string = "RemediationSkippedNodeRecovered"
+
+	// EventRemediationDeferredClusterTooNew is emitted in case when machine remediation
+	// is deferred because the Cluster is younger than the remediation grace period after its creation.
+	EventRemediationDeferredClusterTooNew string = "RemediationDeferredClusterTooNew"
+
+	// EventRemediationDeferredPDB is emitted in case when machine remediation
+	// is deferred because evicting the pods on its node would violate a PodDisruptionBudget.
+	EventRemediationDeferredPDB string = "RemediationDeferredPDB"
+
+	// EventNodeDrainedBeforeRemediation is emitted in case when the node of an unhealthy machine
+	// has been drained before remediating the machine.
+	EventNodeDrainedBeforeRemediation string = "NodeDrainedBeforeRemediation"
+
+	// EventNodeDrainBeforeRemediationFailed is emitted in case when the node of an unhealthy machine
+	// could not be drained before remediating the machine, which is then remediated regardless.
+	EventNodeDrainBeforeRemediationFailed string = "NodeDrainBeforeRemediationFailed"
+
+	// EventMachineOwnerScaledDown is emitted in case when an unhealthy machine is remediated
+	// by scaling down its owner.
+	EventMachineOwnerScaledDown string = "MachineOwnerScaledDown"
+
+	// EventRemediationInitiated is emitted in case when the remediation of an unhealthy machine
+	// has been initiated; the message references the ID of the remediation.
+	EventRemediationInitiated string = "RemediationInitiated"
+
 	maxUnhealthyKeyLog     = "max unhealthy"
 	unhealthyTargetsKeyLog = "unhealthy targets"
 	unhealthyRangeKeyLog   = "unhealthy range"
 	totalTargetKeyLog      = "total target"
+	maxTargetsKeyLog       = "max targets"
+	quorumKeyLog           = "quorum"
 )
 
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;patch
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines;machines/status,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinesets;machinedeployments,verbs=get;list;watch;patch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinehealthchecks;machinehealthchecks/status;machinehealthchecks/finalizers,verbs=get;list;watch;update;patch
 
 // Reconciler reconciles a MachineHealthCheck object.
@@ -79,8 +143,33 @@ type Reconciler struct {
 	// WatchFilterValue is the label value used to filter events prior to reconciliation.
 	WatchFilterValue string
 
-	controller controller.Controller
-	recorder   record.EventRecorder
+	// RemediationNotifier, if set, is notified every time a remediation is initiated.
+	RemediationNotifier RemediationNotifier
+
+	// MaxTargets, if greater than zero, is the maximum number of Machines a MachineHealthCheck can target;
+	// remediation is blocked for MachineHealthChecks with a selector matching more Machines.
+	MaxTargets int
+
+	// RemediationKillSwitchConfigMap, if set, is the ConfigMap acting as a kill switch for the remediations of all the
+	// MachineHealthChecks: while it has the RemediationGloballyDisabledAnnotation set to "true", the health check results
+	// are reported, but no remediation is initiated.
+	// NOTE: The ConfigMap is read at every reconcile, so the kill switch applies without restarting the controller.
+	RemediationKillSwitchConfigMap client.ObjectKey
+
+	// FailureDomainRoundRobinRemediation, if true, selects the unhealthy Machines to remediate round-robin across
+	// failure domains, so that when the number of remediations is limited, e.g. by MaxInFlightRemediations, they
+	// are spread across failure domains instead of being concentrated in one of them.
+	FailureDomainRoundRobinRemediation bool
+
+	// ReadNodesFromWorkloadCluster, if true, reads the nodes of the workload cluster with an uncached client at every
+	// reconcile, instead of relying on the cache of the workload cluster, so that the health checks are not affected by
+	// node status updates lagging behind in the cache.
+	// NOTE: This creates a new client to the workload cluster at every reconcile, which adds load on the API servers.
+	ReadNodesFromWorkloadCluster bool
+
+	controller         controller.Controller
+	recorder           record.EventRecorder
+	remoteClientGetter remote.ClusterClientGetter
 }
 
 func (r *Reconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
@@ -111,6 +200,9 @@ func (r *Reconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, opt
 
 	r.controller = controller
 	r.recorder = mgr.GetEventRecorderFor("machinehealthcheck-controller")
+	if r.remoteClientGetter == nil {
+		r.remoteClientGetter = remote.NewClusterClient
+	}
 	return nil
 }
 
@@ -137,6 +229,10 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Re
 
 	cluster, err := util.GetClusterByName(ctx, r.Client, m.Namespace, m.Spec.ClusterName)
 	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// NOTE: There is no need to requeue, the MachineHealthCheck is reconciled again when the Cluster is created.
+			return ctrl.Result{}, r.reconcileClusterNotFound(ctx, log, m)
+		}
 		log.Error(err, "Failed to fetch Cluster for MachineHealthCheck")
 		return ctrl.Result{}, err
 	}
@@ -147,6 +243,22 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Re
 		return ctrl.Result{}, nil
 	}
 
+	// Return early if the Cluster is being deleted; Machines are expected to go unhealthy
+	// while they are torn down, and remediating them would fight the deletion.
+	if !cluster.DeletionTimestamp.IsZero() {
+		log.Info("Skipping remediation because the Cluster is being deleted")
+		r.recorder.Event(m, corev1.EventTypeNormal, EventRemediationSkippedClusterDeleting, "Remediation is skipped because the Cluster is being deleted")
+		return ctrl.Result{}, nil
+	}
+
+	// Reconcile labels and owner references.
+	// NOTE: This is done before initializing the patch helper, given that its merge patch replaces the owner references
+	// as a whole list, and thus could drop owner references added by other controllers in the meantime.
+	if err := r.reconcileClusterLabelAndOwnerRef(ctx, m, cluster); err != nil {
+		log.Error(err, "Failed to reconcile cluster label and owner reference")
+		return ctrl.Result{}, err
+	}
+
 	// Initialize the patch helper
 	patchHelper, err := patch.NewHelper(m, r.Client)
 	if err != nil {
@@ -166,12 +278,6 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Re
 		}
 	}()
 
-	// Reconcile labels.
-	if m.Labels == nil {
-		m.Labels = make(map[string]string)
-	}
-	m.Labels[clusterv1.ClusterLabelName] = m.Spec.ClusterName
-
 	result, err := r.reconcile(ctx, log, cluster, m)
 	if err != nil {
 		log.Error(err, "Failed to reconcile MachineHealthCheck")
@@ -184,15 +290,69 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Re
 	return result, nil
 }
 
-func (r *Reconciler) reconcile(ctx context.Context, logger logr.Logger, cluster *clusterv1.Cluster, m *clusterv1.MachineHealthCheck) (ctrl.Result, error) {
-	// Ensure the MachineHealthCheck is owned by the Cluster it belongs to
+// reconcileClusterNotFound reports on a MachineHealthCheck that the Cluster it references does not exist, so it is not
+// mistaken for a MachineHealthCheck which is quietly matching no Machines.
+func (r *Reconciler) reconcileClusterNotFound(ctx context.Context, logger logr.Logger, m *clusterv1.MachineHealthCheck) error {
+	patchHelper, err := patch.NewHelper(m, r.Client)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Cluster referenced by the MachineHealthCheck does not exist")
+	r.recorder.Eventf(m, corev1.EventTypeWarning, EventClusterNotFound, "Cluster %s referenced by the MachineHealthCheck does not exist", m.Spec.ClusterName)
+
+	m.Status.RemediationsAllowed = 0
+	conditions.MarkFalse(m, clusterv1.RemediationAllowedCondition, clusterv1.ClusterNotFoundReason, clusterv1.ConditionSeverityWarning, "Cluster %s does not exist", m.Spec.ClusterName)
+	return patchHelper.Patch(ctx, m)
+}
+
+// reconcileClusterLabelAndOwnerRef persists the cluster-name label and the owner reference to the Cluster on the
+// MachineHealthCheck, if missing. The patch carries the resourceVersion, so it is retried on the latest copy of the
+// object in case of conflicts instead of overwriting concurrent changes to the owner references.
+func (r *Reconciler) reconcileClusterLabelAndOwnerRef(ctx context.Context, m *clusterv1.MachineHealthCheck, cluster *clusterv1.Cluster) error {
+	desired := m.DeepCopy()
+	ensureClusterLabelAndOwnerRef(desired, cluster)
+	if equality.Semantic.DeepEqual(desired.ObjectMeta, m.ObjectMeta) {
+		return nil
+	}
+
+	latest := m.DeepCopy()
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		patchBase := client.MergeFromWithOptions(latest.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		ensureClusterLabelAndOwnerRef(latest, cluster)
+		err := r.Client.Patch(ctx, latest, patchBase)
+		if apierrors.IsConflict(err) {
+			latest = &clusterv1.MachineHealthCheck{}
+			if getErr := r.Client.Get(ctx, client.ObjectKeyFromObject(m), latest); getErr != nil {
+				return getErr
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to patch cluster label and owner reference")
+	}
+	*m = *latest
+	return nil
+}
+
+// ensureClusterLabelAndOwnerRef ensures the MachineHealthCheck has the cluster-name label and is owned by the Cluster it belongs to.
+// NOTE: This func is idempotent, so it can be safely applied again on a fresh copy of the object.
+func ensureClusterLabelAndOwnerRef(m *clusterv1.MachineHealthCheck, cluster *clusterv1.Cluster) {
+	if m.Labels == nil {
+		m.Labels = make(map[string]string)
+	}
+	m.Labels[clusterv1.ClusterLabelName] = m.Spec.ClusterName
+
 	m.OwnerReferences = util.EnsureOwnerRef(m.OwnerReferences, metav1.OwnerReference{
 		APIVersion: clusterv1.GroupVersion.String(),
 		Kind:       "Cluster",
 		Name:       cluster.Name,
 		UID:        cluster.UID,
 	})
+}
 
+func (r *Reconciler) reconcile(ctx context.Context, logger logr.Logger, cluster *clusterv1.Cluster, m *clusterv1.MachineHealthCheck) (ctrl.Result, error) {
 	// Get the remote cluster cache to use as a client.Reader.
 	remoteClient, err := r.Tracker.GetClient(ctx, util.ObjectKey(cluster))
 	if err != nil {
@@ -205,9 +365,24 @@ func (r *Reconciler) reconcile(ctx context.Context, logger logr.Logger, cluster
 		return ctrl.Result{}, err
 	}
 
+	// The uncached client to the workload cluster is shared by all the operations of this reconcile requiring it.
+	workloadClient := r.uncachedWorkloadClient(ctx, cluster)
+
+	nodeReader, err := r.nodeReader(remoteClient, workloadClient)
+	if err != nil {
+		logger.Error(err, "error creating client for reading nodes on target cluster")
+		return ctrl.Result{}, err
+	}
+
+	// Initialize a patch helper for persisting the target counts before evaluating remediation.
+	countsPatchHelper, err := patch.NewHelper(m, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to build patch helper")
+	}
+
 	// fetch all targets
 	logger.V(3).Info("Finding targets")
-	targets, err := r.getTargetsFromMHC(ctx, logger, remoteClient, cluster, m)
+	targets, err := r.getTargetsFromMHC(ctx, logger, nodeReader, workloadClient, cluster, m)
 	if err != nil {
 		logger.Error(err, "Failed to fetch targets from MachineHealthCheck")
 		return ctrl.Result{}, err
@@ -227,11 +402,90 @@ func (r *Reconciler) reconcile(ctx context.Context, logger logr.Logger, cluster
 	}
 
 	// health check all targets and reconcile mhc status
-	healthy, unhealthy, nextCheckTimes := r.healthCheckTargets(targets, logger, *nodeStartupTimeout)
+	result := r.healthCheckTargets(targets, logger, *nodeStartupTimeout)
+	healthy, unhealthy, nextCheckTimes := result.healthy, result.unhealthy, result.nextCheckTimes
 	m.Status.CurrentHealthy = int32(len(healthy))
+	m.Status.TargetHealth = result.targetHealth
+	m.Status.NextRemediationTime = result.nextRemediationTime
+	sort.Slice(m.Status.TargetHealth, func(i, j int) bool { return m.Status.TargetHealth[i].Machine < m.Status.TargetHealth[j].Machine })
+
+	// a healthy machine created after the last remediation is a successful replacement, so remediation can resume
+	resetFailedReplacements(m, healthy)
+
+	// defer remediation of the unhealthy targets not yet found unhealthy for enough consecutive health checks, if required
+	unhealthy, deferredCheckTimes := applyUnhealthyObservationThreshold(m, targets, healthy, unhealthy)
+	nextCheckTimes = append(nextCheckTimes, deferredCheckTimes...)
+
+	// persist the target counts computed in this reconcile before evaluating remediation, so remediation decisions are
+	// never taken against counts which are not reported yet, e.g. the zero counts of a new MachineHealthCheck;
+	// if the counts can't be persisted, remediation is skipped until the next reconcile.
+	if err := countsPatchHelper.Patch(ctx, m); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to persist target counts before evaluating remediation")
+	}
+
+	// if all the targets are unhealthy, escalate with a distinct event, given that remediation is usually either
+	// short-circuited or not going to help, e.g. during a cluster wide outage; targets which are not healthy but not
+	// unhealthy yet, e.g. failing a health check within its timeout, are not considered unhealthy
+	if totalTargets > 0 && len(unhealthy) == totalTargets {
+		logger.Info("All targets are unhealthy, manual intervention might be required", totalTargetKeyLog, totalTargets)
+		r.recorder.Eventf(
+			m,
+			corev1.EventTypeWarning,
+			EventAllTargetsUnhealthy,
+			"All the %d targets of the MachineHealthCheck are unhealthy, manual intervention might be required",
+			totalTargets,
+		)
+	}
+
+	// if remediation is globally disabled by the kill switch, only report the health check results on the targets
+	globallyDisabled, err := r.remediationGloballyDisabled(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if globallyDisabled {
+		message := "Remediation is disabled for all the MachineHealthChecks by the kill switch"
+		r.recorder.Event(m, corev1.EventTypeWarning, EventRemediationGloballyDisabled, message)
+		return r.reportWithoutRemediation(ctx, logger, m, clusterv1.RemediationGloballyDisabledReason, message, append(healthy, unhealthy...), nextCheckTimes)
+	}
+
+	// if remediation is disabled, only report the health check results on the targets
+	if m.Spec.RemediationDisabled {
+		return r.reportWithoutRemediation(ctx, logger, m, clusterv1.RemediationDisabledReason, "Remediation is disabled", append(healthy, unhealthy...), nextCheckTimes)
+	}
+
+	// if the cluster has been created recently, defer remediation until the grace period after the cluster creation elapses
+	if remaining := remediationGracePeriodRemaining(cluster, m, time.Now()); remaining > 0 {
+		message := fmt.Sprintf("Remediation is deferred until the Cluster is older than %s", m.Spec.RemediationGracePeriodAfterClusterCreation.Duration)
+		r.recorder.Event(m, corev1.EventTypeNormal, EventRemediationDeferredClusterTooNew, message)
+		return r.reportWithoutRemediation(ctx, logger, m, clusterv1.ClusterTooNewReason, message, append(healthy, unhealthy...), append(nextCheckTimes, remaining))
+	}
+
+	// if the machines replacing the remediated ones keep failing to become ready, remediating further would just burn
+	// machines, so remediation is paused until a replacement succeeds, e.g. after the machine configuration is fixed
+	if replacementsFailing(m) {
+		logger.V(3).Info(
+			"Short-circuiting remediation",
+			"consecutiveFailedReplacements", m.Status.ConsecutiveFailedReplacements,
+			"maxConsecutiveFailedReplacements", *m.Spec.MaxConsecutiveFailedReplacements,
+		)
+		message := fmt.Sprintf("Remediation is paused, the last %d machines replacing remediated machines failed to get a node; manual intervention is required",
+			m.Status.ConsecutiveFailedReplacements)
+		return r.shortCircuitRemediation(ctx, m, clusterv1.ReplacementsFailingReason, message, append(healthy, unhealthy...), nextCheckTimes)
+	}
+
+	// check MHC selector against the safety ceiling, so a too broad selector can't trigger a fleet-wide remediation
+	if err := validateSelectorBreadth(m, totalTargets, r.MaxTargets); err != nil {
+		logger.V(3).Info(
+			"Short-circuiting remediation",
+			totalTargetKeyLog, totalTargets,
+			maxTargetsKeyLog, r.MaxTargets,
+		)
+		message := fmt.Sprintf("Remediation is not allowed, %v", err)
+		return r.shortCircuitRemediation(ctx, m, clusterv1.TooBroadSelectorReason, message, append(healthy, unhealthy...), nextCheckTimes)
+	}
 
 	// check MHC current health against MaxUnhealthy
-	remediationAllowed, remediationCount, err := isAllowedRemediation(m)
+	remediationAllowed, remediationCount, err := isAllowedRemediation(m, len(unhealthy))
 	if err != nil {
 		return ctrl.Result{}, errors.Wrapf(err, "error checking if remediation is allowed")
 	}
@@ -264,32 +518,28 @@ func (r *Reconciler) reconcile(ctx context.Context, logger logr.Logger, cluster
 		}
 
 		// Remediation not allowed, the number of not started or unhealthy machines either exceeds maxUnhealthy (or) not within unhealthyRange
-		m.Status.RemediationsAllowed = 0
-		conditions.Set(m, &clusterv1.Condition{
-			Type:     clusterv1.RemediationAllowedCondition,
-			Status:   corev1.ConditionFalse,
-			Severity: clusterv1.ConditionSeverityWarning,
-			Reason:   clusterv1.TooManyUnhealthyReason,
-			Message:  message,
-		})
-
-		r.recorder.Event(
-			m,
-			corev1.EventTypeWarning,
-			EventRemediationRestricted,
-			message,
-		)
-		errList := []error{}
-		for _, t := range append(healthy, unhealthy...) {
-			if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
-				errList = append(errList, errors.Wrapf(err, "failed to patch machine status for machine: %s/%s", t.Machine.Namespace, t.Machine.Name))
-				continue
-			}
+		return r.shortCircuitRemediation(ctx, m, clusterv1.TooManyUnhealthyReason, message, append(healthy, unhealthy...), nextCheckTimes)
+	}
+
+	// check MHC current health against the quorum of the targets, if required
+	if m.Spec.PreserveQuorum {
+		quorumPreserved, quorumRemediationCount := isQuorumPreserved(m)
+		if !quorumPreserved {
+			logger.V(3).Info(
+				"Short-circuiting remediation",
+				totalTargetKeyLog, totalTargets,
+				quorumKeyLog, quorum(m.Status.ExpectedMachines),
+				unhealthyTargetsKeyLog, len(unhealthy),
+			)
+			message := fmt.Sprintf("Remediation is not allowed, the number of healthy machines is below quorum (total: %v, healthy: %v, quorum: %v)",
+				totalTargets,
+				m.Status.CurrentHealthy,
+				quorum(m.Status.ExpectedMachines))
+			return r.shortCircuitRemediation(ctx, m, clusterv1.TooManyUnhealthyReason, message, append(healthy, unhealthy...), nextCheckTimes)
 		}
-		if len(errList) > 0 {
-			return ctrl.Result{}, kerrors.NewAggregate(errList)
+		if quorumRemediationCount < remediationCount {
+			remediationCount = quorumRemediationCount
 		}
-		return reconcile.Result{Requeue: true}, nil
 	}
 
 	if m.Spec.UnhealthyRange == nil {
@@ -312,9 +562,22 @@ func (r *Reconciler) reconcile(ctx context.Context, logger logr.Logger, cluster
 	m.Status.RemediationsAllowed = remediationCount
 	conditions.MarkTrue(m, clusterv1.RemediationAllowedCondition)
 
-	errList := r.patchUnhealthyTargets(ctx, logger, unhealthy, cluster, m)
+	inFlightRemediations := 0
+	if m.Spec.MaxInFlightRemediations != nil {
+		inFlightRemediations = r.countInFlightRemediations(ctx, targets, m)
+	}
+
+	if r.FailureDomainRoundRobinRemediation {
+		unhealthy = orderByFailureDomainRoundRobin(unhealthy)
+	}
+
+	errList := r.patchUnhealthyTargets(ctx, logger, unhealthy, workloadClient, cluster, m, inFlightRemediations)
 	errList = append(errList, r.patchHealthyTargets(ctx, logger, healthy, m)...)
 
+	// Pending remediations, e.g. waiting for the node of the machine to be drained, are retried after the requested delay.
+	errList, pendingCheckTimes := splitRemediationPending(errList)
+	nextCheckTimes = append(nextCheckTimes, pendingCheckTimes...)
+
 	// handle update errors
 	if len(errList) > 0 {
 		logger.V(3).Info("Error(s) marking machine, requeueing")
@@ -331,6 +594,93 @@ func (r *Reconciler) reconcile(ctx context.Context, logger logr.Logger, cluster
 	return ctrl.Result{}, nil
 }
 
+// reportWithoutRemediation patches the health check results on the targets of a MachineHealthCheck for which remediation
+// is disabled or deferred, without taking any remediation action; the reason is reported on the RemediationAllowed condition.
+func (r *Reconciler) reportWithoutRemediation(ctx context.Context, logger logr.Logger, m *clusterv1.MachineHealthCheck, reason, message string, targets []healthCheckTarget, nextCheckTimes []time.Duration) (ctrl.Result, error) {
+	logger.V(3).Info("Remediation is not allowed, reporting health check results only", "reason", reason)
+
+	m.Status.RemediationsAllowed = 0
+	conditions.MarkFalse(m, clusterv1.RemediationAllowedCondition, reason, clusterv1.ConditionSeverityInfo, message)
+
+	errList := []error{}
+	for _, t := range targets {
+		if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
+			errList = append(errList, errors.Wrapf(err, "failed to patch machine status for machine: %s/%s", t.Machine.Namespace, t.Machine.Name))
+		}
+	}
+	if len(errList) > 0 {
+		return ctrl.Result{}, kerrors.NewAggregate(errList)
+	}
+
+	if minNextCheck := minDuration(nextCheckTimes); minNextCheck > 0 {
+		return ctrl.Result{RequeueAfter: minNextCheck}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// remediationGloballyDisabled returns true if the kill switch ConfigMap has the RemediationGloballyDisabledAnnotation
+// set to "true"; a missing ConfigMap means the kill switch is off.
+// NOTE: If the kill switch can't be read, an error is returned so no remediation is initiated.
+func (r *Reconciler) remediationGloballyDisabled(ctx context.Context) (bool, error) {
+	if r.RemediationKillSwitchConfigMap.Name == "" {
+		return false, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, r.RemediationKillSwitchConfigMap, configMap); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to get remediation kill switch ConfigMap %s", r.RemediationKillSwitchConfigMap)
+	}
+	return configMap.Annotations[clusterv1.RemediationGloballyDisabledAnnotation] == "true", nil
+}
+
+// remediationGracePeriodRemaining returns how long remediation must still be deferred after the creation of the Cluster,
+// according to the RemediationGracePeriodAfterClusterCreation of the MachineHealthCheck.
+func remediationGracePeriodRemaining(cluster *clusterv1.Cluster, m *clusterv1.MachineHealthCheck, now time.Time) time.Duration {
+	if m.Spec.RemediationGracePeriodAfterClusterCreation == nil {
+		return 0
+	}
+	return cluster.CreationTimestamp.Add(m.Spec.RemediationGracePeriodAfterClusterCreation.Duration).Sub(now)
+}
+
+// shortCircuitRemediation blocks any further remediation by the MachineHealthCheck, reporting the reason
+// on the RemediationAllowed condition, and patches the health check results on the targets.
+// The MachineHealthCheck is requeued when the first pending health check deadline elapses, e.g. the NodeStartupTimeout
+// of a machine without a node, given that targets going unhealthy might change the remediation decision.
+func (r *Reconciler) shortCircuitRemediation(ctx context.Context, m *clusterv1.MachineHealthCheck, reason, message string, targets []healthCheckTarget, nextCheckTimes []time.Duration) (ctrl.Result, error) {
+	m.Status.RemediationsAllowed = 0
+	conditions.Set(m, &clusterv1.Condition{
+		Type:     clusterv1.RemediationAllowedCondition,
+		Status:   corev1.ConditionFalse,
+		Severity: clusterv1.ConditionSeverityWarning,
+		Reason:   reason,
+		Message:  message,
+	})
+
+	r.recorder.Event(
+		m,
+		corev1.EventTypeWarning,
+		EventRemediationRestricted,
+		message,
+	)
+	errList := []error{}
+	for _, t := range targets {
+		if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
+			errList = append(errList, errors.Wrapf(err, "failed to patch machine status for machine: %s/%s", t.Machine.Namespace, t.Machine.Name))
+			continue
+		}
+	}
+	if len(errList) > 0 {
+		return ctrl.Result{}, kerrors.NewAggregate(errList)
+	}
+	if minNextCheck := minDuration(nextCheckTimes); minNextCheck > 0 {
+		return ctrl.Result{RequeueAfter: minNextCheck}, nil
+	}
+	return reconcile.Result{Requeue: true}, nil
+}
+
 // patchHealthyTargets patches healthy machines with MachineHealthCheckSucceededCondition.
 func (r *Reconciler) patchHealthyTargets(ctx context.Context, logger logr.Logger, healthy []healthCheckTarget, m *clusterv1.MachineHealthCheck) []error {
 	errList := []error{}
@@ -355,6 +705,18 @@ func (r *Reconciler) patchHealthyTargets(ctx context.Context, logger logr.Logger
 			}
 		}
 
+		if hasDrainBeforeRemediationStarted(t.Machine) {
+			kubeClient, err := r.workloadClusterClientset(ctx, client.ObjectKey{Namespace: t.Machine.Namespace, Name: t.Machine.Spec.ClusterName})
+			if err != nil {
+				errList = append(errList, errors.Wrapf(err, "failed to create client to workload cluster"))
+				continue
+			}
+			if err := uncordonRecoveredNode(ctx, logger, kubeClient, t); err != nil {
+				errList = append(errList, errors.Wrapf(err, "failed to uncordon the node of recovered machine %s/%s", t.Machine.Namespace, t.Machine.Name))
+				continue
+			}
+		}
+
 		if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
 			logger.Error(err, "failed to patch healthy machine status for machine", "machine", t.Machine.GetName())
 			errList = append(errList, errors.Wrapf(err, "failed to patch healthy machine status for machine: %s/%s", t.Machine.Namespace, t.Machine.Name))
@@ -363,77 +725,178 @@ func (r *Reconciler) patchHealthyTargets(ctx context.Context, logger logr.Logger
 	return errList
 }
 
+// uncachedWorkloadClient returns a function returning an uncached client to the workload cluster, used for reading
+// objects which are not cached by the management cluster, e.g. node leases, pods and PodDisruptionBudgets.
+// The client is created on first use, so it is created at most once per reconcile, and only if required.
+func (r *Reconciler) uncachedWorkloadClient(ctx context.Context, cluster *clusterv1.Cluster) func() (client.Client, error) {
+	var uncachedClient client.Client
+	var err error
+	return func() (client.Client, error) {
+		if uncachedClient == nil && err == nil {
+			uncachedClient, err = r.remoteClientGetter(ctx, "machinehealthcheck-controller", r.Client, util.ObjectKey(cluster))
+			if err != nil {
+				err = errors.Wrap(err, "failed to create uncached client to workload cluster")
+			}
+		}
+		return uncachedClient, err
+	}
+}
+
+// nodeReader returns the client used for reading the nodes of the workload cluster, i.e. the client backed by the cache
+// of the workload cluster, or the uncached client if ReadNodesFromWorkloadCluster is set.
+func (r *Reconciler) nodeReader(cachedClient client.Reader, uncachedClient func() (client.Client, error)) (client.Reader, error) {
+	if !r.ReadNodesFromWorkloadCluster {
+		return cachedClient, nil
+	}
+	return uncachedClient()
+}
+
 // patchUnhealthyTargets patches machines with MachineOwnerRemediatedCondition for remediation.
-func (r *Reconciler) patchUnhealthyTargets(ctx context.Context, logger logr.Logger, unhealthy []healthCheckTarget, cluster *clusterv1.Cluster, m *clusterv1.MachineHealthCheck) []error {
+// If MaxInFlightRemediations is set, new remediations are started only while the number of remediations
+// in progress, starting from inFlightRemediations, is below the limit.
+// The uncached client to the workload cluster is used for checking PodDisruptionBudgets and for confirming nodes are
+// still unhealthy, so pods and PodDisruptionBudgets of the workload cluster are not cached by the management cluster,
+// and the nodes are read from the API server rather than from a possibly stale cache.
+func (r *Reconciler) patchUnhealthyTargets(ctx context.Context, logger logr.Logger, unhealthy []healthCheckTarget, workloadClient func() (client.Client, error), cluster *clusterv1.Cluster, m *clusterv1.MachineHealthCheck, inFlightRemediations int) []error {
+	concurrencyLimited := false
+	canStartRemediation := func(t healthCheckTarget) bool {
+		if m.Spec.MaxInFlightRemediations == nil {
+			return true
+		}
+		if inFlightRemediations < int(*m.Spec.MaxInFlightRemediations) {
+			inFlightRemediations++
+			return true
+		}
+		logger.Info("Target has failed health check, but the maximum number of remediations in progress has been reached so delaying remediation", "target", t.string(), "inFlightRemediations", inFlightRemediations, "maxInFlightRemediations", *m.Spec.MaxInFlightRemediations)
+		concurrencyLimited = true
+		return false
+	}
+
+	// nodeRecoveredBeforeRemediation confirms the node is still unhealthy right before remediating the machine, given
+	// that it might have recovered after the target has been health checked, e.g. while other targets were being processed.
+	nodeRecoveredBeforeRemediation := func(t healthCheckTarget, condition *clusterv1.Condition) (bool, error) {
+		if condition.Reason != clusterv1.UnhealthyNodeConditionReason {
+			return false, nil
+		}
+		c, err := workloadClient()
+		if err != nil {
+			return false, err
+		}
+		return nodeRecovered(ctx, c, t)
+	}
+
 	// mark for remediation
 	errList := []error{}
 	for _, t := range unhealthy {
 		condition := conditions.Get(t.Machine, clusterv1.MachineHealthCheckSucceededCondition)
+		remediationInitiated := false
+		remediationID := ""
+
+		ownerMachineSet, err := r.getOwnerMachineSet(ctx, t.Machine)
+		if err != nil {
+			errList = append(errList, errors.Wrapf(err, "failed to get the MachineSet owning machine %s/%s", t.Machine.Namespace, t.Machine.Name))
+			continue
+		}
+		ownerScalingDown := isMachineSetScalingDown(ownerMachineSet)
+
+		var blockingPDBs []string
+		if m.Spec.RespectPodDisruptionBudgets && t.Node != nil {
+			c, err := workloadClient()
+			if err != nil {
+				errList = append(errList, errors.Wrapf(err, "failed to check PodDisruptionBudgets for machine %s/%s", t.Machine.Namespace, t.Machine.Name))
+				continue
+			}
+			blockingPDBs, err = podDisruptionBudgetsBlockingNodeDrain(ctx, c, t.Node.Name)
+			if err != nil {
+				errList = append(errList, errors.Wrapf(err, "failed to check PodDisruptionBudgets for machine %s/%s", t.Machine.Namespace, t.Machine.Name))
+				continue
+			}
+		}
 
 		if annotations.IsPaused(cluster, t.Machine) {
 			logger.Info("Machine has failed health check, but machine is paused so skipping remediation", "target", t.string(), "reason", condition.Reason, "message", condition.Message)
+		} else if annotations.HasRemediationInProgress(t.Machine) {
+			logger.Info("Machine has failed health check, but machine is already being remediated so skipping remediation", "target", t.string(), "reason", condition.Reason, "message", condition.Message)
+		} else if ownerScalingDown {
+			logger.Info("Machine has failed health check, but the MachineSet owning it is scaling down so skipping remediation", "target", t.string(), "reason", condition.Reason, "message", condition.Message)
+			r.recorder.Eventf(
+				t.Machine,
+				corev1.EventTypeNormal,
+				EventRemediationSkippedScalingDown,
+				"Remediation of Machine %v is skipped because the MachineSet owning it is scaling down",
+				t.string(),
+			)
+		} else if len(blockingPDBs) > 0 {
+			logger.Info("Machine has failed health check, but evicting the pods on its node would violate PodDisruptionBudgets so deferring remediation", "target", t.string(), "podDisruptionBudgets", blockingPDBs, "reason", condition.Reason, "message", condition.Message)
+			r.recorder.Eventf(
+				t.Machine,
+				corev1.EventTypeNormal,
+				EventRemediationDeferredPDB,
+				"Remediation of Machine %v is deferred because evicting the pods on its node would violate PodDisruptionBudgets %s",
+				t.string(),
+				strings.Join(blockingPDBs, ", "),
+			)
+		} else if strategy := r.remediationStrategyFor(m, ownerMachineSet); strategy.InProgress(ctx, t) {
+			logger.V(3).Info("Machine has failed health check, but a remediation is already in progress so skipping remediation", "target", t.string(), "reason", condition.Reason, "message", condition.Message)
+		} else if recovered, err := nodeRecoveredBeforeRemediation(t, condition); err != nil {
+			errList = append(errList, errors.Wrapf(err, "failed to confirm the node of machine %s/%s is unhealthy", t.Machine.Namespace, t.Machine.Name))
+			continue
+		} else if recovered {
+			// NOTE: The machine is not patched, so the stale health check results are not persisted; the machine
+			// is going to be health checked again on the next reconcile.
+			logger.Info("Machine has failed health check, but its node recovered in the meantime so skipping remediation", "target", t.string(), "reason", condition.Reason, "message", condition.Message)
+			r.recorder.Eventf(
+				t.Machine,
+				corev1.EventTypeNormal,
+				EventRemediationSkippedNodeRecovered,
+				"Remediation of Machine %v is skipped because its node recovered",
+				t.string(),
+			)
+			recordRemediationAttempt(m, remediationResultSkipped)
+			continue
+		} else if !hasDrainBeforeRemediationStarted(t.Machine) && !canStartRemediation(t) {
+			// NOTE: Machines whose node is being drained before remediation are already counted as in flight, so
+			// continuing their remediation is not limited.
+			recordRemediationAttempt(m, remediationResultSkipped)
+			if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
+				errList = append(errList, errors.Wrapf(err, "failed to patch unhealthy machine status for machine: %s/%s", t.Machine.Namespace, t.Machine.Name))
+			}
+			continue
 		} else {
-			if m.Spec.RemediationTemplate != nil {
-				// If external remediation request already exists,
-				// return early
-				if r.externalRemediationRequestExists(ctx, m, t.Machine.Name) {
-					return errList
-				}
-
-				cloneOwnerRef := &metav1.OwnerReference{
-					APIVersion: clusterv1.GroupVersion.String(),
-					Kind:       "Machine",
-					Name:       t.Machine.Name,
-					UID:        t.Machine.UID,
-				}
-
-				from, err := external.Get(ctx, r.Client, m.Spec.RemediationTemplate, t.Machine.Namespace)
-				if err != nil {
-					conditions.MarkFalse(m, clusterv1.ExternalRemediationTemplateAvailable, clusterv1.ExternalRemediationTemplateNotFound, clusterv1.ConditionSeverityError, err.Error())
-					errList = append(errList, errors.Wrapf(err, "error retrieving remediation template %v %q for machine %q in namespace %q within cluster %q", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, m.Spec.ClusterName))
-					return errList
-				}
-
-				generateTemplateInput := &external.GenerateTemplateInput{
-					Template:    from,
-					TemplateRef: m.Spec.RemediationTemplate,
-					Namespace:   t.Machine.Namespace,
-					ClusterName: t.Machine.ClusterName,
-					OwnerRef:    cloneOwnerRef,
-				}
-				to, err := external.GenerateTemplate(generateTemplateInput)
-				if err != nil {
-					errList = append(errList, errors.Wrapf(err, "failed to create template for remediation request %v %q for machine %q in namespace %q within cluster %q", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, m.Spec.ClusterName))
-					return errList
-				}
-
-				// Set the Remediation Request to match the Machine name, the name is used to
-				// guarantee uniqueness between runs. A Machine should only ever have a single
-				// remediation object of a specific GVK created.
-				//
-				// NOTE: This doesn't guarantee uniqueness across different MHC objects watching
-				// the same Machine, users are in charge of setting health checks and remediation properly.
-				to.SetName(t.Machine.Name)
-
-				logger.Info("Target has failed health check, creating an external remediation request", "remediation request name", to.GetName(), "target", t.string(), "reason", condition.Reason, "message", condition.Message)
-				// Create the external clone.
-				if err := r.Client.Create(ctx, to); err != nil {
-					conditions.MarkFalse(m, clusterv1.ExternalRemediationRequestAvailable, clusterv1.ExternalRemediationRequestCreationFailed, clusterv1.ConditionSeverityError, err.Error())
-					errList = append(errList, errors.Wrapf(err, "error creating remediation request for machine %q in namespace %q within cluster %q", t.Machine.Name, t.Machine.Namespace, t.Machine.ClusterName))
-					return errList
-				}
-			} else {
-				logger.Info("Target has failed health check, marking for remediation", "target", t.string(), "reason", condition.Reason, "message", condition.Message)
-				// NOTE: MHC is responsible for creating MachineOwnerRemediatedCondition if missing or to trigger another remediation if the previous one is completed;
-				// instead, if a remediation is in already progress, the remediation owner is responsible for completing the process and MHC should not overwrite the condition.
-				if !conditions.Has(t.Machine, clusterv1.MachineOwnerRemediatedCondition) || conditions.IsTrue(t.Machine, clusterv1.MachineOwnerRemediatedCondition) {
-					conditions.MarkFalse(t.Machine, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "")
+			if err := strategy.Remediate(ctx, logger, t); err != nil {
+				var pending *remediationPendingError
+				if errors.As(err, &pending) {
+					// NOTE: The machine is patched, so the progress of the pending remediation is persisted; the pending
+					// error is returned to the caller, which requeues the MachineHealthCheck accordingly.
+					logger.Info("Target has failed health check, but its remediation is pending", "target", t.string(), "pending", pending.message)
+					recordRemediationAttempt(m, remediationResultSkipped)
+					if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
+						errList = append(errList, errors.Wrapf(err, "failed to patch unhealthy machine status for machine: %s/%s", t.Machine.Namespace, t.Machine.Name))
+						continue
+					}
+					errList = append(errList, pending)
+					continue
 				}
+				recordRemediationAttempt(m, remediationResultFailed)
+				errList = append(errList, err)
+				continue
 			}
+			remediationInitiated = true
+			remediationID = newRemediationID(t.Machine, time.Now())
+			annotations.AddAnnotations(t.Machine, map[string]string{clusterv1.RemediationIDAnnotation: remediationID})
 		}
 
-		if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
-			errList = append(errList, errors.Wrapf(err, "failed to patch unhealthy machine status for machine: %s/%s", t.Machine.Namespace, t.Machine.Name))
+		patchErr := t.patchHelper.Patch(ctx, t.Machine)
+		switch {
+		case !remediationInitiated:
+			recordRemediationAttempt(m, remediationResultSkipped)
+		case patchErr != nil:
+			recordRemediationAttempt(m, remediationResultFailed)
+		default:
+			recordRemediationAttempt(m, remediationResultSuccess)
+		}
+		if patchErr != nil {
+			errList = append(errList, errors.Wrapf(patchErr, "failed to patch unhealthy machine status for machine: %s/%s", t.Machine.Namespace, t.Machine.Name))
 			continue
 		}
 		r.recorder.Eventf(
@@ -443,10 +906,340 @@ func (r *Reconciler) patchUnhealthyTargets(ctx context.Context, logger logr.Logg
 			"Machine %v has been marked as unhealthy",
 			t.string(),
 		)
+
+		if remediationInitiated {
+			reason := ""
+			if condition != nil {
+				reason = condition.Reason
+			}
+			r.recorder.Eventf(
+				t.Machine,
+				corev1.EventTypeNormal,
+				EventRemediationInitiated,
+				"Remediation %s of Machine %v has been initiated, reason: %s",
+				remediationID,
+				t.string(),
+				reason,
+			)
+			recordFailedReplacement(m, t)
+			recordRemediation(m, t, condition, remediationID, time.Now())
+			r.notifyRemediation(ctx, logger, t, condition)
+		}
+	}
+
+	if concurrencyLimited {
+		r.recorder.Eventf(
+			m,
+			corev1.EventTypeWarning,
+			EventRemediationConcurrencyLimited,
+			"Remediation is delayed, the number of remediations in progress has reached maxInFlightRemediations (%d)",
+			*m.Spec.MaxInFlightRemediations,
+		)
 	}
 	return errList
 }
 
+// getOwnerMachineSet returns the MachineSet owning the machine, or nil if the machine is not owned by a MachineSet.
+func (r *Reconciler) getOwnerMachineSet(ctx context.Context, machine *clusterv1.Machine) (*clusterv1.MachineSet, error) {
+	for _, ref := range machine.OwnerReferences {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return nil, err
+		}
+		if ref.Kind != "MachineSet" || gv.Group != clusterv1.GroupVersion.Group {
+			continue
+		}
+
+		ms := &clusterv1.MachineSet{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: ref.Name}, ms); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return ms, nil
+	}
+	return nil, nil
+}
+
+// isMachineSetScalingDown returns true if the MachineSet is being deleted, scaled to zero or scaled down; in this case
+// remediating the machines it owns would only create churn, given that the MachineSet is going to delete machines,
+// and unhealthy machines are deleted first.
+func isMachineSetScalingDown(ms *clusterv1.MachineSet) bool {
+	if ms == nil {
+		return false
+	}
+	if !ms.DeletionTimestamp.IsZero() {
+		return true
+	}
+	if ms.Spec.Replicas == nil {
+		return false
+	}
+	return *ms.Spec.Replicas == 0 || *ms.Spec.Replicas < ms.Status.Replicas
+}
+
+// getOwnerMachineDeployment returns the MachineDeployment owning the MachineSet, or nil if the MachineSet is not owned
+// by a MachineDeployment.
+func (r *Reconciler) getOwnerMachineDeployment(ctx context.Context, ms *clusterv1.MachineSet) (*clusterv1.MachineDeployment, error) {
+	ref := metav1.GetControllerOf(ms)
+	if ref == nil || ref.Kind != "MachineDeployment" {
+		return nil, nil
+	}
+	md := &clusterv1.MachineDeployment{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: ms.Namespace, Name: ref.Name}, md); err != nil {
+		return nil, errors.Wrapf(err, "failed to get MachineDeployment %s", ref.Name)
+	}
+	return md, nil
+}
+
+// isMachineDeploymentRollingOut returns true if any MachineSet owned by the MachineDeployment, other than the given one,
+// has replicas, i.e. if scaling down the MachineDeployment might scale down a MachineSet other than the given one.
+func (r *Reconciler) isMachineDeploymentRollingOut(ctx context.Context, md *clusterv1.MachineDeployment, ms *clusterv1.MachineSet) (bool, error) {
+	msList := &clusterv1.MachineSetList{}
+	if err := r.Client.List(ctx, msList, client.InNamespace(md.Namespace)); err != nil {
+		return false, errors.Wrapf(err, "failed to list MachineSets")
+	}
+	for i := range msList.Items {
+		other := &msList.Items[i]
+		if other.Name == ms.Name {
+			continue
+		}
+		if ref := metav1.GetControllerOf(other); ref == nil || ref.Kind != "MachineDeployment" || ref.Name != md.Name {
+			continue
+		}
+		if pointer.Int32Deref(other.Spec.Replicas, 0) > 0 || other.Status.Replicas > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// scaleDownMachineSetOwner scales down by one the MachineDeployment owning the MachineSet, if any, or the MachineSet,
+// given that the MachineDeployment would otherwise revert the replicas of the MachineSet; it returns the kind and name
+// of the scaled down object.
+// The machine is recorded on the scaled down object together with the new replicas, so calling this func again
+// for the same machine does not scale down again.
+func (r *Reconciler) scaleDownMachineSetOwner(ctx context.Context, ms *clusterv1.MachineSet, md *clusterv1.MachineDeployment, machine *clusterv1.Machine) (string, error) {
+	var obj client.Object = ms
+	replicas := &ms.Spec.Replicas
+	owner := fmt.Sprintf("MachineSet %s", ms.Name)
+	if md != nil {
+		obj = md
+		replicas = &md.Spec.Replicas
+		owner = fmt.Sprintf("MachineDeployment %s", md.Name)
+	}
+
+	scaledDownFor, err := r.scaledDownForMachines(ctx, obj)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to scale down %s", owner)
+	}
+	if scaledDownFor.Has(machine.Name) {
+		return owner, nil
+	}
+
+	newReplicas, err := scaledDownReplicas(*replicas)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to scale down %s", owner)
+	}
+	patchBase := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	*replicas = newReplicas
+	annotations.AddAnnotations(obj, map[string]string{scaledDownForMachinesAnnotation: strings.Join(scaledDownFor.Insert(machine.Name).List(), ",")})
+	if err := r.Client.Patch(ctx, obj, patchBase); err != nil {
+		return "", errors.Wrapf(err, "failed to scale down %s", owner)
+	}
+	return owner, nil
+}
+
+// scaledDownForMachines returns the names of the machines the object has been scaled down for, as recorded in the
+// scaled down for machines annotation; machines which do not exist anymore are dropped.
+func (r *Reconciler) scaledDownForMachines(ctx context.Context, obj client.Object) (sets.String, error) {
+	scaledDownFor := sets.NewString()
+	value := obj.GetAnnotations()[scaledDownForMachinesAnnotation]
+	if value == "" {
+		return scaledDownFor, nil
+	}
+	for _, name := range strings.Split(value, ",") {
+		if err := r.Client.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}, &clusterv1.Machine{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to get machine %s", name)
+		}
+		scaledDownFor.Insert(name)
+	}
+	return scaledDownFor, nil
+}
+
+// scaledDownReplicas returns the given replicas decreased by one; replicas default to 1 if not set.
+func scaledDownReplicas(replicas *int32) (*int32, error) {
+	current := pointer.Int32Deref(replicas, 1)
+	if current <= 0 {
+		return nil, errors.New("there are no replicas to scale down")
+	}
+	return pointer.Int32(current - 1), nil
+}
+
+// podDisruptionBudgetsBlockingNodeDrain returns the PodDisruptionBudgets, as namespace/name, which would be violated by evicting
+// the pods running on a node of the workload cluster.
+func podDisruptionBudgetsBlockingNodeDrain(ctx context.Context, c client.Reader, nodeName string) ([]string, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.MatchingFields{"spec.nodeName": nodeName}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list pods on node %s", nodeName)
+	}
+	if len(pods.Items) == 0 {
+		return nil, nil
+	}
+	pdbs := &policyv1.PodDisruptionBudgetList{}
+	if err := c.List(ctx, pdbs); err != nil {
+		return nil, errors.Wrap(err, "failed to list PodDisruptionBudgets")
+	}
+	return podDisruptionBudgetsBlockingEviction(pods.Items, pdbs.Items), nil
+}
+
+// podDisruptionBudgetsBlockingEviction returns the PodDisruptionBudgets, as namespace/name, which would be violated by evicting
+// all the given pods, i.e. the ones matching more pods than the disruptions they allow; pods which are not evicted
+// when draining a node according to collections.IsPodEvictedOnDrain are ignored.
+func podDisruptionBudgetsBlockingEviction(pods []corev1.Pod, pdbs []policyv1.PodDisruptionBudget) []string {
+	blocking := []string{}
+	for i := range pdbs {
+		pdb := &pdbs[i]
+		// NOTE: A nil selector matches no pods, while an empty selector matches all the pods in the namespace.
+		if pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+
+		evictions := int32(0)
+		for j := range pods {
+			pod := &pods[j]
+			if pod.Namespace != pdb.Namespace || !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			if !collections.IsPodEvictedOnDrain(pod) {
+				continue
+			}
+			evictions++
+		}
+		if evictions > pdb.Status.DisruptionsAllowed {
+			blocking = append(blocking, fmt.Sprintf("%s/%s", pdb.Namespace, pdb.Name))
+		}
+	}
+	sort.Strings(blocking)
+	return blocking
+}
+
+// countInFlightRemediations returns the number of remediations in progress for the targets of a MachineHealthCheck, i.e.
+// machines marked for remediation and not deleted yet, machines being remediated by their owner, machines being deleted,
+// machines whose node is being drained before remediation, and machines without a node yet, which are likely replacing
+// remediated machines.
+func (r *Reconciler) countInFlightRemediations(ctx context.Context, targets []healthCheckTarget, m *clusterv1.MachineHealthCheck) int {
+	count := 0
+	for _, t := range targets {
+		switch {
+		case !t.Machine.DeletionTimestamp.IsZero():
+			count++
+		case annotations.HasRemediationInProgress(t.Machine):
+			count++
+		case hasDrainBeforeRemediationStarted(t.Machine):
+			count++
+		case conditions.IsFalse(t.Machine, clusterv1.MachineOwnerRemediatedCondition):
+			count++
+		case t.Node == nil && !t.nodeMissing:
+			count++
+		case m.Spec.RemediationTemplate != nil && r.externalRemediationRequestExists(ctx, m, t.Machine.Name):
+			count++
+		}
+	}
+	return count
+}
+
+// newRemediationID returns the ID of a remediation of the machine initiated at the given time.
+// NOTE: The ID includes the machine UID, so remediations of machines re-created with the same name get different IDs.
+func newRemediationID(machine *clusterv1.Machine, now time.Time) string {
+	return fmt.Sprintf("%s-%s-%d", machine.Name, string(machine.UID), now.Unix())
+}
+
+// recordRemediation adds a record for a remediation initiated for the target to the remediation history of the
+// MachineHealthCheck, evicting the oldest records if the history exceeds MaxRemediationHistory.
+func recordRemediation(m *clusterv1.MachineHealthCheck, t healthCheckTarget, condition *clusterv1.Condition, id string, now time.Time) {
+	record := clusterv1.RemediationRecord{
+		ID:      id,
+		Time:    metav1.NewTime(now),
+		Machine: t.Machine.Name,
+	}
+	if condition != nil {
+		record.Reason = condition.Reason
+	}
+	m.Status.RemediationHistory = append(m.Status.RemediationHistory, record)
+	if len(m.Status.RemediationHistory) > clusterv1.MaxRemediationHistory {
+		m.Status.RemediationHistory = m.Status.RemediationHistory[len(m.Status.RemediationHistory)-clusterv1.MaxRemediationHistory:]
+	}
+}
+
+// isReplacement returns true if the machine has been created after the last remediation initiated by the
+// MachineHealthCheck, and thus it is assumed to be replacing a remediated machine.
+func isReplacement(m *clusterv1.MachineHealthCheck, machine *clusterv1.Machine) bool {
+	if len(m.Status.RemediationHistory) == 0 {
+		return false
+	}
+	lastRemediation := m.Status.RemediationHistory[len(m.Status.RemediationHistory)-1]
+	return machine.CreationTimestamp.After(lastRemediation.Time.Time)
+}
+
+// recordFailedReplacement updates the number of consecutive failed replacements of the MachineHealthCheck before
+// remediating the target; the target is a failed replacement if it is replacing a remediated machine and it never
+// got a node, otherwise the streak of failed replacements is broken.
+// NOTE: This must be called before recording the remediation of the target.
+func recordFailedReplacement(m *clusterv1.MachineHealthCheck, t healthCheckTarget) {
+	if isReplacement(m, t.Machine) && t.Machine.Status.NodeRef == nil {
+		m.Status.ConsecutiveFailedReplacements++
+		return
+	}
+	m.Status.ConsecutiveFailedReplacements = 0
+}
+
+// resetFailedReplacements resets the number of consecutive failed replacements of the MachineHealthCheck if any of
+// the healthy targets is replacing a remediated machine.
+func resetFailedReplacements(m *clusterv1.MachineHealthCheck, healthy []healthCheckTarget) {
+	for _, t := range healthy {
+		if isReplacement(m, t.Machine) {
+			m.Status.ConsecutiveFailedReplacements = 0
+			return
+		}
+	}
+}
+
+// replacementsFailing returns true if remediation must be paused because the number of consecutive failed replacements
+// reached the MaxConsecutiveFailedReplacements of the MachineHealthCheck.
+func replacementsFailing(m *clusterv1.MachineHealthCheck) bool {
+	return m.Spec.MaxConsecutiveFailedReplacements != nil && m.Status.ConsecutiveFailedReplacements >= *m.Spec.MaxConsecutiveFailedReplacements
+}
+
+// notifyRemediation notifies the RemediationNotifier, if any, that a remediation has been initiated for the target.
+// NOTE: Notifications are best effort, and failures are logged without affecting remediation.
+func (r *Reconciler) notifyRemediation(ctx context.Context, logger logr.Logger, t healthCheckTarget, condition *clusterv1.Condition) {
+	if r.RemediationNotifier == nil {
+		return
+	}
+
+	notification := RemediationNotification{
+		Namespace:          t.Machine.Namespace,
+		Cluster:            t.Machine.Spec.ClusterName,
+		MachineHealthCheck: t.MHC.Name,
+		Machine:            t.Machine.Name,
+	}
+	if condition != nil {
+		notification.Reason = condition.Reason
+		notification.Message = condition.Message
+	}
+	if err := r.RemediationNotifier.NotifyRemediation(ctx, notification); err != nil {
+		logger.Error(err, "Failed to send remediation notification", "target", t.string())
+	}
+}
+
 // clusterToMachineHealthCheck maps events from Cluster objects to
 // MachineHealthCheck objects that belong to the Cluster.
 func (r *Reconciler) clusterToMachineHealthCheck(o client.Object) []reconcile.Request {
@@ -460,15 +1253,25 @@ func (r *Reconciler) clusterToMachineHealthCheck(o client.Object) []reconcile.Re
 		context.TODO(),
 		mhcList,
 		client.InNamespace(c.Namespace),
-		client.MatchingLabels{clusterv1.ClusterLabelName: c.Name},
+		client.MatchingFields{index.MachineHealthCheckClusterNameField: c.Name},
 	); err != nil {
 		return nil
 	}
 
-	// This list should only contain MachineHealthChecks which belong to the given Cluster
+	// The index lookup should only return MachineHealthChecks which belong to the given Cluster,
+	// but double check in case the reader does not support field selectors;
+	// requests are deduplicated in case the same MachineHealthCheck is listed more than once.
 	requests := []reconcile.Request{}
+	seen := map[types.NamespacedName]bool{}
 	for _, mhc := range mhcList.Items {
+		if mhc.Spec.ClusterName != c.Name {
+			continue
+		}
 		key := types.NamespacedName{Namespace: mhc.Namespace, Name: mhc.Name}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
 		requests = append(requests, reconcile.Request{NamespacedName: key})
 	}
 	return requests
@@ -566,9 +1369,29 @@ func machineNames(machines []*clusterv1.Machine) []string {
 	return result
 }
 
+// validateSelectorBreadth returns an error if the MachineHealthCheck selector is empty, or if it matches
+// more than maxTargets Machines; a maxTargets value lower or equal to zero disables the latter check.
+// NOTE: Empty selectors are rejected by the webhook too, but they are checked again here as
+// a safety rail for objects created while the webhook was not enforcing this rule.
+func validateSelectorBreadth(mhc *clusterv1.MachineHealthCheck, totalTargets, maxTargets int) error {
+	selector, err := metav1.LabelSelectorAsSelector(&mhc.Spec.Selector)
+	if err != nil {
+		return errors.Wrap(err, "failed to build selector")
+	}
+	if selector.Empty() {
+		return errors.New("the selector is empty and matches all the machines in the cluster")
+	}
+	if maxTargets > 0 && totalTargets > maxTargets {
+		return errors.Errorf("the selector matches more machines than allowed (total: %v, maxTargets: %v)", totalTargets, maxTargets)
+	}
+	return nil
+}
+
 // isAllowedRemediation checks the value of the MaxUnhealthy field to determine
 // returns whether remediation should be allowed or not, the remediation count, and error if any.
-func isAllowedRemediation(mhc *clusterv1.MachineHealthCheck) (bool, int32, error) {
+// unhealthyTargets is the number of targets observed as unhealthy, which is used when MaxUnhealthy is evaluated
+// against the observed targets only.
+func isAllowedRemediation(mhc *clusterv1.MachineHealthCheck, unhealthyTargets int) (bool, int32, error) {
 	var remediationAllowed bool
 	var remediationCount int32
 	if mhc.Spec.UnhealthyRange != nil {
@@ -582,18 +1405,42 @@ func isAllowedRemediation(mhc *clusterv1.MachineHealthCheck) (bool, int32, error
 		return remediationAllowed, remediationCount, nil
 	}
 
-	maxUnhealthy, err := getMaxUnhealthy(mhc)
+	// By default, MaxUnhealthy is evaluated against the expected machines, counting machines not healthy yet as unhealthy;
+	// otherwise it is evaluated against the targets observed as either healthy or unhealthy.
+	total, unhealthyMachineCount := int(mhc.Status.ExpectedMachines), unhealthyMachineCount(mhc)
+	if mhc.Spec.MaxUnhealthyBase == clusterv1.MaxUnhealthyBaseObservedTargets {
+		total, unhealthyMachineCount = int(mhc.Status.CurrentHealthy)+unhealthyTargets, unhealthyTargets
+	}
+
+	maxUnhealthy, err := getMaxUnhealthy(mhc, total)
 	if err != nil {
 		return false, 0, err
 	}
 
 	// Remediation is not allowed if unhealthy is above maxUnhealthy
-	unhealthyMachineCount := unhealthyMachineCount(mhc)
 	remediationAllowed = unhealthyMachineCount <= maxUnhealthy
 	remediationCount = int32(maxUnhealthy - unhealthyMachineCount)
 	return remediationAllowed, remediationCount, nil
 }
 
+// isQuorumPreserved returns whether a quorum of the expected machines is healthy, and how many more machines
+// can become unhealthy before the quorum is lost.
+// NOTE: This applies the same math used for MaxUnhealthy, considering (N-1)/2 as maximum number of unhealthy machines.
+func isQuorumPreserved(mhc *clusterv1.MachineHealthCheck) (bool, int32) {
+	// If there are no expected machines, there is no quorum to preserve.
+	if mhc.Status.ExpectedMachines == 0 {
+		return true, 0
+	}
+	maxUnhealthy := int(mhc.Status.ExpectedMachines - quorum(mhc.Status.ExpectedMachines))
+	unhealthyMachineCount := unhealthyMachineCount(mhc)
+	return unhealthyMachineCount <= maxUnhealthy, int32(maxUnhealthy - unhealthyMachineCount)
+}
+
+// quorum returns the raft quorum for the given number of members, i.e. (N/2)+1.
+func quorum(members int32) int32 {
+	return members/2 + 1
+}
+
 // getUnhealthyRange parses an integer range and returns the min and max values
 // Eg. [2-5] will return (2,5,nil).
 func getUnhealthyRange(mhc *clusterv1.MachineHealthCheck) (int, int, error) {
@@ -619,14 +1466,19 @@ func getUnhealthyRange(mhc *clusterv1.MachineHealthCheck) (int, int, error) {
 	return int(min), int(max), nil
 }
 
-func getMaxUnhealthy(mhc *clusterv1.MachineHealthCheck) (int, error) {
+// getMaxUnhealthy returns the value of MaxUnhealthy, scaled against the given total number of machines if it is a percentage;
+// scaled percentages are rounded down, but not below MaxUnhealthyFloor.
+func getMaxUnhealthy(mhc *clusterv1.MachineHealthCheck, total int) (int, error) {
 	if mhc.Spec.MaxUnhealthy == nil {
 		return 0, errors.New("spec.maxUnhealthy must be set")
 	}
-	maxUnhealthy, err := intstr.GetScaledValueFromIntOrPercent(mhc.Spec.MaxUnhealthy, int(mhc.Status.ExpectedMachines), false)
+	maxUnhealthy, err := intstr.GetScaledValueFromIntOrPercent(mhc.Spec.MaxUnhealthy, total, false)
 	if err != nil {
 		return 0, err
 	}
+	if mhc.Spec.MaxUnhealthy.Type == intstr.String && maxUnhealthy < int(mhc.Spec.MaxUnhealthyFloor) {
+		maxUnhealthy = int(mhc.Spec.MaxUnhealthyFloor)
+	}
 	return maxUnhealthy, nil
 }
 
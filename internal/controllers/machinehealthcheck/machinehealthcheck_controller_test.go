@@ -20,16 +20,20 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -1801,6 +1805,61 @@ func TestMachineHealthCheck_Reconcile(t *testing.T) {
 	})
 }
 
+func TestReconcileClusterLabelAndOwnerRef(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testClusterName,
+			Namespace: metav1.NamespaceDefault,
+			UID:       "test-uid",
+		},
+	}
+	clusterOwnerRef := metav1.OwnerReference{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       "Cluster",
+		Name:       cluster.Name,
+		UID:        cluster.UID,
+	}
+	concurrentOwnerRef := metav1.OwnerReference{
+		APIVersion: "example.com/v1",
+		Kind:       "Owner",
+		Name:       "concurrent",
+		UID:        "concurrent-uid",
+	}
+	mhc := newMachineHealthCheck(cluster.Namespace, cluster.Name)
+	mhc.Name = "mhc"
+	mhc.Labels = map[string]string{"foo": "bar"}
+
+	cl := fake.NewClientBuilder().WithObjects(cluster, mhc).Build()
+	r := &Reconciler{Client: cl}
+
+	// Read the MachineHealthCheck, as the reconciler does.
+	m := &clusterv1.MachineHealthCheck{}
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(mhc), m)).To(Succeed())
+
+	// Simulate another controller adding an owner reference between read and write.
+	concurrent := m.DeepCopy()
+	concurrent.OwnerReferences = append(concurrent.OwnerReferences, concurrentOwnerRef)
+	g.Expect(cl.Update(ctx, concurrent)).To(Succeed())
+
+	// Reconciling the stale copy retries on the conflict and preserves the concurrent owner reference.
+	g.Expect(r.reconcileClusterLabelAndOwnerRef(ctx, m, cluster)).To(Succeed())
+
+	got := &clusterv1.MachineHealthCheck{}
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(mhc), got)).To(Succeed())
+	g.Expect(got.Labels).To(HaveKeyWithValue(clusterv1.ClusterLabelName, cluster.Name))
+	g.Expect(got.Labels).To(HaveKeyWithValue("foo", "bar"))
+	g.Expect(got.OwnerReferences).To(ConsistOf(concurrentOwnerRef, clusterOwnerRef))
+
+	// The MachineHealthCheck is refreshed to the persisted copy, so the patch helper doesn't carry stale changes.
+	g.Expect(m).To(Equal(got))
+
+	// Reconciling again is a no-op.
+	g.Expect(r.reconcileClusterLabelAndOwnerRef(ctx, m, cluster)).To(Succeed())
+	g.Expect(m).To(Equal(got))
+}
+
 func TestClusterToMachineHealthCheck(t *testing.T) {
 	fakeClient := fake.NewClientBuilder().Build()
 
@@ -1880,6 +1939,127 @@ func TestClusterToMachineHealthCheck(t *testing.T) {
 	}
 }
 
+func TestClusterToMachineHealthCheckDeduplicatesRequests(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	labels := make(map[string]string)
+
+	mhc1 := newMachineHealthCheckWithLabels("mhc1", namespace, clusterName, labels)
+	mhc2 := newMachineHealthCheckWithLabels("mhc2", namespace, clusterName, labels)
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+	}
+
+	r := &Reconciler{
+		Client: &duplicatingListClient{Client: fake.NewClientBuilder().WithObjects(mhc1, mhc2).Build()},
+	}
+
+	got := r.clusterToMachineHealthCheck(cluster)
+	g.Expect(got).To(ConsistOf(
+		reconcile.Request{NamespacedName: util.ObjectKey(mhc1)},
+		reconcile.Request{NamespacedName: util.ObjectKey(mhc2)},
+	))
+}
+
+func TestClusterToMachineHealthCheckWithManyMachineHealthChecks(t *testing.T) {
+	g := NewWithT(t)
+
+	ns, err := env.CreateNamespace(ctx, "test-mhc-mapping")
+	g.Expect(err).ToNot(HaveOccurred())
+	defer func() {
+		g.Expect(env.DeleteAllOf(ctx, &clusterv1.MachineHealthCheck{}, client.InNamespace(ns.Name))).To(Succeed())
+		g.Expect(env.Cleanup(ctx, ns)).To(Succeed())
+	}()
+
+	r := &Reconciler{Client: env.GetClient()}
+	cluster := createMachineHealthChecksForMapping(g, ns.Name, 1000, 2)
+
+	g.Expect(r.clusterToMachineHealthCheck(cluster)).To(ConsistOf(
+		reconcile.Request{NamespacedName: client.ObjectKey{Namespace: ns.Name, Name: "mhc-0-0"}},
+		reconcile.Request{NamespacedName: client.ObjectKey{Namespace: ns.Name, Name: "mhc-0-1"}},
+	))
+}
+
+func BenchmarkClusterToMachineHealthCheck(b *testing.B) {
+	g := NewWithT(b)
+
+	ns, err := env.CreateNamespace(ctx, "bench-mhc-mapping")
+	g.Expect(err).ToNot(HaveOccurred())
+	defer func() {
+		g.Expect(env.DeleteAllOf(ctx, &clusterv1.MachineHealthCheck{}, client.InNamespace(ns.Name))).To(Succeed())
+		g.Expect(env.Cleanup(ctx, ns)).To(Succeed())
+	}()
+
+	r := &Reconciler{Client: env.GetClient()}
+	cluster := createMachineHealthChecksForMapping(g, ns.Name, 1000, 2)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.clusterToMachineHealthCheck(cluster)
+	}
+}
+
+// createMachineHealthChecksForMapping creates perCluster MachineHealthChecks for each of the given number of
+// Clusters in the namespace, waits for them to be in the cache and returns the first Cluster.
+func createMachineHealthChecksForMapping(g *WithT, namespace string, clusters, perCluster int) *clusterv1.Cluster {
+	errs := make(chan error, clusters*perCluster)
+	wg := sync.WaitGroup{}
+	for i := 0; i < clusters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < perCluster; j++ {
+				mhc := newMachineHealthCheckWithLabels(fmt.Sprintf("mhc-%d-%d", i, j), namespace, fmt.Sprintf("cluster-%d", i), nil)
+				if err := env.Create(ctx, mhc); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	errList := []error{}
+	for err := range errs {
+		errList = append(errList, err)
+	}
+	g.Expect(kerrors.NewAggregate(errList)).ToNot(HaveOccurred())
+
+	g.Eventually(func() int {
+		mhcList := &clusterv1.MachineHealthCheckList{}
+		if err := env.List(ctx, mhcList, client.InNamespace(namespace)); err != nil {
+			return 0
+		}
+		return len(mhcList.Items)
+	}, timeout).Should(Equal(clusters * perCluster))
+
+	return &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-0",
+			Namespace: namespace,
+		},
+	}
+}
+
+// duplicatingListClient is a client returning every MachineHealthCheck twice when listing MachineHealthChecks.
+type duplicatingListClient struct {
+	client.Client
+}
+
+func (c *duplicatingListClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	if mhcList, ok := list.(*clusterv1.MachineHealthCheckList); ok {
+		mhcList.Items = append(mhcList.Items, mhcList.Items...)
+	}
+	return nil
+}
+
 func TestMachineToMachineHealthCheck(t *testing.T) {
 	fakeClient := fake.NewClientBuilder().Build()
 
@@ -1900,12 +2080,41 @@ func TestMachineToMachineHealthCheck(t *testing.T) {
 	mhc4 := newMachineHealthCheckWithLabels("mhc4", "othernamespace", clusterName, labels)
 	machine1 := newTestMachine("machine1", namespace, clusterName, nodeName, labels)
 
+	// MachineHealthChecks selecting by expressions only; they are expected to match the same way as the Reconciler does.
+	withMatchExpressions := func(name string, requirements ...metav1.LabelSelectorRequirement) *clusterv1.MachineHealthCheck {
+		mhc := newMachineHealthCheckWithLabels(name, namespace, clusterName, nil)
+		mhc.Spec.Selector.MatchLabels = nil
+		mhc.Spec.Selector.MatchExpressions = requirements
+		return mhc
+	}
+	mhcIn := withMatchExpressions("mhc-in", metav1.LabelSelectorRequirement{Key: "nodepool", Operator: metav1.LabelSelectorOpIn, Values: []string{"bar", "baz"}})
+	mhcInReq := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mhcIn.Namespace, Name: mhcIn.Name}}
+	mhcNotIn := withMatchExpressions("mhc-notin", metav1.LabelSelectorRequirement{Key: "nodepool", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"bar"}})
+	mhcExists := withMatchExpressions("mhc-exists", metav1.LabelSelectorRequirement{Key: "nodepool", Operator: metav1.LabelSelectorOpExists})
+	mhcExistsReq := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mhcExists.Namespace, Name: mhcExists.Name}}
+	mhcDoesNotExist := withMatchExpressions("mhc-doesnotexist", metav1.LabelSelectorRequirement{Key: "nodepool", Operator: metav1.LabelSelectorOpDoesNotExist})
+	mhcMixed := newMachineHealthCheckWithLabels("mhc-mixed", namespace, clusterName, map[string]string{"cluster": "foo"})
+	mhcMixed.Spec.Selector.MatchExpressions = []metav1.LabelSelectorRequirement{{Key: "nodepool", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"other"}}}
+	mhcMixedReq := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mhcMixed.Namespace, Name: mhcMixed.Name}}
+
 	testCases := []struct {
 		name     string
 		toCreate []clusterv1.MachineHealthCheck
 		object   client.Object
 		expected []reconcile.Request
 	}{
+		{
+			name:     "when MachineHealthChecks match expressions for the Machine in the same namespace",
+			toCreate: []clusterv1.MachineHealthCheck{*mhcIn, *mhcExists, *mhcMixed},
+			object:   machine1,
+			expected: []reconcile.Request{mhcInReq, mhcExistsReq, mhcMixedReq},
+		},
+		{
+			name:     "when MachineHealthChecks do not match expressions for the Machine in the same namespace",
+			toCreate: []clusterv1.MachineHealthCheck{*mhcNotIn, *mhcDoesNotExist},
+			object:   machine1,
+			expected: []reconcile.Request{},
+		},
 		{
 			name:     "when a MachineHealthCheck matches labels for the Machine in the same namespace",
 			toCreate: []clusterv1.MachineHealthCheck{*mhc1},
@@ -2163,8 +2372,273 @@ func TestIsAllowedRemediation(t *testing.T) {
 				},
 			}
 
-			remediationAllowed, _, _ := isAllowedRemediation(mhc)
+			remediationAllowed, _, _ := isAllowedRemediation(mhc, int(tc.expectedMachines-tc.currentHealthy))
+			g.Expect(remediationAllowed).To(Equal(tc.allowed))
+		})
+	}
+}
+
+func TestIsAllowedRemediationMaxUnhealthyBase(t *testing.T) {
+	// During a scale up, 10 machines are expected but only 6 have been observed: 4 healthy, 2 unhealthy,
+	// while the other 4 are still waiting for a node.
+	testCases := []struct {
+		name             string
+		maxUnhealthyBase clusterv1.MaxUnhealthyBase
+		maxUnhealthy     intstr.IntOrString
+		allowed          bool
+		remediationCount int32
+	}{
+		{
+			name:         "ExpectedMachines is the default, and counts machines waiting for a node as unhealthy",
+			maxUnhealthy: intstr.FromString("40%"),
+			allowed:      false,
+		},
+		{
+			name:             "ExpectedMachines counts machines waiting for a node as unhealthy",
+			maxUnhealthyBase: clusterv1.MaxUnhealthyBaseExpectedMachines,
+			maxUnhealthy:     intstr.FromString("60%"),
+			allowed:          true,
+			remediationCount: 0,
+		},
+		{
+			name:             "ObservedTargets scales percentages against the observed targets only",
+			maxUnhealthyBase: clusterv1.MaxUnhealthyBaseObservedTargets,
+			maxUnhealthy:     intstr.FromString("40%"),
+			allowed:          true,
+			remediationCount: 0,
+		},
+		{
+			name:             "ObservedTargets does not count machines waiting for a node as unhealthy",
+			maxUnhealthyBase: clusterv1.MaxUnhealthyBaseObservedTargets,
+			maxUnhealthy:     intstr.FromInt(3),
+			allowed:          true,
+			remediationCount: 1,
+		},
+		{
+			name:             "ObservedTargets does not allow remediation when the observed unhealthy machines exceed maxUnhealthy",
+			maxUnhealthyBase: clusterv1.MaxUnhealthyBaseObservedTargets,
+			maxUnhealthy:     intstr.FromString("30%"),
+			allowed:          false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := &clusterv1.MachineHealthCheck{
+				Spec: clusterv1.MachineHealthCheckSpec{
+					MaxUnhealthy:     &tc.maxUnhealthy,
+					MaxUnhealthyBase: tc.maxUnhealthyBase,
+				},
+				Status: clusterv1.MachineHealthCheckStatus{
+					ExpectedMachines: 10,
+					CurrentHealthy:   4,
+				},
+			}
+
+			remediationAllowed, remediationCount, err := isAllowedRemediation(mhc, 2)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(remediationAllowed).To(Equal(tc.allowed))
+			if tc.allowed {
+				g.Expect(remediationCount).To(Equal(tc.remediationCount))
+			}
+		})
+	}
+}
+
+func TestIsAllowedRemediationMaxUnhealthyFloor(t *testing.T) {
+	testCases := []struct {
+		name              string
+		maxUnhealthy      intstr.IntOrString
+		maxUnhealthyFloor int32
+		expectedMachines  int32
+		currentHealthy    int32
+		allowed           bool
+		remediationCount  int32
+	}{
+		{
+			name:             "a percentage rounding to zero does not allow remediation without a floor",
+			maxUnhealthy:     intstr.FromString("25%"),
+			expectedMachines: 2,
+			currentHealthy:   1,
+			allowed:          false,
+		},
+		{
+			name:              "a percentage rounding to zero resolves to the floor",
+			maxUnhealthy:      intstr.FromString("25%"),
+			maxUnhealthyFloor: 1,
+			expectedMachines:  2,
+			currentHealthy:    1,
+			allowed:           true,
+			remediationCount:  0,
+		},
+		{
+			name:              "the floor does not allow more unhealthy machines than itself",
+			maxUnhealthy:      intstr.FromString("25%"),
+			maxUnhealthyFloor: 1,
+			expectedMachines:  3,
+			currentHealthy:    1,
+			allowed:           false,
+		},
+		{
+			name:              "a percentage resolving above the floor is not changed",
+			maxUnhealthy:      intstr.FromString("50%"),
+			maxUnhealthyFloor: 1,
+			expectedMachines:  6,
+			currentHealthy:    5,
+			allowed:           true,
+			remediationCount:  2,
+		},
+		{
+			name:              "the floor does not apply to absolute numbers",
+			maxUnhealthy:      intstr.FromInt(0),
+			maxUnhealthyFloor: 1,
+			expectedMachines:  2,
+			currentHealthy:    1,
+			allowed:           false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := &clusterv1.MachineHealthCheck{
+				Spec: clusterv1.MachineHealthCheckSpec{
+					MaxUnhealthy:      &tc.maxUnhealthy,
+					MaxUnhealthyFloor: tc.maxUnhealthyFloor,
+				},
+				Status: clusterv1.MachineHealthCheckStatus{
+					ExpectedMachines: tc.expectedMachines,
+					CurrentHealthy:   tc.currentHealthy,
+				},
+			}
+
+			remediationAllowed, remediationCount, err := isAllowedRemediation(mhc, int(tc.expectedMachines-tc.currentHealthy))
+			g.Expect(err).ToNot(HaveOccurred())
 			g.Expect(remediationAllowed).To(Equal(tc.allowed))
+			if tc.allowed {
+				g.Expect(remediationCount).To(Equal(tc.remediationCount))
+			}
+		})
+	}
+}
+
+func TestIsQuorumPreserved(t *testing.T) {
+	testCases := []struct {
+		expectedMachines int32
+		currentHealthy   int32
+		allowed          bool
+		remediationCount int32
+	}{
+		{expectedMachines: 0, currentHealthy: 0, allowed: true, remediationCount: 0},
+		{expectedMachines: 1, currentHealthy: 1, allowed: true, remediationCount: 0},
+		{expectedMachines: 1, currentHealthy: 0, allowed: false},
+		{expectedMachines: 3, currentHealthy: 3, allowed: true, remediationCount: 1},
+		{expectedMachines: 3, currentHealthy: 2, allowed: true, remediationCount: 0},
+		{expectedMachines: 3, currentHealthy: 1, allowed: false},
+		{expectedMachines: 5, currentHealthy: 5, allowed: true, remediationCount: 2},
+		{expectedMachines: 5, currentHealthy: 4, allowed: true, remediationCount: 1},
+		{expectedMachines: 5, currentHealthy: 3, allowed: true, remediationCount: 0},
+		{expectedMachines: 5, currentHealthy: 2, allowed: false},
+		{expectedMachines: 7, currentHealthy: 7, allowed: true, remediationCount: 3},
+		{expectedMachines: 7, currentHealthy: 5, allowed: true, remediationCount: 1},
+		{expectedMachines: 7, currentHealthy: 4, allowed: true, remediationCount: 0},
+		{expectedMachines: 7, currentHealthy: 3, allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%d healthy out of %d", tc.currentHealthy, tc.expectedMachines), func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := &clusterv1.MachineHealthCheck{
+				Spec: clusterv1.MachineHealthCheckSpec{
+					PreserveQuorum: true,
+				},
+				Status: clusterv1.MachineHealthCheckStatus{
+					ExpectedMachines: tc.expectedMachines,
+					CurrentHealthy:   tc.currentHealthy,
+				},
+			}
+
+			allowed, remediationCount := isQuorumPreserved(mhc)
+			g.Expect(allowed).To(Equal(tc.allowed))
+			if tc.allowed {
+				g.Expect(remediationCount).To(Equal(tc.remediationCount))
+			}
+		})
+	}
+}
+
+func TestValidateSelectorBreadth(t *testing.T) {
+	testCases := []struct {
+		name         string
+		selector     metav1.LabelSelector
+		totalTargets int
+		maxTargets   int
+		wantErr      bool
+	}{
+		{
+			name:         "when the selector is empty",
+			selector:     metav1.LabelSelector{},
+			totalTargets: 3,
+			maxTargets:   0,
+			wantErr:      true,
+		},
+		{
+			name:         "when the selector is empty and within maxTargets",
+			selector:     metav1.LabelSelector{},
+			totalTargets: 3,
+			maxTargets:   5,
+			wantErr:      true,
+		},
+		{
+			name:         "when maxTargets is not set",
+			selector:     metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			totalTargets: 100,
+			maxTargets:   0,
+			wantErr:      false,
+		},
+		{
+			name:         "when the selector matches less machines than maxTargets",
+			selector:     metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			totalTargets: 3,
+			maxTargets:   5,
+			wantErr:      false,
+		},
+		{
+			name:         "when the selector matches as many machines as maxTargets",
+			selector:     metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			totalTargets: 5,
+			maxTargets:   5,
+			wantErr:      false,
+		},
+		{
+			name:         "when the selector matches more machines than maxTargets",
+			selector:     metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			totalTargets: 6,
+			maxTargets:   5,
+			wantErr:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := &clusterv1.MachineHealthCheck{
+				Spec: clusterv1.MachineHealthCheckSpec{
+					Selector: tc.selector,
+				},
+			}
+
+			err := validateSelectorBreadth(mhc, tc.totalTargets, tc.maxTargets)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
 		})
 	}
 }
@@ -2227,7 +2701,7 @@ func TestGetMaxUnhealthy(t *testing.T) {
 				},
 			}
 
-			maxUnhealthy, err := getMaxUnhealthy(mhc)
+			maxUnhealthy, err := getMaxUnhealthy(mhc, int(tc.actualMachineCount))
 			if tc.expectedErr != nil {
 				g.Expect(err).To(MatchError(tc.expectedErr.Error()))
 			} else {
@@ -2590,9 +3064,10 @@ func TestPatchTargets(t *testing.T) {
 		mhc,
 	).Build()
 	r := &Reconciler{
-		Client:   cl,
-		recorder: record.NewFakeRecorder(32),
-		Tracker:  remote.NewTestClusterCacheTracker(logr.New(log.NullLogSink{}), cl, scheme.Scheme, client.ObjectKey{Name: clusterName, Namespace: namespace}, "machinehealthcheck-watchClusterNodes"),
+		Client:             cl,
+		remoteClientGetter: remoteClientGetterFor(cl),
+		recorder:           record.NewFakeRecorder(32),
+		Tracker:            remote.NewTestClusterCacheTracker(logr.New(log.NullLogSink{}), cl, scheme.Scheme, client.ObjectKey{Name: clusterName, Namespace: namespace}, "machinehealthcheck-watchClusterNodes"),
 	}
 
 	// To make the patch fail, create patchHelper with a different client.
@@ -2617,10 +3092,1342 @@ func TestPatchTargets(t *testing.T) {
 	}
 
 	// Target with wrong patch helper will fail but the other one will be patched.
-	g.Expect(len(r.patchUnhealthyTargets(context.TODO(), logr.New(log.NullLogSink{}), []healthCheckTarget{target1, target3}, defaultCluster, mhc))).To(BeNumerically(">", 0))
+	g.Expect(len(r.patchUnhealthyTargets(context.TODO(), logr.New(log.NullLogSink{}), []healthCheckTarget{target1, target3}, r.uncachedWorkloadClient(ctx, defaultCluster), defaultCluster, mhc, 0))).To(BeNumerically(">", 0))
 	g.Expect(cl.Get(ctx, client.ObjectKey{Name: machine2.Name, Namespace: machine2.Namespace}, machine2)).NotTo(HaveOccurred())
 	g.Expect(conditions.Get(machine2, clusterv1.MachineOwnerRemediatedCondition).Status).To(Equal(corev1.ConditionFalse))
 
 	// Target with wrong patch helper will fail but the other one will be patched.
 	g.Expect(len(r.patchHealthyTargets(context.TODO(), logr.New(log.NullLogSink{}), []healthCheckTarget{target1, target3}, mhc))).To(BeNumerically(">", 0))
 }
+
+func TestReconcileComputesCountsBeforeRemediation(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+	}
+	conditions.MarkTrue(cluster, clusterv1.InfrastructureReadyCondition)
+	conditions.MarkTrue(cluster, clusterv1.ControlPlaneInitializedCondition)
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	// A brand-new MachineHealthCheck, which never reported any count; evaluating 40% of zero expected machines with
+	// zero unhealthy machines would allow remediation, while 40% of 2 machines with 1 unhealthy does not.
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	maxUnhealthy := intstr.FromString("40%")
+	mhc.Spec.MaxUnhealthy = &maxUnhealthy
+
+	healthyNode := newTestNode("healthy-node")
+	unhealthyNode := newTestUnhealthyNode("unhealthy-node", corev1.NodeReady, corev1.ConditionUnknown, 10*time.Minute)
+	healthyMachine := newTestMachine("healthy-machine", namespace, clusterName, healthyNode.Name, labels)
+	unhealthyMachine := newTestMachine("unhealthy-machine", namespace, clusterName, unhealthyNode.Name, labels)
+
+	cl := fake.NewClientBuilder().WithObjects(cluster, mhc, healthyNode, unhealthyNode, healthyMachine, unhealthyMachine).Build()
+	r := &Reconciler{
+		Client:             cl,
+		remoteClientGetter: remoteClientGetterFor(cl),
+		recorder:           record.NewFakeRecorder(32),
+		Tracker:            remote.NewTestClusterCacheTracker(logr.New(log.NullLogSink{}), cl, scheme.Scheme, client.ObjectKey{Name: clusterName, Namespace: namespace}, "machinehealthcheck-watchClusterNodes"),
+	}
+
+	_, err := r.reconcile(ctx, logr.New(log.NullLogSink{}), cluster, mhc)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Remediation has been evaluated against the counts computed in this reconcile.
+	g.Expect(conditions.IsFalse(mhc, clusterv1.RemediationAllowedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(mhc, clusterv1.RemediationAllowedCondition)).To(Equal(clusterv1.TooManyUnhealthyReason))
+
+	// The counts have been persisted before evaluating remediation.
+	persisted := &clusterv1.MachineHealthCheck{}
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(mhc), persisted)).To(Succeed())
+	g.Expect(persisted.Status.ExpectedMachines).To(Equal(int32(2)))
+	g.Expect(persisted.Status.CurrentHealthy).To(Equal(int32(1)))
+	g.Expect(persisted.Status.Targets).To(ConsistOf(healthyMachine.Name, unhealthyMachine.Name))
+
+	// The unhealthy machine has not been remediated.
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(unhealthyMachine), unhealthyMachine)).To(Succeed())
+	g.Expect(conditions.Has(unhealthyMachine, clusterv1.MachineOwnerRemediatedCondition)).To(BeFalse())
+}
+
+func TestReconcileAllTargetsUnhealthy(t *testing.T) {
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	reconcileWithNodes := func(g *WithT, nodes ...*corev1.Node) []string {
+		cluster := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      clusterName,
+				Namespace: namespace,
+			},
+		}
+		conditions.MarkTrue(cluster, clusterv1.InfrastructureReadyCondition)
+		conditions.MarkTrue(cluster, clusterv1.ControlPlaneInitializedCondition)
+		mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+		maxUnhealthy := intstr.FromString("40%")
+		mhc.Spec.MaxUnhealthy = &maxUnhealthy
+
+		objs := []client.Object{cluster, mhc}
+		for _, node := range nodes {
+			objs = append(objs, node, newTestMachine("machine-"+node.Name, namespace, clusterName, node.Name, labels))
+		}
+		cl := fake.NewClientBuilder().WithObjects(objs...).Build()
+		recorder := record.NewFakeRecorder(32)
+		r := &Reconciler{
+			Client:             cl,
+			remoteClientGetter: remoteClientGetterFor(cl),
+			recorder:           recorder,
+			Tracker:            remote.NewTestClusterCacheTracker(logr.New(log.NullLogSink{}), cl, scheme.Scheme, client.ObjectKey{Name: clusterName, Namespace: namespace}, "machinehealthcheck-watchClusterNodes"),
+		}
+
+		_, err := r.reconcile(ctx, logr.New(log.NullLogSink{}), cluster, mhc)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		var events []string
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		return events
+	}
+
+	t.Run("emits an event when all the targets are unhealthy", func(t *testing.T) {
+		g := NewWithT(t)
+
+		events := reconcileWithNodes(g,
+			newTestUnhealthyNode("node-1", corev1.NodeReady, corev1.ConditionUnknown, 10*time.Minute),
+			newTestUnhealthyNode("node-2", corev1.NodeReady, corev1.ConditionUnknown, 10*time.Minute),
+		)
+		g.Expect(events).To(ContainElement(And(ContainSubstring(corev1.EventTypeWarning), ContainSubstring(EventAllTargetsUnhealthy))))
+		// The event is distinct from the short-circuiting one.
+		g.Expect(events).To(ContainElement(ContainSubstring(EventRemediationRestricted)))
+	})
+
+	t.Run("does not emit the event when some targets are healthy", func(t *testing.T) {
+		g := NewWithT(t)
+
+		events := reconcileWithNodes(g,
+			newTestNode("node-1"),
+			newTestUnhealthyNode("node-2", corev1.NodeReady, corev1.ConditionUnknown, 10*time.Minute),
+		)
+		g.Expect(events).ToNot(ContainElement(ContainSubstring(EventAllTargetsUnhealthy)))
+	})
+
+	t.Run("does not emit the event when the targets are not healthy but not unhealthy yet", func(t *testing.T) {
+		g := NewWithT(t)
+
+		// The nodes are not ready, but not for longer than the timeout of the unhealthy condition.
+		events := reconcileWithNodes(g,
+			newTestUnhealthyNode("node-1", corev1.NodeReady, corev1.ConditionUnknown, 10*time.Second),
+			newTestUnhealthyNode("node-2", corev1.NodeReady, corev1.ConditionUnknown, 10*time.Second),
+		)
+		g.Expect(events).ToNot(ContainElement(ContainSubstring(EventAllTargetsUnhealthy)))
+	})
+
+	t.Run("does not emit the event when there are no targets", func(t *testing.T) {
+		g := NewWithT(t)
+
+		events := reconcileWithNodes(g)
+		g.Expect(events).ToNot(ContainElement(ContainSubstring(EventAllTargetsUnhealthy)))
+	})
+}
+
+func TestReconcileRemediationKillSwitch(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+	}
+	conditions.MarkTrue(cluster, clusterv1.InfrastructureReadyCondition)
+	conditions.MarkTrue(cluster, clusterv1.ControlPlaneInitializedCondition)
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	healthyNode := newTestNode("healthy-node")
+	unhealthyNode := newTestUnhealthyNode("unhealthy-node", corev1.NodeReady, corev1.ConditionUnknown, 10*time.Minute)
+	healthyMachine := newTestMachine("healthy-machine", namespace, clusterName, healthyNode.Name, labels)
+	unhealthyMachine := newTestMachine("unhealthy-machine", namespace, clusterName, unhealthyNode.Name, labels)
+	killSwitch := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "capi-system",
+			Name:        "mhc-kill-switch",
+			Annotations: map[string]string{clusterv1.RemediationGloballyDisabledAnnotation: "true"},
+		},
+	}
+
+	cl := fake.NewClientBuilder().WithObjects(cluster, mhc, healthyNode, unhealthyNode, healthyMachine, unhealthyMachine, killSwitch).Build()
+	recorder := record.NewFakeRecorder(32)
+	r := &Reconciler{
+		Client:                         cl,
+		recorder:                       recorder,
+		Tracker:                        remote.NewTestClusterCacheTracker(logr.New(log.NullLogSink{}), cl, scheme.Scheme, client.ObjectKey{Name: clusterName, Namespace: namespace}, "machinehealthcheck-watchClusterNodes"),
+		RemediationKillSwitchConfigMap: client.ObjectKeyFromObject(killSwitch),
+		remoteClientGetter:             remoteClientGetterFor(cl),
+	}
+
+	// The kill switch is on, so the unhealthy machine is reported but not remediated.
+	_, err := r.reconcile(ctx, logr.New(log.NullLogSink{}), cluster, mhc)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conditions.IsFalse(mhc, clusterv1.RemediationAllowedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(mhc, clusterv1.RemediationAllowedCondition)).To(Equal(clusterv1.RemediationGloballyDisabledReason))
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(unhealthyMachine), unhealthyMachine)).To(Succeed())
+	g.Expect(conditions.IsFalse(unhealthyMachine, clusterv1.MachineHealthCheckSucceededCondition)).To(BeTrue())
+	g.Expect(conditions.Has(unhealthyMachine, clusterv1.MachineOwnerRemediatedCondition)).To(BeFalse())
+
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	g.Expect(events).To(ContainElement(ContainSubstring(EventRemediationGloballyDisabled)))
+
+	// The kill switch is turned off, so the unhealthy machine is remediated.
+	killSwitch.Annotations[clusterv1.RemediationGloballyDisabledAnnotation] = "false"
+	g.Expect(cl.Update(ctx, killSwitch)).To(Succeed())
+
+	_, err = r.reconcile(ctx, logr.New(log.NullLogSink{}), cluster, mhc)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conditions.IsTrue(mhc, clusterv1.RemediationAllowedCondition)).To(BeTrue())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(unhealthyMachine), unhealthyMachine)).To(Succeed())
+	g.Expect(conditions.IsFalse(unhealthyMachine, clusterv1.MachineOwnerRemediatedCondition)).To(BeTrue())
+
+	// A missing kill switch is the same as the kill switch being off.
+	g.Expect(cl.Delete(ctx, killSwitch)).To(Succeed())
+	disabled, err := r.remediationGloballyDisabled(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(disabled).To(BeFalse())
+}
+
+func TestReconcileClusterNotFound(t *testing.T) {
+	g := NewWithT(t)
+
+	mhc := newMachineHealthCheck(metav1.NamespaceDefault, "does-not-exist")
+	mhc.Name = "mhc"
+
+	cl := fake.NewClientBuilder().WithObjects(mhc).Build()
+	recorder := record.NewFakeRecorder(32)
+	r := &Reconciler{
+		Client:             cl,
+		remoteClientGetter: remoteClientGetterFor(cl),
+		recorder:           recorder,
+	}
+
+	_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(mhc)})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(mhc), mhc)).To(Succeed())
+	g.Expect(conditions.IsFalse(mhc, clusterv1.RemediationAllowedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(mhc, clusterv1.RemediationAllowedCondition)).To(Equal(clusterv1.ClusterNotFoundReason))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring(EventClusterNotFound)))
+}
+
+func TestPatchUnhealthyTargetsMaxInFlightRemediations(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	defaultCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+	}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	mhc.Spec.MaxInFlightRemediations = pointer.Int32(1)
+	machine1 := newTestMachine("machine1", namespace, clusterName, "nodeName", labels)
+	conditions.MarkFalse(machine1, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+	machine2 := newTestMachine("machine2", namespace, clusterName, "nodeName", labels)
+	conditions.MarkFalse(machine2, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+
+	cl := fake.NewClientBuilder().WithObjects(machine1, machine2, mhc).Build()
+	recorder := record.NewFakeRecorder(32)
+	r := &Reconciler{
+		Client:             cl,
+		remoteClientGetter: remoteClientGetterFor(cl),
+		recorder:           recorder,
+	}
+
+	var targets []healthCheckTarget
+	for _, m := range []*clusterv1.Machine{machine1, machine2} {
+		patchHelper, err := patch.NewHelper(m, cl)
+		g.Expect(err).ToNot(HaveOccurred())
+		targets = append(targets, healthCheckTarget{
+			MHC:         mhc,
+			Machine:     m,
+			patchHelper: patchHelper,
+			Node:        &corev1.Node{},
+		})
+	}
+
+	g.Expect(r.countInFlightRemediations(ctx, targets, mhc)).To(Equal(0))
+
+	// Only the first target is marked for remediation, the second one is delayed.
+	g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), targets, r.uncachedWorkloadClient(ctx, defaultCluster), defaultCluster, mhc, 0)).To(BeEmpty())
+
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine1), machine1)).To(Succeed())
+	g.Expect(conditions.IsFalse(machine1, clusterv1.MachineOwnerRemediatedCondition)).To(BeTrue())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine2), machine2)).To(Succeed())
+	g.Expect(conditions.Has(machine2, clusterv1.MachineOwnerRemediatedCondition)).To(BeFalse())
+	g.Expect(conditions.IsFalse(machine2, clusterv1.MachineHealthCheckSucceededCondition)).To(BeTrue())
+
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	g.Expect(events).To(ContainElement(ContainSubstring(EventRemediationConcurrencyLimited)))
+
+	// The remediation of the first target is now in progress.
+	g.Expect(r.countInFlightRemediations(ctx, targets, mhc)).To(Equal(1))
+}
+
+func TestCountInFlightRemediations(t *testing.T) {
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+
+	healthy := newTestMachine("healthy", namespace, clusterName, "node1", labels)
+	remediating := newTestMachine("remediating", namespace, clusterName, "node2", labels)
+	conditions.MarkFalse(remediating, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "")
+	deleting := newTestMachine("deleting", namespace, clusterName, "node3", labels)
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	provisioning := newTestMachine("provisioning", namespace, clusterName, "", labels)
+	provisioning.Status.NodeRef = nil
+	nodeGone := newTestMachine("node-gone", namespace, clusterName, "node4", labels)
+	remediatedByOwner := newTestMachine("remediated-by-owner", namespace, clusterName, "node5", labels)
+	remediatedByOwner.Annotations = map[string]string{clusterv1.RemediationInProgressAnnotation: ""}
+
+	tests := []struct {
+		name    string
+		targets []healthCheckTarget
+		want    int
+	}{
+		{
+			name:    "no targets",
+			targets: nil,
+			want:    0,
+		},
+		{
+			name:    "healthy target",
+			targets: []healthCheckTarget{{Machine: healthy, Node: &corev1.Node{}}},
+			want:    0,
+		},
+		{
+			name:    "target with node missing is not in flight",
+			targets: []healthCheckTarget{{Machine: nodeGone, nodeMissing: true}},
+			want:    0,
+		},
+		{
+			name:    "target being remediated by its owner",
+			targets: []healthCheckTarget{{Machine: remediatedByOwner, Node: &corev1.Node{}}},
+			want:    1,
+		},
+		{
+			name: "targets being remediated, deleted or provisioned",
+			targets: []healthCheckTarget{
+				{Machine: healthy, Node: &corev1.Node{}},
+				{Machine: remediating, Node: &corev1.Node{}},
+				{Machine: deleting, Node: &corev1.Node{}},
+				{Machine: provisioning},
+			},
+			want: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &Reconciler{Client: fake.NewClientBuilder().Build()}
+			g.Expect(r.countInFlightRemediations(ctx, tt.targets, mhc)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestPatchUnhealthyTargetsRemediationInProgress(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	defaultCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+	}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	machine := newTestMachine("machine1", namespace, clusterName, "nodeName", labels)
+	machine.Annotations = map[string]string{clusterv1.RemediationInProgressAnnotation: ""}
+	conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+
+	cl := fake.NewClientBuilder().WithObjects(machine, mhc).Build()
+	notifier := &fakeRemediationNotifier{}
+	r := &Reconciler{
+		Client:              cl,
+		remoteClientGetter:  remoteClientGetterFor(cl),
+		recorder:            record.NewFakeRecorder(32),
+		RemediationNotifier: notifier,
+	}
+
+	patchHelper, err := patch.NewHelper(machine, cl)
+	g.Expect(err).ToNot(HaveOccurred())
+	target := healthCheckTarget{
+		MHC:         mhc,
+		Machine:     machine,
+		patchHelper: patchHelper,
+		Node:        &corev1.Node{},
+	}
+
+	// The machine is reported as unhealthy, but the remediation is left to the controller which is already remediating it.
+	g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), []healthCheckTarget{target}, r.uncachedWorkloadClient(ctx, defaultCluster), defaultCluster, mhc, 0)).To(BeEmpty())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+	g.Expect(conditions.IsFalse(machine, clusterv1.MachineHealthCheckSucceededCondition)).To(BeTrue())
+	g.Expect(conditions.Has(machine, clusterv1.MachineOwnerRemediatedCondition)).To(BeFalse())
+	g.Expect(notifier.notifications).To(BeEmpty())
+}
+
+func TestPatchUnhealthyTargetsNodeRecovered(t *testing.T) {
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	defaultCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+	}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	tests := []struct {
+		name              string
+		nodeReadyStatus   corev1.ConditionStatus
+		paused            bool
+		expectRemediation bool
+		expectNodeRead    bool
+	}{
+		{
+			name:              "node recovered between the health check and the remediation",
+			nodeReadyStatus:   corev1.ConditionTrue,
+			expectRemediation: false,
+			expectNodeRead:    true,
+		},
+		{
+			name:              "node still unhealthy",
+			nodeReadyStatus:   corev1.ConditionUnknown,
+			expectRemediation: true,
+			expectNodeRead:    true,
+		},
+		{
+			name:              "node is not read if the machine is not going to be remediated anyway",
+			nodeReadyStatus:   corev1.ConditionTrue,
+			paused:            true,
+			expectRemediation: false,
+			expectNodeRead:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+			machine := newTestMachine("machine1", namespace, clusterName, "node1", labels)
+			if tt.paused {
+				machine.Annotations = map[string]string{clusterv1.PausedAnnotation: ""}
+			}
+
+			// The node as observed by the health check, and as it is when the remediation is issued.
+			evaluatedNode := newTestUnhealthyNode("node1", corev1.NodeReady, corev1.ConditionUnknown, 10*time.Minute)
+			currentNode := evaluatedNode.DeepCopy()
+			currentNode.Status.Conditions[0].Status = tt.nodeReadyStatus
+			currentNode.Status.Conditions[0].LastTransitionTime = metav1.Now()
+
+			// The node is read from the workload cluster with an uncached client.
+			cl := fake.NewClientBuilder().WithObjects(machine, mhc).Build()
+			workloadClient := fake.NewClientBuilder().WithObjects(currentNode).Build()
+			nodeRead := false
+			recorder := record.NewFakeRecorder(32)
+			r := &Reconciler{
+				Client:   cl,
+				recorder: recorder,
+				remoteClientGetter: func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+					nodeRead = true
+					return workloadClient, nil
+				},
+			}
+
+			conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+			patchHelper, err := patch.NewHelper(machine, cl)
+			g.Expect(err).ToNot(HaveOccurred())
+			target := healthCheckTarget{
+				MHC:         mhc,
+				Machine:     machine,
+				patchHelper: patchHelper,
+				Node:        evaluatedNode,
+			}
+
+			g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), []healthCheckTarget{target}, r.uncachedWorkloadClient(ctx, defaultCluster), defaultCluster, mhc, 0)).To(BeEmpty())
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+			g.Expect(conditions.Has(machine, clusterv1.MachineOwnerRemediatedCondition)).To(Equal(tt.expectRemediation))
+			g.Expect(nodeRead).To(Equal(tt.expectNodeRead))
+
+			skippedEventSent := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, EventRemediationSkippedNodeRecovered) {
+					skippedEventSent = true
+				}
+			}
+			g.Expect(skippedEventSent).To(Equal(tt.expectNodeRead && !tt.expectRemediation))
+		})
+	}
+}
+
+func TestPatchUnhealthyTargetsOwnerMachineSetScalingDown(t *testing.T) {
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	defaultCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+	}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	tests := []struct {
+		name                   string
+		replicas               *int32
+		statusReplicas         int32
+		expectRemediation      bool
+		expectSkippedEventSent bool
+	}{
+		{
+			name:                   "MachineSet scaling to zero",
+			replicas:               pointer.Int32(0),
+			statusReplicas:         2,
+			expectRemediation:      false,
+			expectSkippedEventSent: true,
+		},
+		{
+			name:                   "MachineSet scaling down",
+			replicas:               pointer.Int32(1),
+			statusReplicas:         2,
+			expectRemediation:      false,
+			expectSkippedEventSent: true,
+		},
+		{
+			name:              "MachineSet not scaling",
+			replicas:          pointer.Int32(2),
+			statusReplicas:    2,
+			expectRemediation: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &clusterv1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ms",
+					Namespace: namespace,
+				},
+				Spec: clusterv1.MachineSetSpec{
+					ClusterName: clusterName,
+					Replicas:    tt.replicas,
+				},
+				Status: clusterv1.MachineSetStatus{
+					Replicas: tt.statusReplicas,
+				},
+			}
+			mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+			machine := newTestMachine("machine1", namespace, clusterName, "nodeName", labels)
+			machine.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: clusterv1.GroupVersion.String(),
+				Kind:       "MachineSet",
+				Name:       ms.Name,
+			}}
+			conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+
+			cl := fake.NewClientBuilder().WithObjects(ms, machine, mhc).Build()
+			recorder := record.NewFakeRecorder(32)
+			r := &Reconciler{
+				Client:             cl,
+				remoteClientGetter: remoteClientGetterFor(cl),
+				recorder:           recorder,
+			}
+
+			patchHelper, err := patch.NewHelper(machine, cl)
+			g.Expect(err).ToNot(HaveOccurred())
+			target := healthCheckTarget{
+				MHC:         mhc,
+				Machine:     machine,
+				patchHelper: patchHelper,
+				Node:        &corev1.Node{},
+			}
+
+			g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), []healthCheckTarget{target}, r.uncachedWorkloadClient(ctx, defaultCluster), defaultCluster, mhc, 0)).To(BeEmpty())
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+			g.Expect(conditions.IsFalse(machine, clusterv1.MachineHealthCheckSucceededCondition)).To(BeTrue())
+			g.Expect(conditions.Has(machine, clusterv1.MachineOwnerRemediatedCondition)).To(Equal(tt.expectRemediation))
+
+			skippedEventSent := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, EventRemediationSkippedScalingDown) {
+					skippedEventSent = true
+				}
+			}
+			g.Expect(skippedEventSent).To(Equal(tt.expectSkippedEventSent))
+		})
+	}
+}
+
+func TestPatchUnhealthyTargetsScaleDownUnhealthyMachines(t *testing.T) {
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	defaultCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+	}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	tests := []struct {
+		name                            string
+		withMachineDeployment           bool
+		withRollout                     bool
+		alreadyMarkedForDeletion        bool
+		expectMachineSetReplicas        int32
+		expectMachineDeploymentReplicas int32
+		expectOwnerRemediation          bool
+	}{
+		{
+			name:                            "MachineSet without a MachineDeployment is scaled down",
+			expectMachineSetReplicas:        2,
+			expectMachineDeploymentReplicas: 3,
+		},
+		{
+			name:                            "MachineDeployment owning the MachineSet is scaled down",
+			withMachineDeployment:           true,
+			expectMachineSetReplicas:        3,
+			expectMachineDeploymentReplicas: 2,
+		},
+		{
+			name:                            "machine is remediated by its owner while the MachineDeployment is rolling out",
+			withMachineDeployment:           true,
+			withRollout:                     true,
+			expectMachineSetReplicas:        3,
+			expectMachineDeploymentReplicas: 3,
+			expectOwnerRemediation:          true,
+		},
+		{
+			name:                            "machine already marked for deletion does not scale down again",
+			withMachineDeployment:           true,
+			alreadyMarkedForDeletion:        true,
+			expectMachineSetReplicas:        3,
+			expectMachineDeploymentReplicas: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			md := &clusterv1.MachineDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "md",
+					Namespace: namespace,
+				},
+				Spec: clusterv1.MachineDeploymentSpec{
+					ClusterName: clusterName,
+					Replicas:    pointer.Int32(3),
+				},
+			}
+			ms := &clusterv1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ms",
+					Namespace: namespace,
+				},
+				Spec: clusterv1.MachineSetSpec{
+					ClusterName: clusterName,
+					Replicas:    pointer.Int32(3),
+				},
+				Status: clusterv1.MachineSetStatus{
+					Replicas: 3,
+				},
+			}
+			if tt.withMachineDeployment {
+				ms.OwnerReferences = []metav1.OwnerReference{{
+					APIVersion: clusterv1.GroupVersion.String(),
+					Kind:       "MachineDeployment",
+					Name:       md.Name,
+					Controller: pointer.Bool(true),
+				}}
+			}
+			oldMS := ms.DeepCopy()
+			oldMS.Name = "ms-old"
+			if !tt.withRollout {
+				oldMS.Spec.Replicas = pointer.Int32(0)
+				oldMS.Status.Replicas = 0
+			}
+			mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+			mhc.Spec.ScaleDownUnhealthyMachines = true
+			machine := newTestMachine("machine1", namespace, clusterName, "nodeName", labels)
+			machine.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: clusterv1.GroupVersion.String(),
+				Kind:       "MachineSet",
+				Name:       ms.Name,
+			}}
+			if tt.alreadyMarkedForDeletion {
+				machine.Annotations = map[string]string{clusterv1.DeleteMachineAnnotation: ""}
+			}
+			conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+
+			cl := fake.NewClientBuilder().WithObjects(md, ms, oldMS, machine, mhc).Build()
+			r := &Reconciler{
+				Client:             cl,
+				remoteClientGetter: remoteClientGetterFor(cl),
+				recorder:           record.NewFakeRecorder(32),
+			}
+
+			patchHelper, err := patch.NewHelper(machine, cl)
+			g.Expect(err).ToNot(HaveOccurred())
+			target := healthCheckTarget{
+				MHC:         mhc,
+				Machine:     machine,
+				patchHelper: patchHelper,
+				Node:        &corev1.Node{},
+			}
+
+			g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), []healthCheckTarget{target}, r.uncachedWorkloadClient(ctx, defaultCluster), defaultCluster, mhc, 0)).To(BeEmpty())
+
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+			g.Expect(machine.Annotations).ToNot(HaveKey(scaleDownPendingAnnotation))
+			if tt.expectOwnerRemediation {
+				g.Expect(machine.Annotations).ToNot(HaveKey(clusterv1.DeleteMachineAnnotation))
+				g.Expect(conditions.IsFalse(machine, clusterv1.MachineOwnerRemediatedCondition)).To(BeTrue())
+			} else {
+				g.Expect(machine.Annotations).To(HaveKey(clusterv1.DeleteMachineAnnotation))
+				// The machine is not replaced by the MachineSet.
+				g.Expect(conditions.Has(machine, clusterv1.MachineOwnerRemediatedCondition)).To(BeFalse())
+			}
+
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(ms), ms)).To(Succeed())
+			g.Expect(*ms.Spec.Replicas).To(Equal(tt.expectMachineSetReplicas))
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(md), md)).To(Succeed())
+			g.Expect(*md.Spec.Replicas).To(Equal(tt.expectMachineDeploymentReplicas))
+		})
+	}
+}
+
+func TestPatchUnhealthyTargetsScaleDownUnhealthyMachinesRetry(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName, Namespace: namespace}}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	md := &clusterv1.MachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "md", Namespace: namespace},
+		Spec:       clusterv1.MachineDeploymentSpec{ClusterName: clusterName, Replicas: pointer.Int32(3)},
+	}
+	ms := &clusterv1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ms",
+			Namespace: namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: clusterv1.GroupVersion.String(),
+				Kind:       "MachineDeployment",
+				Name:       md.Name,
+				Controller: pointer.Bool(true),
+			}},
+		},
+		Spec:   clusterv1.MachineSetSpec{ClusterName: clusterName, Replicas: pointer.Int32(3)},
+		Status: clusterv1.MachineSetStatus{Replicas: 3},
+	}
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	mhc.Spec.ScaleDownUnhealthyMachines = true
+	machine := newTestMachine("machine1", namespace, clusterName, "nodeName", labels)
+	machine.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       "MachineSet",
+		Name:       ms.Name,
+	}}
+
+	cl := fake.NewClientBuilder().WithObjects(md, ms, machine, mhc).Build()
+	failingClient := &failingPatchClient{Client: cl}
+	r := &Reconciler{
+		Client:             failingClient,
+		remoteClientGetter: remoteClientGetterFor(cl),
+		recorder:           record.NewFakeRecorder(32),
+	}
+
+	newTarget := func() healthCheckTarget {
+		m := &clusterv1.Machine{}
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), m)).To(Succeed())
+		conditions.MarkFalse(m, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+		patchHelper, err := patch.NewHelper(m, cl)
+		g.Expect(err).ToNot(HaveOccurred())
+		return healthCheckTarget{MHC: mhc, Machine: m, patchHelper: patchHelper, Node: &corev1.Node{}}
+	}
+
+	// Marking the machine for deletion fails, so the owner is not scaled down, given that it could delete another machine.
+	failingClient.failPatch = func(*clusterv1.Machine) bool { return true }
+	g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), []healthCheckTarget{newTarget()}, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)).ToNot(BeEmpty())
+
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+	g.Expect(machine.Annotations).ToNot(HaveKey(clusterv1.DeleteMachineAnnotation))
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(md), md)).To(Succeed())
+	g.Expect(*md.Spec.Replicas).To(Equal(int32(3)))
+
+	// Patching the machine fails after the owner has been scaled down.
+	failingClient.failPatch = func(m *clusterv1.Machine) bool {
+		_, scaleDownPending := m.Annotations[scaleDownPendingAnnotation]
+		return !scaleDownPending
+	}
+	g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), []healthCheckTarget{newTarget()}, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)).ToNot(BeEmpty())
+
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKey(clusterv1.DeleteMachineAnnotation))
+	g.Expect(machine.Annotations).To(HaveKey(scaleDownPendingAnnotation))
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(md), md)).To(Succeed())
+	g.Expect(*md.Spec.Replicas).To(Equal(int32(2)))
+
+	// The remediation is in progress only once the owner scale down has completed, so it is retried on the next
+	// reconcile, without scaling down the owner again.
+	failingClient.failPatch = nil
+	target := newTarget()
+	g.Expect(r.remediationStrategyFor(mhc, ms).InProgress(ctx, target)).To(BeFalse())
+	g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), []healthCheckTarget{target}, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)).To(BeEmpty())
+
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKey(clusterv1.DeleteMachineAnnotation))
+	g.Expect(machine.Annotations).ToNot(HaveKey(scaleDownPendingAnnotation))
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(md), md)).To(Succeed())
+	g.Expect(*md.Spec.Replicas).To(Equal(int32(2)))
+	g.Expect(r.remediationStrategyFor(mhc, ms).InProgress(ctx, newTarget())).To(BeTrue())
+}
+
+// failingPatchClient is a client failing to patch the Machines matching failPatch, if set.
+type failingPatchClient struct {
+	client.Client
+	failPatch func(machine *clusterv1.Machine) bool
+}
+
+func (c *failingPatchClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if m, ok := obj.(*clusterv1.Machine); ok && c.failPatch != nil && c.failPatch(m) {
+		return errors.New("failed to patch machine")
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func TestShortCircuitRemediationRequeue(t *testing.T) {
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	tests := []struct {
+		name           string
+		nextCheckTimes []time.Duration
+		expectedResult reconcile.Result
+	}{
+		{
+			name:           "requeues when there are no pending deadlines",
+			nextCheckTimes: nil,
+			expectedResult: reconcile.Result{Requeue: true},
+		},
+		{
+			name: "requeues when the first pending deadline elapses, e.g. a partially elapsed node startup timeout",
+			// The node startup timeout of a machine without a node elapses in 4m, an unhealthy condition timeout in 7m.
+			nextCheckTimes: []time.Duration{7 * time.Minute, 4 * time.Minute},
+			expectedResult: reconcile.Result{RequeueAfter: 4 * time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+			machine := newTestMachine("machine1", namespace, clusterName, "nodeName", labels)
+			cl := fake.NewClientBuilder().WithObjects(machine, mhc).Build()
+			r := &Reconciler{
+				Client:             cl,
+				remoteClientGetter: remoteClientGetterFor(cl),
+				recorder:           record.NewFakeRecorder(32),
+			}
+
+			patchHelper, err := patch.NewHelper(machine, cl)
+			g.Expect(err).ToNot(HaveOccurred())
+			target := healthCheckTarget{MHC: mhc, Machine: machine, patchHelper: patchHelper}
+
+			result, err := r.shortCircuitRemediation(ctx, mhc, clusterv1.TooManyUnhealthyReason, "Remediation is not allowed", []healthCheckTarget{target}, tt.nextCheckTimes)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result).To(Equal(tt.expectedResult))
+			g.Expect(conditions.IsFalse(mhc, clusterv1.RemediationAllowedCondition)).To(BeTrue())
+		})
+	}
+}
+
+func TestReportWithoutRemediation(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	mhc.Spec.RemediationDisabled = true
+	machine := newTestMachine("machine1", namespace, clusterName, "nodeName", labels)
+
+	cl := fake.NewClientBuilder().WithObjects(machine, mhc).Build()
+	r := &Reconciler{
+		Client:             cl,
+		remoteClientGetter: remoteClientGetterFor(cl),
+		recorder:           record.NewFakeRecorder(32),
+	}
+
+	patchHelper, err := patch.NewHelper(machine, cl)
+	g.Expect(err).ToNot(HaveOccurred())
+	conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+	target := healthCheckTarget{
+		MHC:         mhc,
+		Machine:     machine,
+		patchHelper: patchHelper,
+		Node:        &corev1.Node{},
+	}
+
+	result, err := r.reportWithoutRemediation(ctx, logr.New(log.NullLogSink{}), mhc, clusterv1.RemediationDisabledReason, "Remediation is disabled", []healthCheckTarget{target}, []time.Duration{time.Minute})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(time.Minute))
+
+	// The health check result is reported on the MachineHealthCheck and on the machine, but the machine is not remediated.
+	g.Expect(mhc.Status.RemediationsAllowed).To(Equal(int32(0)))
+	g.Expect(conditions.IsFalse(mhc, clusterv1.RemediationAllowedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(mhc, clusterv1.RemediationAllowedCondition)).To(Equal(clusterv1.RemediationDisabledReason))
+
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+	g.Expect(conditions.IsFalse(machine, clusterv1.MachineHealthCheckSucceededCondition)).To(BeTrue())
+	g.Expect(conditions.Has(machine, clusterv1.MachineOwnerRemediatedCondition)).To(BeFalse())
+}
+
+func TestRemediationGracePeriodRemaining(t *testing.T) {
+	now := time.Now()
+	newCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              testClusterName,
+			Namespace:         metav1.NamespaceDefault,
+			CreationTimestamp: metav1.NewTime(now.Add(-time.Minute)),
+		},
+	}
+	oldCluster := newCluster.DeepCopy()
+	oldCluster.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+
+	tests := []struct {
+		name        string
+		cluster     *clusterv1.Cluster
+		gracePeriod *metav1.Duration
+		want        time.Duration
+	}{
+		{
+			name:        "grace period not set",
+			cluster:     newCluster,
+			gracePeriod: nil,
+			want:        0,
+		},
+		{
+			name:        "freshly created cluster within the grace period",
+			cluster:     newCluster,
+			gracePeriod: &metav1.Duration{Duration: 10 * time.Minute},
+			want:        9 * time.Minute,
+		},
+		{
+			name:        "cluster older than the grace period",
+			cluster:     oldCluster,
+			gracePeriod: &metav1.Duration{Duration: 10 * time.Minute},
+			want:        -50 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := newMachineHealthCheckWithLabels("mhc", metav1.NamespaceDefault, testClusterName, map[string]string{"cluster": "foo"})
+			mhc.Spec.RemediationGracePeriodAfterClusterCreation = tt.gracePeriod
+			g.Expect(remediationGracePeriodRemaining(tt.cluster, mhc, now)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestRecordRemediation(t *testing.T) {
+	g := NewWithT(t)
+
+	mhc := newMachineHealthCheckWithLabels("mhc", metav1.NamespaceDefault, testClusterName, map[string]string{"cluster": "foo"})
+	condition := conditions.FalseCondition(clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+	now := time.Now()
+
+	// Records are appended to the history, oldest first.
+	for i := 0; i < clusterv1.MaxRemediationHistory; i++ {
+		machine := newTestMachine(fmt.Sprintf("machine%d", i), metav1.NamespaceDefault, testClusterName, "nodeName", nil)
+		recordRemediation(mhc, healthCheckTarget{MHC: mhc, Machine: machine}, condition, fmt.Sprintf("id%d", i), now.Add(time.Duration(i)*time.Minute))
+	}
+	g.Expect(mhc.Status.RemediationHistory).To(HaveLen(clusterv1.MaxRemediationHistory))
+	g.Expect(mhc.Status.RemediationHistory[0]).To(Equal(clusterv1.RemediationRecord{
+		ID:      "id0",
+		Time:    metav1.NewTime(now),
+		Machine: "machine0",
+		Reason:  clusterv1.UnhealthyNodeConditionReason,
+	}))
+
+	// When the history is full, the oldest record is evicted.
+	machine := newTestMachine("machine-new", metav1.NamespaceDefault, testClusterName, "nodeName", nil)
+	recordRemediation(mhc, healthCheckTarget{MHC: mhc, Machine: machine}, nil, "id-new", now.Add(time.Hour))
+	g.Expect(mhc.Status.RemediationHistory).To(HaveLen(clusterv1.MaxRemediationHistory))
+	g.Expect(mhc.Status.RemediationHistory[0].Machine).To(Equal("machine1"))
+	g.Expect(mhc.Status.RemediationHistory[clusterv1.MaxRemediationHistory-1]).To(Equal(clusterv1.RemediationRecord{
+		ID:      "id-new",
+		Time:    metav1.NewTime(now.Add(time.Hour)),
+		Machine: "machine-new",
+	}))
+}
+
+func TestPatchUnhealthyTargetsRecordsRemediationID(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: testClusterName}}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+	mhc := newMachineHealthCheckWithLabels("mhc-remediation-id", metav1.NamespaceDefault, testClusterName, labels)
+	machine := newTestMachine("machine1", metav1.NamespaceDefault, testClusterName, "nodeName", labels)
+	machine.UID = "machine1-uid"
+	conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+
+	cl := fake.NewClientBuilder().WithObjects(machine, mhc).Build()
+	patchHelper, err := patch.NewHelper(machine, cl)
+	g.Expect(err).ToNot(HaveOccurred())
+	targets := []healthCheckTarget{{MHC: mhc, Machine: machine, patchHelper: patchHelper, Node: &corev1.Node{}}}
+	recorder := record.NewFakeRecorder(32)
+	r := &Reconciler{Client: cl, recorder: recorder, remoteClientGetter: remoteClientGetterFor(cl)}
+
+	g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), targets, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)).To(BeEmpty())
+
+	// The remediation is recorded in the MachineHealthCheck status with an ID.
+	g.Expect(mhc.Status.RemediationHistory).To(HaveLen(1))
+	id := mhc.Status.RemediationHistory[0].ID
+	g.Expect(id).To(HavePrefix("machine1-machine1-uid-"))
+	g.Expect(mhc.Status.RemediationHistory[0].Machine).To(Equal("machine1"))
+	g.Expect(mhc.Status.RemediationHistory[0].Reason).To(Equal(clusterv1.UnhealthyNodeConditionReason))
+
+	// The remediated machine is annotated with the remediation ID.
+	got := &clusterv1.Machine{}
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), got)).To(Succeed())
+	g.Expect(got.Annotations).To(HaveKeyWithValue(clusterv1.RemediationIDAnnotation, id))
+
+	// The remediation event references the remediation ID.
+	g.Expect(recorder.Events).To(Receive(ContainSubstring(EventMachineMarkedUnhealthy)))
+	g.Expect(recorder.Events).To(Receive(And(ContainSubstring(EventRemediationInitiated), ContainSubstring(id))))
+}
+
+func TestPatchUnhealthyTargetsWorkloadClientError(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: testClusterName}}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+	mhc := newMachineHealthCheckWithLabels("mhc-workload-client-error", metav1.NamespaceDefault, testClusterName, labels)
+	mhc.Spec.RespectPodDisruptionBudgets = true
+	machineWithNode := newTestMachine("machine1", metav1.NamespaceDefault, testClusterName, "nodeName", labels)
+	machineWithoutNode := newTestMachine("machine2", metav1.NamespaceDefault, testClusterName, "", labels)
+
+	cl := fake.NewClientBuilder().WithObjects(machineWithNode, machineWithoutNode, mhc).Build()
+	var targets []healthCheckTarget
+	for _, machine := range []*clusterv1.Machine{machineWithNode, machineWithoutNode} {
+		conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.NodeNotFoundReason, clusterv1.ConditionSeverityWarning, "")
+		patchHelper, err := patch.NewHelper(machine, cl)
+		g.Expect(err).ToNot(HaveOccurred())
+		targets = append(targets, healthCheckTarget{MHC: mhc, Machine: machine, patchHelper: patchHelper})
+	}
+	targets[0].Node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "nodeName"}}
+
+	r := &Reconciler{
+		Client:   cl,
+		recorder: record.NewFakeRecorder(32),
+		remoteClientGetter: func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+			return nil, errors.New("failed to read kubeconfig")
+		},
+	}
+
+	// The PodDisruptionBudgets of the first target can't be checked, but the other targets are still processed.
+	errList := r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), targets, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)
+	g.Expect(errList).To(HaveLen(1))
+	g.Expect(errList[0].Error()).To(ContainSubstring("machine1"))
+
+	got := &clusterv1.Machine{}
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machineWithNode), got)).To(Succeed())
+	g.Expect(conditions.Has(got, clusterv1.MachineOwnerRemediatedCondition)).To(BeFalse())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machineWithoutNode), got)).To(Succeed())
+	g.Expect(conditions.IsFalse(got, clusterv1.MachineOwnerRemediatedCondition)).To(BeTrue())
+}
+
+func TestPatchUnhealthyTargetsPausesRemediationOnFailedReplacements(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: testClusterName}}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+	mhc := newMachineHealthCheckWithLabels("mhc-failed-replacements", metav1.NamespaceDefault, testClusterName, labels)
+	mhc.Spec.MaxConsecutiveFailedReplacements = pointer.Int32(2)
+	cl := fake.NewClientBuilder().WithObjects(mhc).Build()
+	r := &Reconciler{Client: cl, recorder: record.NewFakeRecorder(32), remoteClientGetter: remoteClientGetterFor(cl)}
+
+	// remediate remediates a machine created at the given time, which never got a node.
+	remediate := func(name string, created time.Time) {
+		machine := newTestMachine(name, metav1.NamespaceDefault, testClusterName, "", labels)
+		machine.Status.NodeRef = nil
+		machine.CreationTimestamp = metav1.NewTime(created)
+		conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.NodeStartupTimeoutReason, clusterv1.ConditionSeverityWarning, "")
+		g.Expect(cl.Create(ctx, machine)).To(Succeed())
+		patchHelper, err := patch.NewHelper(machine, cl)
+		g.Expect(err).ToNot(HaveOccurred())
+		targets := []healthCheckTarget{{MHC: mhc, Machine: machine, patchHelper: patchHelper}}
+		g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), targets, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)).To(BeEmpty())
+	}
+
+	// The first remediation is not a failed replacement, given that no machine has been remediated before.
+	remediate("machine0", time.Now().Add(-time.Hour))
+	g.Expect(mhc.Status.ConsecutiveFailedReplacements).To(BeEquivalentTo(0))
+	g.Expect(replacementsFailing(mhc)).To(BeFalse())
+
+	// The machines replacing the remediated ones never get a node, so they are failed replacements.
+	remediate("machine1", time.Now().Add(time.Minute))
+	g.Expect(mhc.Status.ConsecutiveFailedReplacements).To(BeEquivalentTo(1))
+	g.Expect(replacementsFailing(mhc)).To(BeFalse())
+
+	remediate("machine2", time.Now().Add(2*time.Minute))
+	g.Expect(mhc.Status.ConsecutiveFailedReplacements).To(BeEquivalentTo(2))
+	g.Expect(replacementsFailing(mhc)).To(BeTrue())
+
+	// A healthy machine created before the last remediation is not a replacement.
+	oldMachine := newTestMachine("machine-old", metav1.NamespaceDefault, testClusterName, "node-old", labels)
+	oldMachine.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	resetFailedReplacements(mhc, []healthCheckTarget{{MHC: mhc, Machine: oldMachine}})
+	g.Expect(replacementsFailing(mhc)).To(BeTrue())
+
+	// Remediation resumes as soon as a replacement becomes healthy.
+	newMachine := newTestMachine("machine-new", metav1.NamespaceDefault, testClusterName, "node-new", labels)
+	newMachine.CreationTimestamp = metav1.NewTime(time.Now().Add(time.Hour))
+	resetFailedReplacements(mhc, []healthCheckTarget{{MHC: mhc, Machine: newMachine}})
+	g.Expect(mhc.Status.ConsecutiveFailedReplacements).To(BeEquivalentTo(0))
+	g.Expect(replacementsFailing(mhc)).To(BeFalse())
+}
+
+func TestReadNodesFromWorkloadCluster(t *testing.T) {
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: testClusterName}}
+	conditions.MarkTrue(cluster, clusterv1.InfrastructureReadyCondition)
+	conditions.MarkTrue(cluster, clusterv1.ControlPlaneInitializedCondition)
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+	mhc := newMachineHealthCheckWithLabels("mhc-read-nodes", metav1.NamespaceDefault, testClusterName, labels)
+	machine := newTestMachine("machine1", metav1.NamespaceDefault, testClusterName, "node1", labels)
+
+	// The cache of the workload cluster still has the node as not ready for longer than the timeout, while the node
+	// already recovered in the workload cluster.
+	staleNode := newTestUnhealthyNode("node1", corev1.NodeReady, corev1.ConditionUnknown, time.Hour)
+	freshNode := newTestNode("node1")
+	freshNode.Status.Conditions = []corev1.NodeCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Minute))},
+	}
+	cachedClient := fake.NewClientBuilder().WithObjects(staleNode).Build()
+	uncachedClient := fake.NewClientBuilder().WithObjects(freshNode).Build()
+
+	unhealthyTargets := func(g *WithT, r *Reconciler) []healthCheckTarget {
+		workloadClient := r.uncachedWorkloadClient(ctx, cluster)
+		nodeReader, err := r.nodeReader(cachedClient, workloadClient)
+		g.Expect(err).ToNot(HaveOccurred())
+		targets, err := r.getTargetsFromMHC(ctx, logr.New(log.NullLogSink{}), nodeReader, workloadClient, cluster, mhc)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(targets).To(HaveLen(1))
+		unhealthy := r.healthCheckTargets(targets, logr.New(log.NullLogSink{}), metav1.Duration{Duration: 10 * time.Minute}).unhealthy
+		return unhealthy
+	}
+
+	t.Run("health checks the cached node by default", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := &Reconciler{
+			Client:   fake.NewClientBuilder().WithObjects(mhc, machine).Build(),
+			recorder: record.NewFakeRecorder(32),
+		}
+		g.Expect(unhealthyTargets(g, r)).To(HaveLen(1))
+	})
+
+	t.Run("health checks the node read from the workload cluster if ReadNodesFromWorkloadCluster is set", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := &Reconciler{
+			Client:                       fake.NewClientBuilder().WithObjects(mhc, machine).Build(),
+			recorder:                     record.NewFakeRecorder(32),
+			ReadNodesFromWorkloadCluster: true,
+			remoteClientGetter: func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+				return uncachedClient, nil
+			},
+		}
+		g.Expect(unhealthyTargets(g, r)).To(BeEmpty())
+	})
+
+	t.Run("fails if the client to the workload cluster can't be created", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := &Reconciler{
+			Client:                       fake.NewClientBuilder().WithObjects(mhc, machine).Build(),
+			ReadNodesFromWorkloadCluster: true,
+			remoteClientGetter: func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+				return nil, errors.New("failed to read kubeconfig")
+			},
+		}
+		_, err := r.nodeReader(cachedClient, r.uncachedWorkloadClient(ctx, cluster))
+		g.Expect(err).To(HaveOccurred())
+	})
+}
+
+func TestUncachedWorkloadClient(t *testing.T) {
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: testClusterName}}
+
+	t.Run("creates the client only on first use", func(t *testing.T) {
+		g := NewWithT(t)
+
+		created := 0
+		uncachedClient := fake.NewClientBuilder().Build()
+		r := &Reconciler{
+			remoteClientGetter: func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+				created++
+				return uncachedClient, nil
+			},
+		}
+
+		workloadClient := r.uncachedWorkloadClient(ctx, cluster)
+		g.Expect(created).To(Equal(0))
+		for i := 0; i < 3; i++ {
+			c, err := workloadClient()
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(c).To(BeIdenticalTo(uncachedClient))
+		}
+		g.Expect(created).To(Equal(1))
+	})
+
+	t.Run("does not retry creating the client after a failure", func(t *testing.T) {
+		g := NewWithT(t)
+
+		created := 0
+		r := &Reconciler{
+			remoteClientGetter: func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+				created++
+				return nil, errors.New("failed to read kubeconfig")
+			},
+		}
+
+		workloadClient := r.uncachedWorkloadClient(ctx, cluster)
+		for i := 0; i < 3; i++ {
+			_, err := workloadClient()
+			g.Expect(err).To(MatchError(ContainSubstring("failed to read kubeconfig")))
+		}
+		g.Expect(created).To(Equal(1))
+	})
+}
+
+func TestPodDisruptionBudgetsBlockingEviction(t *testing.T) {
+	newPod := func(name, namespace string, labels map[string]string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	newPDB := func(name string, selector *metav1.LabelSelector, disruptionsAllowed int32) policyv1.PodDisruptionBudget {
+		return policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceDefault},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: selector},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+		}
+	}
+	dbSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}
+
+	daemonSetPod := newPod("daemonset-pod", metav1.NamespaceDefault, map[string]string{"app": "db"})
+	daemonSetPod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "ds", Controller: pointer.BoolPtr(true)}}
+	mirrorPod := newPod("mirror-pod", metav1.NamespaceDefault, map[string]string{"app": "db"})
+	mirrorPod.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: ""}
+	terminatedPod := newPod("terminated-pod", metav1.NamespaceDefault, map[string]string{"app": "db"})
+	terminatedPod.Status.Phase = corev1.PodSucceeded
+
+	testCases := []struct {
+		name     string
+		pods     []corev1.Pod
+		pdbs     []policyv1.PodDisruptionBudget
+		expected []string
+	}{
+		{
+			name:     "no pods matching the PodDisruptionBudget",
+			pods:     []corev1.Pod{newPod("web", metav1.NamespaceDefault, map[string]string{"app": "web"})},
+			pdbs:     []policyv1.PodDisruptionBudget{newPDB("db", dbSelector, 0)},
+			expected: []string{},
+		},
+		{
+			name:     "pods matching the PodDisruptionBudget within the allowed disruptions",
+			pods:     []corev1.Pod{newPod("db-0", metav1.NamespaceDefault, map[string]string{"app": "db"})},
+			pdbs:     []policyv1.PodDisruptionBudget{newPDB("db", dbSelector, 1)},
+			expected: []string{},
+		},
+		{
+			name: "pods matching the PodDisruptionBudget exceeding the allowed disruptions",
+			pods: []corev1.Pod{
+				newPod("db-0", metav1.NamespaceDefault, map[string]string{"app": "db"}),
+				newPod("db-1", metav1.NamespaceDefault, map[string]string{"app": "db"}),
+			},
+			pdbs:     []policyv1.PodDisruptionBudget{newPDB("db", dbSelector, 1)},
+			expected: []string{"default/db"},
+		},
+		{
+			name:     "pods in another namespace are ignored",
+			pods:     []corev1.Pod{newPod("db-0", "other", map[string]string{"app": "db"})},
+			pdbs:     []policyv1.PodDisruptionBudget{newPDB("db", dbSelector, 0)},
+			expected: []string{},
+		},
+		{
+			name:     "pods not evicted when draining a node are ignored",
+			pods:     []corev1.Pod{daemonSetPod, mirrorPod, terminatedPod},
+			pdbs:     []policyv1.PodDisruptionBudget{newPDB("db", dbSelector, 0)},
+			expected: []string{},
+		},
+		{
+			name: "a nil selector matches no pods, an empty selector matches all pods",
+			pods: []corev1.Pod{newPod("web", metav1.NamespaceDefault, map[string]string{"app": "web"})},
+			pdbs: []policyv1.PodDisruptionBudget{
+				newPDB("nil-selector", nil, 0),
+				newPDB("empty-selector", &metav1.LabelSelector{}, 0),
+			},
+			expected: []string{"default/empty-selector"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(podDisruptionBudgetsBlockingEviction(tc.pods, tc.pdbs)).To(Equal(tc.expected))
+		})
+	}
+}
+
+// remoteClientGetterFor returns a remote.ClusterClientGetter which returns the given client as the uncached client to
+// the workload cluster.
+func remoteClientGetterFor(c client.Client) remote.ClusterClientGetter {
+	return func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+		return c, nil
+	}
+}
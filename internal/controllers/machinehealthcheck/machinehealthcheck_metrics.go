@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machinehealthcheck
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// remediationResult is the outcome of the remediation decision for an unhealthy target.
+type remediationResult string
+
+const (
+	// remediationResultSuccess is used when the remediation of a target has been initiated.
+	remediationResultSuccess remediationResult = "success"
+
+	// remediationResultFailed is used when initiating the remediation of a target failed, e.g. because
+	// creating the external remediation request or patching the machine returned an error.
+	remediationResultFailed remediationResult = "failed"
+
+	// remediationResultSkipped is used when the remediation of a target has been skipped or deferred, e.g.
+	// because the machine is paused, a remediation is already in progress or PodDisruptionBudgets would be violated.
+	remediationResultSkipped remediationResult = "skipped"
+)
+
+var remediationAttemptsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "mhc_remediation_attempts_total",
+		Help: "Number of remediation decisions taken by MachineHealthChecks for unhealthy targets, by result.",
+	},
+	[]string{"cluster", "mhc", "result"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(remediationAttemptsTotal)
+}
+
+// recordRemediationAttempt increments the remediation attempts counter of the MachineHealthCheck for the given result.
+// NOTE: The cluster and mhc labels are in the namespace/name format, given that names are unique only within a namespace.
+func recordRemediationAttempt(m *clusterv1.MachineHealthCheck, result remediationResult) {
+	remediationAttemptsTotal.WithLabelValues(
+		m.Namespace+"/"+m.Spec.ClusterName,
+		m.Namespace+"/"+m.Name,
+		string(result),
+	).Inc()
+}
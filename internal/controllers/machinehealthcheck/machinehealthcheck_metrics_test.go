@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machinehealthcheck
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+)
+
+func TestPatchUnhealthyTargetsRecordsRemediationAttempts(t *testing.T) {
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: clusterName}}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	attempts := func(m *clusterv1.MachineHealthCheck, result remediationResult) float64 {
+		return testutil.ToFloat64(remediationAttemptsTotal.WithLabelValues(namespace+"/"+clusterName, namespace+"/"+m.Name, string(result)))
+	}
+
+	newTargets := func(g *WithT, mhc *clusterv1.MachineHealthCheck, machineNames ...string) (client.Client, []healthCheckTarget) {
+		var machines []client.Object
+		for _, name := range machineNames {
+			machine := newTestMachine(name, namespace, clusterName, "nodeName", labels)
+			conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+			machines = append(machines, machine)
+		}
+		cl := fake.NewClientBuilder().WithObjects(append(machines, mhc)...).Build()
+
+		var targets []healthCheckTarget
+		for _, obj := range machines {
+			machine := obj.(*clusterv1.Machine)
+			patchHelper, err := patch.NewHelper(machine, cl)
+			g.Expect(err).ToNot(HaveOccurred())
+			targets = append(targets, healthCheckTarget{
+				MHC:         mhc,
+				Machine:     machine,
+				patchHelper: patchHelper,
+				Node:        &corev1.Node{},
+			})
+		}
+		return cl, targets
+	}
+
+	t.Run("records successful and skipped remediations", func(t *testing.T) {
+		g := NewWithT(t)
+
+		mhc := newMachineHealthCheckWithLabels("mhc-metrics-success", namespace, clusterName, labels)
+		mhc.Spec.MaxInFlightRemediations = pointer.Int32(1)
+		cl, targets := newTargets(g, mhc, "machine1", "machine2")
+		r := &Reconciler{Client: cl, recorder: record.NewFakeRecorder(32), remoteClientGetter: remoteClientGetterFor(cl)}
+
+		// Only the first target is remediated, the second one is delayed.
+		g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), targets, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)).To(BeEmpty())
+		g.Expect(attempts(mhc, remediationResultSuccess)).To(Equal(float64(1)))
+		g.Expect(attempts(mhc, remediationResultSkipped)).To(Equal(float64(1)))
+		g.Expect(attempts(mhc, remediationResultFailed)).To(Equal(float64(0)))
+
+		// Both targets are skipped, given that the remediation of the first target is now in progress.
+		g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), targets, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 1)).To(BeEmpty())
+		g.Expect(attempts(mhc, remediationResultSuccess)).To(Equal(float64(1)))
+		g.Expect(attempts(mhc, remediationResultSkipped)).To(Equal(float64(3)))
+	})
+
+	t.Run("records failed remediations", func(t *testing.T) {
+		g := NewWithT(t)
+
+		mhc := newMachineHealthCheckWithLabels("mhc-metrics-failed", namespace, clusterName, labels)
+		mhc.Spec.RemediationTemplate = &corev1.ObjectReference{
+			APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+			Kind:       "InfrastructureRemediationTemplate",
+			Namespace:  namespace,
+			Name:       "does-not-exist",
+		}
+		cl, targets := newTargets(g, mhc, "machine1")
+		r := &Reconciler{Client: cl, recorder: record.NewFakeRecorder(32), remoteClientGetter: remoteClientGetterFor(cl)}
+
+		// The remediation template does not exist, so creating the remediation request fails.
+		g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), targets, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)).To(HaveLen(1))
+		g.Expect(attempts(mhc, remediationResultFailed)).To(Equal(float64(1)))
+		g.Expect(attempts(mhc, remediationResultSuccess)).To(Equal(float64(0)))
+		g.Expect(attempts(mhc, remediationResultSkipped)).To(Equal(float64(0)))
+	})
+}
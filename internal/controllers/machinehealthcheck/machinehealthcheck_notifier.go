@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machinehealthcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultWebhookNotifierTimeout is the timeout used by WebhookRemediationNotifier when no HTTP client is provided.
+const defaultWebhookNotifierTimeout = 10 * time.Second
+
+// RemediationNotification contains the details of a remediation initiated by a MachineHealthCheck.
+type RemediationNotification struct {
+	// Namespace is the namespace of the Cluster, the MachineHealthCheck and the Machine.
+	Namespace string `json:"namespace"`
+
+	// Cluster is the name of the Cluster the Machine belongs to.
+	Cluster string `json:"cluster"`
+
+	// MachineHealthCheck is the name of the MachineHealthCheck that initiated the remediation.
+	MachineHealthCheck string `json:"machineHealthCheck"`
+
+	// Machine is the name of the Machine being remediated.
+	Machine string `json:"machine"`
+
+	// Reason is the reason why the Machine failed the health check.
+	Reason string `json:"reason"`
+
+	// Message is a human readable message about why the Machine failed the health check.
+	Message string `json:"message,omitempty"`
+}
+
+// RemediationNotifier sends notifications to external systems when a remediation is initiated.
+// NOTE: This is an integration point distinct from Kubernetes events, e.g. for paging or for logging to external systems.
+type RemediationNotifier interface {
+	NotifyRemediation(ctx context.Context, notification RemediationNotification) error
+}
+
+// WebhookRemediationNotifier sends remediation notifications as a JSON POST request to an HTTP endpoint.
+type WebhookRemediationNotifier struct {
+	// Endpoint is the URL the notifications are sent to.
+	Endpoint string
+
+	// Client is the HTTP client used to send notifications; if not set, a client with a default timeout is used.
+	Client *http.Client
+}
+
+// NotifyRemediation implements RemediationNotifier.
+func (n *WebhookRemediationNotifier) NotifyRemediation(ctx context.Context, notification RemediationNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal remediation notification")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create remediation notification request for %q", n.Endpoint)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookNotifierTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send remediation notification to %q", n.Endpoint)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("failed to send remediation notification to %q: unexpected status code %d", n.Endpoint, resp.StatusCode)
+	}
+	return nil
+}
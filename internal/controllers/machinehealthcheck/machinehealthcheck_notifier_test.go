@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machinehealthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+)
+
+func TestWebhookRemediationNotifier(t *testing.T) {
+	notification := RemediationNotification{
+		Namespace:          metav1.NamespaceDefault,
+		Cluster:            testClusterName,
+		MachineHealthCheck: "mhc",
+		Machine:            "machine1",
+		Reason:             clusterv1.UnhealthyNodeConditionReason,
+		Message:            "Condition Ready on node is reporting status Unknown for more than 5m0s",
+	}
+
+	t.Run("sends the notification to the endpoint", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var received RemediationNotification
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			g.Expect(r.Method).To(Equal(http.MethodPost))
+			g.Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+			g.Expect(json.NewDecoder(r.Body).Decode(&received)).To(Succeed())
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		notifier := &WebhookRemediationNotifier{Endpoint: server.URL, Client: server.Client()}
+		g.Expect(notifier.NotifyRemediation(ctx, notification)).To(Succeed())
+		g.Expect(received).To(Equal(notification))
+	})
+
+	t.Run("returns an error if the endpoint does not accept the notification", func(t *testing.T) {
+		g := NewWithT(t)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		notifier := &WebhookRemediationNotifier{Endpoint: server.URL, Client: server.Client()}
+		g.Expect(notifier.NotifyRemediation(ctx, notification)).ToNot(Succeed())
+	})
+}
+
+func TestPatchUnhealthyTargetsNotifiesRemediation(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+	}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	machine := newTestMachine("machine1", namespace, clusterName, "nodeName", labels)
+	conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+
+	cl := fake.NewClientBuilder().WithObjects(machine, mhc).Build()
+	notifier := &fakeRemediationNotifier{}
+	r := &Reconciler{
+		Client:              cl,
+		remoteClientGetter:  remoteClientGetterFor(cl),
+		recorder:            record.NewFakeRecorder(32),
+		RemediationNotifier: notifier,
+	}
+
+	patchHelper, err := patch.NewHelper(machine, cl)
+	g.Expect(err).ToNot(HaveOccurred())
+	target := healthCheckTarget{
+		MHC:         mhc,
+		Machine:     machine,
+		patchHelper: patchHelper,
+	}
+
+	// The first time the target is found unhealthy remediation is initiated, and a notification is sent.
+	g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), []healthCheckTarget{target}, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)).To(BeEmpty())
+	g.Expect(notifier.notifications).To(ConsistOf(RemediationNotification{
+		Namespace:          namespace,
+		Cluster:            clusterName,
+		MachineHealthCheck: mhc.Name,
+		Machine:            machine.Name,
+		Reason:             clusterv1.UnhealthyNodeConditionReason,
+	}))
+	g.Expect(mhc.Status.RemediationHistory).To(HaveLen(1))
+
+	// While remediation is in progress, no further notifications are sent.
+	g.Expect(r.patchUnhealthyTargets(ctx, logr.New(log.NullLogSink{}), []healthCheckTarget{target}, r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)).To(BeEmpty())
+	g.Expect(notifier.notifications).To(HaveLen(1))
+	g.Expect(mhc.Status.RemediationHistory).To(HaveLen(1))
+}
+
+type fakeRemediationNotifier struct {
+	notifications []RemediationNotification
+}
+
+func (n *fakeRemediationNotifier) NotifyRemediation(_ context.Context, notification RemediationNotification) error {
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
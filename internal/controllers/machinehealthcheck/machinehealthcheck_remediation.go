@@ -0,0 +1,479 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machinehealthcheck
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	kubedrain "k8s.io/kubectl/pkg/drain"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/external"
+	"sigs.k8s.io/cluster-api/controllers/noderefutil"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	logutil "sigs.k8s.io/cluster-api/internal/log"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// defaultDrainBeforeRemediationTimeout is the maximum time spent draining the node of an unhealthy machine before
+// remediating it, when DrainBeforeRemediation is set and DrainBeforeRemediationTimeout is not.
+const defaultDrainBeforeRemediationTimeout = time.Minute
+
+// drainBeforeRemediationAttemptTimeout is the maximum time spent by each attempt to drain the node of an unhealthy
+// machine before remediating it; the drain is retried after this interval if it did not complete.
+const drainBeforeRemediationAttemptTimeout = 20 * time.Second
+
+// drainBeforeRemediationStartedAnnotation records on an unhealthy machine the time its node started being drained
+// before remediation, so the drain timeout is checked across reconciles.
+const drainBeforeRemediationStartedAnnotation = "machinehealthcheck.cluster.x-k8s.io/drain-before-remediation-started"
+
+// remediationStrategy defines how a MachineHealthCheck remediates the machines of its unhealthy targets.
+// NOTE: Safety checks which apply to every strategy, e.g. paused machines, owners scaling down, PodDisruptionBudgets
+// and the maximum number of remediations in progress, are performed by the reconciler before calling the strategy.
+type remediationStrategy interface {
+	// InProgress returns true if a remediation initiated by this strategy is already in progress for the target,
+	// in which case a new remediation must not be initiated.
+	InProgress(ctx context.Context, t healthCheckTarget) bool
+
+	// Remediate initiates the remediation of the target; changes to the machine are persisted by the caller.
+	Remediate(ctx context.Context, logger logr.Logger, t healthCheckTarget) error
+}
+
+// remediationStrategyFor returns the remediationStrategy to be used for a target of the MachineHealthCheck, according to
+// its spec; ownerMachineSet is the MachineSet owning the machine of the target, if any.
+func (r *Reconciler) remediationStrategyFor(m *clusterv1.MachineHealthCheck, ownerMachineSet *clusterv1.MachineSet) remediationStrategy {
+	var strategy remediationStrategy
+	switch {
+	case m.Spec.ScaleDownUnhealthyMachines && ownerMachineSet != nil:
+		strategy = &scaleDownOwnerRemediationStrategy{reconciler: r, ownerMachineSet: ownerMachineSet}
+	case m.Spec.RemediationTemplate != nil:
+		// NOTE: Draining does not apply to external remediation, which is not necessarily deleting the machine.
+		return &externalRemediationStrategy{reconciler: r, mhc: m}
+	default:
+		strategy = &deleteRemediationStrategy{}
+	}
+
+	if m.Spec.DrainBeforeRemediation {
+		return &drainBeforeRemediationStrategy{
+			remediationStrategy: strategy,
+			reconciler:          r,
+			mhc:                 m,
+			clientsetFor:        r.workloadClusterClientset,
+			attemptTimeout:      drainBeforeRemediationAttemptTimeout,
+		}
+	}
+	return strategy
+}
+
+// deleteRemediationStrategy marks the machine for remediation by its owner, e.g. a MachineSet or the
+// KubeadmControlPlane, which remediates it by deleting it and creating a replacement. This is the default.
+type deleteRemediationStrategy struct{}
+
+// InProgress implements remediationStrategy.
+// NOTE: MHC is responsible for creating MachineOwnerRemediatedCondition if missing or to trigger another remediation if the
+// previous one is completed; instead, if a remediation is already in progress, the remediation owner is responsible for
+// completing the process and MHC should not overwrite the condition.
+func (s *deleteRemediationStrategy) InProgress(_ context.Context, t healthCheckTarget) bool {
+	return conditions.IsFalse(t.Machine, clusterv1.MachineOwnerRemediatedCondition)
+}
+
+// Remediate implements remediationStrategy.
+func (s *deleteRemediationStrategy) Remediate(_ context.Context, logger logr.Logger, t healthCheckTarget) error {
+	condition := conditions.Get(t.Machine, clusterv1.MachineHealthCheckSucceededCondition)
+	logger.Info("Target has failed health check, marking for remediation", "target", t.string(), "reason", condition.Reason, "message", condition.Message)
+	conditions.MarkFalse(t.Machine, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "")
+	return nil
+}
+
+// externalRemediationStrategy remediates the machine by creating a remediation request from the RemediationTemplate
+// of the MachineHealthCheck, which is then processed by an external remediation controller.
+type externalRemediationStrategy struct {
+	reconciler *Reconciler
+	mhc        *clusterv1.MachineHealthCheck
+}
+
+// InProgress implements remediationStrategy.
+func (s *externalRemediationStrategy) InProgress(ctx context.Context, t healthCheckTarget) bool {
+	return s.reconciler.externalRemediationRequestExists(ctx, s.mhc, t.Machine.Name)
+}
+
+// Remediate implements remediationStrategy.
+func (s *externalRemediationStrategy) Remediate(ctx context.Context, logger logr.Logger, t healthCheckTarget) error {
+	m := s.mhc
+	cloneOwnerRef := &metav1.OwnerReference{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       "Machine",
+		Name:       t.Machine.Name,
+		UID:        t.Machine.UID,
+	}
+
+	from, err := external.Get(ctx, s.reconciler.Client, m.Spec.RemediationTemplate, t.Machine.Namespace)
+	if err != nil {
+		conditions.MarkFalse(m, clusterv1.ExternalRemediationTemplateAvailable, clusterv1.ExternalRemediationTemplateNotFound, clusterv1.ConditionSeverityError, err.Error())
+		return errors.Wrapf(err, "error retrieving remediation template %v %q for machine %q in namespace %q within cluster %q", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, m.Spec.ClusterName)
+	}
+
+	generateTemplateInput := &external.GenerateTemplateInput{
+		Template:    from,
+		TemplateRef: m.Spec.RemediationTemplate,
+		Namespace:   t.Machine.Namespace,
+		ClusterName: t.Machine.ClusterName,
+		OwnerRef:    cloneOwnerRef,
+	}
+	to, err := external.GenerateTemplate(generateTemplateInput)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create template for remediation request %v %q for machine %q in namespace %q within cluster %q", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, m.Spec.ClusterName)
+	}
+
+	// Set the Remediation Request to match the Machine name, the name is used to
+	// guarantee uniqueness between runs. A Machine should only ever have a single
+	// remediation object of a specific GVK created.
+	//
+	// NOTE: This doesn't guarantee uniqueness across different MHC objects watching
+	// the same Machine, users are in charge of setting health checks and remediation properly.
+	to.SetName(t.Machine.Name)
+
+	condition := conditions.Get(t.Machine, clusterv1.MachineHealthCheckSucceededCondition)
+	logger.Info("Target has failed health check, creating an external remediation request", "remediation request name", to.GetName(), "target", t.string(), "reason", condition.Reason, "message", condition.Message)
+	// Create the external clone.
+	if err := s.reconciler.Client.Create(ctx, to); err != nil {
+		conditions.MarkFalse(m, clusterv1.ExternalRemediationRequestAvailable, clusterv1.ExternalRemediationRequestCreationFailed, clusterv1.ConditionSeverityError, err.Error())
+		return errors.Wrapf(err, "error creating remediation request for machine %q in namespace %q within cluster %q", t.Machine.Name, t.Machine.Namespace, t.Machine.ClusterName)
+	}
+	return nil
+}
+
+const (
+	// scaleDownPendingAnnotation is set on a machine, together with the delete machine annotation, before scaling down
+	// its owner; it is removed once the owner has been scaled down, so a scale down which failed is retried.
+	scaleDownPendingAnnotation = "machinehealthcheck.cluster.x-k8s.io/scale-down-pending"
+
+	// scaledDownForMachinesAnnotation is set on the MachineDeployment or MachineSet scaled down by the MachineHealthCheck,
+	// with the comma separated names of the machines it has been scaled down for; it is updated together with the replicas,
+	// so the owner is scaled down only once for each machine, even if persisting the changes to the machine fails.
+	scaledDownForMachinesAnnotation = "machinehealthcheck.cluster.x-k8s.io/scaled-down-for-machines"
+)
+
+// scaleDownOwnerRemediationStrategy remediates the machine by marking it for deletion and scaling down its owner,
+// so the machine is deleted without being replaced.
+// NOTE: While the MachineDeployment owning the MachineSet is rolling out, scaling down the MachineDeployment might
+// scale down another MachineSet, so the machine is marked for remediation by its owner instead.
+type scaleDownOwnerRemediationStrategy struct {
+	reconciler      *Reconciler
+	ownerMachineSet *clusterv1.MachineSet
+}
+
+// InProgress implements remediationStrategy.
+// NOTE: Machines with the delete machine annotation but without the scale down pending annotation are either being
+// deleted after the owner has been scaled down, or have been marked for deletion by other controllers, e.g. the
+// cluster autoscaler, which are expected to scale down the owner as well.
+func (s *scaleDownOwnerRemediationStrategy) InProgress(_ context.Context, t healthCheckTarget) bool {
+	if conditions.IsFalse(t.Machine, clusterv1.MachineOwnerRemediatedCondition) {
+		return true
+	}
+	_, markedForDeletion := t.Machine.Annotations[clusterv1.DeleteMachineAnnotation]
+	_, scaleDownPending := t.Machine.Annotations[scaleDownPendingAnnotation]
+	return markedForDeletion && !scaleDownPending
+}
+
+// Remediate implements remediationStrategy.
+func (s *scaleDownOwnerRemediationStrategy) Remediate(ctx context.Context, logger logr.Logger, t healthCheckTarget) error {
+	md, err := s.reconciler.getOwnerMachineDeployment(ctx, s.ownerMachineSet)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the MachineDeployment owning machine %s/%s", t.Machine.Namespace, t.Machine.Name)
+	}
+	if md != nil {
+		rollingOut, err := s.reconciler.isMachineDeploymentRollingOut(ctx, md, s.ownerMachineSet)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check if MachineDeployment %s is rolling out", md.Name)
+		}
+		if rollingOut {
+			logger.Info("MachineDeployment owning the target is rolling out, remediating the target instead of scaling down", "target", t.string(), "machineDeployment", md.Name)
+			return (&deleteRemediationStrategy{}).Remediate(ctx, logger, t)
+		}
+	}
+
+	condition := conditions.Get(t.Machine, clusterv1.MachineHealthCheckSucceededCondition)
+	logger.Info("Target has failed health check, marking for deletion and scaling down its owner", "target", t.string(), "reason", condition.Reason, "message", condition.Message)
+
+	// Persist the delete machine annotation before scaling down the owner, so the MachineSet deletes this machine
+	// and not another one. NOTE: A copy of the machine is patched, given that the health check results in the
+	// machine are persisted later by the caller.
+	if _, ok := t.Machine.Annotations[scaleDownPendingAnnotation]; !ok {
+		machine := t.Machine.DeepCopy()
+		patchBase := client.MergeFrom(machine.DeepCopy())
+		annotations.AddAnnotations(machine, map[string]string{clusterv1.DeleteMachineAnnotation: "", scaleDownPendingAnnotation: ""})
+		if err := s.reconciler.Client.Patch(ctx, machine, patchBase); err != nil {
+			return errors.Wrapf(err, "failed to mark machine %s/%s for deletion", t.Machine.Namespace, t.Machine.Name)
+		}
+		annotations.AddAnnotations(t.Machine, map[string]string{clusterv1.DeleteMachineAnnotation: "", scaleDownPendingAnnotation: ""})
+	}
+
+	owner, err := s.reconciler.scaleDownMachineSetOwner(ctx, s.ownerMachineSet, md, t.Machine)
+	if err != nil {
+		return errors.Wrapf(err, "failed to scale down the owner of machine %s/%s", t.Machine.Namespace, t.Machine.Name)
+	}
+
+	// NOTE: If removing the scale down pending annotation fails, the remediation is retried, without scaling down
+	// the owner again.
+	machine := t.Machine.DeepCopy()
+	patchBase := client.MergeFrom(machine.DeepCopy())
+	delete(machine.Annotations, scaleDownPendingAnnotation)
+	if err := s.reconciler.Client.Patch(ctx, machine, patchBase); err != nil {
+		return errors.Wrapf(err, "failed to complete scaling down the owner of machine %s/%s", t.Machine.Namespace, t.Machine.Name)
+	}
+	delete(t.Machine.Annotations, scaleDownPendingAnnotation)
+	s.reconciler.recorder.Eventf(
+		t.Machine,
+		corev1.EventTypeNormal,
+		EventMachineOwnerScaledDown,
+		"Machine %v has been marked for deletion and %s has been scaled down",
+		t.string(),
+		owner,
+	)
+	return nil
+}
+
+// drainBeforeRemediationStrategy cordons and drains the node of the machine before remediating it with the wrapped
+// remediationStrategy, so the pods running on the node are evicted gracefully before the machine gets deleted.
+// Like the Machine controller does before deleting a machine, the node is drained across reconciles, with a short
+// timeout for each attempt, until the drain completes or the drain timeout of the MachineHealthCheck is exceeded.
+// Draining is best effort: if it fails, or does not complete within the timeout, the machine is remediated regardless.
+type drainBeforeRemediationStrategy struct {
+	remediationStrategy
+
+	reconciler *Reconciler
+	mhc        *clusterv1.MachineHealthCheck
+
+	// clientsetFor returns the clientset used for draining nodes in the workload cluster.
+	clientsetFor func(ctx context.Context, cluster client.ObjectKey) (kubernetes.Interface, error)
+
+	// attemptTimeout is the maximum time spent by each attempt to drain the node.
+	attemptTimeout time.Duration
+}
+
+// Remediate implements remediationStrategy.
+func (s *drainBeforeRemediationStrategy) Remediate(ctx context.Context, logger logr.Logger, t healthCheckTarget) error {
+	nodeName := t.nodeName()
+	if nodeName == "" {
+		delete(t.Machine.Annotations, drainBeforeRemediationStartedAnnotation)
+		return s.remediationStrategy.Remediate(ctx, logger, t)
+	}
+
+	timeout := defaultDrainBeforeRemediationTimeout
+	if s.mhc.Spec.DrainBeforeRemediationTimeout != nil {
+		timeout = s.mhc.Spec.DrainBeforeRemediationTimeout.Duration
+	}
+
+	// The time the drain started is recorded on the machine, so it can be checked against the drain timeout
+	// across reconciles.
+	startedAt, ok := drainBeforeRemediationStartTime(t.Machine)
+	if !ok {
+		startedAt = time.Now()
+		annotations.AddAnnotations(t.Machine, map[string]string{drainBeforeRemediationStartedAnnotation: startedAt.UTC().Format(time.RFC3339)})
+	}
+
+	var drainErr error
+	if time.Since(startedAt) >= timeout {
+		drainErr = errors.Errorf("drain did not complete within %s", timeout)
+	} else {
+		drained, err := s.drainNode(ctx, logger, t.Machine, nodeName)
+		if err == nil && !drained {
+			// The drain is retried on the next reconcile, until it completes or the timeout is exceeded.
+			return &remediationPendingError{
+				requeueAfter: s.attemptTimeout,
+				message:      fmt.Sprintf("waiting for node %s to be drained", nodeName),
+			}
+		}
+		drainErr = err
+	}
+
+	if drainErr != nil {
+		logger.Info("Failed to drain the node of the target before remediation, remediating regardless", "target", t.string(), "error", drainErr.Error())
+		s.reconciler.recorder.Eventf(
+			t.Machine,
+			corev1.EventTypeWarning,
+			EventNodeDrainBeforeRemediationFailed,
+			"Failed to drain node %s before remediating Machine %v, remediating regardless: %v",
+			nodeName,
+			t.string(),
+			drainErr,
+		)
+	} else {
+		s.reconciler.recorder.Eventf(
+			t.Machine,
+			corev1.EventTypeNormal,
+			EventNodeDrainedBeforeRemediation,
+			"Node %s has been drained before remediating Machine %v",
+			nodeName,
+			t.string(),
+		)
+	}
+	delete(t.Machine.Annotations, drainBeforeRemediationStartedAnnotation)
+	return s.remediationStrategy.Remediate(ctx, logger, t)
+}
+
+// drainNode cordons the node and makes an attempt to drain it, waiting at most for the attempt timeout for the pods
+// to be evicted; it returns true if the node has been drained, or false if the drain has to be retried.
+func (s *drainBeforeRemediationStrategy) drainNode(ctx context.Context, logger logr.Logger, machine *clusterv1.Machine, nodeName string) (bool, error) {
+	log := logger.WithValues("node", nodeName)
+
+	kubeClient, err := s.clientsetFor(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machine.Spec.ClusterName})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to create client to workload cluster")
+	}
+
+	node, err := kubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// There is nothing to drain if the node is gone.
+			return true, nil
+		}
+		return false, errors.Wrapf(err, "failed to get node %s", nodeName)
+	}
+
+	drainer := &kubedrain.Helper{
+		Client:              kubeClient,
+		Ctx:                 ctx,
+		Force:               true,
+		IgnoreAllDaemonSets: true,
+		DeleteEmptyDirData:  true,
+		GracePeriodSeconds:  -1,
+		// If a pod is not evicted within the attempt timeout, the eviction is retried on the next reconcile
+		// (to allow other machines to be reconciled).
+		Timeout: s.attemptTimeout,
+		OnPodDeletedOrEvicted: func(pod *corev1.Pod, usingEviction bool) {
+			verbStr := "Deleted"
+			if usingEviction {
+				verbStr = "Evicted"
+			}
+			log.Info(fmt.Sprintf("%s pod from Node", verbStr), "pod", fmt.Sprintf("%s/%s", pod.Name, pod.Namespace))
+		},
+		Out: logutil.Writer{LogFunc: log.Info},
+		ErrOut: logutil.Writer{LogFunc: func(msg string, keysAndValues ...interface{}) {
+			log.Error(nil, msg, keysAndValues...)
+		}},
+	}
+
+	if noderefutil.IsNodeUnreachable(node) {
+		// When the node is unreachable its pods can't terminate, so they are not waited for.
+		drainer.SkipWaitForDeleteTimeoutSeconds = 1
+	}
+
+	if err := kubedrain.RunCordonOrUncordon(drainer, node, true); err != nil {
+		return false, errors.Wrapf(err, "failed to cordon node %s", nodeName)
+	}
+	if err := kubedrain.RunNodeDrain(drainer, nodeName); err != nil {
+		log.Info("Drain not completed yet, retrying", "error", err.Error(), "retryIn", s.attemptTimeout.String())
+		return false, nil
+	}
+	log.Info("Drain successful")
+	return true, nil
+}
+
+// drainBeforeRemediationStartTime returns the time the node of the machine started being drained before remediation,
+// if any.
+func drainBeforeRemediationStartTime(machine *clusterv1.Machine) (time.Time, bool) {
+	value, ok := machine.GetAnnotations()[drainBeforeRemediationStartedAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	startedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return startedAt, true
+}
+
+// hasDrainBeforeRemediationStarted returns true if the node of the machine is being drained before remediation.
+func hasDrainBeforeRemediationStarted(machine *clusterv1.Machine) bool {
+	_, ok := machine.GetAnnotations()[drainBeforeRemediationStartedAnnotation]
+	return ok
+}
+
+// uncordonRecoveredNode uncordons the node of a target which recovered while the node was being drained before
+// remediation, and removes the drain start time from the machine; changes to the machine are persisted by the caller.
+func uncordonRecoveredNode(ctx context.Context, logger logr.Logger, kubeClient kubernetes.Interface, t healthCheckTarget) error {
+	if nodeName := t.nodeName(); nodeName != "" {
+		node, err := kubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get node %s", nodeName)
+		}
+		if err == nil {
+			drainer := &kubedrain.Helper{Client: kubeClient, Ctx: ctx}
+			if err := kubedrain.RunCordonOrUncordon(drainer, node, false); err != nil {
+				return errors.Wrapf(err, "failed to uncordon node %s", nodeName)
+			}
+			logger.Info("Uncordoned the node of a target which recovered while being drained before remediation", "target", t.string(), "node", nodeName)
+		}
+	}
+	delete(t.Machine.Annotations, drainBeforeRemediationStartedAnnotation)
+	return nil
+}
+
+// remediationPendingError is returned by a remediationStrategy when the remediation of a target can't be initiated yet,
+// e.g. because the strategy is waiting for the node of the machine to be drained, and has to be retried later.
+type remediationPendingError struct {
+	requeueAfter time.Duration
+	message      string
+}
+
+func (e *remediationPendingError) Error() string {
+	return fmt.Sprintf("remediation is pending: %s", e.message)
+}
+
+// splitRemediationPending splits the errors returned by patching unhealthy targets into actual errors and the
+// delays after which pending remediations have to be retried.
+func splitRemediationPending(errList []error) ([]error, []time.Duration) {
+	var actualErrs []error
+	var requeueAfter []time.Duration
+	for _, err := range errList {
+		var pending *remediationPendingError
+		if errors.As(err, &pending) {
+			requeueAfter = append(requeueAfter, pending.requeueAfter)
+			continue
+		}
+		actualErrs = append(actualErrs, err)
+	}
+	return actualErrs, requeueAfter
+}
+
+// workloadClusterClientset returns a clientset for the workload cluster.
+func (r *Reconciler) workloadClusterClientset(ctx context.Context, cluster client.ObjectKey) (kubernetes.Interface, error) {
+	restConfig, err := remote.RESTConfig(ctx, "machinehealthcheck-controller", r.Client, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+var (
+	_ remediationStrategy = &deleteRemediationStrategy{}
+	_ remediationStrategy = &externalRemediationStrategy{}
+	_ remediationStrategy = &scaleDownOwnerRemediationStrategy{}
+	_ remediationStrategy = &drainBeforeRemediationStrategy{}
+)
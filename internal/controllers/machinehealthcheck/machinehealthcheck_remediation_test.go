@@ -0,0 +1,265 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machinehealthcheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+func TestRemediationStrategyFor(t *testing.T) {
+	ownerMachineSet := &clusterv1.MachineSet{}
+	remediationTemplate := &corev1.ObjectReference{Kind: "InfrastructureRemediationTemplate", Name: "template"}
+
+	tests := []struct {
+		name            string
+		spec            clusterv1.MachineHealthCheckSpec
+		ownerMachineSet *clusterv1.MachineSet
+		expected        remediationStrategy
+	}{
+		{
+			name:     "deletes machines by default",
+			expected: &deleteRemediationStrategy{},
+		},
+		{
+			name:     "uses the remediation template if set",
+			spec:     clusterv1.MachineHealthCheckSpec{RemediationTemplate: remediationTemplate},
+			expected: &externalRemediationStrategy{},
+		},
+		{
+			name:            "scales down the owner if enabled and the machine is owned by a MachineSet",
+			spec:            clusterv1.MachineHealthCheckSpec{ScaleDownUnhealthyMachines: true, RemediationTemplate: remediationTemplate},
+			ownerMachineSet: ownerMachineSet,
+			expected:        &scaleDownOwnerRemediationStrategy{},
+		},
+		{
+			name:     "falls back to the other strategies if scaling down is enabled but the machine is not owned by a MachineSet",
+			spec:     clusterv1.MachineHealthCheckSpec{ScaleDownUnhealthyMachines: true},
+			expected: &deleteRemediationStrategy{},
+		},
+		{
+			name:     "drains the node before remediating if enabled",
+			spec:     clusterv1.MachineHealthCheckSpec{DrainBeforeRemediation: true},
+			expected: &drainBeforeRemediationStrategy{},
+		},
+		{
+			name:     "does not drain the node if the remediation template is set",
+			spec:     clusterv1.MachineHealthCheckSpec{DrainBeforeRemediation: true, RemediationTemplate: remediationTemplate},
+			expected: &externalRemediationStrategy{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &Reconciler{}
+			strategy := r.remediationStrategyFor(&clusterv1.MachineHealthCheck{Spec: tt.spec}, tt.ownerMachineSet)
+			g.Expect(strategy).To(BeAssignableToTypeOf(tt.expected))
+		})
+	}
+}
+
+func TestDeleteRemediationStrategy(t *testing.T) {
+	g := NewWithT(t)
+
+	machine := newTestMachine("machine1", "default", "cluster", "node1", map[string]string{})
+	conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.NodeNotFoundReason, clusterv1.ConditionSeverityWarning, "")
+	target := healthCheckTarget{
+		MHC:     newMachineHealthCheck("default", "cluster"),
+		Machine: machine,
+	}
+
+	strategy := &deleteRemediationStrategy{}
+	g.Expect(strategy.InProgress(ctx, target)).To(BeFalse())
+
+	g.Expect(strategy.Remediate(ctx, logr.New(log.NullLogSink{}), target)).To(Succeed())
+	g.Expect(conditions.IsFalse(machine, clusterv1.MachineOwnerRemediatedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(machine, clusterv1.MachineOwnerRemediatedCondition)).To(Equal(clusterv1.WaitingForRemediationReason))
+
+	// Once the owner is remediating the machine, the remediation is in progress until the owner completes it.
+	g.Expect(strategy.InProgress(ctx, target)).To(BeTrue())
+
+	conditions.MarkTrue(machine, clusterv1.MachineOwnerRemediatedCondition)
+	g.Expect(strategy.InProgress(ctx, target)).To(BeFalse())
+}
+
+func TestScaleDownOwnerRemediationStrategyInProgress(t *testing.T) {
+	g := NewWithT(t)
+
+	machine := newTestMachine("machine1", "default", "cluster", "node1", map[string]string{})
+	target := healthCheckTarget{
+		MHC:     newMachineHealthCheck("default", "cluster"),
+		Machine: machine,
+	}
+
+	strategy := &scaleDownOwnerRemediationStrategy{}
+	g.Expect(strategy.InProgress(ctx, target)).To(BeFalse())
+
+	// The owner has not been scaled down yet.
+	machine.Annotations = map[string]string{clusterv1.DeleteMachineAnnotation: "", scaleDownPendingAnnotation: ""}
+	g.Expect(strategy.InProgress(ctx, target)).To(BeFalse())
+
+	machine.Annotations = map[string]string{clusterv1.DeleteMachineAnnotation: ""}
+	g.Expect(strategy.InProgress(ctx, target)).To(BeTrue())
+}
+
+func TestDrainBeforeRemediationStrategy(t *testing.T) {
+	newTarget := func() (healthCheckTarget, *corev1.Node, *corev1.Pod) {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: metav1.NamespaceDefault},
+			Spec:       corev1.PodSpec{NodeName: node.Name},
+		}
+		machine := newTestMachine("machine1", metav1.NamespaceDefault, "cluster", node.Name, map[string]string{})
+		conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+		mhc := newMachineHealthCheck(metav1.NamespaceDefault, "cluster")
+		mhc.Spec.DrainBeforeRemediation = true
+		return healthCheckTarget{MHC: mhc, Machine: machine, Node: node}, node, pod
+	}
+
+	newKubeClient := func(objs ...runtime.Object) *fakekubernetes.Clientset {
+		kubeClient := fakekubernetes.NewSimpleClientset(objs...)
+		// The eviction API is not advertised, so the pods are deleted when draining.
+		kubeClient.Resources = []*metav1.APIResourceList{
+			{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod"}}},
+		}
+		return kubeClient
+	}
+
+	newStrategy := func(mhc *clusterv1.MachineHealthCheck, kubeClient kubernetes.Interface, recorder record.EventRecorder) *drainBeforeRemediationStrategy {
+		return &drainBeforeRemediationStrategy{
+			remediationStrategy: &deleteRemediationStrategy{},
+			reconciler:          &Reconciler{recorder: recorder},
+			mhc:                 mhc,
+			clientsetFor: func(context.Context, client.ObjectKey) (kubernetes.Interface, error) {
+				return kubeClient, nil
+			},
+			attemptTimeout: 100 * time.Millisecond,
+		}
+	}
+
+	// newStuckKubeClient returns a clientset for which the pods never go away, e.g. because they are stuck terminating.
+	newStuckKubeClient := func(objs ...runtime.Object) *fakekubernetes.Clientset {
+		kubeClient := newKubeClient(objs...)
+		kubeClient.PrependReactor("delete", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, nil
+		})
+		return kubeClient
+	}
+
+	t.Run("drains the node before remediating the machine", func(t *testing.T) {
+		g := NewWithT(t)
+
+		target, node, pod := newTarget()
+		kubeClient := newKubeClient(node, pod)
+		recorder := record.NewFakeRecorder(32)
+
+		g.Expect(newStrategy(target.MHC, kubeClient, recorder).Remediate(ctx, logr.New(log.NullLogSink{}), target)).To(Succeed())
+
+		drainedNode, err := kubeClient.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(drainedNode.Spec.Unschedulable).To(BeTrue())
+		_, err = kubeClient.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		g.Expect(recorder.Events).To(Receive(ContainSubstring(EventNodeDrainedBeforeRemediation)))
+		g.Expect(conditions.IsFalse(target.Machine, clusterv1.MachineOwnerRemediatedCondition)).To(BeTrue())
+		g.Expect(target.Machine.Annotations).ToNot(HaveKey(drainBeforeRemediationStartedAnnotation))
+	})
+
+	t.Run("retries the drain later if it does not complete within the attempt timeout", func(t *testing.T) {
+		g := NewWithT(t)
+
+		target, node, pod := newTarget()
+		kubeClient := newStuckKubeClient(node, pod)
+		recorder := record.NewFakeRecorder(32)
+
+		err := newStrategy(target.MHC, kubeClient, recorder).Remediate(ctx, logr.New(log.NullLogSink{}), target)
+		g.Expect(err).To(HaveOccurred())
+		pending := &remediationPendingError{}
+		g.Expect(errors.As(err, &pending)).To(BeTrue())
+		g.Expect(pending.requeueAfter).To(Equal(100 * time.Millisecond))
+
+		cordonedNode, err := kubeClient.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cordonedNode.Spec.Unschedulable).To(BeTrue())
+
+		// The start time of the drain is recorded, and the machine is not remediated yet.
+		g.Expect(target.Machine.Annotations).To(HaveKey(drainBeforeRemediationStartedAnnotation))
+		g.Expect(conditions.Get(target.Machine, clusterv1.MachineOwnerRemediatedCondition)).To(BeNil())
+		g.Expect(recorder.Events).ToNot(Receive())
+	})
+
+	t.Run("remediates the machine regardless if the drain times out", func(t *testing.T) {
+		g := NewWithT(t)
+
+		target, node, pod := newTarget()
+		target.MHC.Spec.DrainBeforeRemediationTimeout = &metav1.Duration{Duration: time.Minute}
+		// The drain started before the timeout.
+		target.Machine.Annotations = map[string]string{
+			drainBeforeRemediationStartedAnnotation: time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		}
+		kubeClient := newStuckKubeClient(node, pod)
+		recorder := record.NewFakeRecorder(32)
+
+		g.Expect(newStrategy(target.MHC, kubeClient, recorder).Remediate(ctx, logr.New(log.NullLogSink{}), target)).To(Succeed())
+
+		_, err := kubeClient.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		g.Expect(recorder.Events).To(Receive(ContainSubstring(EventNodeDrainBeforeRemediationFailed)))
+		g.Expect(conditions.IsFalse(target.Machine, clusterv1.MachineOwnerRemediatedCondition)).To(BeTrue())
+		g.Expect(target.Machine.Annotations).ToNot(HaveKey(drainBeforeRemediationStartedAnnotation))
+	})
+
+	t.Run("uncordons the node if the machine recovered while draining", func(t *testing.T) {
+		g := NewWithT(t)
+
+		target, node, _ := newTarget()
+		node.Spec.Unschedulable = true
+		target.Machine.Annotations = map[string]string{
+			drainBeforeRemediationStartedAnnotation: time.Now().UTC().Format(time.RFC3339),
+		}
+		kubeClient := newKubeClient(node)
+
+		g.Expect(uncordonRecoveredNode(ctx, logr.New(log.NullLogSink{}), kubeClient, target)).To(Succeed())
+
+		uncordonedNode, err := kubeClient.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(uncordonedNode.Spec.Unschedulable).To(BeFalse())
+		g.Expect(target.Machine.Annotations).ToNot(HaveKey(drainBeforeRemediationStartedAnnotation))
+	})
+}
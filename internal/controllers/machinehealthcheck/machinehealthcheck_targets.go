@@ -19,20 +19,28 @@ package machinehealthcheck
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/blang/semver"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/external"
+	"sigs.k8s.io/cluster-api/controllers/noderefutil"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/version"
 )
 
 const (
@@ -45,6 +53,10 @@ const (
 	EventDetectedUnhealthy string = "DetectedUnhealthy"
 )
 
+// unhealthyObservationInterval is the interval between the health checks of an unhealthy target whose remediation is
+// deferred until it has been found unhealthy for UnhealthyObservationThreshold consecutive health checks.
+const unhealthyObservationInterval = 10 * time.Second
+
 var (
 	// We allow users to disable the nodeStartupTimeout by setting the duration to 0.
 	disabledNodeStartupTimeout = clusterv1.ZeroDuration
@@ -59,6 +71,18 @@ type healthCheckTarget struct {
 	MHC         *clusterv1.MachineHealthCheck
 	patchHelper *patch.Helper
 	nodeMissing bool
+
+	// nodeReplaced is true if the node has the name in the machine's NodeRef, but a different UID and providerID, i.e.
+	// the node the machine referenced has been deleted and a new node has been created with the same name.
+	nodeReplaced bool
+
+	// infrastructureMissing is true if the infrastructure object referenced by the machine does not exist; it is
+	// checked only if the MachineHealthCheck has InfrastructureMissingTimeout set.
+	infrastructureMissing bool
+
+	// nodeLease is the Lease of the node in the kube-node-lease namespace; it is read only if the
+	// MachineHealthCheck has NodeLeaseStaleTimeout set, and it is nil if the node has no Lease.
+	nodeLease *coordinationv1.Lease
 }
 
 func (t *healthCheckTarget) string() string {
@@ -87,41 +111,90 @@ func (t *healthCheckTarget) nodeName() string {
 // If the target doesn't currently need rememdiation, provide a duration after
 // which the target should next be checked.
 // The target should be requeued after this duration.
-func (t *healthCheckTarget) needsRemediation(logger logr.Logger, timeoutForMachineToHaveNode metav1.Duration) (bool, time.Duration) {
+// Additionally, if the target is failing a health check, either unhealthy or still within the timeout, the failing
+// health check which is going to make the target unhealthy first is returned.
+func (t *healthCheckTarget) needsRemediation(logger logr.Logger, timeoutForMachineToHaveNode metav1.Duration, now time.Time) (bool, time.Duration, *clusterv1.TargetHealth) {
 	var nextCheckTimes []time.Duration
-	now := time.Now()
+
+	var health *clusterv1.TargetHealth
+	failing := func(reason, message string, since, unhealthyAt time.Time) {
+		if health != nil && !unhealthyAt.Before(health.UnhealthyAt.Time) {
+			return
+		}
+		health = &clusterv1.TargetHealth{Machine: t.Machine.Name, Reason: reason, Message: message, UnhealthyAt: &metav1.Time{Time: unhealthyAt}}
+		if !since.IsZero() {
+			health.Since = &metav1.Time{Time: since}
+		}
+	}
 
 	if t.Machine.Status.FailureReason != nil {
 		conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.MachineHasFailureReason, clusterv1.ConditionSeverityWarning, "FailureReason: %v", t.Machine.Status.FailureReason)
 		logger.V(3).Info("Target is unhealthy", "failureReason", t.Machine.Status.FailureReason)
-		return true, time.Duration(0)
+		failing(clusterv1.MachineHasFailureReason, fmt.Sprintf("FailureReason: %v", *t.Machine.Status.FailureReason), time.Time{}, now)
+		return true, time.Duration(0), health
 	}
 
 	if t.Machine.Status.FailureMessage != nil {
 		conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.MachineHasFailureReason, clusterv1.ConditionSeverityWarning, "FailureMessage: %v", t.Machine.Status.FailureMessage)
 		logger.V(3).Info("Target is unhealthy", "failureMessage", t.Machine.Status.FailureMessage)
-		return true, time.Duration(0)
+		failing(clusterv1.MachineHasFailureReason, fmt.Sprintf("FailureMessage: %v", *t.Machine.Status.FailureMessage), time.Time{}, now)
+		return true, time.Duration(0), health
 	}
 
 	// the node does not exist
 	if t.nodeMissing {
+		missingSince := nodeMissingSince(t.Machine, now)
+		if t.MHC.Spec.NodeMissingTimeout != nil {
+			timeout := t.MHC.Spec.NodeMissingTimeout.Duration
+			failing(clusterv1.NodeNotFoundReason, "Node is missing", missingSince, missingSince.Add(timeout))
+			if !missingSince.Add(timeout).Before(now) {
+				logger.V(3).Info("Node is missing, but not for longer than allowed timeout", "timeout", timeout.String())
+				durationUnhealthy := now.Sub(missingSince)
+				return false, timeout - durationUnhealthy + time.Second, health
+			}
+		}
 		logger.V(3).Info("Target is unhealthy: node is missing")
+		failing(clusterv1.NodeNotFoundReason, "Node is missing", missingSince, missingSince)
 		conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.NodeNotFoundReason, clusterv1.ConditionSeverityWarning, "")
-		return true, time.Duration(0)
+		return true, time.Duration(0), health
+	}
+
+	// the node has been replaced by a new node with the same name, so the machine references a node which no longer exists
+	if t.nodeReplaced {
+		logger.V(3).Info("Target is unhealthy: node has been replaced by a new node with the same name")
+		failing(clusterv1.NodeReplacedReason, "Node has been replaced by a new node with the same name", time.Time{}, now)
+		conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.NodeReplacedReason, clusterv1.ConditionSeverityWarning, "Node %s has UID %s, but the machine references UID %s", t.Node.Name, t.Node.UID, t.Machine.Status.NodeRef.UID)
+		return true, time.Duration(0), health
+	}
+
+	// the infrastructure object referenced by the machine has been deleted out of band
+	if t.infrastructureMissing {
+		missingSince := infrastructureMissingSince(t.Machine)
+		timeout := t.MHC.Spec.InfrastructureMissingTimeout.Duration
+		ref := t.Machine.Spec.InfrastructureRef
+		failing(clusterv1.InfrastructureMissingReason, fmt.Sprintf("%s %s is missing", ref.Kind, ref.Name), missingSince, missingSince.Add(timeout))
+		if !missingSince.Add(timeout).Before(now) {
+			logger.V(3).Info("Infrastructure is missing, but not for longer than allowed timeout", "timeout", timeout.String())
+			durationUnhealthy := now.Sub(missingSince)
+			return false, timeout - durationUnhealthy + time.Second, health
+		}
+		logger.V(3).Info("Target is unhealthy: infrastructure is missing", "kind", ref.Kind, "name", ref.Name)
+		conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.InfrastructureMissingReason, clusterv1.ConditionSeverityWarning, "%s %s is missing for more than %s", ref.Kind, ref.Name, timeout.String())
+		return true, time.Duration(0), health
 	}
 
 	// Don't penalize any Machine/Node if the control plane has not been initialized.
 	if !conditions.IsTrue(t.Cluster, clusterv1.ControlPlaneInitializedCondition) {
 		logger.V(3).Info("Not evaluating target health because the control plane has not yet been initialized")
 		// Return a nextCheck time of 0 because we'll get requeued when the Cluster is updated.
-		return false, 0
+		return false, 0, nil
 	}
 
 	// Don't penalize any Machine/Node if the cluster infrastructure is not ready.
 	if !conditions.IsTrue(t.Cluster, clusterv1.InfrastructureReadyCondition) {
 		logger.V(3).Info("Not evaluating target health because the cluster infrastructure is not ready")
 		// Return a nextCheck time of 0 because we'll get requeued when the Cluster is updated.
-		return false, 0
+		return false, 0, nil
 	}
 
 	// the node has not been set yet
@@ -129,7 +202,7 @@ func (t *healthCheckTarget) needsRemediation(logger logr.Logger, timeoutForMachi
 		if timeoutForMachineToHaveNode == disabledNodeStartupTimeout {
 			// Startup timeout is disabled so no need to go any further.
 			// No node yet to check conditions, can return early here.
-			return false, 0
+			return false, 0, nil
 		}
 
 		controlPlaneInitializedTime := conditions.GetLastTransitionTime(t.Cluster, clusterv1.ControlPlaneInitializedCondition).Time
@@ -146,17 +219,96 @@ func (t *healthCheckTarget) needsRemediation(logger logr.Logger, timeoutForMachi
 			comparisonTime = clusterInfraReadyTime
 		}
 		logger.V(3).Info("Using comparison time", "time", comparisonTime)
+		failing(clusterv1.NodeStartupTimeoutReason, "Waiting for the node to report startup", comparisonTime, comparisonTime.Add(timeoutForMachineToHaveNode.Duration))
 
 		if comparisonTime.Add(timeoutForMachineToHaveNode.Duration).Before(now) {
 			conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.NodeStartupTimeoutReason, clusterv1.ConditionSeverityWarning, "Node failed to report startup in %s", timeoutForMachineToHaveNode.String())
 			logger.V(3).Info("Target is unhealthy: machine has no node", "duration", timeoutForMachineToHaveNode.String())
-			return true, time.Duration(0)
+			return true, time.Duration(0), health
 		}
 
 		durationUnhealthy := now.Sub(comparisonTime)
 		nextCheck := timeoutForMachineToHaveNode.Duration - durationUnhealthy + time.Second
 
-		return false, nextCheck
+		return false, nextCheck, health
+	}
+
+	// check the unreachable taint, if enabled
+	if t.MHC.Spec.UnreachableTaintTimeout != nil {
+		timeout := t.MHC.Spec.UnreachableTaintTimeout.Duration
+		if taint := getNodeTaint(t.Node, corev1.TaintNodeUnreachable); taint != nil && taint.TimeAdded != nil {
+			failing(clusterv1.UnreachableNodeTaintReason, fmt.Sprintf("Node has taint %s", corev1.TaintNodeUnreachable), taint.TimeAdded.Time, taint.TimeAdded.Add(timeout))
+			// If the taint has been on the node for longer than the timeout, return true with no requeue time.
+			if taint.TimeAdded.Add(timeout).Before(now) {
+				conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnreachableNodeTaintReason, clusterv1.ConditionSeverityWarning, "Node has taint %s for more than %s", corev1.TaintNodeUnreachable, timeout.String())
+				logger.V(3).Info("Target is unhealthy: node has the unreachable taint longer than allowed timeout", "taint", corev1.TaintNodeUnreachable, "timeout", timeout.String())
+				return true, time.Duration(0), health
+			}
+
+			durationUnhealthy := now.Sub(taint.TimeAdded.Time)
+			nextCheck := timeout - durationUnhealthy + time.Second
+			if nextCheck > 0 {
+				nextCheckTimes = append(nextCheckTimes, nextCheck)
+			}
+		}
+	}
+
+	// check the node lease, if enabled
+	if t.MHC.Spec.NodeLeaseStaleTimeout != nil && t.nodeLease != nil && t.nodeLease.Spec.RenewTime != nil {
+		timeout := t.MHC.Spec.NodeLeaseStaleTimeout.Duration
+		renewTime := t.nodeLease.Spec.RenewTime.Time
+		failing(clusterv1.NodeLeaseStaleReason, "Node lease has not been renewed", renewTime, renewTime.Add(timeout))
+
+		// If the lease has not been renewed for longer than the timeout, return true with no requeue time.
+		if renewTime.Add(timeout).Before(now) {
+			conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.NodeLeaseStaleReason, clusterv1.ConditionSeverityWarning, "Node lease has not been renewed for more than %s", timeout.String())
+			logger.V(3).Info("Target is unhealthy: node lease has not been renewed for longer than allowed timeout", "renewTime", renewTime, "timeout", timeout.String())
+			return true, time.Duration(0), health
+		}
+
+		durationUnhealthy := now.Sub(renewTime)
+		nextCheck := timeout - durationUnhealthy + time.Second
+		if nextCheck > 0 {
+			nextCheckTimes = append(nextCheckTimes, nextCheck)
+		}
+	}
+
+	// check the kubelet version, if enabled
+	if t.MHC.Spec.KubeletVersionMismatchTimeout != nil && kubeletVersionMismatch(t.Machine, t.Node) {
+		timeout := t.MHC.Spec.KubeletVersionMismatchTimeout.Duration
+		mismatchSince := kubeletVersionMismatchSince(t.Node)
+		failing(clusterv1.KubeletVersionMismatchReason, fmt.Sprintf("Node reports kubelet version %s instead of %s", t.Node.Status.NodeInfo.KubeletVersion, *t.Machine.Spec.Version), mismatchSince, mismatchSince.Add(timeout))
+
+		// If the version has been mismatching for longer than the timeout, return true with no requeue time.
+		if mismatchSince.Add(timeout).Before(now) {
+			conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.KubeletVersionMismatchReason, clusterv1.ConditionSeverityWarning, "Node reports kubelet version %s instead of %s for more than %s", t.Node.Status.NodeInfo.KubeletVersion, *t.Machine.Spec.Version, timeout.String())
+			logger.V(3).Info("Target is unhealthy: node reports an unexpected kubelet version longer than allowed timeout", "kubeletVersion", t.Node.Status.NodeInfo.KubeletVersion, "version", *t.Machine.Spec.Version, "timeout", timeout.String())
+			return true, time.Duration(0), health
+		}
+
+		durationUnhealthy := now.Sub(mismatchSince)
+		nextCheck := timeout - durationUnhealthy + time.Second
+		if nextCheck > 0 {
+			nextCheckTimes = append(nextCheckTimes, nextCheck)
+		}
+	}
+
+	// Unhealthy conditions don't count during the grace period after the node creation, if any.
+	var gracePeriodEnd time.Time
+	if t.MHC.Spec.UnhealthyConditionsGracePeriodAfterNodeCreation != nil {
+		gracePeriodEnd = t.Node.CreationTimestamp.Add(t.MHC.Spec.UnhealthyConditionsGracePeriodAfterNodeCreation.Duration)
+	}
+
+	// The Ready condition doesn't count during the grace period after it transitioned while the kubelet kept
+	// posting heartbeats, e.g. while a restarted kubelet stabilizes, if any; the other conditions are not
+	// affected by the kubelet restarting and don't get this grace period.
+	readyGracePeriodEnd := gracePeriodEnd
+	if t.MHC.Spec.KubeletRestartGracePeriod != nil {
+		if transitionedAt := kubeletReadyTransitionedAt(t.Node); !transitionedAt.IsZero() {
+			if restartGracePeriodEnd := transitionedAt.Add(t.MHC.Spec.KubeletRestartGracePeriod.Duration); restartGracePeriodEnd.After(readyGracePeriodEnd) {
+				readyGracePeriodEnd = restartGracePeriodEnd
+			}
+		}
 	}
 
 	// check conditions
@@ -169,34 +321,125 @@ func (t *healthCheckTarget) needsRemediation(logger logr.Logger, timeoutForMachi
 			continue
 		}
 
+		conditionGracePeriodEnd := gracePeriodEnd
+		if c.Type == corev1.NodeReady {
+			conditionGracePeriodEnd = readyGracePeriodEnd
+		}
+		unhealthySince := nodeCondition.LastTransitionTime.Time
+		if conditionGracePeriodEnd.After(unhealthySince) {
+			unhealthySince = conditionGracePeriodEnd
+		}
+		failing(clusterv1.UnhealthyNodeConditionReason, fmt.Sprintf("Condition %s on node is reporting status %s", c.Type, c.Status), unhealthySince, unhealthySince.Add(c.Timeout.Duration))
+
 		// If the condition has been in the unhealthy state for longer than the
 		// timeout, return true with no requeue time.
-		if nodeCondition.LastTransitionTime.Add(c.Timeout.Duration).Before(now) {
+		if unhealthySince.Add(c.Timeout.Duration).Before(now) {
 			conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "Condition %s on node is reporting status %s for more than %s", c.Type, c.Status, c.Timeout.Duration.String())
 			logger.V(3).Info("Target is unhealthy: condition is in state longer than allowed timeout", "condition", c.Type, "state", c.Status, "timeout", c.Timeout.Duration.String())
-			return true, time.Duration(0)
+			return true, time.Duration(0), health
 		}
 
-		durationUnhealthy := now.Sub(nodeCondition.LastTransitionTime.Time)
+		durationUnhealthy := now.Sub(unhealthySince)
 		nextCheck := c.Timeout.Duration - durationUnhealthy + time.Second
 		if nextCheck > 0 {
 			nextCheckTimes = append(nextCheckTimes, nextCheck)
 		}
 	}
-	return false, minDuration(nextCheckTimes)
+	return false, minDuration(nextCheckTimes), health
+}
+
+// nodeMissingSince returns the time since when the node of a machine is missing, i.e. when the machine controller
+// reported the node as not found; if this is not reported yet, the node is considered missing since now.
+func nodeMissingSince(machine *clusterv1.Machine, now time.Time) time.Time {
+	if condition := conditions.Get(machine, clusterv1.MachineNodeHealthyCondition); condition != nil &&
+		condition.Status == corev1.ConditionFalse && condition.Reason == clusterv1.NodeNotFoundReason {
+		return condition.LastTransitionTime.Time
+	}
+	return now
+}
+
+// infrastructureMissingSince returns the time since when the infrastructure object of a machine is considered missing,
+// i.e. the last transition of the InfrastructureReady condition of the machine, or its creation if the condition is not set.
+func infrastructureMissingSince(machine *clusterv1.Machine) time.Time {
+	if condition := conditions.Get(machine, clusterv1.InfrastructureReadyCondition); condition != nil {
+		return condition.LastTransitionTime.Time
+	}
+	return machine.CreationTimestamp.Time
+}
+
+// kubeletVersionMismatch returns true if the node reports a kubelet version different from the machine's version;
+// versions which can't be parsed are not considered mismatching, as well as build metadata, e.g. +vendor.1.
+func kubeletVersionMismatch(machine *clusterv1.Machine, node *corev1.Node) bool {
+	if machine.Spec.Version == nil || node.Status.NodeInfo.KubeletVersion == "" {
+		return false
+	}
+	machineVersion, err := semver.ParseTolerant(*machine.Spec.Version)
+	if err != nil {
+		return false
+	}
+	kubeletVersion, err := semver.ParseTolerant(node.Status.NodeInfo.KubeletVersion)
+	if err != nil {
+		return false
+	}
+	return version.Compare(machineVersion, kubeletVersion) != 0
+}
+
+// kubeletReadyTransitionedAt returns the time the Ready condition of a node last transitioned at, if the kubelet kept
+// posting heartbeats since then, as it happens when the kubelet restarts; it returns the zero time if the node has
+// no Ready condition or if the Ready condition has been set to Unknown because the kubelet stopped posting heartbeats,
+// e.g. because the node is down.
+func kubeletReadyTransitionedAt(node *corev1.Node) time.Time {
+	ready := getNodeCondition(node, corev1.NodeReady)
+	if ready == nil || ready.Status == corev1.ConditionUnknown {
+		return time.Time{}
+	}
+	if ready.LastHeartbeatTime.Time.Before(ready.LastTransitionTime.Time) {
+		return time.Time{}
+	}
+	return ready.LastTransitionTime.Time
+}
+
+// kubeletVersionMismatchSince returns the time since when the kubelet version of a node is expected to be mismatching,
+// i.e. the creation of the node or the last transition of its Ready condition, e.g. when the kubelet was restarted
+// with another version, whichever is more recent.
+func kubeletVersionMismatchSince(node *corev1.Node) time.Time {
+	since := node.CreationTimestamp.Time
+	if readyCondition := getNodeCondition(node, corev1.NodeReady); readyCondition != nil && readyCondition.LastTransitionTime.After(since) {
+		since = readyCondition.LastTransitionTime.Time
+	}
+	return since
 }
 
 // getTargetsFromMHC uses the MachineHealthCheck's selector to fetch machines
 // and their nodes targeted by the health check, ready for health checking.
-func (r *Reconciler) getTargetsFromMHC(ctx context.Context, logger logr.Logger, clusterClient client.Reader, cluster *clusterv1.Cluster, mhc *clusterv1.MachineHealthCheck) ([]healthCheckTarget, error) {
-	machines, err := r.getMachinesFromMHC(ctx, mhc)
+// Machines matched by the selector but belonging to another cluster are excluded, and reported
+// using the SelectorScopedToCluster condition of the MachineHealthCheck.
+func (r *Reconciler) getTargetsFromMHC(ctx context.Context, logger logr.Logger, clusterClient client.Reader, workloadClient func() (client.Client, error), cluster *clusterv1.Cluster, mhc *clusterv1.MachineHealthCheck) ([]healthCheckTarget, error) {
+	machines, foreignMachines, err := r.getMachinesFromMHC(ctx, mhc)
 	if err != nil {
 		return nil, errors.Wrap(err, "error getting machines from MachineHealthCheck")
 	}
+	if len(foreignMachines) > 0 {
+		logger.Info("Not targeting machines matched by the selector, because they belong to another cluster", "machines", foreignMachines)
+		conditions.MarkFalse(mhc, clusterv1.SelectorScopedToClusterCondition, clusterv1.SelectorMatchesForeignClusterReason, clusterv1.ConditionSeverityWarning,
+			"Selector matches machines belonging to other clusters, which are excluded from the health check: %s", strings.Join(foreignMachines, ", "))
+	} else {
+		conditions.Delete(mhc, clusterv1.SelectorScopedToClusterCondition)
+	}
 	if len(machines) == 0 {
 		return nil, nil
 	}
 
+	// Node Leases are read with an uncached client, so the management cluster doesn't cache all the Leases in
+	// the kube-node-lease namespace of the workload cluster; each Lease is read with a Get, scoped to the node.
+	var leaseReader client.Reader
+	if mhc.Spec.NodeLeaseStaleTimeout != nil {
+		leaseReader, err = workloadClient()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read node leases")
+		}
+	}
+
 	targets := []healthCheckTarget{}
 	for k := range machines {
 		skip, reason := shouldSkipRemediation(&machines[k])
@@ -224,20 +467,36 @@ func (r *Reconciler) getTargetsFromMHC(ctx context.Context, logger logr.Logger,
 			// A node has been seen for this machine, but it no longer exists
 			target.nodeMissing = true
 		}
+		if role := excludedNodeRole(node, mhc.Spec.ExcludedNodeRoles); role != "" {
+			logger.V(3).Info("Not targeting machine because its node has an excluded role", "machine", machines[k].Name, "node", node.Name, "role", role)
+			continue
+		}
 		target.Node = node
+		target.nodeReplaced = nodeReplaced(target.Machine, node)
+		if mhc.Spec.InfrastructureMissingTimeout != nil {
+			target.infrastructureMissing, err = infrastructureMissing(ctx, r.Client, target.Machine)
+			if err != nil {
+				return nil, errors.Wrap(err, "error getting infrastructure")
+			}
+		}
+		if mhc.Spec.NodeLeaseStaleTimeout != nil && node != nil {
+			target.nodeLease, err = getNodeLease(ctx, leaseReader, node.Name)
+			if err != nil {
+				return nil, errors.Wrap(err, "error getting node lease")
+			}
+		}
 		targets = append(targets, target)
 	}
 	return targets, nil
 }
 
 // getMachinesFromMHC fetches Machines matched by the MachineHealthCheck's
-// label selector.
-func (r *Reconciler) getMachinesFromMHC(ctx context.Context, mhc *clusterv1.MachineHealthCheck) ([]clusterv1.Machine, error) {
-	selector, err := metav1.LabelSelectorAsSelector(metav1.CloneSelectorAndAddLabel(
-		&mhc.Spec.Selector, clusterv1.ClusterLabelName, mhc.Spec.ClusterName,
-	))
+// label selector; Machines whose cluster name label does not match the Cluster
+// of the MachineHealthCheck are not returned, and their names are returned separately.
+func (r *Reconciler) getMachinesFromMHC(ctx context.Context, mhc *clusterv1.MachineHealthCheck) ([]clusterv1.Machine, []string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&mhc.Spec.Selector)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to build selector")
+		return nil, nil, errors.Wrap(err, "failed to build selector")
 	}
 
 	var machineList clusterv1.MachineList
@@ -247,9 +506,20 @@ func (r *Reconciler) getMachinesFromMHC(ctx context.Context, mhc *clusterv1.Mach
 		client.MatchingLabelsSelector{Selector: selector},
 		client.InNamespace(mhc.GetNamespace()),
 	); err != nil {
-		return nil, errors.Wrap(err, "failed to list machines")
+		return nil, nil, errors.Wrap(err, "failed to list machines")
 	}
-	return machineList.Items, nil
+
+	machines := []clusterv1.Machine{}
+	var foreignMachines []string
+	for _, machine := range machineList.Items {
+		if machine.Labels[clusterv1.ClusterLabelName] != mhc.Spec.ClusterName {
+			foreignMachines = append(foreignMachines, machine.Name)
+			continue
+		}
+		machines = append(machines, machine)
+	}
+	sort.Strings(foreignMachines)
+	return machines, foreignMachines, nil
 }
 
 // getNodeFromMachine fetches the node from a local or remote cluster for a
@@ -271,43 +541,238 @@ func (r *Reconciler) getNodeFromMachine(ctx context.Context, clusterClient clien
 	return node, nil
 }
 
+// infrastructureMissing returns true if the infrastructure object referenced by a machine does not exist.
+func infrastructureMissing(ctx context.Context, c client.Reader, machine *clusterv1.Machine) (bool, error) {
+	if _, err := external.Get(ctx, c, &machine.Spec.InfrastructureRef, machine.Namespace); err != nil {
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// getNodeLease fetches the Lease of a node from the kube-node-lease namespace of the workload cluster;
+// it returns nil if the node has no Lease, e.g. because the kubelet has not created it yet.
+func getNodeLease(ctx context.Context, clusterClient client.Reader, nodeName string) (*coordinationv1.Lease, error) {
+	lease := &coordinationv1.Lease{}
+	if err := clusterClient.Get(ctx, client.ObjectKey{Namespace: corev1.NamespaceNodeLease, Name: nodeName}, lease); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return lease, nil
+}
+
+// nodeReplaced returns true if the node does not match the UID in the machine's NodeRef, if any, and the node does not
+// belong to the machine's infrastructure anymore; this happens when the node the machine referenced has been deleted
+// and a new node, backed by a different instance, has been created with the same name.
+// NOTE: a node re-registered by the same instance, e.g. after its Node object was deleted and recreated by the kubelet,
+// keeps the machine's providerID; in this case the Machine controller updates the UID in the NodeRef instead.
+func nodeReplaced(machine *clusterv1.Machine, node *corev1.Node) bool {
+	if node == nil || machine.Status.NodeRef == nil || machine.Status.NodeRef.UID == "" {
+		return false
+	}
+	if node.UID == machine.Status.NodeRef.UID {
+		return false
+	}
+	if machine.Spec.ProviderID == nil || node.Spec.ProviderID == "" {
+		// Without both providerIDs it is not possible to tell a re-registered node from a replaced one.
+		return false
+	}
+	machineProviderID, err := noderefutil.NewProviderID(*machine.Spec.ProviderID)
+	if err != nil {
+		return false
+	}
+	nodeProviderID, err := noderefutil.NewProviderID(node.Spec.ProviderID)
+	if err != nil {
+		return false
+	}
+	return !machineProviderID.Equals(nodeProviderID)
+}
+
+// nodeRecovered reads the node of an unhealthy target again and returns true if it no longer matches any of the
+// unhealthy conditions of the MachineHealthCheck, e.g. because its Ready condition transitioned back to True after
+// the target has been health checked; a node which does not exist anymore is not considered recovered.
+func nodeRecovered(ctx context.Context, clusterClient client.Reader, t healthCheckTarget) (bool, error) {
+	if t.Node == nil || t.Machine.Status.NodeRef == nil {
+		return false, nil
+	}
+
+	node := &corev1.Node{}
+	if err := clusterClient.Get(ctx, client.ObjectKey{Name: t.Machine.Status.NodeRef.Name}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to get node %s", t.Machine.Status.NodeRef.Name)
+	}
+
+	for _, c := range t.MHC.Spec.UnhealthyConditions {
+		if nodeCondition := getNodeCondition(node, c.Type); nodeCondition != nil && nodeCondition.Status == c.Status {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 // healthCheckTargets health checks a slice of targets
-// and gives a data to measure the average health.
-func (r *Reconciler) healthCheckTargets(targets []healthCheckTarget, logger logr.Logger, timeoutForMachineToHaveNode metav1.Duration) ([]healthCheckTarget, []healthCheckTarget, []time.Duration) {
+// and gives a data to measure the average health, as well as why each target not healthy is failing a health check.
+func (r *Reconciler) healthCheckTargets(targets []healthCheckTarget, logger logr.Logger, timeoutForMachineToHaveNode metav1.Duration) healthCheckResult {
+	result := partitionTargets(targets, logger, timeoutForMachineToHaveNode, time.Now())
+
+	for _, t := range result.pending {
+		r.recorder.Eventf(
+			t.Machine,
+			corev1.EventTypeNormal,
+			EventDetectedUnhealthy,
+			"Machine %v has unhealthy node %v",
+			t.string(),
+			t.nodeName(),
+		)
+	}
+	return result
+}
+
+// applyUnhealthyObservationThreshold tracks, in the status of the MachineHealthCheck, the number of consecutive health
+// checks each target has been found unhealthy at, and defers the remediation of the unhealthy targets until they have
+// been found unhealthy for UnhealthyObservationThreshold consecutive health checks; healthy targets reset the count.
+// It returns the targets to be remediated, and the durations after which the deferred targets should be checked again.
+func applyUnhealthyObservationThreshold(m *clusterv1.MachineHealthCheck, targets, healthy, unhealthy []healthCheckTarget) ([]healthCheckTarget, []time.Duration) {
+	if m.Spec.UnhealthyObservationThreshold <= 1 {
+		m.Status.UnhealthyObservations = nil
+		return unhealthy, nil
+	}
+
+	previous := make(map[string]int32, len(m.Status.UnhealthyObservations))
+	for _, o := range m.Status.UnhealthyObservations {
+		previous[o.Machine] = o.Count
+	}
+	current := make(map[string]int32, len(previous))
+
+	// Targets neither healthy nor unhealthy, e.g. pending targets, keep their count.
+	for _, t := range targets {
+		if count, ok := previous[t.Machine.Name]; ok {
+			current[t.Machine.Name] = count
+		}
+	}
+	for _, t := range healthy {
+		delete(current, t.Machine.Name)
+	}
+
+	var toRemediate []healthCheckTarget
 	var nextCheckTimes []time.Duration
-	var unhealthy []healthCheckTarget
-	var healthy []healthCheckTarget
+	for _, t := range unhealthy {
+		count := previous[t.Machine.Name] + 1
+		current[t.Machine.Name] = count
+		if count < m.Spec.UnhealthyObservationThreshold {
+			nextCheckTimes = append(nextCheckTimes, unhealthyObservationInterval)
+			continue
+		}
+		toRemediate = append(toRemediate, t)
+	}
+
+	m.Status.UnhealthyObservations = nil
+	for _, t := range targets {
+		if count, ok := current[t.Machine.Name]; ok {
+			m.Status.UnhealthyObservations = append(m.Status.UnhealthyObservations, clusterv1.UnhealthyObservation{Machine: t.Machine.Name, Count: count})
+		}
+	}
+	sort.Slice(m.Status.UnhealthyObservations, func(i, j int) bool {
+		return m.Status.UnhealthyObservations[i].Machine < m.Status.UnhealthyObservations[j].Machine
+	})
+	return toRemediate, nextCheckTimes
+}
 
+// orderByFailureDomainRoundRobin returns the targets ordered round-robin across the failure domains of their machines,
+// so that when only some of the targets can be remediated, e.g. due to MaxInFlightRemediations, the remediations are
+// spread across failure domains instead of being concentrated in one of them.
+// Failure domains are visited in alphabetical order, with machines without a failure domain grouped together; the
+// relative order of the targets within the same failure domain is preserved.
+func orderByFailureDomainRoundRobin(targets []healthCheckTarget) []healthCheckTarget {
+	byFailureDomain := map[string][]healthCheckTarget{}
+	failureDomains := []string{}
 	for _, t := range targets {
-		logger = logger.WithValues("Target", t.string())
+		failureDomain := pointer.StringDeref(t.Machine.Spec.FailureDomain, "")
+		if _, ok := byFailureDomain[failureDomain]; !ok {
+			failureDomains = append(failureDomains, failureDomain)
+		}
+		byFailureDomain[failureDomain] = append(byFailureDomain[failureDomain], t)
+	}
+	sort.Strings(failureDomains)
+
+	ordered := make([]healthCheckTarget, 0, len(targets))
+	for i := 0; len(ordered) < len(targets); i++ {
+		for _, failureDomain := range failureDomains {
+			if i < len(byFailureDomain[failureDomain]) {
+				ordered = append(ordered, byFailureDomain[failureDomain][i])
+			}
+		}
+	}
+	return ordered
+}
+
+// healthCheckResult groups the targets of a MachineHealthCheck by their health.
+type healthCheckResult struct {
+	// healthy targets have a node which is passing all the health checks.
+	healthy []healthCheckTarget
+
+	// unhealthy targets are failing a health check for longer than the timeout, and need remediation.
+	unhealthy []healthCheckTarget
+
+	// pending targets are failing a health check, or waiting for a node, but they are still within the timeout.
+	pending []healthCheckTarget
+
+	// nextCheckTimes contains, for each pending target, the duration after which the target should be checked again.
+	nextCheckTimes []time.Duration
+
+	// targetHealth contains, for each unhealthy or pending target, why the target is failing a health check.
+	targetHealth []clusterv1.TargetHealth
+
+	// nextRemediationTime is the earliest time at which a pending target is going to be considered unhealthy,
+	// if any.
+	nextRemediationTime *metav1.Time
+}
+
+// partitionTargets health checks a slice of targets at the given time, grouping them into
+// healthy, unhealthy and pending targets in a single pass.
+// NOTE: Targets not being evaluated (e.g. because the control plane is not initialized yet,
+// or the machine is being deleted) are not part of any group.
+func partitionTargets(targets []healthCheckTarget, logger logr.Logger, timeoutForMachineToHaveNode metav1.Duration, now time.Time) healthCheckResult {
+	result := healthCheckResult{}
+
+	for _, t := range targets {
+		logger := logger.WithValues("Target", t.string())
 		logger.V(3).Info("Health checking target")
-		needsRemediation, nextCheck := t.needsRemediation(logger, timeoutForMachineToHaveNode)
+		needsRemediation, nextCheck, health := t.needsRemediation(logger, timeoutForMachineToHaveNode, now)
 
 		if needsRemediation {
-			unhealthy = append(unhealthy, t)
+			result.unhealthy = append(result.unhealthy, t)
+			if health != nil {
+				result.targetHealth = append(result.targetHealth, *health)
+			}
 			continue
 		}
 
 		if nextCheck > 0 {
 			logger.V(3).Info("Target is likely to go unhealthy", "timeUntilUnhealthy", nextCheck.Truncate(time.Second).String())
-			r.recorder.Eventf(
-				t.Machine,
-				corev1.EventTypeNormal,
-				EventDetectedUnhealthy,
-				"Machine %v has unhealthy node %v",
-				t.string(),
-				t.nodeName(),
-			)
-			nextCheckTimes = append(nextCheckTimes, nextCheck)
+			result.pending = append(result.pending, t)
+			result.nextCheckTimes = append(result.nextCheckTimes, nextCheck)
+			if health != nil {
+				result.targetHealth = append(result.targetHealth, *health)
+				if result.nextRemediationTime == nil || health.UnhealthyAt.Before(result.nextRemediationTime) {
+					result.nextRemediationTime = health.UnhealthyAt.DeepCopy()
+				}
+			}
 			continue
 		}
 
 		if t.Machine.DeletionTimestamp.IsZero() && t.Node != nil {
 			conditions.MarkTrue(t.Machine, clusterv1.MachineHealthCheckSucceededCondition)
-			healthy = append(healthy, t)
+			result.healthy = append(result.healthy, t)
 		}
 	}
-	return healthy, unhealthy, nextCheckTimes
+	return result
 }
 
 // getNodeCondition returns node condition by type.
@@ -320,6 +785,28 @@ func getNodeCondition(node *corev1.Node, conditionType corev1.NodeConditionType)
 	return nil
 }
 
+// excludedNodeRole returns the first of the excluded roles the node has, if any.
+func excludedNodeRole(node *corev1.Node, excludedRoles []string) string {
+	if node == nil {
+		return ""
+	}
+	for _, role := range excludedRoles {
+		if _, ok := node.Labels[clusterv1.NodeRoleLabelPrefix+role]; ok {
+			return role
+		}
+	}
+	return ""
+}
+
+func getNodeTaint(node *corev1.Node, key string) *corev1.Taint {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == key {
+			return &taint
+		}
+	}
+	return nil
+}
+
 func minDuration(durations []time.Duration) time.Duration {
 	if len(durations) == 0 {
 		return time.Duration(0)
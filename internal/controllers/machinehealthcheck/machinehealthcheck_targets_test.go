@@ -21,9 +21,12 @@ import (
 	"time"
 
 	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -91,10 +94,27 @@ func TestGetTargetsFromMHC(t *testing.T) {
 	testMachine6 := newTestMachine("machine6", namespace, clusterName, testNode6.Name, mhcSelector)
 	testMachine6.Annotations = map[string]string{"cluster.x-k8s.io/paused": ""}
 
+	// machine whose node has been replaced by a new node with the same name
+	testNode7 := newTestNode("node7")
+	testNode7.UID = "new-uid"
+	testNode7.Spec.ProviderID = "test:///new-instance"
+	testMachine7 := newTestMachine("machine7", namespace, clusterName, testNode7.Name, mhcSelector)
+	testMachine7.Spec.ProviderID = pointer.String("test:///old-instance")
+	testMachine7.Status.NodeRef.UID = "old-uid"
+
+	// A node which has been re-registered by the same instance, i.e. it has a new UID but the same providerID
+	testNode8 := newTestNode("node8")
+	testNode8.UID = "new-uid"
+	testNode8.Spec.ProviderID = "test:///instance"
+	testMachine8 := newTestMachine("machine8", namespace, clusterName, testNode8.Name, mhcSelector)
+	testMachine8.Spec.ProviderID = pointer.String("test:///instance")
+	testMachine8.Status.NodeRef.UID = "old-uid"
+
 	testCases := []struct {
-		desc            string
-		toCreate        []client.Object
-		expectedTargets []healthCheckTarget
+		desc                    string
+		toCreate                []client.Object
+		expectedTargets         []healthCheckTarget
+		expectedForeignMachines bool
 	}{
 		{
 			desc:            "with no matching machines",
@@ -139,6 +159,19 @@ func TestGetTargetsFromMHC(t *testing.T) {
 					Node:    testNode3,
 				},
 			},
+			expectedForeignMachines: true,
+		},
+		{
+			desc:     "when the selector matches a machine labeled for a different cluster",
+			toCreate: append(baseObjects, testNode1, testMachine1, testNode4, testMachine4),
+			expectedTargets: []healthCheckTarget{
+				{
+					Machine: testMachine1,
+					MHC:     testMHC,
+					Node:    testNode1,
+				},
+			},
+			expectedForeignMachines: true,
 		},
 		{
 			desc:     "with machines having skip-remediation or paused annotation",
@@ -151,6 +184,29 @@ func TestGetTargetsFromMHC(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc:     "when a machine's node has been replaced by a new node with the same name",
+			toCreate: append(baseObjects, testNode7, testMachine7),
+			expectedTargets: []healthCheckTarget{
+				{
+					Machine:      testMachine7,
+					MHC:          testMHC,
+					Node:         testNode7,
+					nodeReplaced: true,
+				},
+			},
+		},
+		{
+			desc:     "when a machine's node has been re-registered with the same providerID",
+			toCreate: append(baseObjects, testNode8, testMachine8),
+			expectedTargets: []healthCheckTarget{
+				{
+					Machine: testMachine8,
+					MHC:     testMHC,
+					Node:    testNode8,
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -169,7 +225,7 @@ func TestGetTargetsFromMHC(t *testing.T) {
 				t.patchHelper = patchHelper
 			}
 
-			targets, err := reconciler.getTargetsFromMHC(ctx, ctrl.LoggerFrom(ctx), k8sClient, cluster, testMHC)
+			targets, err := reconciler.getTargetsFromMHC(ctx, ctrl.LoggerFrom(ctx), k8sClient, reconciler.uncachedWorkloadClient(ctx, cluster), cluster, testMHC)
 			gs.Expect(err).ToNot(HaveOccurred())
 
 			gs.Expect(len(targets)).To(Equal(len(tc.expectedTargets)))
@@ -178,11 +234,295 @@ func TestGetTargetsFromMHC(t *testing.T) {
 				gs.Expect(target.Machine).To(Equal(expectedTarget.Machine))
 				gs.Expect(target.MHC).To(Equal(expectedTarget.MHC))
 				gs.Expect(target.Node).To(Equal(expectedTarget.Node))
+				gs.Expect(target.nodeReplaced).To(Equal(expectedTarget.nodeReplaced))
+			}
+
+			if tc.expectedForeignMachines {
+				gs.Expect(conditions.IsFalse(testMHC, clusterv1.SelectorScopedToClusterCondition)).To(BeTrue())
+				gs.Expect(conditions.GetReason(testMHC, clusterv1.SelectorScopedToClusterCondition)).To(Equal(clusterv1.SelectorMatchesForeignClusterReason))
+				gs.Expect(*conditions.GetSeverity(testMHC, clusterv1.SelectorScopedToClusterCondition)).To(Equal(clusterv1.ConditionSeverityWarning))
+				gs.Expect(conditions.GetMessage(testMHC, clusterv1.SelectorScopedToClusterCondition)).To(ContainSubstring(testMachine4.Name))
+				return
+			}
+			gs.Expect(conditions.Has(testMHC, clusterv1.SelectorScopedToClusterCondition)).To(BeFalse())
+		})
+	}
+}
+
+func TestGetTargetsFromMHCMatchExpressions(t *testing.T) {
+	namespace := "test-mhc"
+	clusterName := "test-cluster"
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      clusterName,
+		},
+	}
+
+	fooNode := newTestNode("foo-node")
+	fooMachine := newTestMachine("foo-machine", namespace, clusterName, fooNode.Name, map[string]string{"machine-group": "foo"})
+	barNode := newTestNode("bar-node")
+	barMachine := newTestMachine("bar-machine", namespace, clusterName, barNode.Name, map[string]string{"machine-group": "bar"})
+	ungroupedNode := newTestNode("ungrouped-node")
+	ungroupedMachine := newTestMachine("ungrouped-machine", namespace, clusterName, ungroupedNode.Name, map[string]string{})
+
+	testCases := []struct {
+		name             string
+		requirement      metav1.LabelSelectorRequirement
+		expectedMachines []*clusterv1.Machine
+	}{
+		{
+			name:             "In selects machines with one of the values",
+			requirement:      metav1.LabelSelectorRequirement{Key: "machine-group", Operator: metav1.LabelSelectorOpIn, Values: []string{"foo", "baz"}},
+			expectedMachines: []*clusterv1.Machine{fooMachine},
+		},
+		{
+			name:             "NotIn selects machines without any of the values, including machines without the label",
+			requirement:      metav1.LabelSelectorRequirement{Key: "machine-group", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"foo"}},
+			expectedMachines: []*clusterv1.Machine{barMachine, ungroupedMachine},
+		},
+		{
+			name:             "Exists selects machines with the label",
+			requirement:      metav1.LabelSelectorRequirement{Key: "machine-group", Operator: metav1.LabelSelectorOpExists},
+			expectedMachines: []*clusterv1.Machine{fooMachine, barMachine},
+		},
+		{
+			name:             "DoesNotExist selects machines without the label",
+			requirement:      metav1.LabelSelectorRequirement{Key: "machine-group", Operator: metav1.LabelSelectorOpDoesNotExist},
+			expectedMachines: []*clusterv1.Machine{ungroupedMachine},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := &clusterv1.MachineHealthCheck{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-mhc",
+					Namespace: namespace,
+				},
+				Spec: clusterv1.MachineHealthCheckSpec{
+					ClusterName: clusterName,
+					Selector: metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{tc.requirement},
+					},
+				},
+			}
+
+			k8sClient := fake.NewClientBuilder().WithObjects(cluster, mhc, fooNode, fooMachine, barNode, barMachine, ungroupedNode, ungroupedMachine).Build()
+			reconciler := &Reconciler{
+				Client: k8sClient,
+			}
+
+			targets, err := reconciler.getTargetsFromMHC(ctx, ctrl.LoggerFrom(ctx), k8sClient, reconciler.uncachedWorkloadClient(ctx, cluster), cluster, mhc)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			gotMachines := make([]*clusterv1.Machine, 0, len(targets))
+			for _, target := range targets {
+				gotMachines = append(gotMachines, target.Machine)
 			}
+			g.Expect(gotMachines).To(ConsistOf(tc.expectedMachines))
 		})
 	}
 }
 
+func TestGetTargetsFromMHCExcludedNodeRoles(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := "test-mhc"
+	clusterName := "test-cluster"
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      clusterName,
+		},
+	}
+
+	mhcSelector := map[string]string{"cluster": clusterName, "machine-group": "foo"}
+	mhc := &clusterv1.MachineHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-mhc",
+			Namespace: namespace,
+		},
+		Spec: clusterv1.MachineHealthCheckSpec{
+			ClusterName: clusterName,
+			Selector: metav1.LabelSelector{
+				MatchLabels: mhcSelector,
+			},
+			ExcludedNodeRoles: []string{"control-plane"},
+		},
+	}
+
+	workerNode := newTestNode("worker-node")
+	workerMachine := newTestMachine("worker-machine", namespace, clusterName, workerNode.Name, mhcSelector)
+	controlPlaneNode := newTestNode("control-plane-node")
+	controlPlaneNode.Labels = map[string]string{clusterv1.NodeRoleLabelPrefix + "control-plane": ""}
+	controlPlaneMachine := newTestMachine("control-plane-machine", namespace, clusterName, controlPlaneNode.Name, mhcSelector)
+
+	k8sClient := fake.NewClientBuilder().WithObjects(cluster, mhc, workerNode, workerMachine, controlPlaneNode, controlPlaneMachine).Build()
+	reconciler := &Reconciler{
+		Client: k8sClient,
+	}
+
+	// The control plane machine is matched by the selector, but it is not targeted because its node has an excluded role.
+	targets, err := reconciler.getTargetsFromMHC(ctx, ctrl.LoggerFrom(ctx), k8sClient, reconciler.uncachedWorkloadClient(ctx, cluster), cluster, mhc)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(targets).To(HaveLen(1))
+	g.Expect(targets[0].Machine).To(Equal(workerMachine))
+	g.Expect(targets[0].Node).To(Equal(workerNode))
+}
+
+func TestGetTargetsFromMHCNodeLease(t *testing.T) {
+	namespace := "test-mhc"
+	clusterName := "test-cluster"
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      clusterName,
+		},
+	}
+
+	mhcSelector := map[string]string{"cluster": clusterName, "machine-group": "foo"}
+	mhc := &clusterv1.MachineHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-mhc",
+			Namespace: namespace,
+		},
+		Spec: clusterv1.MachineHealthCheckSpec{
+			ClusterName: clusterName,
+			Selector: metav1.LabelSelector{
+				MatchLabels: mhcSelector,
+			},
+			NodeLeaseStaleTimeout: &metav1.Duration{Duration: time.Minute},
+		},
+	}
+
+	nodeWithLease := newTestNode("node-with-lease")
+	machineWithLease := newTestMachine("machine-with-lease", namespace, clusterName, nodeWithLease.Name, mhcSelector)
+	lease := newTestNodeLease(nodeWithLease.Name, 120*time.Second)
+	nodeWithoutLease := newTestNode("node-without-lease")
+	machineWithoutLease := newTestMachine("machine-without-lease", namespace, clusterName, nodeWithoutLease.Name, mhcSelector)
+
+	// The leases are read with an uncached client to the workload cluster, not from the cache used for reading the nodes.
+	k8sClient := fake.NewClientBuilder().WithObjects(cluster, mhc, nodeWithLease, machineWithLease, nodeWithoutLease, machineWithoutLease).Build()
+	uncachedClient := fake.NewClientBuilder().WithObjects(lease).Build()
+	reconciler := &Reconciler{
+		Client:             k8sClient,
+		remoteClientGetter: remoteClientGetterFor(uncachedClient),
+	}
+
+	t.Run("reads the node leases if the node lease stale timeout is set", func(t *testing.T) {
+		g := NewWithT(t)
+
+		targets, err := reconciler.getTargetsFromMHC(ctx, ctrl.LoggerFrom(ctx), k8sClient, reconciler.uncachedWorkloadClient(ctx, cluster), cluster, mhc)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(targets).To(HaveLen(2))
+		for _, target := range targets {
+			switch target.Machine.Name {
+			case machineWithLease.Name:
+				g.Expect(target.nodeLease).ToNot(BeNil())
+				g.Expect(target.nodeLease.Spec.RenewTime.Time).To(BeTemporally("~", lease.Spec.RenewTime.Time, time.Millisecond))
+			default:
+				g.Expect(target.nodeLease).To(BeNil())
+			}
+		}
+	})
+
+	t.Run("does not read the node leases if the node lease stale timeout is not set", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := mhc.DeepCopy()
+		m.Spec.NodeLeaseStaleTimeout = nil
+		targets, err := reconciler.getTargetsFromMHC(ctx, ctrl.LoggerFrom(ctx), k8sClient, reconciler.uncachedWorkloadClient(ctx, cluster), cluster, m)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(targets).To(HaveLen(2))
+		for _, target := range targets {
+			g.Expect(target.nodeLease).To(BeNil())
+		}
+	})
+}
+
+func TestGetTargetsFromMHCInfrastructureMissing(t *testing.T) {
+	namespace := "test-mhc"
+	clusterName := "test-cluster"
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      clusterName,
+		},
+	}
+
+	mhcSelector := map[string]string{"cluster": clusterName, "machine-group": "foo"}
+	mhc := &clusterv1.MachineHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-mhc",
+			Namespace: namespace,
+		},
+		Spec: clusterv1.MachineHealthCheckSpec{
+			ClusterName: clusterName,
+			Selector: metav1.LabelSelector{
+				MatchLabels: mhcSelector,
+			},
+			InfrastructureMissingTimeout: &metav1.Duration{Duration: 10 * time.Minute},
+		},
+	}
+
+	infraMachine := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "infrastructure.cluster.x-k8s.io/v1beta1",
+			"kind":       "GenericInfrastructureMachine",
+			"metadata": map[string]interface{}{
+				"name":      "infra-machine",
+				"namespace": namespace,
+			},
+		},
+	}
+	infrastructureRef := func(name string) corev1.ObjectReference {
+		return corev1.ObjectReference{APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1", Kind: "GenericInfrastructureMachine", Name: name}
+	}
+
+	nodeWithInfrastructure := newTestNode("node-with-infrastructure")
+	machineWithInfrastructure := newTestMachine("machine-with-infrastructure", namespace, clusterName, nodeWithInfrastructure.Name, mhcSelector)
+	machineWithInfrastructure.Spec.InfrastructureRef = infrastructureRef(infraMachine.GetName())
+	nodeWithoutInfrastructure := newTestNode("node-without-infrastructure")
+	machineWithoutInfrastructure := newTestMachine("machine-without-infrastructure", namespace, clusterName, nodeWithoutInfrastructure.Name, mhcSelector)
+	machineWithoutInfrastructure.Spec.InfrastructureRef = infrastructureRef("deleted-infra-machine")
+
+	k8sClient := fake.NewClientBuilder().WithObjects(cluster, mhc, infraMachine, nodeWithInfrastructure, machineWithInfrastructure, nodeWithoutInfrastructure, machineWithoutInfrastructure).Build()
+	reconciler := &Reconciler{
+		Client: k8sClient,
+	}
+
+	t.Run("checks the infrastructure if the infrastructure missing timeout is set", func(t *testing.T) {
+		g := NewWithT(t)
+
+		targets, err := reconciler.getTargetsFromMHC(ctx, ctrl.LoggerFrom(ctx), k8sClient, reconciler.uncachedWorkloadClient(ctx, cluster), cluster, mhc)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(targets).To(HaveLen(2))
+		for _, target := range targets {
+			g.Expect(target.infrastructureMissing).To(Equal(target.Machine.Name == machineWithoutInfrastructure.Name))
+		}
+	})
+
+	t.Run("does not check the infrastructure if the infrastructure missing timeout is not set", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := mhc.DeepCopy()
+		m.Spec.InfrastructureMissingTimeout = nil
+		targets, err := reconciler.getTargetsFromMHC(ctx, ctrl.LoggerFrom(ctx), k8sClient, reconciler.uncachedWorkloadClient(ctx, cluster), cluster, m)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(targets).To(HaveLen(2))
+		for _, target := range targets {
+			g.Expect(target.infrastructureMissing).To(BeFalse())
+		}
+	})
+}
+
 func TestHealthCheckTargets(t *testing.T) {
 	namespace := "test-mhc"
 	clusterName := "test-cluster"
@@ -270,6 +610,78 @@ func TestHealthCheckTargets(t *testing.T) {
 		nodeMissing: true,
 	}
 
+	// Targets for when the Node has been seen, but has now gone, and the MHC tolerates missing nodes for a while
+	testMHCWithNodeMissingTimeout := testMHC.DeepCopy()
+	testMHCWithNodeMissingTimeout.Spec.NodeMissingTimeout = &metav1.Duration{Duration: time.Minute}
+
+	testMachineNodeMissing30s := testMachine.DeepCopy()
+	testMachineNodeMissing30s.Status.Conditions = clusterv1.Conditions{
+		{
+			Type:               clusterv1.MachineNodeHealthyCondition,
+			Status:             corev1.ConditionFalse,
+			Severity:           clusterv1.ConditionSeverityError,
+			Reason:             clusterv1.NodeNotFoundReason,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-30 * time.Second)),
+		},
+	}
+	nodeGoneAway30s := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithNodeMissingTimeout,
+		Machine:     testMachineNodeMissing30s,
+		Node:        &corev1.Node{},
+		nodeMissing: true,
+	}
+
+	testMachineNodeMissing120s := testMachineNodeMissing30s.DeepCopy()
+	testMachineNodeMissing120s.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-120 * time.Second))
+	nodeGoneAway120s := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithNodeMissingTimeout,
+		Machine:     testMachineNodeMissing120s,
+		Node:        &corev1.Node{},
+		nodeMissing: true,
+	}
+
+	// Targets for when the infrastructure object of the machine has been deleted out of band
+	testMHCWithInfrastructureMissingTimeout := testMHC.DeepCopy()
+	testMHCWithInfrastructureMissingTimeout.Spec.InfrastructureMissingTimeout = &metav1.Duration{Duration: 10 * time.Minute}
+
+	testMachineInfrastructureMissing5m := testMachine.DeepCopy()
+	testMachineInfrastructureMissing5m.Spec.InfrastructureRef = corev1.ObjectReference{Kind: "GenericInfrastructureMachine", Name: "machine1"}
+	testMachineInfrastructureMissing5m.Status.Conditions = clusterv1.Conditions{
+		{
+			Type:               clusterv1.InfrastructureReadyCondition,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-5 * time.Minute)),
+		},
+	}
+	infrastructureMissing5m := healthCheckTarget{
+		Cluster:               cluster,
+		MHC:                   testMHCWithInfrastructureMissingTimeout,
+		Machine:               testMachineInfrastructureMissing5m,
+		Node:                  newTestNode("node1"),
+		infrastructureMissing: true,
+	}
+
+	testMachineInfrastructureMissing15m := testMachineInfrastructureMissing5m.DeepCopy()
+	testMachineInfrastructureMissing15m.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-15 * time.Minute))
+	infrastructureMissing15m := healthCheckTarget{
+		Cluster:               cluster,
+		MHC:                   testMHCWithInfrastructureMissingTimeout,
+		Machine:               testMachineInfrastructureMissing15m,
+		Node:                  newTestNode("node1"),
+		infrastructureMissing: true,
+	}
+
+	// Target for when the Node has gone, but the machine controller did not report it as not found yet
+	nodeGoneAwayNotReported := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithNodeMissingTimeout,
+		Machine:     testMachine,
+		Node:        &corev1.Node{},
+		nodeMissing: true,
+	}
+
 	// Target for when the node has been in an unknown state for shorter than the timeout
 	testNodeUnknown200 := newTestUnhealthyNode("node1", corev1.NodeReady, corev1.ConditionUnknown, 200*time.Second)
 	nodeUnknown200 := healthCheckTarget{
@@ -311,74 +723,408 @@ func TestHealthCheckTargets(t *testing.T) {
 		nodeMissing: false,
 	}
 
-	testCases := []struct {
-		desc                        string
-		targets                     []healthCheckTarget
-		timeoutForMachineToHaveNode *time.Duration
-		expectedHealthy             []healthCheckTarget
-		expectedNeedsRemediation    []healthCheckTarget
-		expectedNextCheckTimes      []time.Duration
-	}{
-		{
-			desc:                     "when the node has not yet started for shorter than the timeout",
-			targets:                  []healthCheckTarget{nodeNotYetStartedTarget400s},
-			expectedHealthy:          []healthCheckTarget{},
-			expectedNeedsRemediation: []healthCheckTarget{},
-			expectedNextCheckTimes:   []time.Duration{timeoutForMachineToHaveNode - 400*time.Second},
-		},
-		{
-			desc:                     "when the node has not yet started for longer than the timeout",
-			targets:                  []healthCheckTarget{nodeNotYetStartedTarget1200s},
-			expectedHealthy:          []healthCheckTarget{},
-			expectedNeedsRemediation: []healthCheckTarget{nodeNotYetStartedTarget1200s},
-			expectedNextCheckTimes:   []time.Duration{},
-		},
-		{
-			desc:                     "when the node has gone away",
-			targets:                  []healthCheckTarget{nodeGoneAway},
-			expectedHealthy:          []healthCheckTarget{},
-			expectedNeedsRemediation: []healthCheckTarget{nodeGoneAway},
-			expectedNextCheckTimes:   []time.Duration{},
-		},
-		{
-			desc:                     "when the node has been in an unknown state for shorter than the timeout",
-			targets:                  []healthCheckTarget{nodeUnknown200},
-			expectedHealthy:          []healthCheckTarget{},
-			expectedNeedsRemediation: []healthCheckTarget{},
-			expectedNextCheckTimes:   []time.Duration{100 * time.Second},
-		},
-		{
-			desc:                     "when the node has been in an unknown state for longer than the timeout",
-			targets:                  []healthCheckTarget{nodeUnknown400},
-			expectedHealthy:          []healthCheckTarget{},
-			expectedNeedsRemediation: []healthCheckTarget{nodeUnknown400},
-			expectedNextCheckTimes:   []time.Duration{},
-		},
-		{
-			desc:                     "when the node is healthy",
-			targets:                  []healthCheckTarget{nodeHealthy},
-			expectedHealthy:          []healthCheckTarget{nodeHealthy},
-			expectedNeedsRemediation: []healthCheckTarget{},
-			expectedNextCheckTimes:   []time.Duration{},
-		},
-		{
-			desc:                     "with a mix of healthy and unhealthy nodes",
-			targets:                  []healthCheckTarget{nodeUnknown100, nodeUnknown200, nodeUnknown400, nodeHealthy},
-			expectedHealthy:          []healthCheckTarget{nodeHealthy},
-			expectedNeedsRemediation: []healthCheckTarget{nodeUnknown400},
-			expectedNextCheckTimes:   []time.Duration{200 * time.Second, 100 * time.Second},
-		},
-		{
-			desc:                        "when the node has not started for a long time but the startup timeout is disabled",
-			targets:                     []healthCheckTarget{nodeNotYetStartedTarget400s},
-			timeoutForMachineToHaveNode: &disabledTimeoutForMachineToHaveNode,
-			expectedHealthy:             []healthCheckTarget{}, // The node is not healthy as it does not have a machine
-			expectedNeedsRemediation:    []healthCheckTarget{},
-			expectedNextCheckTimes:      []time.Duration{}, // We don't have a timeout so no way to know when to re-check
-		},
+	// Target for when the node has been replaced by a new, healthy, node with the same name
+	testMachineStaleNodeRef := testMachine.DeepCopy()
+	testMachineStaleNodeRef.Status.NodeRef.UID = "67890"
+	nodeReplaced := healthCheckTarget{
+		Cluster:      cluster,
+		MHC:          testMHC,
+		Machine:      testMachineStaleNodeRef,
+		Node:         testNodeHealthy,
+		nodeReplaced: true,
 	}
 
-	for _, tc := range testCases {
+	// Targets for when the node has the unreachable taint and the MHC is configured to remediate on it
+	testMHCWithUnreachableTaintTimeout := testMHC.DeepCopy()
+	testMHCWithUnreachableTaintTimeout.Spec.UnreachableTaintTimeout = &metav1.Duration{Duration: time.Minute}
+
+	testNodeUnreachable30 := newTestUnreachableNode("node1", 30*time.Second)
+	nodeUnreachable30 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithUnreachableTaintTimeout,
+		Machine:     testMachine,
+		Node:        testNodeUnreachable30,
+		nodeMissing: false,
+	}
+
+	testNodeUnreachable120 := newTestUnreachableNode("node1", 120*time.Second)
+	nodeUnreachable120 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithUnreachableTaintTimeout,
+		Machine:     testMachine,
+		Node:        testNodeUnreachable120,
+		nodeMissing: false,
+	}
+
+	// Target for when the node has the unreachable taint but the MHC is not configured to remediate on it
+	nodeUnreachable120TaintTimeoutNotSet := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHC,
+		Machine:     testMachine,
+		Node:        testNodeUnreachable120,
+		nodeMissing: false,
+	}
+
+	// Targets for when the MHC is configured to remediate on a stale node lease
+	testMHCWithNodeLeaseStaleTimeout := testMHC.DeepCopy()
+	testMHCWithNodeLeaseStaleTimeout.Spec.NodeLeaseStaleTimeout = &metav1.Duration{Duration: time.Minute}
+
+	nodeLeaseRenewed30 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithNodeLeaseStaleTimeout,
+		Machine:     testMachine,
+		Node:        testNodeHealthy,
+		nodeMissing: false,
+		nodeLease:   newTestNodeLease("node1", 30*time.Second),
+	}
+
+	nodeLeaseRenewed120 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithNodeLeaseStaleTimeout,
+		Machine:     testMachine,
+		Node:        testNodeHealthy,
+		nodeMissing: false,
+		nodeLease:   newTestNodeLease("node1", 120*time.Second),
+	}
+
+	// Targets for when the node reports a kubelet version different from the machine's version and the MHC is
+	// configured to remediate on it
+	testMHCWithKubeletVersionMismatchTimeout := testMHC.DeepCopy()
+	testMHCWithKubeletVersionMismatchTimeout.Spec.KubeletVersionMismatchTimeout = &metav1.Duration{Duration: time.Minute}
+
+	testVersionedMachine := testMachine.DeepCopy()
+	testVersionedMachine.Spec.Version = pointer.String("v1.22.0")
+
+	nodeKubeletVersionMatching := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithKubeletVersionMismatchTimeout,
+		Machine:     testVersionedMachine,
+		Node:        newTestNodeWithKubeletVersion("node1", "v1.22.0", 120*time.Second),
+		nodeMissing: false,
+	}
+
+	nodeKubeletVersionMismatching30 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithKubeletVersionMismatchTimeout,
+		Machine:     testVersionedMachine,
+		Node:        newTestNodeWithKubeletVersion("node1", "v1.21.5", 30*time.Second),
+		nodeMissing: false,
+	}
+
+	testNodeKubeletVersionMismatching120 := newTestNodeWithKubeletVersion("node1", "v1.21.5", 120*time.Second)
+	nodeKubeletVersionMismatching120 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithKubeletVersionMismatchTimeout,
+		Machine:     testVersionedMachine,
+		Node:        testNodeKubeletVersionMismatching120,
+		nodeMissing: false,
+	}
+
+	// Target for when the node reports a kubelet version different from the machine's version but the MHC is not
+	// configured to remediate on it
+	nodeKubeletVersionMismatching120TimeoutNotSet := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHC,
+		Machine:     testVersionedMachine,
+		Node:        testNodeKubeletVersionMismatching120,
+		nodeMissing: false,
+	}
+
+	// Targets for when the node has been in an unknown state for longer than the timeout and the MHC has a grace period
+	// after the node creation
+	testMHCWithNodeCreationGracePeriod := testMHC.DeepCopy()
+	testMHCWithNodeCreationGracePeriod.Spec.UnhealthyConditionsGracePeriodAfterNodeCreation = &metav1.Duration{Duration: 10 * time.Minute}
+
+	testNodeCreated400sUnknown400 := newTestUnhealthyNode("node1", corev1.NodeReady, corev1.ConditionUnknown, 400*time.Second)
+	testNodeCreated400sUnknown400.CreationTimestamp = metav1.NewTime(time.Now().Add(-400 * time.Second))
+	nodeJustCreatedUnknown400 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithNodeCreationGracePeriod,
+		Machine:     testMachine,
+		Node:        testNodeCreated400sUnknown400,
+		nodeMissing: false,
+	}
+
+	testNodeCreated1hUnknown400 := newTestUnhealthyNode("node1", corev1.NodeReady, corev1.ConditionUnknown, 400*time.Second)
+	testNodeCreated1hUnknown400.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	nodeEstablishedUnknown400 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithNodeCreationGracePeriod,
+		Machine:     testMachine,
+		Node:        testNodeCreated1hUnknown400,
+		nodeMissing: false,
+	}
+
+	// Targets for when the node has been in an unhealthy state for longer than the timeout and the MHC has a grace
+	// period after a restart of the kubelet
+	testMHCWithKubeletRestartGracePeriod := testMHC.DeepCopy()
+	testMHCWithKubeletRestartGracePeriod.Spec.KubeletRestartGracePeriod = &metav1.Duration{Duration: 10 * time.Minute}
+
+	// The kubelet restarted 400s ago, reporting Ready=False while it stabilizes, and kept posting heartbeats since.
+	testNodeKubeletRestartedFalse400 := newTestUnhealthyNode("node1", corev1.NodeReady, corev1.ConditionFalse, 400*time.Second)
+	testNodeKubeletRestartedFalse400.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	testNodeKubeletRestartedFalse400.Status.Conditions[0].LastHeartbeatTime = metav1.NewTime(time.Now().Add(-10 * time.Second))
+	nodeKubeletRestartedFalse400 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithKubeletRestartGracePeriod,
+		Machine:     testMachine,
+		Node:        testNodeKubeletRestartedFalse400,
+		nodeMissing: false,
+	}
+
+	// The kubelet stopped posting heartbeats 440s ago, and the Ready condition has been set to Unknown 400s ago.
+	testNodeDownUnknown400 := newTestUnhealthyNode("node1", corev1.NodeReady, corev1.ConditionUnknown, 400*time.Second)
+	testNodeDownUnknown400.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	testNodeDownUnknown400.Status.Conditions[0].LastHeartbeatTime = metav1.NewTime(time.Now().Add(-440 * time.Second))
+	nodeDownUnknown400 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithKubeletRestartGracePeriod,
+		Machine:     testMachine,
+		Node:        testNodeDownUnknown400,
+		nodeMissing: false,
+	}
+
+	// The kubelet restarted 100s ago and the node has been reporting disk pressure for 400s, which gets no grace period.
+	testMHCWithKubeletRestartGracePeriodAndDiskPressure := testMHCWithKubeletRestartGracePeriod.DeepCopy()
+	testMHCWithKubeletRestartGracePeriodAndDiskPressure.Spec.UnhealthyConditions = append(testMHCWithKubeletRestartGracePeriodAndDiskPressure.Spec.UnhealthyConditions, clusterv1.UnhealthyCondition{
+		Type:    corev1.NodeDiskPressure,
+		Status:  corev1.ConditionTrue,
+		Timeout: metav1.Duration{Duration: 5 * time.Minute},
+	})
+	testNodeKubeletRestartedDiskPressure400 := newTestUnhealthyNode("node1", corev1.NodeDiskPressure, corev1.ConditionTrue, 400*time.Second)
+	testNodeKubeletRestartedDiskPressure400.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	testNodeKubeletRestartedDiskPressure400.Status.Conditions = append(testNodeKubeletRestartedDiskPressure400.Status.Conditions, corev1.NodeCondition{
+		Type:               corev1.NodeReady,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-100 * time.Second)),
+		LastHeartbeatTime:  metav1.NewTime(time.Now().Add(-10 * time.Second)),
+	})
+	nodeKubeletRestartedDiskPressure400 := healthCheckTarget{
+		Cluster:     cluster,
+		MHC:         testMHCWithKubeletRestartGracePeriodAndDiskPressure,
+		Machine:     testMachine,
+		Node:        testNodeKubeletRestartedDiskPressure400,
+		nodeMissing: false,
+	}
+
+	testCases := []struct {
+		desc                        string
+		targets                     []healthCheckTarget
+		timeoutForMachineToHaveNode *time.Duration
+		expectedHealthy             []healthCheckTarget
+		expectedNeedsRemediation    []healthCheckTarget
+		expectedNextCheckTimes      []time.Duration
+	}{
+		{
+			desc:                     "when the node has not yet started for shorter than the timeout",
+			targets:                  []healthCheckTarget{nodeNotYetStartedTarget400s},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{timeoutForMachineToHaveNode - 400*time.Second},
+		},
+		{
+			desc:                     "when the node has not yet started for shorter than the timeout and another node is unhealthy for shorter than the timeout",
+			targets:                  []healthCheckTarget{nodeNotYetStartedTarget400s, nodeUnknown200},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			// Both the remaining startup timeout and the remaining unhealthy condition timeout are pending deadlines.
+			expectedNextCheckTimes: []time.Duration{timeoutForMachineToHaveNode - 400*time.Second, 100 * time.Second},
+		},
+		{
+			desc:                     "when the node has not yet started for longer than the timeout",
+			targets:                  []healthCheckTarget{nodeNotYetStartedTarget1200s},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeNotYetStartedTarget1200s},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the node has gone away",
+			targets:                  []healthCheckTarget{nodeGoneAway},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeGoneAway},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the node has been missing for shorter than the node missing timeout",
+			targets:                  []healthCheckTarget{nodeGoneAway30s},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{30 * time.Second},
+		},
+		{
+			desc:                     "when the node has been missing for longer than the node missing timeout",
+			targets:                  []healthCheckTarget{nodeGoneAway120s},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeGoneAway120s},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the infrastructure has been missing for shorter than the infrastructure missing timeout",
+			targets:                  []healthCheckTarget{infrastructureMissing5m},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{5 * time.Minute},
+		},
+		{
+			desc:                     "when the infrastructure has been missing for longer than the infrastructure missing timeout",
+			targets:                  []healthCheckTarget{infrastructureMissing15m},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{infrastructureMissing15m},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the node is missing but the machine did not report it as not found yet",
+			targets:                  []healthCheckTarget{nodeGoneAwayNotReported},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{time.Minute + time.Second},
+		},
+		{
+			desc:                     "when the node has been in an unknown state for shorter than the timeout",
+			targets:                  []healthCheckTarget{nodeUnknown200},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{100 * time.Second},
+		},
+		{
+			desc:                     "when the node has been in an unknown state for longer than the timeout",
+			targets:                  []healthCheckTarget{nodeUnknown400},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeUnknown400},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the node is healthy",
+			targets:                  []healthCheckTarget{nodeHealthy},
+			expectedHealthy:          []healthCheckTarget{nodeHealthy},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the node has been replaced by a new node with the same name",
+			targets:                  []healthCheckTarget{nodeReplaced},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeReplaced},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "with a mix of healthy and unhealthy nodes",
+			targets:                  []healthCheckTarget{nodeUnknown100, nodeUnknown200, nodeUnknown400, nodeHealthy},
+			expectedHealthy:          []healthCheckTarget{nodeHealthy},
+			expectedNeedsRemediation: []healthCheckTarget{nodeUnknown400},
+			expectedNextCheckTimes:   []time.Duration{200 * time.Second, 100 * time.Second},
+		},
+		{
+			desc:                     "when the node has the unreachable taint for shorter than the timeout",
+			targets:                  []healthCheckTarget{nodeUnreachable30},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{30 * time.Second},
+		},
+		{
+			desc:                     "when the node has the unreachable taint for longer than the timeout",
+			targets:                  []healthCheckTarget{nodeUnreachable120},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeUnreachable120},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the node has the unreachable taint but the unreachable taint timeout is not set",
+			targets:                  []healthCheckTarget{nodeUnreachable120TaintTimeoutNotSet},
+			expectedHealthy:          []healthCheckTarget{nodeUnreachable120TaintTimeoutNotSet},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when a node created within the grace period has been in an unknown state for longer than the timeout",
+			targets:                  []healthCheckTarget{nodeJustCreatedUnknown400},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			// The timeout of 300s is counted from the end of the grace period, which ends in 200s.
+			expectedNextCheckTimes: []time.Duration{500 * time.Second},
+		},
+		{
+			desc:                     "when a node created before the grace period has been in an unknown state for longer than the timeout",
+			targets:                  []healthCheckTarget{nodeEstablishedUnknown400},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeEstablishedUnknown400},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the kubelet of a node restarted within the grace period and the node has been unhealthy for longer than the timeout",
+			targets:                  []healthCheckTarget{nodeKubeletRestartedFalse400},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			// The timeout of 300s is counted from the end of the grace period, which ends in 200s.
+			expectedNextCheckTimes: []time.Duration{500 * time.Second},
+		},
+		{
+			desc:                     "when a node is down and has been in an unknown state for longer than the timeout with a kubelet restart grace period",
+			targets:                  []healthCheckTarget{nodeDownUnknown400},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeDownUnknown400},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the kubelet of a node restarted within the grace period and the node has been reporting another unhealthy condition for longer than the timeout",
+			targets:                  []healthCheckTarget{nodeKubeletRestartedDiskPressure400},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeKubeletRestartedDiskPressure400},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the node lease has been renewed within the timeout",
+			targets:                  []healthCheckTarget{nodeLeaseRenewed30},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{30 * time.Second},
+		},
+		{
+			desc:                     "when the node lease has not been renewed for longer than the timeout",
+			targets:                  []healthCheckTarget{nodeLeaseRenewed120},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeLeaseRenewed120},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the node reports the kubelet version of the machine",
+			targets:                  []healthCheckTarget{nodeKubeletVersionMatching},
+			expectedHealthy:          []healthCheckTarget{nodeKubeletVersionMatching},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the node reports an unexpected kubelet version for shorter than the timeout",
+			targets:                  []healthCheckTarget{nodeKubeletVersionMismatching30},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{30 * time.Second},
+		},
+		{
+			desc:                     "when the node reports an unexpected kubelet version for longer than the timeout",
+			targets:                  []healthCheckTarget{nodeKubeletVersionMismatching120},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{nodeKubeletVersionMismatching120},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the node reports an unexpected kubelet version but the kubelet version mismatch timeout is not set",
+			targets:                  []healthCheckTarget{nodeKubeletVersionMismatching120TimeoutNotSet},
+			expectedHealthy:          []healthCheckTarget{nodeKubeletVersionMismatching120TimeoutNotSet},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                        "when the node has not started for a long time but the startup timeout is disabled",
+			targets:                     []healthCheckTarget{nodeNotYetStartedTarget400s},
+			timeoutForMachineToHaveNode: &disabledTimeoutForMachineToHaveNode,
+			expectedHealthy:             []healthCheckTarget{}, // The node is not healthy as it does not have a machine
+			expectedNeedsRemediation:    []healthCheckTarget{},
+			expectedNextCheckTimes:      []time.Duration{}, // We don't have a timeout so no way to know when to re-check
+		},
+	}
+
+	for _, tc := range testCases {
 		t.Run(tc.desc, func(t *testing.T) {
 			gs := NewWithT(t)
 
@@ -393,7 +1139,8 @@ func TestHealthCheckTargets(t *testing.T) {
 				timeout.Duration = *tc.timeoutForMachineToHaveNode
 			}
 
-			healthy, unhealthy, nextCheckTimes := reconciler.healthCheckTargets(tc.targets, ctrl.LoggerFrom(ctx), timeout)
+			result := reconciler.healthCheckTargets(tc.targets, ctrl.LoggerFrom(ctx), timeout)
+			healthy, unhealthy, nextCheckTimes := result.healthy, result.unhealthy, result.nextCheckTimes
 
 			// Round durations down to nearest second account for minute differences
 			// in timing when running tests
@@ -412,6 +1159,146 @@ func TestHealthCheckTargets(t *testing.T) {
 	}
 }
 
+func TestHealthCheckTargetsWithNonReadyNodeConditions(t *testing.T) {
+	namespace := "test-mhc"
+	clusterName := "test-cluster"
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      clusterName,
+		},
+	}
+	conditions.MarkTrue(cluster, clusterv1.InfrastructureReadyCondition)
+	conditions.MarkTrue(cluster, clusterv1.ControlPlaneInitializedCondition)
+
+	mhcSelector := map[string]string{"cluster": clusterName, "machine-group": "foo"}
+
+	// Create a test MHC checking only node conditions other than Ready.
+	testMHC := &clusterv1.MachineHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-mhc",
+			Namespace: namespace,
+		},
+		Spec: clusterv1.MachineHealthCheckSpec{
+			Selector: metav1.LabelSelector{
+				MatchLabels: mhcSelector,
+			},
+			ClusterName: clusterName,
+			UnhealthyConditions: []clusterv1.UnhealthyCondition{
+				{
+					Type:    corev1.NodeDiskPressure,
+					Status:  corev1.ConditionTrue,
+					Timeout: metav1.Duration{Duration: 5 * time.Minute},
+				},
+				{
+					Type:    corev1.NodeConditionType("KernelDeadlock"),
+					Status:  corev1.ConditionTrue,
+					Timeout: metav1.Duration{Duration: 5 * time.Minute},
+				},
+			},
+		},
+	}
+
+	newTarget := func(node *corev1.Node) healthCheckTarget {
+		return healthCheckTarget{
+			Cluster: cluster,
+			MHC:     testMHC,
+			Machine: newTestMachine("machine1", namespace, clusterName, node.Name, mhcSelector),
+			Node:    node,
+		}
+	}
+
+	// Node reporting disk pressure in addition to being ready, longer than the timeout.
+	testNodeDiskPressure400 := newTestUnhealthyNode("node1", corev1.NodeDiskPressure, corev1.ConditionTrue, 400*time.Second)
+	testNodeDiskPressure400.Status.Conditions = append(testNodeDiskPressure400.Status.Conditions, corev1.NodeCondition{
+		Type:               corev1.NodeReady,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+	})
+
+	testCases := []struct {
+		desc                   string
+		node                   *corev1.Node
+		expectHealthy          bool
+		expectUnhealthy        bool
+		expectedNextCheckTimes []time.Duration
+	}{
+		{
+			desc:                   "when the node reports disk pressure for shorter than the timeout",
+			node:                   newTestUnhealthyNode("node1", corev1.NodeDiskPressure, corev1.ConditionTrue, 200*time.Second),
+			expectedNextCheckTimes: []time.Duration{100 * time.Second},
+		},
+		{
+			desc:                   "when the node reports disk pressure for longer than the timeout",
+			node:                   newTestUnhealthyNode("node1", corev1.NodeDiskPressure, corev1.ConditionTrue, 400*time.Second),
+			expectUnhealthy:        true,
+			expectedNextCheckTimes: []time.Duration{},
+		},
+		{
+			desc:                   "when the node reports disk pressure for longer than the timeout while being ready",
+			node:                   testNodeDiskPressure400,
+			expectUnhealthy:        true,
+			expectedNextCheckTimes: []time.Duration{},
+		},
+		{
+			desc:                   "when the node reports a custom condition for longer than the timeout",
+			node:                   newTestUnhealthyNode("node1", corev1.NodeConditionType("KernelDeadlock"), corev1.ConditionTrue, 400*time.Second),
+			expectUnhealthy:        true,
+			expectedNextCheckTimes: []time.Duration{},
+		},
+		{
+			desc:                   "when the node recovered from disk pressure",
+			node:                   newTestUnhealthyNode("node1", corev1.NodeDiskPressure, corev1.ConditionFalse, 400*time.Second),
+			expectHealthy:          true,
+			expectedNextCheckTimes: []time.Duration{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			g := NewWithT(t)
+
+			reconciler := &Reconciler{
+				recorder: record.NewFakeRecorder(5),
+			}
+
+			target := newTarget(tc.node)
+			// The machine has previously been found unhealthy, so recovery resets the health check result.
+			conditions.MarkFalse(target.Machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+
+			result := reconciler.healthCheckTargets([]healthCheckTarget{target}, ctrl.LoggerFrom(ctx), metav1.Duration{Duration: 10 * time.Minute})
+			healthy, unhealthy, nextCheckTimes := result.healthy, result.unhealthy, result.nextCheckTimes
+
+			roundDurations := func(in []time.Duration) []time.Duration {
+				out := []time.Duration{}
+				for _, d := range in {
+					out = append(out, d.Truncate(time.Second))
+				}
+				return out
+			}
+
+			g.Expect(healthy).To(HaveLen(boolToInt(tc.expectHealthy)))
+			g.Expect(unhealthy).To(HaveLen(boolToInt(tc.expectUnhealthy)))
+			g.Expect(nextCheckTimes).To(WithTransform(roundDurations, ConsistOf(tc.expectedNextCheckTimes)))
+			switch {
+			case tc.expectHealthy:
+				g.Expect(conditions.IsTrue(target.Machine, clusterv1.MachineHealthCheckSucceededCondition)).To(BeTrue())
+			case tc.expectUnhealthy:
+				g.Expect(conditions.IsFalse(target.Machine, clusterv1.MachineHealthCheckSucceededCondition)).To(BeTrue())
+				g.Expect(conditions.GetReason(target.Machine, clusterv1.MachineHealthCheckSucceededCondition)).To(Equal(clusterv1.UnhealthyNodeConditionReason))
+			}
+		})
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func newTestMachine(name, namespace, clusterName, nodeName string, labels map[string]string) *clusterv1.Machine {
 	// Copy the labels so that the map is unique to each test Machine
 	l := make(map[string]string)
@@ -448,6 +1335,178 @@ func newTestMachine(name, namespace, clusterName, nodeName string, labels map[st
 	}
 }
 
+func TestPartitionTargets(t *testing.T) {
+	namespace := "test-mhc"
+	clusterName := "test-cluster"
+	now := time.Now()
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      clusterName,
+		},
+	}
+	conditions.MarkTrue(cluster, clusterv1.InfrastructureReadyCondition)
+	conditions.MarkTrue(cluster, clusterv1.ControlPlaneInitializedCondition)
+
+	mhcSelector := map[string]string{"cluster": clusterName, "machine-group": "foo"}
+	testMHC := &clusterv1.MachineHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-mhc",
+			Namespace: namespace,
+		},
+		Spec: clusterv1.MachineHealthCheckSpec{
+			Selector: metav1.LabelSelector{
+				MatchLabels: mhcSelector,
+			},
+			ClusterName: clusterName,
+			UnhealthyConditions: []clusterv1.UnhealthyCondition{
+				{
+					Type:    corev1.NodeReady,
+					Status:  corev1.ConditionUnknown,
+					Timeout: metav1.Duration{Duration: 5 * time.Minute},
+				},
+			},
+		},
+	}
+
+	newTarget := func(name string, node *corev1.Node) healthCheckTarget {
+		return healthCheckTarget{
+			Cluster: cluster,
+			MHC:     testMHC,
+			Machine: newTestMachine(name, namespace, clusterName, node.Name, mhcSelector),
+			Node:    node,
+		}
+	}
+	newUnknownNode := func(name string, unknownFor time.Duration) *corev1.Node {
+		node := newTestUnhealthyNode(name, corev1.NodeReady, corev1.ConditionUnknown, 0)
+		node.Status.Conditions[0].LastTransitionTime = metav1.NewTime(now.Add(-unknownFor))
+		return node
+	}
+
+	healthy := newTarget("healthy", newTestNode("healthy"))
+	unknown100 := newTarget("unknown100", newUnknownNode("unknown100", 100*time.Second))
+	unknown200 := newTarget("unknown200", newUnknownNode("unknown200", 200*time.Second))
+	unknown400 := newTarget("unknown400", newUnknownNode("unknown400", 400*time.Second))
+	targets := []healthCheckTarget{healthy, unknown100, unknown200, unknown400}
+
+	testCases := []struct {
+		desc                   string
+		now                    time.Time
+		expectedHealthy        []healthCheckTarget
+		expectedUnhealthy      []healthCheckTarget
+		expectedPending        []healthCheckTarget
+		expectedNextCheckTimes []time.Duration
+	}{
+		{
+			desc:                   "targets failing a health check within the timeout are pending",
+			now:                    now,
+			expectedHealthy:        []healthCheckTarget{healthy},
+			expectedUnhealthy:      []healthCheckTarget{unknown400},
+			expectedPending:        []healthCheckTarget{unknown100, unknown200},
+			expectedNextCheckTimes: []time.Duration{201 * time.Second, 101 * time.Second},
+		},
+		{
+			desc:                   "pending targets become unhealthy once the timeout is exceeded",
+			now:                    now.Add(150 * time.Second),
+			expectedHealthy:        []healthCheckTarget{healthy},
+			expectedUnhealthy:      []healthCheckTarget{unknown200, unknown400},
+			expectedPending:        []healthCheckTarget{unknown100},
+			expectedNextCheckTimes: []time.Duration{51 * time.Second},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			g := NewWithT(t)
+
+			result := partitionTargets(targets, ctrl.LoggerFrom(ctx), metav1.Duration{Duration: 10 * time.Minute}, tc.now)
+
+			g.Expect(result.healthy).To(ConsistOf(tc.expectedHealthy))
+			g.Expect(result.unhealthy).To(ConsistOf(tc.expectedUnhealthy))
+			g.Expect(result.pending).To(ConsistOf(tc.expectedPending))
+			g.Expect(result.nextCheckTimes).To(ConsistOf(tc.expectedNextCheckTimes))
+		})
+	}
+}
+
+func TestPartitionTargetsTargetHealth(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	now := time.Now()
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: clusterName}}
+	conditions.MarkTrue(cluster, clusterv1.InfrastructureReadyCondition)
+	conditions.MarkTrue(cluster, clusterv1.ControlPlaneInitializedCondition)
+
+	mhcSelector := map[string]string{"cluster": clusterName, "machine-group": "foo"}
+	testMHC := &clusterv1.MachineHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-mhc", Namespace: namespace},
+		Spec: clusterv1.MachineHealthCheckSpec{
+			Selector:    metav1.LabelSelector{MatchLabels: mhcSelector},
+			ClusterName: clusterName,
+			UnhealthyConditions: []clusterv1.UnhealthyCondition{
+				{
+					Type:    corev1.NodeReady,
+					Status:  corev1.ConditionUnknown,
+					Timeout: metav1.Duration{Duration: 5 * time.Minute},
+				},
+				{
+					Type:    corev1.NodeReady,
+					Status:  corev1.ConditionFalse,
+					Timeout: metav1.Duration{Duration: 5 * time.Minute},
+				},
+			},
+		},
+	}
+
+	newTarget := func(name string, status corev1.ConditionStatus, unhealthyFor time.Duration) healthCheckTarget {
+		node := newTestUnhealthyNode(name, corev1.NodeReady, status, 0)
+		node.Status.Conditions[0].LastTransitionTime = metav1.NewTime(now.Add(-unhealthyFor))
+		return healthCheckTarget{
+			Cluster: cluster,
+			MHC:     testMHC,
+			Machine: newTestMachine(name, namespace, clusterName, node.Name, mhcSelector),
+			Node:    node,
+		}
+	}
+	healthy := healthCheckTarget{
+		Cluster: cluster,
+		MHC:     testMHC,
+		Machine: newTestMachine("healthy", namespace, clusterName, "healthy", mhcSelector),
+		Node:    newTestNode("healthy"),
+	}
+	pending := newTarget("pending", corev1.ConditionUnknown, 100*time.Second)
+	unhealthy := newTarget("unhealthy", corev1.ConditionFalse, 400*time.Second)
+
+	result := partitionTargets([]healthCheckTarget{healthy, pending, unhealthy}, ctrl.LoggerFrom(ctx), metav1.Duration{Duration: 10 * time.Minute}, now)
+
+	// Only the targets failing a health check are reported, with the matching unhealthy condition and its timing.
+	g.Expect(result.targetHealth).To(HaveLen(2))
+
+	g.Expect(result.targetHealth[0].Machine).To(Equal("pending"))
+	g.Expect(result.targetHealth[0].Reason).To(Equal(clusterv1.UnhealthyNodeConditionReason))
+	g.Expect(result.targetHealth[0].Message).To(Equal("Condition Ready on node is reporting status Unknown"))
+	g.Expect(result.targetHealth[0].Since.Time).To(BeTemporally("==", now.Add(-100*time.Second)))
+	g.Expect(result.targetHealth[0].UnhealthyAt.Time).To(BeTemporally("==", now.Add(200*time.Second)))
+
+	g.Expect(result.targetHealth[1].Machine).To(Equal("unhealthy"))
+	g.Expect(result.targetHealth[1].Reason).To(Equal(clusterv1.UnhealthyNodeConditionReason))
+	g.Expect(result.targetHealth[1].Message).To(Equal("Condition Ready on node is reporting status False"))
+	g.Expect(result.targetHealth[1].Since.Time).To(BeTemporally("==", now.Add(-400*time.Second)))
+	g.Expect(result.targetHealth[1].UnhealthyAt.Time).To(BeTemporally("==", now.Add(-100*time.Second)))
+
+	// The next remediation is when the partially elapsed unhealthy condition of the pending target crosses its timeout.
+	g.Expect(result.nextRemediationTime).ToNot(BeNil())
+	g.Expect(result.nextRemediationTime.Time).To(BeTemporally("==", now.Add(200*time.Second)))
+
+	// Without pending targets there is no next remediation.
+	result = partitionTargets([]healthCheckTarget{healthy, unhealthy}, ctrl.LoggerFrom(ctx), metav1.Duration{Duration: 10 * time.Minute}, now)
+	g.Expect(result.nextRemediationTime).To(BeNil())
+}
+
 func newTestNode(name string) *corev1.Node {
 	return &corev1.Node{
 		TypeMeta: metav1.TypeMeta{
@@ -477,3 +1536,186 @@ func newTestUnhealthyNode(name string, condition corev1.NodeConditionType, statu
 		},
 	}
 }
+
+func newTestNodeWithKubeletVersion(name, kubeletVersion string, readyFor time.Duration) *corev1.Node {
+	node := newTestNode(name)
+	node.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	node.Status.NodeInfo.KubeletVersion = kubeletVersion
+	node.Status.Conditions = []corev1.NodeCondition{
+		{
+			Type:               corev1.NodeReady,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-readyFor)),
+		},
+	}
+	return node
+}
+
+func newTestUnreachableNode(name string, unreachableFor time.Duration) *corev1.Node {
+	node := newTestNode(name)
+	node.Spec.Taints = []corev1.Taint{
+		{
+			Key:       corev1.TaintNodeUnreachable,
+			Effect:    corev1.TaintEffectNoExecute,
+			TimeAdded: &metav1.Time{Time: time.Now().Add(-unreachableFor)},
+		},
+	}
+	return node
+}
+
+func newTestNodeLease(name string, renewedAgo time.Duration) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: corev1.NamespaceNodeLease,
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity: pointer.String(name),
+			RenewTime:      &metav1.MicroTime{Time: time.Now().Add(-renewedAgo)},
+		},
+	}
+}
+
+func TestApplyUnhealthyObservationThreshold(t *testing.T) {
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	newTarget := func(name string) healthCheckTarget {
+		return healthCheckTarget{MHC: mhc, Machine: newTestMachine(name, namespace, clusterName, name, labels)}
+	}
+	flapping := newTarget("flapping")
+	failing := newTarget("failing")
+	targets := []healthCheckTarget{flapping, failing}
+
+	t.Run("defers remediation until the threshold is reached, and resets the count on healthy observations", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := mhc.DeepCopy()
+		m.Spec.UnhealthyObservationThreshold = 3
+
+		// First unhealthy observation for both targets.
+		toRemediate, nextCheckTimes := applyUnhealthyObservationThreshold(m, targets, nil, []healthCheckTarget{flapping, failing})
+		g.Expect(toRemediate).To(BeEmpty())
+		g.Expect(nextCheckTimes).To(ConsistOf(unhealthyObservationInterval, unhealthyObservationInterval))
+		g.Expect(m.Status.UnhealthyObservations).To(Equal([]clusterv1.UnhealthyObservation{
+			{Machine: "failing", Count: 1},
+			{Machine: "flapping", Count: 1},
+		}))
+
+		// Second unhealthy observation for both targets.
+		toRemediate, _ = applyUnhealthyObservationThreshold(m, targets, nil, []healthCheckTarget{flapping, failing})
+		g.Expect(toRemediate).To(BeEmpty())
+		g.Expect(m.Status.UnhealthyObservations).To(Equal([]clusterv1.UnhealthyObservation{
+			{Machine: "failing", Count: 2},
+			{Machine: "flapping", Count: 2},
+		}))
+
+		// The flapping target is intermittently observed as healthy, which resets its count.
+		toRemediate, _ = applyUnhealthyObservationThreshold(m, targets, []healthCheckTarget{flapping}, []healthCheckTarget{failing})
+		g.Expect(toRemediate).To(ConsistOf(failing))
+		g.Expect(m.Status.UnhealthyObservations).To(Equal([]clusterv1.UnhealthyObservation{
+			{Machine: "failing", Count: 3},
+		}))
+
+		// The flapping target is unhealthy again, but it starts counting from scratch.
+		toRemediate, nextCheckTimes = applyUnhealthyObservationThreshold(m, targets, nil, []healthCheckTarget{flapping, failing})
+		g.Expect(toRemediate).To(ConsistOf(failing))
+		g.Expect(nextCheckTimes).To(ConsistOf(unhealthyObservationInterval))
+		g.Expect(m.Status.UnhealthyObservations).To(Equal([]clusterv1.UnhealthyObservation{
+			{Machine: "failing", Count: 4},
+			{Machine: "flapping", Count: 1},
+		}))
+
+		// Observations for machines which are not targets anymore are dropped, while pending targets keep their count.
+		toRemediate, _ = applyUnhealthyObservationThreshold(m, []healthCheckTarget{flapping}, nil, nil)
+		g.Expect(toRemediate).To(BeEmpty())
+		g.Expect(m.Status.UnhealthyObservations).To(Equal([]clusterv1.UnhealthyObservation{
+			{Machine: "flapping", Count: 1},
+		}))
+	})
+
+	t.Run("remediates unhealthy targets right away if the threshold is not set", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := mhc.DeepCopy()
+		m.Status.UnhealthyObservations = []clusterv1.UnhealthyObservation{{Machine: "failing", Count: 1}}
+
+		toRemediate, nextCheckTimes := applyUnhealthyObservationThreshold(m, targets, []healthCheckTarget{flapping}, []healthCheckTarget{failing})
+		g.Expect(toRemediate).To(ConsistOf(failing))
+		g.Expect(nextCheckTimes).To(BeEmpty())
+		g.Expect(m.Status.UnhealthyObservations).To(BeNil())
+	})
+}
+
+func TestOrderByFailureDomainRoundRobin(t *testing.T) {
+	namespace := metav1.NamespaceDefault
+	clusterName := testClusterName
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: clusterName}}
+
+	newUnhealthyMachine := func(name string, failureDomain string) *clusterv1.Machine {
+		machine := newTestMachine(name, namespace, clusterName, "nodeName", labels)
+		machine.Spec.FailureDomain = pointer.String(failureDomain)
+		conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.UnhealthyNodeConditionReason, clusterv1.ConditionSeverityWarning, "")
+		return machine
+	}
+
+	t.Run("orders targets round-robin across failure domains", func(t *testing.T) {
+		g := NewWithT(t)
+
+		targets := []healthCheckTarget{
+			{Machine: newUnhealthyMachine("machine-a1", "fd-a")},
+			{Machine: newUnhealthyMachine("machine-a2", "fd-a")},
+			{Machine: newUnhealthyMachine("machine-a3", "fd-a")},
+			{Machine: newUnhealthyMachine("machine-c1", "fd-c")},
+			{Machine: newUnhealthyMachine("machine-b1", "fd-b")},
+			{Machine: newUnhealthyMachine("machine-b2", "fd-b")},
+		}
+
+		var names []string
+		for _, t := range orderByFailureDomainRoundRobin(targets) {
+			names = append(names, t.Machine.Name)
+		}
+		g.Expect(names).To(Equal([]string{"machine-a1", "machine-b1", "machine-c1", "machine-a2", "machine-b2", "machine-a3"}))
+	})
+
+	t.Run("remediates machines in different failure domains when the budget is limited", func(t *testing.T) {
+		g := NewWithT(t)
+
+		mhc := newMachineHealthCheckWithLabels("mhc-round-robin", namespace, clusterName, labels)
+		mhc.Spec.MaxInFlightRemediations = pointer.Int32(2)
+
+		machines := []*clusterv1.Machine{
+			newUnhealthyMachine("machine-a1", "fd-a"),
+			newUnhealthyMachine("machine-a2", "fd-a"),
+			newUnhealthyMachine("machine-b1", "fd-b"),
+			newUnhealthyMachine("machine-c1", "fd-c"),
+		}
+		objs := []client.Object{mhc}
+		for _, m := range machines {
+			objs = append(objs, m)
+		}
+		cl := fake.NewClientBuilder().WithObjects(objs...).Build()
+
+		var unhealthy []healthCheckTarget
+		for _, m := range machines {
+			patchHelper, err := patch.NewHelper(m, cl)
+			g.Expect(err).ToNot(HaveOccurred())
+			unhealthy = append(unhealthy, healthCheckTarget{MHC: mhc, Machine: m, Node: &corev1.Node{}, patchHelper: patchHelper})
+		}
+
+		r := &Reconciler{Client: cl, recorder: record.NewFakeRecorder(32), remoteClientGetter: remoteClientGetterFor(cl)}
+		errList := r.patchUnhealthyTargets(ctx, ctrl.LoggerFrom(ctx), orderByFailureDomainRoundRobin(unhealthy), r.uncachedWorkloadClient(ctx, cluster), cluster, mhc, 0)
+		g.Expect(errList).To(BeEmpty())
+
+		remediatedFailureDomains := map[string]bool{}
+		for _, m := range machines {
+			if conditions.IsFalse(m, clusterv1.MachineOwnerRemediatedCondition) {
+				remediatedFailureDomains[*m.Spec.FailureDomain] = true
+			}
+		}
+		g.Expect(remediatedFailureDomains).To(Equal(map[string]bool{"fd-a": true, "fd-b": true}))
+	})
+}
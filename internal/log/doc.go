@@ -14,5 +14,5 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package log provides log utilities for the topology package.
+// Package log provides log utilities for the controllers, e.g. the topology controllers.
 package log
@@ -0,0 +1,28 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+// Writer implements io.Writer interface as a pass-through for klog.
+type Writer struct {
+	LogFunc func(msg string, keysAndValues ...interface{})
+}
+
+// Write passes string(p) into writer's LogFunc and always returns len(p).
+func (w Writer) Write(p []byte) (n int, err error) {
+	w.LogFunc(string(p))
+	return len(p), nil
+}
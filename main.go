@@ -82,6 +82,11 @@ var (
 	machinePoolConcurrency        int
 	clusterResourceSetConcurrency int
 	machineHealthCheckConcurrency int
+	mhcNotificationEndpoint       string
+	mhcMaxTargets                 int
+	mhcKillSwitchConfigMap        string
+	mhcFailureDomainRoundRobin    bool
+	mhcReadNodesFromWorkload      bool
 	syncPeriod                    time.Duration
 	webhookPort                   int
 	webhookCertDir                string
@@ -164,6 +169,21 @@ func InitFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&machineHealthCheckConcurrency, "machinehealthcheck-concurrency", 10,
 		"Number of machine health checks to process simultaneously")
 
+	fs.StringVar(&mhcNotificationEndpoint, "machinehealthcheck-remediation-notification-endpoint", "",
+		"HTTP endpoint notified with a JSON POST request every time a MachineHealthCheck initiates a remediation. If unspecified, no notifications are sent.")
+
+	fs.IntVar(&mhcMaxTargets, "machinehealthcheck-max-targets", 0,
+		"Maximum number of machines a MachineHealthCheck can target; remediation is blocked for MachineHealthChecks matching more machines. If unspecified or 0, no limit is enforced.")
+
+	fs.StringVar(&mhcKillSwitchConfigMap, "machinehealthcheck-kill-switch-configmap", "",
+		"ConfigMap, in the namespace/name format, acting as a kill switch for the remediations of all the MachineHealthChecks: while it has the cluster.x-k8s.io/remediation-globally-disabled annotation set to \"true\", no remediation is initiated. If unspecified, there is no kill switch.")
+
+	fs.BoolVar(&mhcFailureDomainRoundRobin, "machinehealthcheck-failure-domain-round-robin", false,
+		"If true, MachineHealthChecks select the unhealthy machines to remediate round-robin across failure domains, so that when the number of remediations is limited by maxInFlightRemediations they are spread across failure domains.")
+
+	fs.BoolVar(&mhcReadNodesFromWorkload, "machinehealthcheck-read-nodes-from-workload-cluster", false,
+		"If true, MachineHealthChecks read the nodes directly from the workload clusters at every reconcile, instead of from the cache of the workload clusters, so that the health checks are not affected by node status updates lagging behind in the cache. This adds load on the workload cluster API servers.")
+
 	fs.DurationVar(&syncPeriod, "sync-period", 10*time.Minute,
 		"The minimum interval at which watched resources are reconciled (e.g. 15m)")
 
@@ -409,9 +429,14 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) {
 	}
 
 	if err := (&controllers.MachineHealthCheckReconciler{
-		Client:           mgr.GetClient(),
-		Tracker:          tracker,
-		WatchFilterValue: watchFilterValue,
+		Client:                             mgr.GetClient(),
+		Tracker:                            tracker,
+		WatchFilterValue:                   watchFilterValue,
+		RemediationNotificationEndpoint:    mhcNotificationEndpoint,
+		MaxTargets:                         mhcMaxTargets,
+		RemediationKillSwitchConfigMap:     mhcKillSwitchConfigMap,
+		FailureDomainRoundRobinRemediation: mhcFailureDomainRoundRobin,
+		ReadNodesFromWorkloadCluster:       mhcReadNodesFromWorkload,
 	}).SetupWithManager(ctx, mgr, concurrency(machineHealthCheckConcurrency)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MachineHealthCheck")
 		os.Exit(1)
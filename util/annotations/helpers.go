@@ -48,6 +48,11 @@ func HasSkipRemediation(o metav1.Object) bool {
 	return hasAnnotation(o, clusterv1.MachineSkipRemediationAnnotation)
 }
 
+// HasRemediationInProgress returns true if the object has the `remediation-in-progress` annotation.
+func HasRemediationInProgress(o metav1.Object) bool {
+	return hasAnnotation(o, clusterv1.RemediationInProgressAnnotation)
+}
+
 // HasWithPrefix returns true if at least one of the annotations has the prefix specified.
 func HasWithPrefix(prefix string, annotations map[string]string) bool {
 	for key := range annotations {
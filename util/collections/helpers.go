@@ -19,8 +19,13 @@ package collections
 
 import (
 	"context"
+	"sort"
 
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -28,6 +33,7 @@ import (
 
 // GetFilteredMachinesForCluster returns a list of machines that can be filtered or not.
 // If no filter is supplied then all machines associated with the target cluster are returned.
+// The returned Machines are unordered; see Machines for how to get them in a deterministic order.
 func GetFilteredMachinesForCluster(ctx context.Context, c client.Reader, cluster *clusterv1.Cluster, filters ...Func) (Machines, error) {
 	ml := &clusterv1.MachineList{}
 	if err := c.List(
@@ -44,3 +50,168 @@ func GetFilteredMachinesForCluster(ctx context.Context, c client.Reader, cluster
 	machines := FromMachineList(ml)
 	return machines.Filter(filters...), nil
 }
+
+// maxOwnerChainDepth is the maximum number of owners walked when resolving the Cluster owning a Machine, e.g.
+// Machine -> MachineSet -> MachineDeployment -> Cluster.
+const maxOwnerChainDepth = 5
+
+// GetUnlabeledMachinesForCluster returns the machines in the namespace of the cluster lacking the cluster name label,
+// whose owner chain resolves to the cluster; this is intended for adopted or legacy machines whose labels weren't set
+// at creation, which are not returned by GetFilteredMachinesForCluster.
+// NOTE: This lists all the machines in the namespace of the cluster, and reads the owners of the unlabeled ones;
+// each owner is read at most once, even if shared by many machines, e.g. the MachineSet owning them.
+func GetUnlabeledMachinesForCluster(ctx context.Context, c client.Reader, cluster *clusterv1.Cluster) (Machines, error) {
+	ml := &clusterv1.MachineList{}
+	if err := c.List(ctx, ml, client.InNamespace(cluster.Namespace)); err != nil {
+		return nil, errors.Wrap(err, "failed to list machines")
+	}
+
+	resolved := map[ownerKey]bool{}
+	machines := New()
+	for i := range ml.Items {
+		machine := &ml.Items[i]
+		if _, ok := machine.Labels[clusterv1.ClusterLabelName]; ok {
+			continue
+		}
+		owned, err := ownerChainResolvesToCluster(ctx, c, machine.Namespace, machine.OwnerReferences, cluster, maxOwnerChainDepth, resolved)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve the owners of machine %s", machine.Name)
+		}
+		if owned {
+			machines.Insert(machine)
+		}
+	}
+	return machines, nil
+}
+
+// ownerKey identifies an owner in the namespace of the cluster, and the depth its owner chain is resolved to.
+type ownerKey struct {
+	group string
+	kind  string
+	name  string
+	depth int
+}
+
+// ownerChainResolvesToCluster returns true if any of the owner references, or of the owner references of the
+// owners up to the given depth, refers to the cluster; owners which do not exist are ignored.
+// The result for each owner is recorded in resolved, so owners shared by many objects are read only once.
+func ownerChainResolvesToCluster(ctx context.Context, c client.Reader, namespace string, refs []metav1.OwnerReference, cluster *clusterv1.Cluster, depth int, resolved map[ownerKey]bool) (bool, error) {
+	if depth == 0 {
+		return false, nil
+	}
+	for _, ref := range refs {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return false, err
+		}
+		if ref.Kind == "Cluster" && gv.Group == clusterv1.GroupVersion.Group {
+			if ref.Name == cluster.Name {
+				return true, nil
+			}
+			continue
+		}
+
+		key := ownerKey{group: gv.Group, kind: ref.Kind, name: ref.Name, depth: depth}
+		if owned, ok := resolved[key]; ok {
+			if owned {
+				return true, nil
+			}
+			continue
+		}
+
+		owner := &unstructured.Unstructured{}
+		owner.SetAPIVersion(ref.APIVersion)
+		owner.SetKind(ref.Kind)
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, owner); err != nil {
+			if apierrors.IsNotFound(err) {
+				resolved[key] = false
+				continue
+			}
+			return false, errors.Wrapf(err, "failed to get %s %s", ref.Kind, ref.Name)
+		}
+		owned, err := ownerChainResolvesToCluster(ctx, c, namespace, owner.GetOwnerReferences(), cluster, depth-1, resolved)
+		if err != nil {
+			return false, err
+		}
+		resolved[key] = owned
+		if owned {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetMachinesByClusterNameAllNamespaces returns the machines with the given cluster name label in all namespaces,
+// optionally filtered; this is intended for management cluster wide tooling, e.g. for auditing or for detecting
+// machines created in a namespace other than the one of their cluster.
+// NOTE: Machines are returned as a list sorted by namespace and name, instead of as Machines, given that machines
+// in different namespaces can have the same name. Use GetFilteredMachinesForCluster for reconciling a cluster,
+// given that machines are expected to be in the same namespace of their cluster.
+func GetMachinesByClusterNameAllNamespaces(ctx context.Context, c client.Reader, clusterName string, filters ...Func) ([]clusterv1.Machine, error) {
+	ml := &clusterv1.MachineList{}
+	if err := c.List(
+		ctx,
+		ml,
+		client.MatchingLabels{
+			clusterv1.ClusterLabelName: clusterName,
+		},
+	); err != nil {
+		return nil, errors.Wrap(err, "failed to list machines")
+	}
+
+	filter := And(filters...)
+	machines := []clusterv1.Machine{}
+	for i := range ml.Items {
+		if filter(&ml.Items[i]) {
+			machines = append(machines, ml.Items[i])
+		}
+	}
+	sort.Slice(machines, func(i, j int) bool {
+		if machines[i].Namespace != machines[j].Namespace {
+			return machines[i].Namespace < machines[j].Namespace
+		}
+		return machines[i].Name < machines[j].Name
+	})
+	return machines, nil
+}
+
+// FilterMachinesLimit returns at most limit machines matching all the given filters, preserving the order of the
+// input list; collecting stops as soon as limit matches are found, so only the machines actually required are evaluated.
+// This is intended for selecting e.g. the N oldest outdated machines, in which case machines must be sorted before
+// calling FilterMachinesLimit, e.g. with Machines.SortedByCreationTimestamp.
+// NOTE: A limit lower or equal to zero returns no machines.
+func FilterMachinesLimit(machines []*clusterv1.Machine, limit int, filters ...Func) []*clusterv1.Machine {
+	filtered := []*clusterv1.Machine{}
+	if limit <= 0 {
+		return filtered
+	}
+	filter := And(filters...)
+	for _, machine := range machines {
+		if !filter(machine) {
+			continue
+		}
+		filtered = append(filtered, machine)
+		if len(filtered) == limit {
+			break
+		}
+	}
+	return filtered
+}
+
+// DescribeFilterResults returns the names of the machines matched by each of the named filters, preserving the order
+// of the input list; filters matching no machines are reported with an empty list.
+// This is intended as a diagnostic aid, e.g. for asserting or logging which machines are considered outdated or
+// owned by the control plane while developing or debugging reconcile logic.
+func DescribeFilterResults(machines []*clusterv1.Machine, named map[string]Func) map[string][]string {
+	results := make(map[string][]string, len(named))
+	for name, filter := range named {
+		matched := []string{}
+		for _, machine := range machines {
+			if filter(machine) {
+				matched = append(matched, machine.Name)
+			}
+		}
+		results[name] = matched
+	}
+	return results
+}
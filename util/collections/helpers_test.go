@@ -0,0 +1,231 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collections_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+)
+
+func TestGetMachinesByClusterNameAllNamespaces(t *testing.T) {
+	g := NewWithT(t)
+
+	machine := func(namespace, name, clusterName string) *clusterv1.Machine {
+		return &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+				Labels: map[string]string{
+					clusterv1.ClusterLabelName: clusterName,
+				},
+			},
+		}
+	}
+	c := fake.NewClientBuilder().WithObjects(
+		machine("ns2", "machine", "my-cluster"),
+		machine("ns1", "machine", "my-cluster"),
+		machine("ns1", "control-plane", "my-cluster"),
+		machine("ns1", "other-machine", "other-cluster"),
+	).Build()
+
+	machines, err := collections.GetMachinesByClusterNameAllNamespaces(ctx, c, "my-cluster")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machineKeys(machines)).To(Equal([]string{"ns1/control-plane", "ns1/machine", "ns2/machine"}))
+
+	// Filters are applied with AND logic.
+	inNamespace := func(m *clusterv1.Machine) bool { return m.Namespace == "ns1" }
+	notControlPlane := func(m *clusterv1.Machine) bool { return m.Name != "control-plane" }
+	machines, err = collections.GetMachinesByClusterNameAllNamespaces(ctx, c, "my-cluster", inNamespace, notControlPlane)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machineKeys(machines)).To(Equal([]string{"ns1/machine"}))
+
+	machines, err = collections.GetMachinesByClusterNameAllNamespaces(ctx, c, "missing-cluster")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machines).To(BeEmpty())
+}
+
+func TestGetUnlabeledMachinesForCluster(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "my-cluster"}}
+	ownerRef := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: clusterv1.GroupVersion.String(), Kind: kind, Name: name}}
+	}
+	machineDeployment := &clusterv1.MachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "md", OwnerReferences: ownerRef("Cluster", "my-cluster")},
+	}
+	machineSet := &clusterv1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "ms", OwnerReferences: ownerRef("MachineDeployment", "md")},
+	}
+	machine := func(name string, labels map[string]string, ownerReferences []metav1.OwnerReference) *clusterv1.Machine {
+		return &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       metav1.NamespaceDefault,
+				Name:            name,
+				Labels:          labels,
+				OwnerReferences: ownerReferences,
+			},
+		}
+	}
+	c := &countingReader{Reader: fake.NewClientBuilder().WithObjects(
+		machineDeployment,
+		machineSet,
+		machine("labeled", map[string]string{clusterv1.ClusterLabelName: "my-cluster"}, ownerRef("MachineSet", "ms")),
+		machine("owned-by-cluster", nil, ownerRef("Cluster", "my-cluster")),
+		machine("owned-via-machineset", nil, ownerRef("MachineSet", "ms")),
+		machine("also-owned-via-machineset", nil, ownerRef("MachineSet", "ms")),
+		machine("owned-by-other-cluster", nil, ownerRef("Cluster", "other-cluster")),
+		machine("owner-not-found", nil, ownerRef("MachineSet", "does-not-exist")),
+		machine("not-owned", nil, nil),
+	).Build(), gets: map[string]int{}}
+
+	// Only the unlabeled machines whose owner chain resolves to the cluster are returned.
+	machines, err := collections.GetUnlabeledMachinesForCluster(ctx, c, cluster)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machines.Names()).To(ConsistOf("owned-by-cluster", "owned-via-machineset", "also-owned-via-machineset"))
+
+	// Owners shared by many machines are read only once.
+	g.Expect(c.gets).To(Equal(map[string]int{"ms": 1, "md": 1, "does-not-exist": 1}))
+}
+
+// countingReader counts the Get calls per object name.
+type countingReader struct {
+	client.Reader
+	gets map[string]int
+}
+
+func (r *countingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	r.gets[key.Name]++
+	return r.Reader.Get(ctx, key, obj)
+}
+
+func TestFilterMachinesLimit(t *testing.T) {
+	machines := []*clusterv1.Machine{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "outdated-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "up-to-date-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "outdated-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "outdated-3"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "up-to-date-2"}},
+	}
+
+	evaluated := 0
+	outdated := func(m *clusterv1.Machine) bool {
+		evaluated++
+		return strings.HasPrefix(m.Name, "outdated")
+	}
+
+	tests := []struct {
+		name              string
+		limit             int
+		filters           []collections.Func
+		expectedMachines  []string
+		expectedEvaluated int
+	}{
+		{
+			name:              "limit larger than the match count returns all the matches",
+			limit:             5,
+			filters:           []collections.Func{outdated},
+			expectedMachines:  []string{"ns1/outdated-1", "ns1/outdated-2", "ns1/outdated-3"},
+			expectedEvaluated: 5,
+		},
+		{
+			name:              "limit equal to the match count returns all the matches",
+			limit:             3,
+			filters:           []collections.Func{outdated},
+			expectedMachines:  []string{"ns1/outdated-1", "ns1/outdated-2", "ns1/outdated-3"},
+			expectedEvaluated: 4,
+		},
+		{
+			name:              "limit smaller than the match count stops at the first matches",
+			limit:             2,
+			filters:           []collections.Func{outdated},
+			expectedMachines:  []string{"ns1/outdated-1", "ns1/outdated-2"},
+			expectedEvaluated: 3,
+		},
+		{
+			name:              "no filters matches all the machines",
+			limit:             2,
+			expectedMachines:  []string{"ns1/outdated-1", "ns1/up-to-date-1"},
+			expectedEvaluated: 0,
+		},
+		{
+			name:              "zero limit returns no machines",
+			limit:             0,
+			filters:           []collections.Func{outdated},
+			expectedMachines:  []string{},
+			expectedEvaluated: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			evaluated = 0
+			machines := collections.FilterMachinesLimit(machines, tt.limit, tt.filters...)
+			keys := []string{}
+			for _, m := range machines {
+				keys = append(keys, m.Namespace+"/"+m.Name)
+			}
+			g.Expect(keys).To(Equal(tt.expectedMachines))
+			g.Expect(evaluated).To(Equal(tt.expectedEvaluated))
+		})
+	}
+}
+
+func machineKeys(machines []clusterv1.Machine) []string {
+	keys := make([]string, 0, len(machines))
+	for _, m := range machines {
+		keys = append(keys, m.Namespace+"/"+m.Name)
+	}
+	return keys
+}
+
+func TestDescribeFilterResults(t *testing.T) {
+	g := NewWithT(t)
+
+	machines := []*clusterv1.Machine{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cp-outdated", Labels: map[string]string{clusterv1.MachineControlPlaneLabelName: ""}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "worker-outdated"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cp-up-to-date", Labels: map[string]string{clusterv1.MachineControlPlaneLabelName: ""}}},
+	}
+
+	results := collections.DescribeFilterResults(machines, map[string]collections.Func{
+		"outdated": func(m *clusterv1.Machine) bool { return strings.HasSuffix(m.Name, "-outdated") },
+		"controlPlane": func(m *clusterv1.Machine) bool {
+			_, ok := m.Labels[clusterv1.MachineControlPlaneLabelName]
+			return ok
+		},
+		"none": func(m *clusterv1.Machine) bool { return false },
+	})
+	g.Expect(results).To(Equal(map[string][]string{
+		"outdated":     {"cp-outdated", "worker-outdated"},
+		"controlPlane": {"cp-outdated", "cp-up-to-date"},
+		"none":         {},
+	}))
+
+	// No filters, no results.
+	g.Expect(collections.DescribeFilterResults(machines, nil)).To(BeEmpty())
+}
@@ -38,6 +38,8 @@ import (
 )
 
 // Machines is a set of Machines.
+// NOTE: Machines has no ordering; use SortedByCreationTimestamp, which uses names as a tie breaker, or Names
+// when a deterministic order is required, e.g. when picking a machine to act on.
 type Machines map[string]*clusterv1.Machine
 
 // MachinesByVersion sorts the list of Machine by spec.version, using their names as tie breaker.
@@ -89,10 +91,10 @@ func FromMachineList(machineList *clusterv1.MachineList) Machines {
 	return ss
 }
 
-// ToMachineList creates a MachineList from the given Machines.
+// ToMachineList creates a MachineList from the given Machines, sorted by creation timestamp and name.
 func ToMachineList(machines Machines) clusterv1.MachineList {
 	ml := clusterv1.MachineList{}
-	for _, m := range machines {
+	for _, m := range machines.SortedByCreationTimestamp() {
 		ml.Items = append(ml.Items, *m)
 	}
 	return ml
@@ -197,13 +199,14 @@ func (s Machines) ConditionGetters() []conditions.Getter {
 	return res
 }
 
-// Names returns a slice of the names of each machine in the collection.
+// Names returns a sorted slice of the names of each machine in the collection.
 // Useful for logging and test assertions.
 func (s Machines) Names() []string {
 	names := make([]string, 0, s.Len())
 	for _, m := range s {
 		names = append(names, m.Name)
 	}
+	sort.Strings(names)
 	return names
 }
 
@@ -58,6 +58,24 @@ func TestMachineCollection(t *testing.T) {
 			g.Expect(collections.New().Names()).To(BeEmpty())
 			g.Expect(collections.FromMachines(machine("1"), machine("2")).Names()).To(ConsistOf("1", "2"))
 		})
+		t.Run("should return the names sorted", func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(collections.FromMachines(machine("b"), machine("c"), machine("a")).Names()).To(Equal([]string{"a", "b", "c"}))
+		})
+	})
+	t.Run("ToMachineList", func(t *testing.T) {
+		t.Run("should return the machines sorted by creation timestamp and name", func(t *testing.T) {
+			g := NewWithT(t)
+			collection := machines()
+			// machine-0 has the same creation timestamp as machine-1, so the name is used as a tie breaker.
+			collection.Insert(machine("machine-0", withCreationTimestamp(metav1.Time{Time: time.Date(2018, 01, 02, 03, 04, 05, 06, time.UTC)})))
+			ml := collections.ToMachineList(collection)
+			names := []string{}
+			for _, m := range ml.Items {
+				names = append(names, m.Name)
+			}
+			g.Expect(names).To(Equal([]string{"machine-0", "machine-1", "machine-2", "machine-3", "machine-4", "machine-5"}))
+		})
 	})
 }
 
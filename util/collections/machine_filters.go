@@ -17,7 +17,10 @@ limitations under the License.
 package collections
 
 import (
+	"time"
+
 	"github.com/blang/semver"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
@@ -29,7 +32,15 @@ import (
 	"sigs.k8s.io/cluster-api/util/conditions"
 )
 
-// Func is the functon definition for a filter.
+// Func is the function definition for a filter.
+//
+// Filters can be stored and passed as values, and composed using And, Or and Not, e.g.
+//
+//	upToDate := collections.And(collections.ActiveMachines, collections.MatchesKubernetesVersion(version))
+//	machines.Filter(collections.Not(upToDate))
+//
+// When passing multiple filters to functions like Machines.Filter or GetFilteredMachinesForCluster,
+// they are combined with AND logic.
 type Func func(machine *clusterv1.Machine) bool
 
 // And returns a filter that returns true if all of the given filters returns true.
@@ -177,6 +188,17 @@ func ShouldRolloutAfter(reconciliationTime, rolloutAfter *metav1.Time) Func {
 	}
 }
 
+// ProvisioningTimedOut returns a filter to find all machines which don't have a node yet, and that have been
+// created more than timeout before now.
+func ProvisioningTimedOut(timeout time.Duration, now time.Time) Func {
+	return func(machine *clusterv1.Machine) bool {
+		if machine == nil || machine.Status.NodeRef != nil {
+			return false
+		}
+		return machine.CreationTimestamp.Add(timeout).Before(now)
+	}
+}
+
 // HasAnnotationKey returns a filter to find all machines that have the
 // specified Annotation key present.
 func HasAnnotationKey(key string) Func {
@@ -191,6 +213,20 @@ func HasAnnotationKey(key string) Func {
 	}
 }
 
+// AnnotationEquals returns a filter to find all machines that have the
+// specified Annotation key with the given value.
+func AnnotationEquals(key, value string) Func {
+	return func(machine *clusterv1.Machine) bool {
+		if machine == nil || machine.Annotations == nil {
+			return false
+		}
+		if v, ok := machine.Annotations[key]; ok && v == value {
+			return true
+		}
+		return false
+	}
+}
+
 // ControlPlaneSelectorForCluster returns the label selector necessary to get control plane machines for a given cluster.
 func ControlPlaneSelectorForCluster(clusterName string) labels.Selector {
 	must := func(r *labels.Requirement, err error) labels.Requirement {
@@ -244,3 +280,56 @@ func HealthyAPIServer() Func {
 		return conditions.IsTrue(machine, controlplanev1.MachineAPIServerPodHealthyCondition)
 	}
 }
+
+// HasCordonedAndDrainedNode returns a filter to find all machines whose node is cordoned and drained,
+// given a prefetched list of the nodes and of the pods of the workload cluster.
+// See IsNodeCordonedAndDrained for details about when a node is considered drained.
+func HasCordonedAndDrainedNode(nodes []corev1.Node, pods []corev1.Pod) Func {
+	nodesByName := make(map[string]*corev1.Node, len(nodes))
+	for i := range nodes {
+		nodesByName[nodes[i].Name] = &nodes[i]
+	}
+	return func(machine *clusterv1.Machine) bool {
+		if machine == nil || machine.Status.NodeRef == nil {
+			return false
+		}
+		node, ok := nodesByName[machine.Status.NodeRef.Name]
+		if !ok {
+			return false
+		}
+		return IsNodeCordonedAndDrained(node, pods)
+	}
+}
+
+// IsNodeCordonedAndDrained returns true if the node is unschedulable and none of the given pods
+// is still running on it, ignoring pods that are not evicted during drain, see IsPodEvictedOnDrain.
+func IsNodeCordonedAndDrained(node *corev1.Node, pods []corev1.Pod) bool {
+	if node == nil || !node.Spec.Unschedulable {
+		return false
+	}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName != node.Name {
+			continue
+		}
+		if IsPodEvictedOnDrain(pod) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPodEvictedOnDrain returns true if the pod is evicted when draining its node, i.e. unless the pod
+// is owned by a DaemonSet, it is a mirror pod or it already terminated.
+func IsPodEvictedOnDrain(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	if controllerRef := metav1.GetControllerOf(pod); controllerRef != nil && controllerRef.Kind == "DaemonSet" {
+		return false
+	}
+	return true
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -165,6 +166,35 @@ func TestShouldRolloutAfter(t *testing.T) {
 	})
 }
 
+func TestProvisioningTimedOut(t *testing.T) {
+	now := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	newMachine := func(age time.Duration, nodeName string) *clusterv1.Machine {
+		m := &clusterv1.Machine{}
+		m.SetCreationTimestamp(metav1.NewTime(now.Add(-age)))
+		if nodeName != "" {
+			m.Status.NodeRef = &corev1.ObjectReference{Name: nodeName}
+		}
+		return m
+	}
+
+	t.Run("if the machine is nil it returns false", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(collections.ProvisioningTimedOut(time.Minute, now)(nil)).To(BeFalse())
+	})
+	t.Run("if the machine without a node is older than the timeout it returns true", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(collections.ProvisioningTimedOut(10*time.Minute, now)(newMachine(11*time.Minute, ""))).To(BeTrue())
+	})
+	t.Run("if the machine without a node is within the timeout it returns false", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(collections.ProvisioningTimedOut(10*time.Minute, now)(newMachine(9*time.Minute, ""))).To(BeFalse())
+	})
+	t.Run("if the machine has a node it returns false", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(collections.ProvisioningTimedOut(10*time.Minute, now)(newMachine(11*time.Minute, "node-1"))).To(BeFalse())
+	})
+}
+
 func TestHashAnnotationKey(t *testing.T) {
 	t.Run("machine with specified annotation returns true", func(t *testing.T) {
 		g := NewWithT(t)
@@ -185,6 +215,60 @@ func TestHashAnnotationKey(t *testing.T) {
 	})
 }
 
+func TestAnnotationEquals(t *testing.T) {
+	tests := []struct {
+		name        string
+		machine     *clusterv1.Machine
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name:     "nil machine returns false",
+			machine:  nil,
+			expected: false,
+		},
+		{
+			name:     "machine without annotations returns false",
+			machine:  &clusterv1.Machine{},
+			expected: false,
+		},
+		{
+			name:        "machine without specified annotation returns false",
+			machine:     &clusterv1.Machine{},
+			annotations: map[string]string{"foo": "1"},
+			expected:    false,
+		},
+		{
+			name:        "machine with specified annotation with a different value returns false",
+			machine:     &clusterv1.Machine{},
+			annotations: map[string]string{"test": "2"},
+			expected:    false,
+		},
+		{
+			name:        "machine with specified annotation with an empty value returns false",
+			machine:     &clusterv1.Machine{},
+			annotations: map[string]string{"test": ""},
+			expected:    false,
+		},
+		{
+			name:        "machine with specified annotation with the same value returns true",
+			machine:     &clusterv1.Machine{},
+			annotations: map[string]string{"test": "1", "foo": "2"},
+			expected:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			if tt.machine != nil {
+				tt.machine.SetAnnotations(tt.annotations)
+			}
+			g.Expect(collections.AnnotationEquals("test", "1")(tt.machine)).To(Equal(tt.expected))
+		})
+	}
+}
+
 func TestInFailureDomain(t *testing.T) {
 	t.Run("nil machine returns false", func(t *testing.T) {
 		g := NewWithT(t)
@@ -350,6 +434,152 @@ func TestHealtyAPIServer(t *testing.T) {
 	})
 }
 
+func TestHasCordonedAndDrainedNode(t *testing.T) {
+	cordonedNode := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "cordoned-node"},
+		Spec:       corev1.NodeSpec{Unschedulable: true},
+	}
+	schedulableNode := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "schedulable-node"},
+	}
+	machineWithNode := func(nodeName string) *clusterv1.Machine {
+		return &clusterv1.Machine{
+			Status: clusterv1.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: nodeName},
+			},
+		}
+	}
+	podOnNode := func(name, nodeName string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	daemonSetPod := podOnNode("daemonset-pod", cordonedNode.Name)
+	daemonSetPod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds", Controller: pointer.Bool(true)}}
+	mirrorPod := podOnNode("mirror-pod", cordonedNode.Name)
+	mirrorPod.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: ""}
+	completedPod := podOnNode("completed-pod", cordonedNode.Name)
+	completedPod.Status.Phase = corev1.PodSucceeded
+	replicaSetPod := podOnNode("replicaset-pod", cordonedNode.Name)
+	replicaSetPod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "rs", Controller: pointer.Bool(true)}}
+
+	nodes := []corev1.Node{cordonedNode, schedulableNode}
+
+	tests := []struct {
+		name     string
+		machine  *clusterv1.Machine
+		pods     []corev1.Pod
+		expected bool
+	}{
+		{
+			name:     "nil machine returns false",
+			machine:  nil,
+			expected: false,
+		},
+		{
+			name:     "machine without a node returns false",
+			machine:  &clusterv1.Machine{},
+			expected: false,
+		},
+		{
+			name:     "machine with a node not in the list returns false",
+			machine:  machineWithNode("unknown-node"),
+			expected: false,
+		},
+		{
+			name:     "machine with a schedulable node returns false",
+			machine:  machineWithNode(schedulableNode.Name),
+			expected: false,
+		},
+		{
+			name:     "machine with a cordoned node without pods returns true",
+			machine:  machineWithNode(cordonedNode.Name),
+			expected: true,
+		},
+		{
+			name:     "machine with a cordoned node with only pods ignored by drain returns true",
+			machine:  machineWithNode(cordonedNode.Name),
+			pods:     []corev1.Pod{daemonSetPod, mirrorPod, completedPod, podOnNode("other-pod", schedulableNode.Name)},
+			expected: true,
+		},
+		{
+			name:     "machine with a cordoned node with remaining workload returns false",
+			machine:  machineWithNode(cordonedNode.Name),
+			pods:     []corev1.Pod{daemonSetPod, replicaSetPod},
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(collections.HasCordonedAndDrainedNode(nodes, tt.pods)(tt.machine)).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestIsPodEvictedOnDrain(t *testing.T) {
+	newPod := func(modify func(*corev1.Pod)) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		modify(pod)
+		return pod
+	}
+
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected bool
+	}{
+		{
+			name:     "running pod is evicted",
+			pod:      newPod(func(*corev1.Pod) {}),
+			expected: true,
+		},
+		{
+			name: "pod owned by a ReplicaSet is evicted",
+			pod: newPod(func(p *corev1.Pod) {
+				p.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "rs", Controller: pointer.Bool(true)}}
+			}),
+			expected: true,
+		},
+		{
+			name: "pod owned by a DaemonSet is not evicted",
+			pod: newPod(func(p *corev1.Pod) {
+				p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds", Controller: pointer.Bool(true)}}
+			}),
+			expected: false,
+		},
+		{
+			name: "mirror pod is not evicted",
+			pod: newPod(func(p *corev1.Pod) {
+				p.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: ""}
+			}),
+			expected: false,
+		},
+		{
+			name:     "succeeded pod is not evicted",
+			pod:      newPod(func(p *corev1.Pod) { p.Status.Phase = corev1.PodSucceeded }),
+			expected: false,
+		},
+		{
+			name:     "failed pod is not evicted",
+			pod:      newPod(func(p *corev1.Pod) { p.Status.Phase = corev1.PodFailed }),
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(collections.IsPodEvictedOnDrain(tt.pod)).To(Equal(tt.expected))
+		})
+	}
+}
+
 func TestGetFilteredMachinesForCluster(t *testing.T) {
 	g := NewWithT(t)
 